@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/storage"
+	"ride-sharing/shared/util"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultAttachmentMaxBytes = 5 << 20
+	defaultAttachmentTypes    = "image/jpeg,image/png,image/webp"
+	defaultAttachmentURLTTL   = 15 * time.Minute
+	// multipartOverhead is what the form around the file may add to the body.
+	multipartOverhead = 64 << 10
+)
+
+// attachmentHandler serves chat attachments: participants upload them and
+// get signed, expiring download URLs issued to them alone. Downloads need no
+// JWT, so the URLs work in <img> tags, but each one is checked against the
+// trip chat pair again, so it stops working once its user leaves the chat.
+type attachmentHandler struct {
+	store        storage.Store
+	signer       *storage.URLSigner
+	rdb          redis.Cmdable
+	maxBytes     int64
+	allowedTypes []string
+	urlTTL       time.Duration
+}
+
+func newAttachmentHandler(store storage.Store, rdb redis.Cmdable) *attachmentHandler {
+	h := &attachmentHandler{
+		store:    store,
+		signer:   storage.NewURLSigner([]byte(env.GetString("CHAT_ATTACHMENT_SECRET", "change-me-in-production"))),
+		rdb:      rdb,
+		maxBytes: int64(env.GetInt("CHAT_ATTACHMENT_MAX_BYTES", defaultAttachmentMaxBytes)),
+		urlTTL:   defaultAttachmentURLTTL,
+	}
+	for _, t := range strings.Split(env.GetString("CHAT_ATTACHMENT_TYPES", defaultAttachmentTypes), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			h.allowedTypes = append(h.allowedTypes, t)
+		}
+	}
+	if d, err := time.ParseDuration(env.GetString("CHAT_ATTACHMENT_URL_TTL", defaultAttachmentURLTTL.String())); err == nil && d > 0 {
+		h.urlTTL = d
+	}
+	return h
+}
+
+func attachmentKey(tripID, attachmentID string) string {
+	return "chat/" + tripID + "/" + attachmentID
+}
+
+// HandleUpload stores the multipart "file" of POST /chat/attachments?tripID=…
+// and returns its ID and a download URL. The content type is sniffed from the
+// file itself; the one the client declares is ignored.
+func (h *attachmentHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handleChatAttachmentUpload")
+	defer span.End()
+
+	userID, ok := r.Context().Value(ctxKeyUserID).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	tripID := r.URL.Query().Get("tripID")
+	if !h.authorize(w, r, tripID, userID) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBytes+multipartOverhead)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			util.RespondWithError(w, http.StatusRequestEntityTooLarge, h.tooLargeMessage(), nil)
+			return
+		}
+		util.RespondWithError(w, http.StatusBadRequest, "Expected a multipart form with a file field", nil)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, h.maxBytes+1))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Failed to read file", nil)
+		return
+	}
+	if int64(len(data)) > h.maxBytes {
+		util.RespondWithError(w, http.StatusRequestEntityTooLarge, h.tooLargeMessage(), nil)
+		return
+	}
+	if len(data) == 0 {
+		util.RespondWithError(w, http.StatusBadRequest, "File is empty", nil)
+		return
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	if !slices.Contains(h.allowedTypes, contentType) {
+		util.RespondWithError(w, http.StatusUnsupportedMediaType,
+			fmt.Sprintf("Files of type %s are not allowed; allowed types are %s", contentType, strings.Join(h.allowedTypes, ", ")), nil)
+		return
+	}
+
+	attachmentID := uuid.NewString()
+	obj := storage.Object{Key: attachmentKey(tripID, attachmentID), ContentType: contentType, Size: int64(len(data))}
+	if err := h.store.Put(ctx, obj, bytes.NewReader(data)); err != nil {
+		log.Printf("HandleChatAttachmentUpload: %v", err)
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to store attachment", nil)
+		return
+	}
+
+	downloadURL, expiresAt := h.signedURL(tripID, attachmentID, userID)
+	util.RespondWithSuccess(w, http.StatusCreated, "Attachment uploaded", ChatAttachmentResponse{
+		AttachmentID: attachmentID,
+		TripID:       tripID,
+		ContentType:  contentType,
+		Size:         obj.Size,
+		URL:          downloadURL,
+		ExpiresAt:    expiresAt.Unix(),
+	})
+}
+
+// HandleURL issues a fresh download URL for an attachment of a trip chat,
+// e.g. for one received over the WebSocket or listed in the history:
+// GET /chat/attachments/url?tripID=…&attachmentID=…
+func (h *attachmentHandler) HandleURL(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "handleChatAttachmentURL")
+	defer span.End()
+
+	userID, ok := r.Context().Value(ctxKeyUserID).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	tripID, attachmentID := query.Get("tripID"), query.Get("attachmentID")
+	if _, err := uuid.Parse(attachmentID); err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid attachmentID", nil)
+		return
+	}
+	if !h.authorize(w, r, tripID, userID) {
+		return
+	}
+
+	downloadURL, expiresAt := h.signedURL(tripID, attachmentID, userID)
+	util.RespondWithSuccess(w, http.StatusOK, "Attachment URL", ChatAttachmentResponse{
+		AttachmentID: attachmentID,
+		TripID:       tripID,
+		URL:          downloadURL,
+		ExpiresAt:    expiresAt.Unix(),
+	})
+}
+
+// HandleDownload streams an attachment to the holder of a URL signed by
+// signedURL: GET /chat/attachments/{tripID}/{attachmentID}?user=…&expires=…&sig=…
+func (h *attachmentHandler) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handleChatAttachmentDownload")
+	defer span.End()
+
+	tripID, attachmentID := r.PathValue("tripID"), r.PathValue("attachmentID")
+	key := attachmentKey(tripID, attachmentID)
+	if storage.ValidateKey(key) != nil {
+		http.NotFound(w, r)
+		return
+	}
+	userID, err := h.signer.Verify(key, r.URL.Query())
+	switch {
+	case errors.Is(err, storage.ErrURLExpired):
+		util.RespondWithError(w, http.StatusGone, "Download URL expired", nil)
+		return
+	case err != nil:
+		util.RespondWithError(w, http.StatusForbidden, "Invalid download URL", nil)
+		return
+	}
+	if !h.authorize(w, r, tripID, userID) {
+		return
+	}
+
+	body, obj, err := h.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("HandleChatAttachmentDownload: %v", err)
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to load attachment", nil)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", obj.ContentType)
+	if obj.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(h.urlTTL.Seconds())))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("HandleChatAttachmentDownload: failed to send %s: %v", key, err)
+	}
+}
+
+// authorize checks that userID is the rider or driver of the trip's chat and
+// writes the error response if not.
+func (h *attachmentHandler) authorize(w http.ResponseWriter, r *http.Request, tripID, userID string) bool {
+	if tripID == "" || storage.ValidateKey(attachmentKey(tripID, "x")) != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid tripID", nil)
+		return false
+	}
+	riderID, driverID, err := messaging.LoadTripChatPair(r.Context(), h.rdb, tripID)
+	switch {
+	case errors.Is(err, messaging.ErrTripChatPairNotFound):
+		util.RespondWithError(w, http.StatusNotFound, "No chat for this trip", nil)
+		return false
+	case err != nil:
+		log.Printf("Failed to load chat pair of trip %s: %v", tripID, err)
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to check trip chat", nil)
+		return false
+	case userID != riderID && userID != driverID:
+		util.RespondWithError(w, http.StatusForbidden, "Not a participant of this trip chat", nil)
+		return false
+	}
+	return true
+}
+
+// signedURL returns the download path of an attachment for userID, relative
+// to api-gateway, and when it expires.
+func (h *attachmentHandler) signedURL(tripID, attachmentID, userID string) (string, time.Time) {
+	expiresAt := time.Now().Add(h.urlTTL).Truncate(time.Second)
+	query := h.signer.Sign(attachmentKey(tripID, attachmentID), userID, expiresAt)
+	return "/chat/attachments/" + url.PathEscape(tripID) + "/" + attachmentID + "?" + query.Encode(), expiresAt
+}
+
+func (h *attachmentHandler) tooLargeMessage() string {
+	return fmt.Sprintf("File is larger than %d KB", h.maxBytes>>10)
+}
@@ -7,4 +7,5 @@ import "ride-sharing/services/api-gateway/grpc_clients"
 var (
 	tripClient  *grpc_clients.TripServiceClient
 	loginClient *grpc_clients.LoginServiceClient
+	chatClient  *grpc_clients.ChatServiceClient
 )
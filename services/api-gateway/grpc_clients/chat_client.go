@@ -0,0 +1,45 @@
+package grpc_clients
+
+import (
+	"log"
+	"os"
+	pb "ride-sharing/shared/proto/chat"
+	"ride-sharing/shared/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type ChatServiceClient struct {
+	Client pb.ChatServiceClient // gRPC client for chat history
+	conn   *grpc.ClientConn     // Keep the connection to close it gracefully on shutdown
+}
+
+func NewChatServiceClient() (*ChatServiceClient, error) {
+	chatServiceURL := os.Getenv("CHAT_SERVICE_URL")
+	if chatServiceURL == "" {
+		chatServiceURL = "chat-service:9096"
+	}
+
+	dialOptions := append(
+		tracing.DialOptionsWithTracing(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+
+	conn, err := grpc.NewClient(chatServiceURL, dialOptions...)
+	if err != nil {
+		log.Println("failed to connect to chat service:", err)
+		return nil, err
+	}
+
+	client := pb.NewChatServiceClient(conn)
+	return &ChatServiceClient{Client: client, conn: conn}, nil
+}
+
+func (c *ChatServiceClient) Close() {
+	if c.conn != nil {
+		if err := c.conn.Close(); err != nil {
+			return
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/tracing"
@@ -18,6 +19,8 @@ import (
 	"github.com/stripe/stripe-go/v81/webhook"
 
 	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var tracer = tracing.GetTracer("api-gateway")
@@ -131,7 +134,137 @@ func HandleCancelTrip(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithSuccess(w, http.StatusOK, "Trip cancelled", nil)
 }
 
-func handleStripeWebhook(w http.ResponseWriter, r *http.Request, rb *messaging.RabbitMQ) {
+func HandleShareTrip(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handleShareTrip")
+	defer span.End()
+
+	var reqBody ShareTripRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, ok := r.Context().Value(ctxKeyUserID).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := types.Validate.Struct(reqBody); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		errors := make([]string, len(validationErrors))
+		for i, e := range validationErrors {
+			errors[i] = util.FormatValidationError(e)
+		}
+		util.RespondWithError(w, http.StatusBadRequest, "Validation failed", errors)
+		return
+	}
+
+	share, err := tripClient.Client.ShareTrip(ctx, reqBody.toProto(userID))
+	if err != nil {
+		log.Printf("HandleShareTrip: gRPC error: %v", err)
+		util.RespondWithError(w, grpcHTTPStatus(err), "Failed to share trip", nil)
+		return
+	}
+
+	util.RespondWithSuccess(w, http.StatusOK, "Trip shared", share)
+}
+
+func HandleRevokeTripShare(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handleRevokeTripShare")
+	defer span.End()
+
+	var reqBody RevokeTripShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, ok := r.Context().Value(ctxKeyUserID).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := types.Validate.Struct(reqBody); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		errors := make([]string, len(validationErrors))
+		for i, e := range validationErrors {
+			errors[i] = util.FormatValidationError(e)
+		}
+		util.RespondWithError(w, http.StatusBadRequest, "Validation failed", errors)
+		return
+	}
+
+	if _, err := tripClient.Client.RevokeTripShare(ctx, reqBody.toProto(userID)); err != nil {
+		log.Printf("HandleRevokeTripShare: gRPC error: %v", err)
+		util.RespondWithError(w, grpcHTTPStatus(err), "Failed to revoke share", nil)
+		return
+	}
+
+	util.RespondWithSuccess(w, http.StatusOK, "Share revoked", nil)
+}
+
+func HandleChatHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "handleChatHistory")
+	defer span.End()
+
+	userID, ok := r.Context().Value(ctxKeyUserID).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	reqBody := ChatHistoryRequest{TripID: query.Get("tripID"), Before: query.Get("before")}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		reqBody.Limit = n
+	}
+
+	if err := types.Validate.Struct(reqBody); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		errors := make([]string, len(validationErrors))
+		for i, e := range validationErrors {
+			errors[i] = util.FormatValidationError(e)
+		}
+		util.RespondWithError(w, http.StatusBadRequest, "Validation failed", errors)
+		return
+	}
+
+	history, err := chatClient.Client.GetChatHistory(ctx, reqBody.toProto(userID))
+	if err != nil {
+		log.Printf("HandleChatHistory: gRPC error: %v", err)
+		util.RespondWithError(w, grpcHTTPStatus(err), "Failed to get chat history", nil)
+		return
+	}
+
+	util.RespondWithSuccess(w, http.StatusOK, "Chat history", history)
+}
+
+// grpcHTTPStatus maps a trip-service or chat-service error to an HTTP status.
+func grpcHTTPStatus(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.FailedPrecondition:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func handleStripeWebhook(w http.ResponseWriter, r *http.Request, rb messaging.Publisher) {
 	ctx, span := tracer.Start(r.Context(), "handleStripeWebhook")
 	defer span.End()
 	body, err := io.ReadAll(r.Body)
@@ -12,6 +12,7 @@ import (
 	"ride-sharing/services/api-gateway/grpc_clients"
 	"ride-sharing/shared/env"
 	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/storage"
 	"ride-sharing/shared/tracing"
 
 	"strings"
@@ -88,6 +89,18 @@ func main() {
 	}
 	defer loginClient.Close()
 
+	chatClient, err = grpc_clients.NewChatServiceClient()
+	if err != nil {
+		log.Fatalf("failed to create chat service client: %v", err)
+	}
+	defer chatClient.Close()
+
+	attachmentStore, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("failed to create attachment storage: %v", err)
+	}
+	attachments := newAttachmentHandler(attachmentStore, rdb)
+
 	mux := http.NewServeMux() // create a new ServeMux for routing
 
 	// Define a simple health check endpoint
@@ -118,6 +131,41 @@ func main() {
 		),
 	))
 
+	// Public share links of active trips (10 req/min per user)
+	mux.Handle("POST /trip/share", wsAuthMiddleware(
+		rateLimiter.Limit(10, 60, userKey("trip:share"))(
+			tracing.WrapHandlerFunc(HandleShareTrip, "/trip/share"),
+		),
+	))
+	mux.Handle("POST /trip/share/revoke", wsAuthMiddleware(
+		rateLimiter.Limit(10, 60, userKey("trip:share"))(
+			tracing.WrapHandlerFunc(HandleRevokeTripShare, "/trip/share/revoke"),
+		),
+	))
+
+	// Trip chat history, for clients that reconnect mid-trip (60 req/min per user)
+	mux.Handle("GET /chat/history", wsAuthMiddleware(
+		rateLimiter.Limit(60, 60, userKey("chat:history"))(
+			tracing.WrapHandlerFunc(HandleChatHistory, "/chat/history"),
+		),
+	))
+
+	// Trip chat attachments (20 uploads/min per user). Downloads are
+	// authorised by their signed URL instead of a JWT.
+	mux.Handle("POST /chat/attachments", wsAuthMiddleware(
+		rateLimiter.Limit(20, 60, userKey("chat:attachment:upload"))(
+			tracing.WrapHandlerFunc(attachments.HandleUpload, "/chat/attachments"),
+		),
+	))
+	mux.Handle("GET /chat/attachments/url", wsAuthMiddleware(
+		rateLimiter.Limit(120, 60, userKey("chat:attachment:url"))(
+			tracing.WrapHandlerFunc(attachments.HandleURL, "/chat/attachments/url"),
+		),
+	))
+	mux.Handle("GET /chat/attachments/{tripID}/{attachmentID}", rateLimiter.Limit(300, 60, ipKey("chat:attachment:download"))(
+		tracing.WrapHandlerFunc(attachments.HandleDownload, "/chat/attachments/{tripID}/{attachmentID}"),
+	))
+
 	// Auth routes
 	mux.Handle("POST /auth/signup", tracing.WrapHandlerFunc(HandleSignup, "/auth/signup"))
 	mux.Handle("POST /auth/login", tracing.WrapHandlerFunc(HandleLogin, "/auth/login"))
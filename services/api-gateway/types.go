@@ -1,6 +1,7 @@
 package main
 
 import (
+	chatpb "ride-sharing/shared/proto/chat"
 	pb "ride-sharing/shared/proto/trip"
 	"ride-sharing/shared/types"
 )
@@ -46,3 +47,56 @@ func (c *CancelTripRequest) toProto(userID string) *pb.CancelTripRequest {
 		UserID: userID,
 	}
 }
+
+type ShareTripRequest struct {
+	TripID     string `json:"tripID" validate:"required,min=1"`
+	TTLSeconds int64  `json:"ttlSeconds" validate:"min=0,max=43200"`
+}
+
+func (s *ShareTripRequest) toProto(userID string) *pb.ShareTripRequest {
+	return &pb.ShareTripRequest{
+		TripID:     s.TripID,
+		UserID:     userID,
+		TtlSeconds: s.TTLSeconds,
+	}
+}
+
+type RevokeTripShareRequest struct {
+	TripID  string `json:"tripID" validate:"required,min=1"`
+	ShareID string `json:"shareID" validate:"required,min=1"`
+}
+
+func (r *RevokeTripShareRequest) toProto(userID string) *pb.RevokeTripShareRequest {
+	return &pb.RevokeTripShareRequest{
+		TripID:  r.TripID,
+		ShareID: r.ShareID,
+		UserID:  userID,
+	}
+}
+
+// ChatHistoryRequest is read from the query string of GET /chat/history.
+type ChatHistoryRequest struct {
+	TripID string `validate:"required,min=1"`
+	Before string // nextCursor of the previous page
+	Limit  int    `validate:"min=0,max=100"`
+}
+
+func (c *ChatHistoryRequest) toProto(userID string) *chatpb.GetChatHistoryRequest {
+	return &chatpb.GetChatHistoryRequest{
+		TripID: c.TripID,
+		UserID: userID,
+		Limit:  int32(c.Limit),
+		Before: c.Before,
+	}
+}
+
+// ChatAttachmentResponse is returned by POST /chat/attachments and
+// GET /chat/attachments/url. URL is relative to api-gateway.
+type ChatAttachmentResponse struct {
+	AttachmentID string `json:"attachmentID"`
+	TripID       string `json:"tripID"`
+	ContentType  string `json:"contentType,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+	URL          string `json:"url"`
+	ExpiresAt    int64  `json:"expiresAt"` // unix seconds
+}
@@ -3,19 +3,29 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"ride-sharing/services/chat-service/internal/infrastructure/events"
+	"ride-sharing/services/chat-service/internal/infrastructure/grpc"
 	"ride-sharing/services/chat-service/internal/infrastructure/repository"
 	"ride-sharing/services/chat-service/internal/service"
 	"ride-sharing/shared/db"
 	"ride-sharing/shared/env"
 	"ride-sharing/shared/messaging"
 	"ride-sharing/shared/tracing"
+
+	pb "ride-sharing/shared/proto/trip"
+
+	"github.com/redis/go-redis/v9"
+	grpcserver "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
+const GrpcAddr = ":9096"
+
 func main() {
 	log.Println("Starting chat-service")
 
@@ -61,20 +71,58 @@ func main() {
 	defer rabbitmq.Close()
 	log.Println("Connected to RabbitMQ")
 
+	// Redis holds the trip chat pairs that decide who may read a chat.
+	rdb := redis.NewClient(&redis.Options{Addr: env.GetString("REDIS_URI", "redis:6379")})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer rdb.Close()
+	log.Println("Connected to Redis")
+
+	// trip-service answers for trips whose chat pair is gone from Redis.
+	tripConn, err := grpcserver.NewClient(env.GetString("TRIP_SERVICE_URL", "trip-service:9093"),
+		append(tracing.DialOptionsWithTracing(), grpcserver.WithTransportCredentials(insecure.NewCredentials()))...)
+	if err != nil {
+		log.Fatalf("Failed to create trip service client: %v", err)
+	}
+	defer tripConn.Close()
+
 	// Wire up layers.
 	repo := repository.NewMongoMessageRepository(database)
+	readStates := repository.NewMongoReadStateRepository(database)
+	participants := repository.NewRedisParticipantResolver(rdb, pb.NewTripServiceClient(tripConn))
 	publisher := events.NewPublisher(rabbitmq)
-	chatSvc := service.New(repo, publisher)
+	chatSvc := service.New(repo, readStates, participants, publisher)
 	consumer := events.NewConsumer(rabbitmq, chatSvc)
 
 	if err := consumer.Start(ctx); err != nil {
 		log.Fatalf("Failed to start chat consumer: %v", err)
 	}
 
+	lis, err := net.Listen("tcp", GrpcAddr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpcserver.NewServer(tracing.WithTracingInterceptors()...)
+	grpc.NewGRPCHandler(grpcServer, chatSvc)
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		log.Printf("Starting gRPC server Chat service on port %s", lis.Addr().String())
+		if err := grpcServer.Serve(lis); err != nil {
+			serverErrors <- err
+		}
+	}()
+
 	log.Println("chat-service running")
 
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
-	<-shutdown
+	select {
+	case err := <-serverErrors:
+		log.Fatalf("server error: %v", err)
+	case <-shutdown:
+	}
 	log.Println("chat-service shutting down")
+	grpcServer.GracefulStop()
 }
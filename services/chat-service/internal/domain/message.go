@@ -1,6 +1,21 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotParticipant is returned when a user asks for a trip chat they are
+	// not the rider or driver of.
+	ErrNotParticipant = errors.New("user is not part of the trip chat")
+	// ErrChatNotFound is returned when the trip does not exist or never had a
+	// driver.
+	ErrChatNotFound = errors.New("trip chat not found")
+	// ErrInvalidCursor is returned for a history cursor that was not issued by
+	// GetHistory.
+	ErrInvalidCursor = errors.New("invalid history cursor")
+)
 
 // Message represents a single chat message in the domain layer.
 type Message struct {
@@ -10,13 +25,59 @@ type Message struct {
 	Text      string
 	SentAt    int64
 	Delivered bool
+	// AttachmentID names a blob uploaded through api-gateway; Text may be
+	// empty when it is set.
+	AttachmentID string
+	// Flags are the moderation flags ws-gateway raised for the message.
+	Flags []string
+	// Blocked messages were never relayed to the peer and are kept only for
+	// support.
+	Blocked bool
+}
+
+// HistoryCursor marks where a history page ended: the next page holds the
+// messages sent before it.
+type HistoryCursor struct {
+	SentAt int64
+	ID     string
+}
+
+// ReadState is how far a participant has read a trip's chat.
+type ReadState struct {
+	TripID    string
+	UserID    string
+	MessageID string // last message read
+	SentAt    int64  // when that message was sent
+	ReadAt    int64
 }
 
 // MessageRepository defines the persistence contract for chat messages.
 type MessageRepository interface {
 	Save(ctx context.Context, msg *Message) error
-	GetByTripID(ctx context.Context, tripID string, limit int) ([]*Message, error)
+	// GetByTripID returns up to limit messages of a trip sent before before,
+	// or the newest ones when before is nil, newest first. Blocked messages
+	// are left out.
+	GetByTripID(ctx context.Context, tripID string, before *HistoryCursor, limit int) ([]*Message, error)
+	// GetByID returns a message of a trip, or nil if there is none.
+	GetByID(ctx context.Context, tripID, messageID string) (*Message, error)
 	MarkDelivered(ctx context.Context, messageID string) error
+	// MarkDeliveredUpTo marks the messages of a trip sent to recipientID
+	// until sentAt as delivered.
+	MarkDeliveredUpTo(ctx context.Context, tripID, recipientID string, sentAt int64) error
+}
+
+// ReadStateRepository stores how far each participant has read a trip's chat.
+type ReadStateRepository interface {
+	// MarkRead moves the participant's read state forward to state. A state
+	// older than the stored one is ignored.
+	MarkRead(ctx context.Context, state *ReadState) error
+	GetByTripID(ctx context.Context, tripID string) ([]*ReadState, error)
+}
+
+// ParticipantResolver returns the rider and driver allowed in a trip's chat,
+// or ErrChatNotFound.
+type ParticipantResolver interface {
+	Participants(ctx context.Context, tripID string) (riderID, driverID string, err error)
 }
 
 // MessagePublisher notifies downstream consumers that a message was persisted.
@@ -24,4 +85,6 @@ type MessageRepository interface {
 // without creating an import cycle with the infrastructure/events package.
 type MessagePublisher interface {
 	PublishDelivered(ctx context.Context, messageID, tripID string) error
+	// PublishFlagged raises a support event for a message moderation flagged.
+	PublishFlagged(ctx context.Context, msg *Message) error
 }
@@ -2,79 +2,79 @@ package events
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 
 	"ride-sharing/services/chat-service/internal/domain"
 	"ride-sharing/services/chat-service/internal/service"
-	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
 )
 
-// Consumer reads chat.cmd.send messages from RabbitMQ and delegates to ChatService.
+// Consumer reads chat.cmd.send and chat.cmd.read messages from RabbitMQ and
+// delegates to ChatService.
 type Consumer struct {
-	rb          *messaging.RabbitMQ
+	rb          messaging.Subscriber
 	chatService *service.ChatService
 }
 
-func NewConsumer(rb *messaging.RabbitMQ, chatService *service.ChatService) *Consumer {
+func NewConsumer(rb messaging.Subscriber, chatService *service.ChatService) *Consumer {
 	return &Consumer{rb: rb, chatService: chatService}
 }
 
-// Start launches a goroutine that processes incoming chat messages.
+// Start subscribes to the chat command queue. Failed messages are retried and
+// then dead-lettered like every other consumer instead of being requeued forever.
 func (c *Consumer) Start(ctx context.Context) error {
-	msgs, err := c.rb.Channel.Consume(
-		messaging.ChatCmdSendQueue,
-		"chat-service-consumer",
-		false, // manual ack for durability
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
+	if err := c.rb.ConsumeMessages(messaging.ChatCmdSendQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		if err := c.handleMessage(ctx, msg); err != nil {
+			log.Printf("chat-service: failed to handle message: %v", err)
+			return err
+		}
+		return nil
+	}); err != nil {
 		return err
 	}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case msg, ok := <-msgs:
-				if !ok {
-					return
-				}
-				if err := c.handleMessage(ctx, msg.Body); err != nil {
-					log.Printf("chat-service: failed to handle message: %v", err)
-					msg.Nack(false, true) // requeue on error
-					continue
-				}
-				msg.Ack(false)
-			}
+	if err := c.rb.ConsumeMessages(messaging.ChatCmdReadQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		if err := c.handleRead(ctx, msg); err != nil {
+			log.Printf("chat-service: failed to handle read receipt: %v", err)
+			return err
 		}
-	}()
+		return nil
+	}); err != nil {
+		return err
+	}
 
-	log.Println("chat-service: consumer started on", messaging.ChatCmdSendQueue)
+	log.Println("chat-service: consumer started on", messaging.ChatCmdSendQueue, "and", messaging.ChatCmdReadQueue)
 	return nil
 }
 
-func (c *Consumer) handleMessage(ctx context.Context, body []byte) error {
-	var amqpMsg contracts.AmqpMessage
-	if err := json.Unmarshal(body, &amqpMsg); err != nil {
-		return err
-	}
-
+func (c *Consumer) handleMessage(ctx context.Context, msg amqp091.Delivery) error {
 	var data messaging.ChatMessageData
-	if err := json.Unmarshal(amqpMsg.Data, &data); err != nil {
+	if _, err := messaging.DecodePayload(msg, &data); err != nil {
 		return err
 	}
 
 	return c.chatService.HandleIncoming(ctx, &domain.Message{
-		ID:       data.MessageID,
-		TripID:   data.TripID,
-		SenderID: data.SenderID,
-		Text:     data.Text,
-		SentAt:   data.SentAt,
+		ID:           data.MessageID,
+		TripID:       data.TripID,
+		SenderID:     data.SenderID,
+		Text:         data.Text,
+		AttachmentID: data.AttachmentID,
+		SentAt:       data.SentAt,
+		Flags:        data.Flags,
+		Blocked:      data.Blocked,
 	})
 }
+
+func (c *Consumer) handleRead(ctx context.Context, msg amqp091.Delivery) error {
+	var data messaging.ChatReadData
+	if _, err := messaging.DecodePayload(msg, &data); err != nil {
+		return err
+	}
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	return c.chatService.MarkRead(ctx, data.TripID, data.ReaderID, data.MessageID, data.ReadAt)
+}
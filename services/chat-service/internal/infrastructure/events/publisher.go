@@ -5,16 +5,17 @@ import (
 	"encoding/json"
 	"log"
 
+	"ride-sharing/services/chat-service/internal/domain"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
 )
 
 // Publisher publishes chat domain events to RabbitMQ.
 type Publisher struct {
-	rb *messaging.RabbitMQ
+	rb messaging.Publisher
 }
 
-func NewPublisher(rb *messaging.RabbitMQ) *Publisher {
+func NewPublisher(rb messaging.Publisher) *Publisher {
 	return &Publisher{rb: rb}
 }
 
@@ -34,3 +35,24 @@ func (p *Publisher) PublishDelivered(ctx context.Context, messageID, tripID stri
 		Data:    data,
 	})
 }
+
+// PublishFlagged raises trip.event.chat_flagged for support. It lands in the
+// trip timeline; the message text stays in chat-service.
+func (p *Publisher) PublishFlagged(ctx context.Context, msg *domain.Message) error {
+	data, err := json.Marshal(messaging.ChatFlaggedData{
+		TripID:    msg.TripID,
+		MessageID: msg.ID,
+		SenderID:  msg.SenderID,
+		Flags:     msg.Flags,
+		Blocked:   msg.Blocked,
+		SentAt:    msg.SentAt,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("chat-service: publishing flagged event for message %s: %v", msg.ID, msg.Flags)
+	return p.rb.PublishMessage(ctx, contracts.TripEventChatFlagged, contracts.AmqpMessage{
+		OwnerID: msg.TripID,
+		Data:    data,
+	})
+}
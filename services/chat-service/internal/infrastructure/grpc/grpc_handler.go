@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"ride-sharing/services/chat-service/internal/domain"
+	"ride-sharing/services/chat-service/internal/service"
+	pb "ride-sharing/shared/proto/chat"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type gRPCHandler struct {
+	pb.UnimplementedChatServiceServer
+	service *service.ChatService
+}
+
+func NewGRPCHandler(server *grpc.Server, chatService *service.ChatService) *gRPCHandler {
+	handler := &gRPCHandler{service: chatService}
+	pb.RegisterChatServiceServer(server, handler)
+	return handler
+}
+
+func (h *gRPCHandler) GetChatHistory(ctx context.Context, req *pb.GetChatHistoryRequest) (*pb.GetChatHistoryResponse, error) {
+	if req.GetTripID() == "" || req.GetUserID() == "" {
+		return nil, status.Error(codes.InvalidArgument, "tripID and userID are required")
+	}
+
+	page, err := h.service.GetHistory(ctx, req.GetTripID(), req.GetUserID(), req.GetBefore(), int(req.GetLimit()))
+	switch {
+	case errors.Is(err, domain.ErrInvalidCursor):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrNotParticipant):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, domain.ErrChatNotFound):
+		return nil, status.Errorf(codes.NotFound, "no chat for trip %s", req.GetTripID())
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to get chat history: %v", err)
+	}
+
+	resp := &pb.GetChatHistoryResponse{
+		Messages:   make([]*pb.ChatMessage, len(page.Messages)),
+		NextCursor: page.NextCursor,
+		ReadStates: make([]*pb.ChatReadState, len(page.ReadStates)),
+	}
+	for i, msg := range page.Messages {
+		resp.Messages[i] = &pb.ChatMessage{
+			Id:           msg.ID,
+			TripID:       msg.TripID,
+			SenderID:     msg.SenderID,
+			Text:         msg.Text,
+			SentAt:       msg.SentAt,
+			Delivered:    msg.Delivered,
+			Flags:        msg.Flags,
+			AttachmentID: msg.AttachmentID,
+		}
+	}
+	for i, state := range page.ReadStates {
+		resp.ReadStates[i] = &pb.ChatReadState{
+			UserID:    state.UserID,
+			MessageID: state.MessageID,
+			SentAt:    state.SentAt,
+			ReadAt:    state.ReadAt,
+		}
+	}
+	return resp, nil
+}
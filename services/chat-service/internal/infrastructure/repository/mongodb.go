@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"ride-sharing/services/chat-service/internal/domain"
@@ -50,6 +51,15 @@ func (r *MongoMessageRepository) Save(ctx context.Context, msg *domain.Message)
 		"sentAt":    msg.SentAt,
 		"delivered": msg.Delivered,
 	}
+	if msg.AttachmentID != "" {
+		doc["attachmentID"] = msg.AttachmentID
+	}
+	if len(msg.Flags) > 0 {
+		doc["flags"] = msg.Flags
+	}
+	if msg.Blocked {
+		doc["blocked"] = true
+	}
 	return tracing.RunInSpan(ctx, "db", "mongodb.chat_messages.insert", tracing.DBSpanAttrs("mongodb",
 		attribute.String("db.collection", collectionName),
 		attribute.String("trip.id", msg.TripID),
@@ -59,51 +69,91 @@ func (r *MongoMessageRepository) Save(ctx context.Context, msg *domain.Message)
 	})
 }
 
-func (r *MongoMessageRepository) GetByTripID(ctx context.Context, tripID string, limit int) ([]*domain.Message, error) {
+// messageDoc is a chat message as stored in MongoDB.
+type messageDoc struct {
+	ID           string   `bson:"_id"`
+	TripID       string   `bson:"tripID"`
+	SenderID     string   `bson:"senderID"`
+	Text         string   `bson:"text"`
+	SentAt       int64    `bson:"sentAt"`
+	Delivered    bool     `bson:"delivered"`
+	AttachmentID string   `bson:"attachmentID,omitempty"`
+	Flags        []string `bson:"flags,omitempty"`
+	Blocked      bool     `bson:"blocked,omitempty"`
+}
+
+func (d *messageDoc) toDomain() *domain.Message {
+	return &domain.Message{
+		ID:           d.ID,
+		TripID:       d.TripID,
+		SenderID:     d.SenderID,
+		Text:         d.Text,
+		SentAt:       d.SentAt,
+		Delivered:    d.Delivered,
+		AttachmentID: d.AttachmentID,
+		Flags:        d.Flags,
+		Blocked:      d.Blocked,
+	}
+}
+
+func (r *MongoMessageRepository) GetByTripID(ctx context.Context, tripID string, before *domain.HistoryCursor, limit int) ([]*domain.Message, error) {
 	if limit <= 0 {
 		limit = 50
 	}
+	// Messages sent in the same second are ordered by ID, so a page boundary
+	// never skips or repeats one.
 	opts := options.Find().
-		SetSort(bson.D{{Key: "sentAt", Value: -1}}).
+		SetSort(bson.D{{Key: "sentAt", Value: -1}, {Key: "_id", Value: -1}}).
 		SetLimit(int64(limit))
+	filter := bson.M{"tripID": tripID, "blocked": bson.M{"$ne": true}}
+	if before != nil {
+		filter["$or"] = bson.A{
+			bson.M{"sentAt": bson.M{"$lt": before.SentAt}},
+			bson.M{"sentAt": before.SentAt, "_id": bson.M{"$lt": before.ID}},
+		}
+	}
 
 	var msgs []*domain.Message
 	err := tracing.RunInSpan(ctx, "db", "mongodb.chat_messages.find_by_trip", tracing.DBSpanAttrs("mongodb",
 		attribute.String("db.collection", collectionName),
 		attribute.String("trip.id", tripID),
 	), func(ctx context.Context, _ trace.Span) error {
-		cursor, err := r.col.Find(ctx, bson.M{"tripID": tripID}, opts)
+		cursor, err := r.col.Find(ctx, filter, opts)
 		if err != nil {
 			return err
 		}
 		defer cursor.Close(ctx)
 
 		for cursor.Next(ctx) {
-			var doc struct {
-				ID        string `bson:"_id"`
-				TripID    string `bson:"tripID"`
-				SenderID  string `bson:"senderID"`
-				Text      string `bson:"text"`
-				SentAt    int64  `bson:"sentAt"`
-				Delivered bool   `bson:"delivered"`
-			}
+			var doc messageDoc
 			if err := cursor.Decode(&doc); err != nil {
 				continue
 			}
-			msgs = append(msgs, &domain.Message{
-				ID:        doc.ID,
-				TripID:    doc.TripID,
-				SenderID:  doc.SenderID,
-				Text:      doc.Text,
-				SentAt:    doc.SentAt,
-				Delivered: doc.Delivered,
-			})
+			msgs = append(msgs, doc.toDomain())
 		}
 		return cursor.Err()
 	})
 	return msgs, err
 }
 
+func (r *MongoMessageRepository) GetByID(ctx context.Context, tripID, messageID string) (*domain.Message, error) {
+	var doc messageDoc
+	err := tracing.RunInSpan(ctx, "db", "mongodb.chat_messages.find_one", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", collectionName),
+		attribute.String("trip.id", tripID),
+		attribute.String("message.id", messageID),
+	), func(ctx context.Context, _ trace.Span) error {
+		return r.col.FindOne(ctx, bson.M{"_id": messageID, "tripID": tripID}).Decode(&doc)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toDomain(), nil
+}
+
 func (r *MongoMessageRepository) MarkDelivered(ctx context.Context, messageID string) error {
 	return tracing.RunInSpan(ctx, "db", "mongodb.chat_messages.update_one", tracing.DBSpanAttrs("mongodb",
 		attribute.String("db.collection", collectionName),
@@ -117,3 +167,22 @@ func (r *MongoMessageRepository) MarkDelivered(ctx context.Context, messageID st
 		return err
 	})
 }
+
+func (r *MongoMessageRepository) MarkDeliveredUpTo(ctx context.Context, tripID, recipientID string, sentAt int64) error {
+	return tracing.RunInSpan(ctx, "db", "mongodb.chat_messages.update_many", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", collectionName),
+		attribute.String("trip.id", tripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		_, err := r.col.UpdateMany(
+			ctx,
+			bson.M{
+				"tripID":    tripID,
+				"senderID":  bson.M{"$ne": recipientID},
+				"sentAt":    bson.M{"$lte": sentAt},
+				"delivered": false,
+			},
+			bson.M{"$set": bson.M{"delivered": true}},
+		)
+		return err
+	})
+}
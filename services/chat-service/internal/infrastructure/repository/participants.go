@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"ride-sharing/services/chat-service/internal/domain"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/trip"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RedisParticipantResolver implements domain.ParticipantResolver with the trip
+// chat pair ws-gateway and driver-service keep in Redis, so chat-service lets
+// in exactly the users the gateway relays chat for. Once the pair is gone,
+// because it expired or the driver was released, it asks trip-service, so
+// participants keep access to the history after the trip.
+type RedisParticipantResolver struct {
+	rdb   *redis.Client
+	trips pb.TripServiceClient
+}
+
+func NewRedisParticipantResolver(rdb *redis.Client, trips pb.TripServiceClient) *RedisParticipantResolver {
+	return &RedisParticipantResolver{rdb: rdb, trips: trips}
+}
+
+func (r *RedisParticipantResolver) Participants(ctx context.Context, tripID string) (string, string, error) {
+	riderID, driverID, err := messaging.LoadTripChatPair(ctx, r.rdb, tripID)
+	if err == nil {
+		return riderID, driverID, nil
+	}
+	if !errors.Is(err, messaging.ErrTripChatPairNotFound) {
+		return "", "", messaging.Transient("redis", err)
+	}
+
+	resp, err := r.trips.GetTrip(ctx, &pb.GetTripRequest{TripID: tripID})
+	if status.Code(err) == codes.NotFound {
+		return "", "", domain.ErrChatNotFound
+	}
+	if err != nil {
+		return "", "", messaging.Transient("trip-service", err)
+	}
+	trip := resp.GetTrip()
+	if trip.GetDriver().GetId() == "" {
+		// No driver ever accepted, so there was never a chat.
+		return "", "", domain.ErrChatNotFound
+	}
+	return trip.GetUserID(), trip.GetDriver().GetId(), nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"ride-sharing/services/chat-service/internal/domain"
+	"ride-sharing/shared/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const readStateCollectionName = "chat_read_states"
+
+// MongoReadStateRepository implements domain.ReadStateRepository using
+// MongoDB, with one document per trip and participant.
+type MongoReadStateRepository struct {
+	col *mongo.Collection
+}
+
+func NewMongoReadStateRepository(db *mongo.Database) *MongoReadStateRepository {
+	col := db.Collection(readStateCollectionName)
+
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "tripID", Value: 1}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := col.Indexes().CreateOne(ctx, indexModel); err != nil {
+		// Log but do not fatal — index creation may fail on read-only replicas.
+		_ = err
+	}
+
+	return &MongoReadStateRepository{col: col}
+}
+
+type readStateDoc struct {
+	ID        string `bson:"_id"`
+	TripID    string `bson:"tripID"`
+	UserID    string `bson:"userID"`
+	MessageID string `bson:"messageID"`
+	SentAt    int64  `bson:"sentAt"`
+	ReadAt    int64  `bson:"readAt"`
+}
+
+func (r *MongoReadStateRepository) MarkRead(ctx context.Context, state *domain.ReadState) error {
+	id := state.TripID + ":" + state.UserID
+	// Only a state behind the new one matches. When the stored state is ahead
+	// the upsert tries to insert a second document with the same _id, which
+	// fails with a duplicate key error and leaves the stored state alone.
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"sentAt": bson.M{"$lt": state.SentAt}},
+			bson.M{"sentAt": state.SentAt, "messageID": bson.M{"$lt": state.MessageID}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"tripID":    state.TripID,
+		"userID":    state.UserID,
+		"messageID": state.MessageID,
+		"sentAt":    state.SentAt,
+		"readAt":    state.ReadAt,
+	}}
+	return tracing.RunInSpan(ctx, "db", "mongodb.chat_read_states.upsert", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", readStateCollectionName),
+		attribute.String("trip.id", state.TripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		_, err := r.col.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (r *MongoReadStateRepository) GetByTripID(ctx context.Context, tripID string) ([]*domain.ReadState, error) {
+	var states []*domain.ReadState
+	err := tracing.RunInSpan(ctx, "db", "mongodb.chat_read_states.find_by_trip", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", readStateCollectionName),
+		attribute.String("trip.id", tripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		cursor, err := r.col.Find(ctx, bson.M{"tripID": tripID})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var doc readStateDoc
+			if err := cursor.Decode(&doc); err != nil {
+				continue
+			}
+			states = append(states, &domain.ReadState{
+				TripID:    doc.TripID,
+				UserID:    doc.UserID,
+				MessageID: doc.MessageID,
+				SentAt:    doc.SentAt,
+				ReadAt:    doc.ReadAt,
+			})
+		}
+		return cursor.Err()
+	})
+	return states, err
+}
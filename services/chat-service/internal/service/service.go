@@ -2,28 +2,50 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
 	"ride-sharing/services/chat-service/internal/domain"
 )
 
-// ChatService orchestrates message persistence and delivery acknowledgement.
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 100
+)
+
+// ChatService orchestrates message persistence, delivery acknowledgement and
+// read receipts.
 type ChatService struct {
-	repo      domain.MessageRepository
-	publisher domain.MessagePublisher
+	repo         domain.MessageRepository
+	readStates   domain.ReadStateRepository
+	participants domain.ParticipantResolver
+	publisher    domain.MessagePublisher
 }
 
-func New(repo domain.MessageRepository, publisher domain.MessagePublisher) *ChatService {
-	return &ChatService{repo: repo, publisher: publisher}
+func New(repo domain.MessageRepository, readStates domain.ReadStateRepository, participants domain.ParticipantResolver, publisher domain.MessagePublisher) *ChatService {
+	return &ChatService{repo: repo, readStates: readStates, participants: participants, publisher: publisher}
 }
 
 // HandleIncoming persists a new message and publishes a delivery receipt.
+// A message moderation flagged also raises a support event; a blocked one
+// never reached the peer, so it gets no receipt.
 func (s *ChatService) HandleIncoming(ctx context.Context, msg *domain.Message) error {
 	if err := s.repo.Save(ctx, msg); err != nil {
 		log.Printf("chat-service: failed to persist message %s: %v", msg.ID, err)
 		return err
 	}
 
+	if len(msg.Flags) > 0 {
+		if err := s.publisher.PublishFlagged(ctx, msg); err != nil {
+			log.Printf("chat-service: failed to publish flagged event for %s: %v", msg.ID, err)
+		}
+	}
+	if msg.Blocked {
+		return nil
+	}
+
 	if err := s.publisher.PublishDelivered(ctx, msg.ID, msg.TripID); err != nil {
 		// Non-fatal: message is persisted; the receipt is best-effort.
 		log.Printf("chat-service: failed to publish delivery receipt for %s: %v", msg.ID, err)
@@ -32,7 +54,116 @@ func (s *ChatService) HandleIncoming(ctx context.Context, msg *domain.Message) e
 	return nil
 }
 
-// GetHistory returns the last n messages for a trip, newest first.
-func (s *ChatService) GetHistory(ctx context.Context, tripID string, limit int) ([]*domain.Message, error) {
-	return s.repo.GetByTripID(ctx, tripID, limit)
+// HistoryPage is one page of a trip's chat, newest message first.
+type HistoryPage struct {
+	Messages   []*domain.Message
+	NextCursor string // empty on the last page
+	ReadStates []*domain.ReadState
+}
+
+// GetHistory returns up to limit messages of a trip sent before the cursor
+// before, or the newest ones when before is empty, together with how far
+// each participant has read. Only the trip's rider and driver may read it.
+// Loading the newest page marks the messages userID received as delivered.
+func (s *ChatService) GetHistory(ctx context.Context, tripID, userID, before string, limit int) (*HistoryPage, error) {
+	if err := s.authorize(ctx, tripID, userID); err != nil {
+		return nil, err
+	}
+	var cursor *domain.HistoryCursor
+	if before != "" {
+		c, err := parseCursor(before)
+		if err != nil {
+			return nil, err
+		}
+		cursor = c
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	limit = min(limit, maxHistoryLimit)
+
+	// One extra message tells whether there is another page.
+	msgs, err := s.repo.GetByTripID(ctx, tripID, cursor, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chat history: %w", err)
+	}
+	page := &HistoryPage{Messages: msgs}
+	if len(msgs) > limit {
+		page.Messages = msgs[:limit]
+		page.NextCursor = formatCursor(page.Messages[limit-1])
+	}
+
+	if cursor == nil && len(page.Messages) > 0 {
+		if err := s.repo.MarkDeliveredUpTo(ctx, tripID, userID, page.Messages[0].SentAt); err != nil {
+			log.Printf("chat-service: failed to mark messages of trip %s delivered to %s: %v", tripID, userID, err)
+		} else {
+			for _, msg := range page.Messages {
+				if msg.SenderID != userID {
+					msg.Delivered = true
+				}
+			}
+		}
+	}
+
+	page.ReadStates, err = s.readStates.GetByTripID(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chat read states: %w", err)
+	}
+	return page, nil
+}
+
+// MarkRead records that readerID has read the chat of tripID up to messageID.
+// Reading a message implies it was delivered, so the messages before it are
+// marked delivered too. The message may still be on its way to the store, in
+// which case an error is returned and the receipt retried.
+func (s *ChatService) MarkRead(ctx context.Context, tripID, readerID, messageID string, readAt int64) error {
+	msg, err := s.repo.GetByID(ctx, tripID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to load message %s: %w", messageID, err)
+	}
+	if msg == nil {
+		return fmt.Errorf("message %s of trip %s is not stored yet", messageID, tripID)
+	}
+
+	if err := s.readStates.MarkRead(ctx, &domain.ReadState{
+		TripID:    tripID,
+		UserID:    readerID,
+		MessageID: messageID,
+		SentAt:    msg.SentAt,
+		ReadAt:    readAt,
+	}); err != nil {
+		return fmt.Errorf("failed to store read state of trip %s: %w", tripID, err)
+	}
+	if err := s.repo.MarkDeliveredUpTo(ctx, tripID, readerID, msg.SentAt); err != nil {
+		return fmt.Errorf("failed to mark messages of trip %s delivered: %w", tripID, err)
+	}
+	return nil
+}
+
+func (s *ChatService) authorize(ctx context.Context, tripID, userID string) error {
+	riderID, driverID, err := s.participants.Participants(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	if userID != riderID && userID != driverID {
+		return domain.ErrNotParticipant
+	}
+	return nil
+}
+
+// formatCursor encodes the position of msg as "sentAt:messageID".
+func formatCursor(msg *domain.Message) string {
+	return strconv.FormatInt(msg.SentAt, 10) + ":" + msg.ID
+}
+
+func parseCursor(s string) (*domain.HistoryCursor, error) {
+	sentAt, id, ok := strings.Cut(s, ":")
+	if !ok || id == "" {
+		return nil, domain.ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(sentAt, 10, 64)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+	return &domain.HistoryCursor{SentAt: n, ID: id}, nil
 }
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/routing"
+	"ride-sharing/shared/types"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ETA targets carried by messaging.RouteETA.
+const (
+	etaTargetPickup      = "pickup"
+	etaTargetDestination = "destination"
+)
+
+const (
+	// etaRefreshInterval and etaRefreshDistance bound how stale a driver's
+	// ETA may get: it is recomputed once either is exceeded, and counted down
+	// from the last route in between.
+	etaRefreshInterval = 15 * time.Second
+	etaRefreshDistance = 200.0 // meters
+	// etaPickupRadius is how close to the pickup the driver has to come for
+	// the ETA to switch to the destination.
+	etaPickupRadius = 50.0 // meters
+	// etaChangeMinSeconds and etaChangeRatio decide when a new ETA differs
+	// enough from the last announced one to send driver.event.eta_changed:
+	// by etaChangeMinSeconds or by etaChangeRatio of it, whichever is larger.
+	etaChangeMinSeconds = 120
+	etaChangeRatio      = 0.25
+	// etaMinRouteInterval is the least time between two route requests for
+	// the same driver, so a provider that keeps failing is not asked on every
+	// location update.
+	etaMinRouteInterval = 5 * time.Second
+	// etaFallbackSpeed, in m/s (about 30 km/h), estimates the ETA over the
+	// straight-line distance while no route to the current target is known.
+	etaFallbackSpeed = 8.0
+	// etaRouteQueueSize bounds the route requests waiting for a worker; more
+	// are dropped and asked again with a later position.
+	etaRouteQueueSize = 256
+)
+
+// luaETASwitchTarget moves the ETA of trip ARGV[1] from the pickup to the
+// destination and forgets the pickup route, so the next location update asks
+// for a route to the destination. It returns 0 if the key holds another trip
+// or already targets the destination.
+var luaETASwitchTarget = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'tripID') ~= ARGV[1] or redis.call('HGET', KEYS[1], 'target') ~= 'pickup' then
+    return 0
+end
+redis.call('HSET', KEYS[1], 'target', 'destination')
+redis.call('HDEL', KEYS[1], 'etaSeconds', 'distance', 'computedAt', 'from', 'requestedAt')
+return 1
+`)
+
+// luaETASave stores a route of trip ARGV[1] to target ARGV[2], given as
+// field/value pairs from ARGV[4] on, and renews the key's expiry to ARGV[3]
+// milliseconds. It returns 0 without writing if the trip ended or its target
+// changed since the route was requested.
+var luaETASave = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'tripID') ~= ARGV[1] or redis.call('HGET', KEYS[1], 'target') ~= ARGV[2] then
+    return 0
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 4))
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+func driverETAKey(driverID string) string {
+	return "driver:" + driverID + ":eta"
+}
+
+// etaState is the ETA of a driver's trip kept in Redis between location
+// updates.
+type etaState struct {
+	tripID      string
+	pickup      types.Coordinate
+	destination types.Coordinate
+
+	// The last computed route and where and when it was computed.
+	target         string
+	etaSeconds     int64
+	distanceMeters float64
+	computedAt     time.Time
+	from           types.Coordinate
+
+	// The ETA last sent in driver.event.eta_changed.
+	announcedTarget string
+	announcedETA    int64
+	announcedAt     time.Time
+
+	// When a route was last requested, by any replica.
+	requestedAt time.Time
+}
+
+// etaTracker computes the ETA of drivers serving a trip: to the pickup until
+// the driver reaches it, then to the destination. Routes come from a
+// routing.Provider, asked by a fixed number of workers off the location
+// path; in between, the last route is counted down. Whenever a new route
+// differs enough from the ETA the rider was last told, the worker publishes
+// driver.event.eta_changed.
+type etaTracker struct {
+	rdb       *redis.Client
+	routes    routing.Provider
+	publisher messaging.Publisher
+
+	requests chan etaRouteRequest
+	mu       sync.Mutex
+	inflight map[string]struct{} // driverIDs with a queued or running request
+}
+
+// etaRouteRequest asks for the route of driverID's trip from one position.
+type etaRouteRequest struct {
+	driverID string
+	riderID  string
+	tripID   string
+	target   string
+	from     types.Coordinate
+	to       types.Coordinate
+}
+
+func newETATracker(rdb *redis.Client, routes routing.Provider, publisher messaging.Publisher) *etaTracker {
+	return &etaTracker{
+		rdb:       rdb,
+		routes:    routes,
+		publisher: publisher,
+		requests:  make(chan etaRouteRequest, etaRouteQueueSize),
+		inflight:  make(map[string]struct{}),
+	}
+}
+
+// Run asks for routes with workers goroutines until ctx is cancelled.
+func (t *etaTracker) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case req := <-t.requests:
+					t.route(ctx, req)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Start begins tracking the ETA of driverID's new trip.
+func (t *etaTracker) Start(ctx context.Context, driverID, tripID string, pickup, destination types.Coordinate) error {
+	key := driverETAKey(driverID)
+	pipe := t.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, map[string]any{
+		"tripID": tripID,
+		"pickup": formatCoordinate(pickup),
+		"dest":   formatCoordinate(destination),
+		"target": etaTargetPickup,
+	})
+	pipe.Expire(ctx, key, activeRiderTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to start ETA of trip %s: %w", tripID, err)
+	}
+	return nil
+}
+
+// Stop stops tracking the ETA of driverID's trip tripID. A later trip of the
+// driver is left alone.
+func (t *etaTracker) Stop(ctx context.Context, driverID, tripID string) error {
+	key := driverETAKey(driverID)
+	current, err := t.rdb.HGet(ctx, key, "tripID").Result()
+	if err == redis.Nil || (err == nil && current != tripID) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up ETA of driver %s: %w", driverID, err)
+	}
+	return t.rdb.Del(ctx, key).Err()
+}
+
+// Update moves driverID to position and returns the ETA of their trip, or nil
+// if they serve none. It never waits for a route: when the last one is stale,
+// a new one is requested in the background and the last one is counted down
+// meanwhile. Until the first route to a target is known, the ETA is estimated
+// from the straight-line distance.
+func (t *etaTracker) Update(ctx context.Context, driverID, riderID string, position types.Coordinate) (*messaging.RouteETA, error) {
+	key := driverETAKey(driverID)
+	fields, err := t.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ETA of driver %s: %w", driverID, err)
+	}
+	if fields["tripID"] == "" {
+		return nil, nil
+	}
+	state, err := parseETAState(fields)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt ETA of driver %s: %w", driverID, err)
+	}
+
+	now := time.Now()
+	if state.target == etaTargetPickup && routing.DistanceMeters(position, state.pickup) <= etaPickupRadius {
+		// Saved right away, so the ETA never goes back to the pickup, even if
+		// no route to the destination is found.
+		if err := luaETASwitchTarget.Run(ctx, t.rdb, []string{key}, state.tripID).Err(); err != nil {
+			return nil, fmt.Errorf("failed to switch ETA of driver %s to the destination: %w", driverID, err)
+		}
+		state.target = etaTargetDestination
+		state.computedAt, state.requestedAt = time.Time{}, time.Time{}
+	}
+	target := state.target
+	to := state.pickup
+	if target == etaTargetDestination {
+		to = state.destination
+	}
+
+	fresh := state.computedAt.IsZero() ||
+		now.Sub(state.computedAt) >= etaRefreshInterval ||
+		routing.DistanceMeters(position, state.from) >= etaRefreshDistance
+	if fresh && now.Sub(state.requestedAt) >= etaMinRouteInterval {
+		t.requestRoute(ctx, key, etaRouteRequest{
+			driverID: driverID,
+			riderID:  riderID,
+			tripID:   state.tripID,
+			target:   target,
+			from:     position,
+			to:       to,
+		}, now)
+	}
+
+	if state.computedAt.IsZero() {
+		distance := routing.DistanceMeters(position, to)
+		return &messaging.RouteETA{
+			Target:         target,
+			ETASeconds:     int64(math.Round(distance / etaFallbackSpeed)),
+			DistanceMeters: distance,
+		}, nil
+	}
+	// Reuse the last route for its target, counted down to now.
+	return &messaging.RouteETA{
+		Target:         state.target,
+		ETASeconds:     max(0, state.etaSeconds-int64(now.Sub(state.computedAt).Seconds())),
+		DistanceMeters: state.distanceMeters,
+	}, nil
+}
+
+// requestRoute queues req unless a request for the driver is already queued
+// or running on this replica, or the queue is full. The request time is
+// stored first, so no replica asks again for the driver within
+// etaMinRouteInterval.
+func (t *etaTracker) requestRoute(ctx context.Context, key string, req etaRouteRequest, now time.Time) {
+	t.mu.Lock()
+	if _, busy := t.inflight[req.driverID]; busy {
+		t.mu.Unlock()
+		return
+	}
+	t.inflight[req.driverID] = struct{}{}
+	t.mu.Unlock()
+
+	if err := t.rdb.HSet(ctx, key, "requestedAt", now.UnixMilli()).Err(); err != nil {
+		log.Printf("eta: failed to record route request of driver %s: %v", req.driverID, err)
+	}
+	select {
+	case t.requests <- req:
+	default:
+		log.Printf("eta: route queue full, skipping driver %s of trip %s", req.driverID, req.tripID)
+		t.done(req.driverID)
+	}
+}
+
+func (t *etaTracker) done(driverID string) {
+	t.mu.Lock()
+	delete(t.inflight, driverID)
+	t.mu.Unlock()
+}
+
+// route asks for the route of req and stores it, unless the driver's trip
+// ended or its target changed meanwhile, and announces it to the rider if it
+// changed enough.
+func (t *etaTracker) route(ctx context.Context, req etaRouteRequest) {
+	defer t.done(req.driverID)
+
+	route, err := t.routes.Route(ctx, req.from, req.to)
+	if err != nil {
+		log.Printf("eta: failed to route driver %s of trip %s: %v", req.driverID, req.tripID, err)
+		return
+	}
+	key := driverETAKey(req.driverID)
+	fields, err := t.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		log.Printf("eta: failed to load ETA of driver %s: %v", req.driverID, err)
+		return
+	}
+	if fields["tripID"] != req.tripID || fields["target"] != req.target {
+		return
+	}
+	state, err := parseETAState(fields)
+	if err != nil {
+		log.Printf("eta: corrupt ETA of driver %s: %v", req.driverID, err)
+		return
+	}
+
+	now := time.Now()
+	state.etaSeconds = int64(math.Round(route.DurationSeconds))
+	state.distanceMeters = route.DistanceMeters
+	state.computedAt = now
+	state.from = req.from
+	changed, err := t.save(ctx, key, state, now)
+	if err != nil {
+		log.Printf("eta: %v", err)
+		return
+	}
+	if changed == nil {
+		return
+	}
+
+	payload, _ := json.Marshal(changed)
+	if err := t.publisher.PublishMessage(ctx, contracts.DriverEventETAChanged, contracts.AmqpMessage{
+		OwnerID: req.riderID,
+		Data:    payload,
+	}); err != nil {
+		log.Printf("eta: failed to publish ETA change of trip %s: %v", req.tripID, err)
+		return
+	}
+	log.Printf("eta: ETA of trip %s is now %ds to %s", req.tripID, changed.ETA.ETASeconds, changed.ETA.Target)
+}
+
+// save stores a freshly computed route and returns the ETA change to announce,
+// if it moved far enough from the last announcement. Nothing is stored or
+// announced if the trip ended or its target changed since state was loaded.
+func (t *etaTracker) save(ctx context.Context, key string, state etaState, now time.Time) (*messaging.DriverETAChangedData, error) {
+	eta := &messaging.RouteETA{
+		Target:         state.target,
+		ETASeconds:     state.etaSeconds,
+		DistanceMeters: state.distanceMeters,
+	}
+	values := []any{
+		"etaSeconds", state.etaSeconds,
+		"distance", state.distanceMeters,
+		"computedAt", state.computedAt.UnixMilli(),
+		"from", formatCoordinate(state.from),
+	}
+
+	var changed *messaging.DriverETAChangedData
+	if state.announcedTarget != state.target {
+		changed = &messaging.DriverETAChangedData{TripID: state.tripID, ETA: eta}
+	} else {
+		previous := max(0, state.announcedETA-int64(now.Sub(state.announcedAt).Seconds()))
+		threshold := max(etaChangeMinSeconds, int64(etaChangeRatio*float64(previous)))
+		if diff := state.etaSeconds - previous; diff >= threshold || -diff >= threshold {
+			changed = &messaging.DriverETAChangedData{TripID: state.tripID, ETA: eta, PreviousETASeconds: previous}
+		}
+	}
+	if changed != nil {
+		values = append(values,
+			"announcedTarget", state.target,
+			"announcedETA", state.etaSeconds,
+			"announcedAt", now.UnixMilli())
+	}
+
+	args := append([]any{state.tripID, state.target, activeRiderTTL.Milliseconds()}, values...)
+	saved, err := luaETASave.Run(ctx, t.rdb, []string{key}, args...).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to store ETA of trip %s: %w", state.tripID, err)
+	}
+	if saved == 0 {
+		return nil, nil
+	}
+	return changed, nil
+}
+
+func parseETAState(fields map[string]string) (etaState, error) {
+	state := etaState{
+		tripID:          fields["tripID"],
+		target:          fields["target"],
+		announcedTarget: fields["announcedTarget"],
+		requestedAt:     parseUnixMilli(fields["requestedAt"]),
+	}
+	var err error
+	if state.pickup, err = parseCoordinate(fields["pickup"]); err != nil {
+		return etaState{}, fmt.Errorf("pickup: %w", err)
+	}
+	if state.destination, err = parseCoordinate(fields["dest"]); err != nil {
+		return etaState{}, fmt.Errorf("destination: %w", err)
+	}
+	if fields["computedAt"] == "" {
+		return state, nil
+	}
+	if state.from, err = parseCoordinate(fields["from"]); err != nil {
+		return etaState{}, fmt.Errorf("from: %w", err)
+	}
+	state.etaSeconds, _ = strconv.ParseInt(fields["etaSeconds"], 10, 64)
+	state.distanceMeters, _ = strconv.ParseFloat(fields["distance"], 64)
+	state.computedAt = parseUnixMilli(fields["computedAt"])
+	state.announcedETA, _ = strconv.ParseInt(fields["announcedETA"], 10, 64)
+	state.announcedAt = parseUnixMilli(fields["announcedAt"])
+	return state, nil
+}
+
+func parseUnixMilli(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func formatCoordinate(c types.Coordinate) string {
+	return strconv.FormatFloat(c.Latitude, 'f', -1, 64) + "," + strconv.FormatFloat(c.Longitude, 'f', -1, 64)
+}
+
+func parseCoordinate(s string) (types.Coordinate, error) {
+	var c types.Coordinate
+	if _, err := fmt.Sscanf(s, "%g,%g", &c.Latitude, &c.Longitude); err != nil {
+		return types.Coordinate{}, fmt.Errorf("invalid coordinate %q", s)
+	}
+	return c, nil
+}
+
+// tripEndpoints returns the pickup and destination of a trip event. The route
+// geometry holds OSRM's [longitude, latitude] pairs, so each proto Coordinate
+// carries the longitude in Latitude and the latitude in Longitude.
+func tripEndpoints(payload messaging.TripEventData) (pickup, destination types.Coordinate, ok bool) {
+	var coords []*pb.Coordinate
+	for _, geometry := range payload.Trip.GetRoute().GetGeometry() {
+		coords = append(coords, geometry.GetCoordinates()...)
+	}
+	if len(coords) == 0 {
+		return types.Coordinate{}, types.Coordinate{}, false
+	}
+	first, last := coords[0], coords[len(coords)-1]
+	pickup = types.Coordinate{Latitude: first.GetLongitude(), Longitude: first.GetLatitude()}
+	if payload.PickupLat != 0 || payload.PickupLng != 0 {
+		pickup = types.Coordinate{Latitude: payload.PickupLat, Longitude: payload.PickupLng}
+	}
+	destination = types.Coordinate{Latitude: last.GetLongitude(), Longitude: last.GetLatitude()}
+	return pickup, destination, true
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/geofence"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/routing"
+	"ride-sharing/shared/types"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Fences of a trip, as named in messaging.TripGeofenceData.
+const (
+	fenceNearby  = "nearby"
+	fencePickup  = "pickup"
+	fenceDropoff = "dropoff"
+)
+
+const (
+	// geofenceNearbyRadius is how close to the pickup the driver counts as
+	// nearby.
+	geofenceNearbyRadius = 500.0 // meters
+	// geofenceArrivedRadius is how close to the pickup or dropoff the driver
+	// counts as arrived. It matches the radius the ETA switches target at.
+	geofenceArrivedRadius = etaPickupRadius
+	// geofenceZoneExitMargin is how far outside a zone the driver has to be
+	// to leave it, so positions along its edge do not flap in and out.
+	geofenceZoneExitMargin = 25.0 // meters
+)
+
+func driverGeofenceKey(driverID string) string {
+	return "driver:" + driverID + ":geofence"
+}
+
+// geofenceEvent is a fence crossing to publish with routingKey.
+type geofenceEvent struct {
+	routingKey string
+	data       messaging.TripGeofenceData
+}
+
+// luaGeofenceCommit stores fence crossings of trip ARGV[1], given as
+// field/value pairs from ARGV[3] on, and renews the key's expiry to ARGV[2]
+// milliseconds. It returns 0 without writing if the key belongs to another
+// trip or none.
+var luaGeofenceCommit = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'tripID') ~= ARGV[1] then
+    return 0
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 3))
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// geofenceCrossings are the crossings of one position. They are recorded with
+// Commit once they were published, so crossings that failed to publish are
+// found again on redelivery instead of being lost.
+type geofenceCrossings struct {
+	tripID  string
+	events  []geofenceEvent
+	updates map[string]any
+}
+
+// geofenceEngine tells when the driver of a trip comes near and arrives at
+// the pickup, arrives at the dropoff, and enters or leaves a named zone. Each
+// trip fence is crossed at most once per trip; the dropoff only counts after
+// the pickup. Which fences were crossed is kept in Redis, so any replica can
+// continue.
+type geofenceEngine struct {
+	rdb   *redis.Client
+	zones []geofence.Zone
+}
+
+func newGeofenceEngine(rdb *redis.Client, zones []geofence.Zone) *geofenceEngine {
+	return &geofenceEngine{rdb: rdb, zones: zones}
+}
+
+// Start begins watching the fences of driverID's new trip. Zones the driver
+// is already in are entered with their first position.
+func (g *geofenceEngine) Start(ctx context.Context, driverID, tripID string, pickup, destination types.Coordinate) error {
+	key := driverGeofenceKey(driverID)
+	pipe := g.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, map[string]any{
+		"tripID": tripID,
+		"pickup": formatCoordinate(pickup),
+		"dest":   formatCoordinate(destination),
+	})
+	pipe.Expire(ctx, key, activeRiderTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to start geofences of trip %s: %w", tripID, err)
+	}
+	return nil
+}
+
+// Stop stops watching the fences of driverID's trip tripID. A later trip of
+// the driver is left alone.
+func (g *geofenceEngine) Stop(ctx context.Context, driverID, tripID string) error {
+	key := driverGeofenceKey(driverID)
+	current, err := g.rdb.HGet(ctx, key, "tripID").Result()
+	if err == redis.Nil || (err == nil && current != tripID) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up geofences of driver %s: %w", driverID, err)
+	}
+	return g.rdb.Del(ctx, key).Err()
+}
+
+// Evaluate returns the fences of driverID's trip that fix crosses, if they
+// serve one, or nil if it crosses none. The driver stays where they were
+// until the crossings are committed.
+func (g *geofenceEngine) Evaluate(ctx context.Context, driverID string, fix locationFix) (*geofenceCrossings, error) {
+	key := driverGeofenceKey(driverID)
+	fields, err := g.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geofences of driver %s: %w", driverID, err)
+	}
+	tripID := fields["tripID"]
+	if tripID == "" {
+		return nil, nil
+	}
+	pickup, err := parseCoordinate(fields["pickup"])
+	if err != nil {
+		return nil, fmt.Errorf("corrupt geofences of driver %s: %w", driverID, err)
+	}
+	destination, err := parseCoordinate(fields["dest"])
+	if err != nil {
+		return nil, fmt.Errorf("corrupt geofences of driver %s: %w", driverID, err)
+	}
+
+	var events []geofenceEvent
+	updates := map[string]any{}
+	crossed := func(routingKey, fence string, distance float64) {
+		data := messaging.TripGeofenceData{
+			TripID:         tripID,
+			DriverID:       driverID,
+			Fence:          fence,
+			Latitude:       fix.position.Latitude,
+			Longitude:      fix.position.Longitude,
+			DistanceMeters: distance,
+			At:             fix.at.UnixMilli(),
+		}
+		events = append(events, geofenceEvent{routingKey: routingKey, data: data})
+		updates[fence] = 1
+	}
+
+	toPickup := routing.DistanceMeters(fix.position, pickup)
+	if fields[fenceNearby] == "" && toPickup <= geofenceNearbyRadius {
+		crossed(contracts.TripEventDriverNearby, fenceNearby, toPickup)
+	}
+	atPickup := fields[fencePickup] != ""
+	if !atPickup && toPickup <= geofenceArrivedRadius {
+		crossed(contracts.TripEventDriverAtPickup, fencePickup, toPickup)
+		atPickup = true
+	}
+	if toDropoff := routing.DistanceMeters(fix.position, destination); atPickup && fields[fenceDropoff] == "" && toDropoff <= geofenceArrivedRadius {
+		crossed(contracts.TripEventDriverAtDropoff, fenceDropoff, toDropoff)
+	}
+
+	var inside []string
+	if fields["zones"] != "" {
+		inside = strings.Split(fields["zones"], ",")
+	}
+	var now []string
+	for _, zone := range g.zones {
+		was := slices.Contains(inside, zone.ID)
+		is := zone.Polygon.Contains(fix.position)
+		if was && !is && zone.Polygon.DistanceMeters(fix.position) <= geofenceZoneExitMargin {
+			is = true
+		}
+		if is {
+			now = append(now, zone.ID)
+		}
+		if is == was {
+			continue
+		}
+		routingKey := contracts.TripEventDriverZoneEntered
+		if was {
+			routingKey = contracts.TripEventDriverZoneExited
+		}
+		events = append(events, geofenceEvent{routingKey: routingKey, data: messaging.TripGeofenceData{
+			TripID:    tripID,
+			DriverID:  driverID,
+			Fence:     zone.ID,
+			ZoneName:  zone.Name,
+			ZoneKind:  zone.Kind,
+			Latitude:  fix.position.Latitude,
+			Longitude: fix.position.Longitude,
+			At:        fix.at.UnixMilli(),
+		}})
+	}
+	if zones := strings.Join(now, ","); zones != fields["zones"] {
+		updates["zones"] = zones
+	}
+
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	return &geofenceCrossings{tripID: tripID, events: events, updates: updates}, nil
+}
+
+// Commit records that driverID crossed the fences in c, after they were
+// published. Nothing is recorded if the driver's trip ended or a new one
+// started since c was evaluated.
+func (g *geofenceEngine) Commit(ctx context.Context, driverID string, c *geofenceCrossings) error {
+	args := []any{c.tripID, activeRiderTTL.Milliseconds()}
+	for field, value := range c.updates {
+		args = append(args, field, value)
+	}
+	if err := luaGeofenceCommit.Run(ctx, g.rdb, []string{driverGeofenceKey(driverID)}, args...).Err(); err != nil {
+		return fmt.Errorf("failed to store geofences of trip %s: %w", c.tripID, err)
+	}
+	return nil
+}
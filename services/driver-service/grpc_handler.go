@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"log"
 	pb "ride-sharing/shared/proto/driver"
 
 	"google.golang.org/grpc"
@@ -12,12 +13,14 @@ import (
 type driverGrpcHandler struct {
 	pb.UnimplementedDriverServiceServer
 
-	service *Service // this is our domain service which has business logic
+	service  *Service // this is our domain service which has business logic
+	presence *presenceTracker
 }
 
-func NewGrpcHandler(s *grpc.Server, service *Service) {
+func NewGrpcHandler(s *grpc.Server, service *Service, presence *presenceTracker) {
 	handler := &driverGrpcHandler{
-		service: service,
+		service:  service,
+		presence: presence,
 	}
 
 	pb.RegisterDriverServiceServer(s, handler) // register our handler with grpc server
@@ -25,6 +28,9 @@ func NewGrpcHandler(s *grpc.Server, service *Service) {
 
 func (h *driverGrpcHandler) UnregisterDriver(ctx context.Context, req *pb.RegisterDriverRequest) (*pb.RegisterDriverResponse, error) {
 	h.service.RemoveDriverFromGeo(ctx, req.GetDriverID(), req.GetPackageSlug())
+	if err := h.presence.GoOffline(ctx, req.GetDriverID()); err != nil {
+		log.Printf("Failed to mark driver %s offline: %v", req.GetDriverID(), err)
+	}
 
 	return &pb.RegisterDriverResponse{
 		Driver: &pb.Driver{
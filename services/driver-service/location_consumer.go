@@ -3,26 +3,33 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/types"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 )
 
 type locationConsumer struct {
-	rabbitmq *messaging.RabbitMQ
-	service  *Service
+	broker    messaging.Broker
+	service   *Service
+	filter    *locationFilter
+	eta       *etaTracker
+	traces    *traceRecorder
+	geofences *geofenceEngine
 }
 
-func NewLocationConsumer(rabbitmq *messaging.RabbitMQ, service *Service) *locationConsumer {
-	return &locationConsumer{rabbitmq: rabbitmq, service: service}
+func NewLocationConsumer(broker messaging.Broker, service *Service, filter *locationFilter, eta *etaTracker, traces *traceRecorder, geofences *geofenceEngine) *locationConsumer {
+	return &locationConsumer{broker: broker, service: service, filter: filter, eta: eta, traces: traces, geofences: geofences}
 }
 
 func (c *locationConsumer) Listen() error {
-	return c.rabbitmq.ConsumeMessages(messaging.DriverLocationUpdateQueue, func(ctx context.Context, msg amqp091.Delivery) error {
-		var message contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &message); err != nil {
+	return c.broker.ConsumeMessages(messaging.DriverLocationUpdateQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		message, err := messaging.DecodeMessage(msg)
+		if err != nil {
 			log.Printf("location_consumer: failed to unmarshal envelope: %v", err)
 			return err
 		}
@@ -33,27 +40,64 @@ func (c *locationConsumer) Listen() error {
 			return err
 		}
 
-		if err := c.service.UpdateDriverLocation(message.OwnerID, payload.PackageSlug, payload.Latitude, payload.Longitude); err != nil {
+		sample := locationSample{
+			position: types.Coordinate{Latitude: payload.Latitude, Longitude: payload.Longitude},
+			accuracy: payload.Accuracy,
+		}
+		if payload.Timestamp > 0 {
+			sample.at = time.UnixMilli(payload.Timestamp)
+		}
+		fix, err := c.filter.Process(ctx, message.OwnerID, sample)
+		if errors.Is(err, errLocationStale) || errors.Is(err, errLocationJump) {
+			log.Printf("location_consumer: dropped location of driver %s: %v", message.OwnerID, err)
+			return nil
+		}
+		if err != nil {
+			log.Printf("location_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+		position := fix.position
+
+		if err := c.service.UpdateDriverLocation(message.OwnerID, payload.PackageSlug, position.Latitude, position.Longitude); err != nil {
 			log.Printf("location_consumer: failed to update location for driver %s: %v", message.OwnerID, err)
-			return err
+			return messaging.Transient("redis", err)
 		}
 
-		log.Printf("location_consumer: updated driver %s → %.5f, %.5f", message.OwnerID, payload.Latitude, payload.Longitude)
+		log.Printf("location_consumer: updated driver %s → %.5f, %.5f", message.OwnerID, position.Latitude, position.Longitude)
+
+		// A missed trace point is not worth holding up the driver's location.
+		if err := c.traces.Record(ctx, message.OwnerID, fix); err != nil {
+			log.Printf("location_consumer: %v", err)
+		}
 
 		// Publish rider-facing location event if this driver has an active rider
-		riderID, err := c.service.GetActiveRider(message.OwnerID)
+		riderID, tripID, err := c.service.GetActiveRider(message.OwnerID)
 		if err != nil {
 			// No active rider or error — just skip rider notification
 			log.Printf("location_consumer: no active rider for driver %s (or error: %v), skipping rider update", message.OwnerID, err)
 			return nil
 		}
 
+		// The ETA is best effort: the location still goes out without one.
+		eta, err := c.eta.Update(ctx, message.OwnerID, riderID, position)
+		if err != nil {
+			log.Printf("location_consumer: %v", err)
+		}
+		crossings, err := c.geofences.Evaluate(ctx, message.OwnerID, fix)
+		if err != nil {
+			log.Printf("location_consumer: %v", err)
+		}
+
 		locationEvent := messaging.DriverLocationEventData{
-			Latitude:  payload.Latitude,
-			Longitude: payload.Longitude,
+			TripID:    tripID,
+			Latitude:  position.Latitude,
+			Longitude: position.Longitude,
+			Bearing:   fix.bearing,
+			Speed:     fix.speed,
+			ETA:       eta,
 		}
 		eventPayload, _ := json.Marshal(locationEvent)
-		if err := c.rabbitmq.PublishMessage(ctx, contracts.DriverEventLocation, contracts.AmqpMessage{
+		if err := c.broker.PublishMessage(ctx, contracts.DriverEventLocation, contracts.AmqpMessage{
 			OwnerID: riderID,
 			Data:    eventPayload,
 		}); err != nil {
@@ -62,6 +106,25 @@ func (c *locationConsumer) Listen() error {
 		}
 
 		log.Printf("location_consumer: published location to rider %s", riderID)
+
+		if crossings == nil {
+			return nil
+		}
+		for _, crossing := range crossings.events {
+			crossingPayload, _ := json.Marshal(crossing.data)
+			if err := c.broker.PublishMessage(ctx, crossing.routingKey, contracts.AmqpMessage{
+				OwnerID: riderID,
+				Data:    crossingPayload,
+			}); err != nil {
+				log.Printf("location_consumer: failed to publish %s of trip %s: %v", crossing.routingKey, crossing.data.TripID, err)
+				return err
+			}
+			log.Printf("location_consumer: driver %s crossed %s fence of trip %s (%s)", message.OwnerID, crossing.data.Fence, crossing.data.TripID, crossing.routingKey)
+		}
+		// Uncommitted crossings are published again with the next position.
+		if err := c.geofences.Commit(ctx, message.OwnerID, crossings); err != nil {
+			log.Printf("location_consumer: %v", err)
+		}
 		return nil
 	})
 }
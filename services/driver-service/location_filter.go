@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"ride-sharing/shared/routing"
+	"ride-sharing/shared/types"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// locationMaxAge drops samples taken longer ago than this, e.g. replayed
+	// after a reconnect. locationMaxSkew is how far ahead a client's clock may
+	// be before its timestamps are replaced with ours.
+	locationMaxAge  = 30 * time.Second
+	locationMaxSkew = 10 * time.Second
+	// locationMaxSpeed is the fastest a car is believed to move, in m/s
+	// (about 250 km/h). Faster jumps are GPS errors.
+	locationMaxSpeed = 70.0
+	// locationMaxRejects is how many jumps in a row are rejected before the
+	// filter gives in and restarts at the new position: after that many, the
+	// filtered position is the one that is wrong.
+	locationMaxRejects = 3
+	// locationTrackGap restarts the filter when a driver reported nothing for
+	// this long; the old position says little about the new one.
+	locationTrackGap = 2 * time.Minute
+	locationTrackTTL = 10 * time.Minute
+	// locationStoreAttempts bounds how often a sample is applied again when
+	// another replica updated the driver's track in the meantime.
+	locationStoreAttempts = 3
+
+	// locationDefaultAccuracy is the accuracy, in meters, assumed for samples
+	// that report none.
+	locationDefaultAccuracy = 15.0
+	locationMinAccuracy     = 1.0
+	// locationProcessNoise is how fast, in m/s, the filter expects the true
+	// position to drift from its estimate between samples. Higher follows
+	// turns faster; lower smooths more.
+	locationProcessNoise = 3.0
+	// locationMinSpeed is the speed, in m/s, below which the driver counts as
+	// standing still and keeps their last bearing.
+	locationMinSpeed = 0.5
+	// locationMaxSnap is the farthest, in meters, a position is moved to snap
+	// it onto a road.
+	locationMaxSnap = 30.0
+)
+
+var (
+	errLocationStale = errors.New("stale or out-of-order location")
+	errLocationJump  = errors.New("impossible location jump")
+)
+
+// luaStoreTrack stores a track, given as field/value pairs from ARGV[3] on,
+// and renews its expiry to ARGV[2] milliseconds, if the track's "at" is still
+// ARGV[1] (empty for no track). It returns 0 without writing otherwise, so two
+// replicas filtering consecutive samples of a driver don't overwrite each
+// other's update.
+var luaStoreTrack = redis.NewScript(`
+if (redis.call('HGET', KEYS[1], 'at') or '') ~= ARGV[1] then
+    return 0
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 3))
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+func driverTrackKey(driverID string) string {
+	return "driver:" + driverID + ":track"
+}
+
+// locationSample is a position as reported by a driver's client.
+type locationSample struct {
+	position types.Coordinate
+	accuracy float64 // meters
+	at       time.Time
+}
+
+// locationFix is a driver's position after filtering.
+type locationFix struct {
+	position types.Coordinate
+	bearing  float64 // degrees from north; 0 while standing still
+	speed    float64 // m/s
+	at       time.Time
+}
+
+// locationTrack is the filter state of one driver: a Kalman filter with a
+// single variance for both axes, as GPS accuracy is reported as a radius.
+type locationTrack struct {
+	at       time.Time // client time of the last accepted sample
+	position types.Coordinate
+	variance float64 // m²; 0 until the first sample
+	bearing  float64
+	speed    float64
+	rejects  int
+}
+
+// apply feeds s to the filter. It fails with errLocationStale or
+// errLocationJump, leaving the track as it was apart from counting the jump.
+func (t *locationTrack) apply(s locationSample) error {
+	if t.variance == 0 || s.at.Sub(t.at) > locationTrackGap {
+		t.reset(s)
+		return nil
+	}
+	dt := s.at.Sub(t.at).Seconds()
+	if dt <= 0 {
+		return errLocationStale
+	}
+
+	// Give the sample the benefit of its own inaccuracy before calling the
+	// jump impossible.
+	distance := routing.DistanceMeters(t.position, s.position)
+	if (distance-s.accuracy)/dt > locationMaxSpeed {
+		t.rejects++
+		if t.rejects > locationMaxRejects {
+			t.reset(s)
+			return nil
+		}
+		return errLocationJump
+	}
+
+	variance := t.variance + dt*locationProcessNoise*locationProcessNoise
+	gain := variance / (variance + s.accuracy*s.accuracy)
+	previous := t.position
+	t.position = types.Coordinate{
+		Latitude:  previous.Latitude + gain*(s.position.Latitude-previous.Latitude),
+		Longitude: previous.Longitude + gain*(s.position.Longitude-previous.Longitude),
+	}
+	t.variance = (1 - gain) * variance
+	t.speed = routing.DistanceMeters(previous, t.position) / dt
+	if t.speed >= locationMinSpeed {
+		t.bearing = routing.BearingDegrees(previous, t.position)
+	}
+	t.at = s.at
+	t.rejects = 0
+	return nil
+}
+
+func (t *locationTrack) reset(s locationSample) {
+	*t = locationTrack{
+		at:       s.at,
+		position: s.position,
+		variance: s.accuracy * s.accuracy,
+		bearing:  t.bearing,
+	}
+}
+
+func (t *locationTrack) fix() locationFix {
+	f := locationFix{position: t.position, speed: t.speed, at: t.at}
+	if t.speed >= locationMinSpeed {
+		f.bearing = t.bearing
+	}
+	return f
+}
+
+// locationFilter turns the positions drivers report into the ones matching,
+// ETAs and riders see. It drops stale and out-of-order samples, rejects
+// impossible jumps, smooths the rest and, if a snapper is set, snaps them
+// onto the road. Each driver's track is kept in Redis so any replica can
+// continue it.
+type locationFilter struct {
+	rdb     *redis.Client
+	snapper routing.Snapper // nil disables snapping
+}
+
+func newLocationFilter(rdb *redis.Client, snapper routing.Snapper) *locationFilter {
+	return &locationFilter{rdb: rdb, snapper: snapper}
+}
+
+// Process filters driverID's sample. It fails with errLocationStale or
+// errLocationJump for samples that should be dropped. A sample taken at the
+// same time as the last accepted one is a redelivery, e.g. after the
+// consumer failed further down, and gets the stored fix again.
+func (f *locationFilter) Process(ctx context.Context, driverID string, s locationSample) (locationFix, error) {
+	now := time.Now()
+	if s.at.IsZero() || s.at.After(now.Add(locationMaxSkew)) {
+		s.at = now
+	}
+	if now.Sub(s.at) > locationMaxAge {
+		return locationFix{}, errLocationStale
+	}
+	if s.accuracy <= 0 {
+		s.accuracy = locationDefaultAccuracy
+	}
+	s.accuracy = max(s.accuracy, locationMinAccuracy)
+
+	key := driverTrackKey(driverID)
+	for attempt := 1; ; attempt++ {
+		fields, err := f.rdb.HGetAll(ctx, key).Result()
+		if err != nil {
+			return locationFix{}, fmt.Errorf("failed to load track of driver %s: %w", driverID, err)
+		}
+		track := parseLocationTrack(fields)
+		if track.variance != 0 && s.at.UnixMilli() == track.at.UnixMilli() {
+			return f.snap(ctx, driverID, track.fix()), nil
+		}
+		applyErr := track.apply(s)
+		if applyErr != nil && !errors.Is(applyErr, errLocationJump) {
+			return locationFix{}, applyErr
+		}
+
+		stored, err := luaStoreTrack.Run(ctx, f.rdb, []string{key},
+			fields["at"], locationTrackTTL.Milliseconds(),
+			"at", track.at.UnixMilli(),
+			"position", formatCoordinate(track.position),
+			"variance", track.variance,
+			"bearing", track.bearing,
+			"speed", track.speed,
+			"rejects", track.rejects,
+		).Int()
+		if err != nil {
+			return locationFix{}, fmt.Errorf("failed to store track of driver %s: %w", driverID, err)
+		}
+		if stored == 0 {
+			// Another replica moved the track on; apply s to its update.
+			if attempt == locationStoreAttempts {
+				return locationFix{}, fmt.Errorf("track of driver %s changed during %d attempts to update it", driverID, attempt)
+			}
+			continue
+		}
+		if applyErr != nil {
+			return locationFix{}, applyErr
+		}
+		return f.snap(ctx, driverID, track.fix()), nil
+	}
+}
+
+// snap moves fix onto the nearest road if a snapper is set and the road is
+// close enough.
+func (f *locationFilter) snap(ctx context.Context, driverID string, fix locationFix) locationFix {
+	if f.snapper == nil {
+		return fix
+	}
+	snapped, err := f.snapper.Snap(ctx, fix.position)
+	switch {
+	case err != nil:
+		log.Printf("location_filter: failed to snap driver %s: %v", driverID, err)
+	case routing.DistanceMeters(fix.position, snapped) <= locationMaxSnap:
+		fix.position = snapped
+	}
+	return fix
+}
+
+// parseLocationTrack reads a track stored by Process. A missing or corrupt
+// one reads as a new track.
+func parseLocationTrack(fields map[string]string) locationTrack {
+	position, err := parseCoordinate(fields["position"])
+	if err != nil {
+		return locationTrack{}
+	}
+	track := locationTrack{position: position, at: parseUnixMilli(fields["at"])}
+	track.variance, _ = strconv.ParseFloat(fields["variance"], 64)
+	track.bearing, _ = strconv.ParseFloat(fields["bearing"], 64)
+	track.speed, _ = strconv.ParseFloat(fields["speed"], 64)
+	track.rejects, _ = strconv.Atoi(fields["rejects"])
+	return track
+}
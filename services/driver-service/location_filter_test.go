@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"ride-sharing/shared/types"
+)
+
+func TestLocationTrackApply(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	// at returns a sample d after start, dLat degrees north of the origin
+	// (0.001° is about 111 m).
+	at := func(d time.Duration, dLat float64) locationSample {
+		return locationSample{
+			position: types.Coordinate{Latitude: 52.52 + dLat, Longitude: 13.405},
+			accuracy: 5,
+			at:       start.Add(d),
+		}
+	}
+	jump := func(d time.Duration) locationSample { return at(d, 0.1) } // 11 km away
+
+	tests := []struct {
+		name    string
+		before  []locationSample
+		sample  locationSample
+		wantErr error
+		check   func(t *testing.T, track locationTrack)
+	}{
+		{
+			name:   "first sample starts the track",
+			sample: at(0, 0),
+			check: func(t *testing.T, track locationTrack) {
+				if track.variance != 25 || track.position.Latitude != 52.52 {
+					t.Errorf("track = %+v, want the sample with variance 25", track)
+				}
+			},
+		},
+		{
+			name:    "same time is stale",
+			before:  []locationSample{at(0, 0)},
+			sample:  at(0, 0.0001),
+			wantErr: errLocationStale,
+		},
+		{
+			name:    "older sample is out of order",
+			before:  []locationSample{at(0, 0), at(10*time.Second, 0.001)},
+			sample:  at(5*time.Second, 0.0005),
+			wantErr: errLocationStale,
+			check: func(t *testing.T, track locationTrack) {
+				if !track.at.Equal(start.Add(10 * time.Second)) {
+					t.Errorf("track moved back to %s", track.at)
+				}
+			},
+		},
+		{
+			name:    "impossible jump is rejected and counted",
+			before:  []locationSample{at(0, 0)},
+			sample:  jump(10 * time.Second),
+			wantErr: errLocationJump,
+			check: func(t *testing.T, track locationTrack) {
+				if track.rejects != 1 || track.position.Latitude != 52.52 || !track.at.Equal(start) {
+					t.Errorf("track = %+v, want it unchanged apart from one reject", track)
+				}
+			},
+		},
+		{
+			name:    "jumps up to the limit are rejected",
+			before:  []locationSample{at(0, 0), jump(time.Second), jump(2 * time.Second)},
+			sample:  jump(3 * time.Second),
+			wantErr: errLocationJump,
+			check: func(t *testing.T, track locationTrack) {
+				if track.rejects != locationMaxRejects {
+					t.Errorf("rejects = %d, want %d", track.rejects, locationMaxRejects)
+				}
+			},
+		},
+		{
+			name:   "jump past the limit restarts the track there",
+			before: []locationSample{at(0, 0), jump(time.Second), jump(2 * time.Second), jump(3 * time.Second)},
+			sample: jump(4 * time.Second),
+			check: func(t *testing.T, track locationTrack) {
+				if track.rejects != 0 || track.position != jump(0).position || track.variance != 25 {
+					t.Errorf("track = %+v, want a new track at the jump", track)
+				}
+			},
+		},
+		{
+			name:   "accepted sample clears the rejects",
+			before: []locationSample{at(0, 0), jump(time.Second)},
+			sample: at(10*time.Second, 0.001),
+			check: func(t *testing.T, track locationTrack) {
+				if track.rejects != 0 {
+					t.Errorf("rejects = %d, want 0", track.rejects)
+				}
+			},
+		},
+		{
+			name:   "gap restarts the track without a jump",
+			before: []locationSample{at(0, 0)},
+			sample: at(locationTrackGap+time.Second, 0.1),
+			check: func(t *testing.T, track locationTrack) {
+				if track.position != at(0, 0.1).position || track.variance != 25 {
+					t.Errorf("track = %+v, want a new track at the sample", track)
+				}
+			},
+		},
+		{
+			name:   "moving sets speed and bearing",
+			before: []locationSample{at(0, 0)},
+			sample: at(10*time.Second, 0.001),
+			check: func(t *testing.T, track locationTrack) {
+				if track.speed < locationMinSpeed || track.position.Latitude <= 52.52 {
+					t.Errorf("track = %+v, want it moving north", track)
+				}
+				if fix := track.fix(); math.Abs(fix.bearing) > 1 {
+					t.Errorf("bearing = %.1f, want north", fix.bearing)
+				}
+			},
+		},
+		{
+			name: "standing still keeps the bearing",
+			// The filter closes in on the stop, moving less each sample.
+			before: []locationSample{at(0, 0), at(10*time.Second, -0.001), at(20*time.Second, -0.001), at(30*time.Second, -0.001)},
+			sample: at(40*time.Second, -0.001),
+			check: func(t *testing.T, track locationTrack) {
+				if track.speed >= locationMinSpeed {
+					t.Fatalf("speed = %.2f, want below %.1f", track.speed, locationMinSpeed)
+				}
+				if math.Abs(track.bearing-180) > 1 {
+					t.Errorf("bearing = %.1f, want the last one (south)", track.bearing)
+				}
+				if fix := track.fix(); fix.bearing != 0 {
+					t.Errorf("fix bearing = %.1f, want 0 while standing still", fix.bearing)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var track locationTrack
+			for _, s := range tt.before {
+				_ = track.apply(s)
+			}
+			if err := track.apply(tt.sample); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("apply = %v, want %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, track)
+			}
+		})
+	}
+}
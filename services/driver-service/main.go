@@ -8,9 +8,13 @@ import (
 	"os"
 	"os/signal"
 	"ride-sharing/shared/env"
+	"ride-sharing/shared/geofence"
 	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/presence"
+	"ride-sharing/shared/routing"
 	"ride-sharing/shared/tracing"
 	"syscall"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	grpcserver "google.golang.org/grpc"
@@ -19,6 +23,13 @@ import (
 const (
 	GrpcAddr = ":9092"
 	HTTPAddr = ":8082"
+
+	// presenceSweepInterval is how often drivers whose heartbeats stopped are
+	// marked offline.
+	presenceSweepInterval = 10 * time.Second
+	// etaRouteWorkers is how many OSRM route requests this replica runs at
+	// once; ETAs are counted down in between.
+	etaRouteWorkers = 4
 )
 
 func main() {
@@ -76,10 +87,32 @@ func main() {
 	}
 
 	serverErrors := make(chan error, 1)
-	svc := NewService(rdb)
+	presenceStore := presence.NewStore(rdb, presence.DefaultTTL)
+	svc := NewService(rdb, presenceStore)
+	presenceTracker := newPresenceTracker(presenceStore, rabbitmq)
+	routes := routing.NewOSRM(env.GetString("OSRM_URL", routing.DefaultOSRMURL))
+	eta := newETATracker(rdb, routes, rabbitmq)
+	go eta.Run(ctx, etaRouteWorkers)
+	var snapper routing.Snapper
+	if env.GetBool("LOCATION_SNAP_TO_ROAD", false) {
+		snapper = routes
+	}
+	locationFilter := newLocationFilter(rdb, snapper)
+	traces := newTraceRecorder(rdb, rabbitmq)
+	// Named zones such as airports come from a GeoJSON file; without one only
+	// the pickup and dropoff of each trip are fenced.
+	var zones []geofence.Zone
+	if path := env.GetString("GEOFENCE_ZONES_FILE", ""); path != "" {
+		if zones, err = geofence.LoadZonesFile(path); err != nil {
+			log.Fatalf("Failed to load geofence zones: %v", err)
+		}
+		log.Printf("Loaded %d geofence zones", len(zones))
+	}
+	geofences := newGeofenceEngine(rdb, zones)
+	go presenceTracker.Run(ctx, presenceSweepInterval)
 	// Starting the gRPC server with otel tracing hooks enabled
 	grpcServer := grpcserver.NewServer(tracing.WithTracingInterceptors()...)
-	NewGrpcHandler(grpcServer, svc)
+	NewGrpcHandler(grpcServer, svc, presenceTracker)
 
 	go func() {
 		log.Printf("Starting gRPC server Driver service on port %s", lis.Addr().String())
@@ -104,20 +137,41 @@ func main() {
 		}
 	}()
 
-	locConsumer := NewLocationConsumer(rabbitmq, svc)
+	locConsumer := NewLocationConsumer(rabbitmq, svc, locationFilter, eta, traces, geofences)
 	go func() {
 		if err := locConsumer.Listen(); err != nil {
 			log.Fatalf("Failed to listen to location updates: %v", err)
 		}
 	}()
 
-	tripAssignedConsumer := NewTripAssignedConsumer(rabbitmq, svc)
+	tripAssignedConsumer := NewTripAssignedConsumer(rabbitmq, svc, eta, traces, geofences)
 	go func() {
 		if err := tripAssignedConsumer.Listen(); err != nil {
 			log.Fatalf("Failed to listen to trip assignments: %v", err)
 		}
 	}()
 
+	releaseConsumer := NewReleaseConsumer(rabbitmq, svc, eta, traces, geofences)
+	go func() {
+		if err := releaseConsumer.Listen(); err != nil {
+			log.Fatalf("Failed to listen to driver releases: %v", err)
+		}
+	}()
+
+	presenceConsumer := NewPresenceConsumer(rabbitmq, svc)
+	go func() {
+		if err := presenceConsumer.Listen(); err != nil {
+			log.Fatalf("Failed to listen to presence changes: %v", err)
+		}
+	}()
+
+	tripEndedConsumer := NewTripEndedConsumer(rabbitmq, svc, eta, traces, geofences)
+	go func() {
+		if err := tripEndedConsumer.Listen(); err != nil {
+			log.Fatalf("Failed to listen to trip endings: %v", err)
+		}
+	}()
+
 	select {
 	case err := <-serverErrors:
 		log.Fatalf("server error: %v", err)
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/presence"
+)
+
+// Reasons carried by driver.event.presence_changed.
+const (
+	presenceReasonDisconnected = "disconnected"
+	presenceReasonExpired      = "heartbeat_expired"
+)
+
+// presenceTracker publishes driver.event.presence_changed whenever a driver
+// goes offline, on disconnect or once their heartbeats stop.
+type presenceTracker struct {
+	store     *presence.Store
+	publisher messaging.Publisher
+}
+
+func newPresenceTracker(store *presence.Store, publisher messaging.Publisher) *presenceTracker {
+	return &presenceTracker{store: store, publisher: publisher}
+}
+
+// GoOffline marks driverID offline after ws-gateway saw their last socket
+// close.
+func (t *presenceTracker) GoOffline(ctx context.Context, driverID string) error {
+	change, changed, err := t.store.MarkOffline(ctx, driverID)
+	if err != nil || !changed {
+		return err
+	}
+	return t.publish(ctx, change, presenceReasonDisconnected)
+}
+
+// Run expires drivers whose heartbeats stopped every interval until ctx is
+// cancelled. Every replica runs it; each change is reported by one of them.
+func (t *presenceTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changes, err := t.store.Expire(ctx)
+			if err != nil {
+				log.Printf("presence: failed to expire drivers: %v", err)
+			}
+			for _, change := range changes {
+				if err := t.publish(ctx, change, presenceReasonExpired); err != nil {
+					log.Printf("presence: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func (t *presenceTracker) publish(ctx context.Context, change presence.Change, reason string) error {
+	payload, err := json.Marshal(messaging.DriverPresenceChangedData{
+		DriverID:      change.DriverID,
+		State:         string(change.State),
+		PreviousState: string(change.Previous),
+		PackageSlug:   change.PackageSlug,
+		LastSeen:      change.LastSeen.UnixMilli(),
+		Reason:        reason,
+	})
+	if err != nil {
+		return err
+	}
+	if err := t.publisher.PublishMessage(ctx, contracts.DriverEventPresenceChanged, contracts.AmqpMessage{
+		OwnerID: change.DriverID,
+		Data:    payload,
+	}); err != nil {
+		return fmt.Errorf("failed to publish presence change of driver %s: %w", change.DriverID, err)
+	}
+	log.Printf("presence: driver %s %s → %s (%s)", change.DriverID, change.Previous, change.State, reason)
+	return nil
+}
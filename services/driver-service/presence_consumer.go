@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/presence"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// presenceConsumer removes drivers that went offline from the GEO index so
+// they are no longer matched.
+type presenceConsumer struct {
+	broker  messaging.Subscriber
+	service *Service
+}
+
+func NewPresenceConsumer(broker messaging.Subscriber, service *Service) *presenceConsumer {
+	return &presenceConsumer{broker: broker, service: service}
+}
+
+func (c *presenceConsumer) Listen() error {
+	return c.broker.ConsumeMessages(messaging.DriverPresenceChangedQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var payload messaging.DriverPresenceChangedData
+		if _, err := messaging.DecodePayload(msg, &payload); err != nil {
+			log.Printf("presence_consumer: failed to decode payload: %v", err)
+			return err
+		}
+		if presence.State(payload.State) != presence.StateOffline || payload.PackageSlug == "" {
+			return nil
+		}
+
+		c.service.RemoveDriverFromGeo(ctx, payload.DriverID, payload.PackageSlug)
+		log.Printf("presence_consumer: driver %s removed from %s (%s)", payload.DriverID, payload.PackageSlug, payload.Reason)
+		return nil
+	})
+}
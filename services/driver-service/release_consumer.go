@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// releaseConsumer drops the pairing of a driver with a trip trip-service's
+// saga gave up on, so the driver's chat and location relay stop.
+type releaseConsumer struct {
+	broker    messaging.Subscriber
+	service   *Service
+	eta       *etaTracker
+	traces    *traceRecorder
+	geofences *geofenceEngine
+}
+
+func NewReleaseConsumer(broker messaging.Subscriber, service *Service, eta *etaTracker, traces *traceRecorder, geofences *geofenceEngine) *releaseConsumer {
+	return &releaseConsumer{broker: broker, service: service, eta: eta, traces: traces, geofences: geofences}
+}
+
+func (c *releaseConsumer) Listen() error {
+	return c.broker.ConsumeMessages(messaging.DriverReleaseQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var payload messaging.DriverReleaseData
+		if _, err := messaging.DecodePayload(msg, &payload); err != nil {
+			log.Printf("release_consumer: failed to decode payload: %v", err)
+			return err
+		}
+
+		if err := c.service.ReleaseDriver(ctx, payload.TripID, payload.DriverID, payload.RiderID); err != nil {
+			log.Printf("release_consumer: failed to release driver %s: %v", payload.DriverID, err)
+			return messaging.Transient("redis", err)
+		}
+
+		if err := c.service.SetDriverAvailable(ctx, payload.DriverID); err != nil {
+			log.Printf("release_consumer: failed to mark driver %s available: %v", payload.DriverID, err)
+			return messaging.Transient("redis", err)
+		}
+
+		if err := c.eta.Stop(ctx, payload.DriverID, payload.TripID); err != nil {
+			log.Printf("release_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+		if err := c.traces.Stop(ctx, payload.DriverID, payload.TripID); err != nil {
+			log.Printf("release_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+		if err := c.geofences.Stop(ctx, payload.DriverID, payload.TripID); err != nil {
+			log.Printf("release_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+
+		log.Printf("release_consumer: driver %s released from trip %s (%s)", payload.DriverID, payload.TripID, payload.Reason)
+		return nil
+	})
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"log"
 	"time"
 
+	"ride-sharing/shared/presence"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,11 +18,12 @@ const (
 const activeDriverTTL = 2 * time.Hour
 
 type Service struct {
-	rdb *redis.Client
+	rdb      *redis.Client
+	presence *presence.Store
 }
 
-func NewService(rdb *redis.Client) *Service {
-	return &Service{rdb: rdb}
+func NewService(rdb *redis.Client, presence *presence.Store) *Service {
+	return &Service{rdb: rdb, presence: presence}
 }
 
 // UpdateDriverLocation upserts the driver's position in the Redis GEO index for the given package.
@@ -55,16 +59,16 @@ func (s *Service) FindAvailableDrivers(packageType string, pickupLat, pickupLng
 		return []string{}
 	}
 
-	// Keep only drivers that currently have at least one active ws-gateway subscriber.
-	// This avoids picking stale GEO entries for disconnected drivers.
+	// Keep only drivers that are connected and not serving a trip. This avoids
+	// picking stale GEO entries for disconnected drivers.
+	presences, err := s.presence.Lookup(ctx, results)
+	if err != nil {
+		log.Printf("Failed to look up driver presence: %v", err)
+		return []string{}
+	}
 	onlineIDs := make([]string, 0, len(results))
 	for _, driverID := range results {
-		// pubnumSub gives the number of subscribers for the driver's event channel, which ws-gateway subscribes to when the driver is online.
-		numSub, err := s.rdb.PubSubNumSub(ctx, "user:"+driverID+":events").Result()
-		if err != nil {
-			continue
-		}
-		if count, ok := numSub["user:"+driverID+":events"]; ok && count > 0 {
+		if presences[driverID].Available() {
 			onlineIDs = append(onlineIDs, driverID)
 		}
 	}
@@ -78,6 +82,10 @@ func activeRiderKey(driverID string) string {
 	return "driver:" + driverID + ":active_rider"
 }
 
+func activeTripKey(driverID string) string {
+	return "driver:" + driverID + ":active_trip"
+}
+
 func activeDriverKey(riderID string) string {
 	return "rider:" + riderID + ":active_driver"
 }
@@ -95,9 +103,20 @@ func (s *Service) SetActiveRider(driverID, riderID string) error {
 	return s.rdb.Set(context.Background(), activeRiderKey(driverID), riderID, activeRiderTTL).Err()
 }
 
-// GetActiveRider returns the riderID currently paired with this driver, or ("", redis.Nil) if none.
-func (s *Service) GetActiveRider(driverID string) (string, error) {
-	return s.rdb.Get(context.Background(), activeRiderKey(driverID)).Result()
+// GetActiveRider returns the riderID currently paired with this driver and
+// the trip they are on, or redis.Nil if none. tripID is empty when the pairing
+// was stored without a trip.
+func (s *Service) GetActiveRider(driverID string) (riderID, tripID string, err error) {
+	values, err := s.rdb.MGet(context.Background(), activeRiderKey(driverID), activeTripKey(driverID)).Result()
+	if err != nil {
+		return "", "", err
+	}
+	riderID, _ = values[0].(string)
+	if riderID == "" {
+		return "", "", redis.Nil
+	}
+	tripID, _ = values[1].(string)
+	return riderID, tripID, nil
 }
 
 // SetTripChatPair stores the confirmed rider/driver trip pairing used by gateway chat authorization.
@@ -107,14 +126,59 @@ func (s *Service) SetTripChatPair(tripID, riderID, driverID string) error {
 	pipe.Set(ctx, activeRiderKey(driverID), riderID, activeRiderTTL)
 	pipe.Set(ctx, activeDriverKey(riderID), driverID, activeDriverTTL)
 	if tripID != "" {
+		pipe.Set(ctx, activeTripKey(driverID), tripID, activeRiderTTL)
 		pipe.Set(ctx, tripChatRiderKey(tripID), riderID, activeRiderTTL)
 		pipe.Set(ctx, tripChatDriverKey(tripID), driverID, activeRiderTTL)
+	} else {
+		pipe.Del(ctx, activeTripKey(driverID))
 	}
 	_, err := pipe.Exec(ctx)
 	return err
 }
 
+// ReleaseDriver removes the pairing SetTripChatPair stored for a trip. Pointers
+// that already moved on to another trip are left alone.
+func (s *Service) ReleaseDriver(ctx context.Context, tripID, driverID, riderID string) error {
+	var activeRider, activeTrip, activeDriver string
+	values, err := s.rdb.MGet(ctx, activeRiderKey(driverID), activeTripKey(driverID), activeDriverKey(riderID)).Result()
+	if err != nil {
+		return err
+	}
+	if len(values) == 3 {
+		activeRider, _ = values[0].(string)
+		activeTrip, _ = values[1].(string)
+		activeDriver, _ = values[2].(string)
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, tripChatRiderKey(tripID), tripChatDriverKey(tripID))
+	if activeRider == riderID {
+		pipe.Del(ctx, activeRiderKey(driverID))
+	}
+	if activeTrip == tripID {
+		pipe.Del(ctx, activeTripKey(driverID))
+	}
+	if activeDriver == driverID {
+		pipe.Del(ctx, activeDriverKey(riderID))
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// SetDriverBusy takes a driver out of matching while they serve a trip, even
+// across a reconnect.
+func (s *Service) SetDriverBusy(ctx context.Context, driverID string) error {
+	_, err := s.presence.SetBusy(ctx, driverID)
+	return err
+}
+
+// SetDriverAvailable returns a busy driver to matching.
+func (s *Service) SetDriverAvailable(ctx context.Context, driverID string) error {
+	_, err := s.presence.SetAvailable(ctx, driverID)
+	return err
+}
+
 // ClearActiveRider removes the driver→rider mapping when the trip ends.
 func (s *Service) ClearActiveRider(driverID string) {
-	s.rdb.Del(context.Background(), activeRiderKey(driverID))
+	s.rdb.Del(context.Background(), activeRiderKey(driverID), activeTripKey(driverID))
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/routing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// traceMinDistance and traceMinInterval down-sample a trip's trace: a
+	// position is kept once the driver moved traceMinDistance meters or
+	// traceMinInterval passed since the last kept one.
+	traceMinDistance = 25.0
+	traceMinInterval = 30 * time.Second
+	// traceBatchSize is how many kept positions are sent to trip-service in
+	// one driver.event.trace message. It must not exceed the schema's
+	// maxItems for points.
+	traceBatchSize = 50
+	// traceFlushLockTTL bounds how long a crashed flush keeps others out.
+	// traceStopWait is how long Stop waits for a running flush to finish.
+	traceFlushLockTTL = 30 * time.Second
+	traceStopWait     = 5 * time.Second
+)
+
+// luaUnlock deletes lock KEYS[1] if it still holds token ARGV[1].
+var luaUnlock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func driverTraceKey(driverID string) string {
+	return "driver:" + driverID + ":trace"
+}
+
+func tripTracePendingKey(tripID string) string {
+	return "trip:" + tripID + ":trace:pending"
+}
+
+func tripTraceFlushLockKey(tripID string) string {
+	return "trip:" + tripID + ":trace:flushing"
+}
+
+// traceRecorder records the path of a driver from accepting a trip to its
+// end. Kept positions wait in Redis until a batch is full or the trip ends,
+// then go to trip-service, which stores the trace.
+type traceRecorder struct {
+	rdb       *redis.Client
+	publisher messaging.Publisher
+}
+
+func newTraceRecorder(rdb *redis.Client, publisher messaging.Publisher) *traceRecorder {
+	return &traceRecorder{rdb: rdb, publisher: publisher}
+}
+
+// Start begins recording the trace of driverID's new trip.
+func (r *traceRecorder) Start(ctx context.Context, driverID, tripID string) error {
+	key := driverTraceKey(driverID)
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "tripID", tripID)
+	pipe.Expire(ctx, key, activeRiderTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to start trace of trip %s: %w", tripID, err)
+	}
+	return nil
+}
+
+// Record adds fix to the trace of driverID's trip, if they serve one and it
+// is far enough from the last kept position.
+func (r *traceRecorder) Record(ctx context.Context, driverID string, fix locationFix) error {
+	key := driverTraceKey(driverID)
+	fields, err := r.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load trace of driver %s: %w", driverID, err)
+	}
+	tripID := fields["tripID"]
+	if tripID == "" {
+		return nil
+	}
+	if last, err := parseCoordinate(fields["last"]); err == nil {
+		lastAt := parseUnixMilli(fields["lastAt"])
+		if !fix.at.After(lastAt) {
+			return nil
+		}
+		if routing.DistanceMeters(last, fix.position) < traceMinDistance && fix.at.Sub(lastAt) < traceMinInterval {
+			return nil
+		}
+	}
+
+	point, _ := json.Marshal(messaging.TracePoint{
+		Latitude:  fix.position.Latitude,
+		Longitude: fix.position.Longitude,
+		At:        fix.at.UnixMilli(),
+		Speed:     fix.speed,
+	})
+	pendingKey := tripTracePendingKey(tripID)
+	pipe := r.rdb.TxPipeline()
+	pending := pipe.RPush(ctx, pendingKey, point)
+	pipe.Expire(ctx, pendingKey, activeRiderTTL)
+	pipe.HSet(ctx, key, "last", formatCoordinate(fix.position), "lastAt", fix.at.UnixMilli())
+	pipe.Expire(ctx, key, activeRiderTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record trace of trip %s: %w", tripID, err)
+	}
+	if pending.Val() < traceBatchSize {
+		return nil
+	}
+	// A flush already running for the trip sends this batch too.
+	unlock, ok, err := r.lockFlush(ctx, tripID)
+	if err != nil || !ok {
+		return err
+	}
+	defer unlock()
+	return r.flush(ctx, driverID, tripID)
+}
+
+// Stop sends what is left of the trace of driverID's trip tripID and stops
+// recording it. A later trip of the driver is left alone, and so are calls
+// for the trip's rider.
+func (r *traceRecorder) Stop(ctx context.Context, driverID, tripID string) error {
+	key := driverTraceKey(driverID)
+	current, err := r.rdb.HGet(ctx, key, "tripID").Result()
+	if err == redis.Nil || (err == nil && current != tripID) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up trace of driver %s: %w", driverID, err)
+	}
+
+	unlock, err := r.waitFlush(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := r.flush(ctx, driverID, tripID); err != nil {
+		return err
+	}
+	if err := r.rdb.Del(ctx, key, tripTracePendingKey(tripID)).Err(); err != nil {
+		return fmt.Errorf("failed to stop trace of trip %s: %w", tripID, err)
+	}
+	return nil
+}
+
+// lockFlush takes the flush lock of tripID, which any replica may hold. It
+// reports false if someone else holds it.
+func (r *traceRecorder) lockFlush(ctx context.Context, tripID string) (unlock func(), ok bool, err error) {
+	key := tripTraceFlushLockKey(tripID)
+	token := strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+	ok, err = r.rdb.SetNX(ctx, key, token, traceFlushLockTTL).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to lock trace of trip %s: %w", tripID, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	return func() {
+		// Not ctx: the lock is released even if the flush was cancelled.
+		if err := luaUnlock.Run(context.Background(), r.rdb, []string{key}, token).Err(); err != nil {
+			log.Printf("trace: failed to unlock trace of trip %s: %v", tripID, err)
+		}
+	}, true, nil
+}
+
+// waitFlush takes the flush lock of tripID, waiting up to traceStopWait for
+// a running flush to finish.
+func (r *traceRecorder) waitFlush(ctx context.Context, tripID string) (unlock func(), err error) {
+	deadline := time.Now().Add(traceStopWait)
+	for {
+		unlock, ok, err := r.lockFlush(ctx, tripID)
+		if err != nil || ok {
+			return unlock, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("trace of trip %s is still being flushed", tripID)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// flush sends the pending positions of tripID to trip-service, one batch at a
+// time. A batch is only dropped from Redis once it was published; trip-service
+// stores a batch sent twice once. The caller holds the trip's flush lock, so
+// no two flushes send the same batch.
+func (r *traceRecorder) flush(ctx context.Context, driverID, tripID string) error {
+	pendingKey := tripTracePendingKey(tripID)
+	for {
+		raw, err := r.rdb.LRange(ctx, pendingKey, 0, traceBatchSize-1).Result()
+		if err != nil {
+			return fmt.Errorf("failed to load trace of trip %s: %w", tripID, err)
+		}
+		if len(raw) == 0 {
+			return nil
+		}
+
+		data := messaging.DriverTraceData{TripID: tripID, DriverID: driverID, Points: make([]*messaging.TracePoint, 0, len(raw))}
+		for _, item := range raw {
+			var point messaging.TracePoint
+			if err := json.Unmarshal([]byte(item), &point); err != nil {
+				continue
+			}
+			data.Points = append(data.Points, &point)
+		}
+		if len(data.Points) > 0 {
+			payload, _ := json.Marshal(data)
+			if err := r.publisher.PublishMessage(ctx, contracts.DriverEventTrace, contracts.AmqpMessage{
+				OwnerID: driverID,
+				Data:    payload,
+			}); err != nil {
+				return fmt.Errorf("failed to publish trace of trip %s: %w", tripID, err)
+			}
+		}
+		if err := r.rdb.LTrim(ctx, pendingKey, int64(len(raw)), -1).Err(); err != nil {
+			return fmt.Errorf("failed to trim trace of trip %s: %w", tripID, err)
+		}
+		if len(raw) < traceBatchSize {
+			return nil
+		}
+	}
+}
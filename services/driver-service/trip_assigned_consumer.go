@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
@@ -10,53 +9,69 @@ import (
 	"github.com/rabbitmq/amqp091-go"
 )
 
-// tripAssignedPayload matches the proto Trip JSON produced by trip.ToProto()
-type tripAssignedPayload struct {
-	ID     string `json:"id"`
-	UserID string `json:"userID"` // riderID
-	Driver struct {
-		ID string `json:"id"` // driverID
-	} `json:"driver"`
-}
-
 type tripAssignedConsumer struct {
-	rabbitmq *messaging.RabbitMQ
-	service  *Service
+	broker    messaging.Subscriber
+	service   *Service
+	eta       *etaTracker
+	traces    *traceRecorder
+	geofences *geofenceEngine
 }
 
-func NewTripAssignedConsumer(rabbitmq *messaging.RabbitMQ, service *Service) *tripAssignedConsumer {
-	return &tripAssignedConsumer{rabbitmq: rabbitmq, service: service}
+func NewTripAssignedConsumer(broker messaging.Subscriber, service *Service, eta *etaTracker, traces *traceRecorder, geofences *geofenceEngine) *tripAssignedConsumer {
+	return &tripAssignedConsumer{broker: broker, service: service, eta: eta, traces: traces, geofences: geofences}
 }
 
 func (c *tripAssignedConsumer) Listen() error {
-	return c.rabbitmq.ConsumeMessages(messaging.DriverTripAssignedQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+	return c.broker.ConsumeMessages(messaging.DriverTripAssignedQueue, func(ctx context.Context, msg amqp091.Delivery) error {
 		if msg.RoutingKey != contracts.TripEventDriverAssigned {
 			return nil
 		}
 
-		var envelope contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &envelope); err != nil {
-			log.Printf("trip_assigned_consumer: failed to unmarshal envelope: %v", err)
+		var payload messaging.TripEventData
+		if _, err := messaging.DecodePayload(msg, &payload); err != nil {
+			log.Printf("trip_assigned_consumer: failed to decode payload: %v", err)
 			return err
 		}
-
-		var payload tripAssignedPayload
-		if err := json.Unmarshal(envelope.Data, &payload); err != nil {
-			log.Printf("trip_assigned_consumer: failed to unmarshal payload: %v", err)
+		if err := payload.Validate(); err != nil {
+			log.Printf("trip_assigned_consumer: invalid payload: %v", err)
 			return err
 		}
 
-		if payload.Driver.ID == "" || payload.UserID == "" {
+		trip := payload.Trip
+		if trip.GetDriver().GetId() == "" || trip.UserID == "" {
 			log.Printf("trip_assigned_consumer: missing driverID or riderID, skipping")
 			return nil
 		}
 
-		if err := c.service.SetTripChatPair(payload.ID, payload.UserID, payload.Driver.ID); err != nil {
-			log.Printf("trip_assigned_consumer: failed to store active trip pair for driver %s: %v", payload.Driver.ID, err)
-			return err
+		if err := c.service.SetTripChatPair(trip.Id, trip.UserID, trip.Driver.Id); err != nil {
+			log.Printf("trip_assigned_consumer: failed to store active trip pair for driver %s: %v", trip.Driver.Id, err)
+			return messaging.Transient("redis", err)
+		}
+
+		if err := c.service.SetDriverBusy(ctx, trip.Driver.Id); err != nil {
+			log.Printf("trip_assigned_consumer: failed to mark driver %s busy: %v", trip.Driver.Id, err)
+			return messaging.Transient("redis", err)
+		}
+
+		if err := c.traces.Start(ctx, trip.Driver.Id, trip.Id); err != nil {
+			log.Printf("trip_assigned_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+
+		if pickup, destination, ok := tripEndpoints(payload); ok {
+			if err := c.eta.Start(ctx, trip.Driver.Id, trip.Id, pickup, destination); err != nil {
+				log.Printf("trip_assigned_consumer: %v", err)
+				return messaging.Transient("redis", err)
+			}
+			if err := c.geofences.Start(ctx, trip.Driver.Id, trip.Id, pickup, destination); err != nil {
+				log.Printf("trip_assigned_consumer: %v", err)
+				return messaging.Transient("redis", err)
+			}
+		} else {
+			log.Printf("trip_assigned_consumer: trip %s has no route, riders get no ETA or geofence events", trip.Id)
 		}
 
-		log.Printf("trip_assigned_consumer: driver %s → rider %s", payload.Driver.ID, payload.UserID)
+		log.Printf("trip_assigned_consumer: driver %s → rider %s", trip.Driver.Id, trip.UserID)
 		return nil
 	})
 }
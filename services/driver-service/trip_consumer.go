@@ -12,27 +12,22 @@ import (
 )
 
 type tripConsumer struct {
-	rabbitmq *messaging.RabbitMQ
-	service  *Service
+	broker  messaging.Broker
+	service *Service
 }
 
-func NewTripConsumer(rabbitmq *messaging.RabbitMQ, service *Service) *tripConsumer {
+func NewTripConsumer(broker messaging.Broker, service *Service) *tripConsumer {
 	return &tripConsumer{
-		rabbitmq: rabbitmq,
-		service:  service,
+		broker:  broker,
+		service: service,
 	}
 }
 
 func (c *tripConsumer) Listen() error {
-	return c.rabbitmq.ConsumeMessages(messaging.FindAvailableDriversQueue, func(ctx context.Context, msg amqp091.Delivery) error {
-		var tripEvent contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &tripEvent); err != nil {
-			log.Printf("Error unmarshaling trip event: %v", err)
-			return err
-		}
+	return c.broker.ConsumeMessages(messaging.FindAvailableDriversQueue, func(ctx context.Context, msg amqp091.Delivery) error {
 		var payload messaging.TripEventData
-		if err := json.Unmarshal(tripEvent.Data, &payload); err != nil {
-			log.Printf("Error unmarshaling trip event data: %v", err)
+		if _, err := messaging.DecodePayload(msg, &payload); err != nil {
+			log.Printf("Error decoding trip event: %v", err)
 			return err
 		}
 		// only these keys are mapped to FindAvailableDriversQueue
@@ -56,7 +51,7 @@ func (c *tripConsumer) handleAndNotifyDrivers(ctx context.Context, payload messa
 	if len(suitableIDs) == 0 {
 		// 	If no driver → publish TripEventNoDriversFound (not bound to this queue, so goes elsewhere — consumed by user/gateway).
 		log.Printf("No suitable drivers found for rider: %s", payload.Trip.UserID)
-		if err := c.rabbitmq.PublishMessage(ctx, contracts.TripEventNoDriversFound, contracts.AmqpMessage{
+		if err := c.broker.PublishMessage(ctx, contracts.TripEventNoDriversFound, contracts.AmqpMessage{
 			OwnerID: payload.Trip.UserID,
 			Data:    marshalledEvent,
 		}); err != nil {
@@ -77,7 +72,7 @@ func (c *tripConsumer) handleAndNotifyDrivers(ctx context.Context, payload messa
 	// if rejected by driver then driver service publishes tripNotInterested event to trip exchange after selecting no option
 	// this event will be read by trip_consumer and new driver will be assigned
 
-	if err := c.rabbitmq.PublishMessage(ctx, contracts.DriverCmdTripRequest, contracts.AmqpMessage{
+	if err := c.broker.PublishMessage(ctx, contracts.DriverCmdTripRequest, contracts.AmqpMessage{
 		OwnerID: suitableDriverID, // recipient of message
 		Data:    marshalledEvent,
 	}); err != nil {
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// tripEndedConsumer makes a busy driver available again once their trip is
+// completed or cancelled.
+type tripEndedConsumer struct {
+	broker    messaging.Subscriber
+	service   *Service
+	eta       *etaTracker
+	traces    *traceRecorder
+	geofences *geofenceEngine
+}
+
+func NewTripEndedConsumer(broker messaging.Subscriber, service *Service, eta *etaTracker, traces *traceRecorder, geofences *geofenceEngine) *tripEndedConsumer {
+	return &tripEndedConsumer{broker: broker, service: service, eta: eta, traces: traces, geofences: geofences}
+}
+
+func (c *tripEndedConsumer) Listen() error {
+	return c.broker.ConsumeMessages(messaging.DriverTripEndedQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var driverID, tripID string
+		switch msg.RoutingKey {
+		case contracts.TripEventCompleted:
+			// Published once per participant; riders have no presence, so
+			// SetAvailable is a no-op for them.
+			var payload messaging.TripCompletedData
+			ownerID, err := messaging.DecodePayload(msg, &payload)
+			if err != nil {
+				log.Printf("trip_ended_consumer: failed to decode payload: %v", err)
+				return err
+			}
+			driverID, tripID = ownerID, payload.TripID
+		case contracts.TripEventCancelled:
+			var payload messaging.TripCancelledData
+			if _, err := messaging.DecodePayload(msg, &payload); err != nil {
+				log.Printf("trip_ended_consumer: failed to decode payload: %v", err)
+				return err
+			}
+			driverID, tripID = payload.DriverID, payload.TripID
+		}
+		if driverID == "" {
+			return nil
+		}
+
+		if err := c.service.SetDriverAvailable(ctx, driverID); err != nil {
+			log.Printf("trip_ended_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+		if err := c.eta.Stop(ctx, driverID, tripID); err != nil {
+			log.Printf("trip_ended_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+		if err := c.traces.Stop(ctx, driverID, tripID); err != nil {
+			log.Printf("trip_ended_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+		if err := c.geofences.Stop(ctx, driverID, tripID); err != nil {
+			log.Printf("trip_ended_consumer: %v", err)
+			return messaging.Transient("redis", err)
+		}
+		return nil
+	})
+}
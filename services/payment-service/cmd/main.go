@@ -99,6 +99,13 @@ func main() {
 		}
 	}()
 
+	voidConsumer := events.NewVoidConsumer(rabbitmq, svc)
+	go func() {
+		if err := voidConsumer.Listen(); err != nil {
+			log.Fatalf("Failed to consume message for voiding payment sessions: %v", err)
+		}
+	}()
+
 	log.Println("Starting RabbitMQ connection")
 
 	// Wait for shutdown signal
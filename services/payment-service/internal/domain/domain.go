@@ -8,10 +8,14 @@ import (
 
 type Service interface {
 	CreatePaymentSession(ctx context.Context, tripID, userID, driverID string, amount int64, currency string) (*types.PaymentIntent, error)
+	// VoidPaymentSession expires the unpaid session of a trip. sessionID may be
+	// empty, in which case the session is looked up by trip.
+	VoidPaymentSession(ctx context.Context, tripID, sessionID string) error
 }
 
 type PaymentProcessor interface {
 	CreatePaymentSession(ctx context.Context, amount int64, currency string, metadata map[string]string) (string, error)
+	ExpirePaymentSession(ctx context.Context, sessionID string) error
 	// GetSessionStatus(ctx context.Context, sessionID string) (types.PaymentStatus, error)
 }
 
@@ -20,4 +24,5 @@ type PaymentProcessor interface {
 type SessionStore interface {
 	Get(ctx context.Context, tripID string) (sessionID string, err error)
 	Set(ctx context.Context, tripID string, sessionID string) error
+	Delete(ctx context.Context, tripID string) error
 }
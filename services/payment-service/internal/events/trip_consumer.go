@@ -13,21 +13,21 @@ import (
 )
 
 type TripConsumer struct {
-	rabbitmq *messaging.RabbitMQ
-	service  domain.Service
+	broker  messaging.Broker
+	service domain.Service
 }
 
-func NewTripConsumer(rabbitmq *messaging.RabbitMQ, service domain.Service) *TripConsumer {
+func NewTripConsumer(broker messaging.Broker, service domain.Service) *TripConsumer {
 	return &TripConsumer{
-		rabbitmq: rabbitmq,
-		service:  service,
+		broker:  broker,
+		service: service,
 	}
 }
 
 func (c *TripConsumer) Listen() error {
-	return c.rabbitmq.ConsumeMessages(messaging.PaymentTripResponseQueue, func(ctx context.Context, msg amqp091.Delivery) error {
-		var message contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &message); err != nil {
+	return c.broker.ConsumeMessages(messaging.PaymentTripResponseQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		message, err := messaging.DecodeMessage(msg)
+		if err != nil {
 			log.Printf("Failed to unmarshal message: %v", err)
 			return err
 		}
@@ -85,7 +85,7 @@ func (c *TripConsumer) handleTripAccepted(ctx context.Context, payload messaging
 
 	// notify user for the payment session created
 	// on payment webhook gives response which redirects to scuess/failure url in frontend with trip start
-	if err := c.rabbitmq.PublishMessage(ctx, contracts.PaymentEventSessionCreated,
+	if err := c.broker.PublishMessage(ctx, contracts.PaymentEventSessionCreated,
 		contracts.AmqpMessage{
 			OwnerID: payload.UserID,
 			Data:    payloadBytes,
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"context"
+	"log"
+
+	"ride-sharing/services/payment-service/internal/domain"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// VoidConsumer expires the checkout sessions of trips trip-service's saga
+// gave up on.
+type VoidConsumer struct {
+	broker  messaging.Subscriber
+	service domain.Service
+}
+
+func NewVoidConsumer(broker messaging.Subscriber, service domain.Service) *VoidConsumer {
+	return &VoidConsumer{
+		broker:  broker,
+		service: service,
+	}
+}
+
+func (c *VoidConsumer) Listen() error {
+	return c.broker.ConsumeMessages(messaging.PaymentVoidSessionQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var payload messaging.PaymentVoidSessionData
+		if _, err := messaging.DecodePayload(msg, &payload); err != nil {
+			log.Printf("Failed to unmarshal void session payload: %v", err)
+			return err
+		}
+
+		if err := c.service.VoidPaymentSession(ctx, payload.TripID, payload.SessionID); err != nil {
+			log.Printf("Failed to void payment session for trip %s: %v", payload.TripID, err)
+			return err
+		}
+
+		log.Printf("Voided payment session for trip %s (%s)", payload.TripID, payload.Reason)
+		return nil
+	})
+}
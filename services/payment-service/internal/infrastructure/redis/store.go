@@ -31,6 +31,10 @@ func (s *sessionStore) Set(ctx context.Context, tripID string, sessionID string)
 	return s.rdb.Set(ctx, key(tripID), sessionID, sessionTTL).Err()
 }
 
+func (s *sessionStore) Delete(ctx context.Context, tripID string) error {
+	return s.rdb.Del(ctx, key(tripID)).Err()
+}
+
 func key(tripID string) string {
 	return "payment:session:" + tripID
 }
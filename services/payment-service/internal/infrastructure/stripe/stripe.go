@@ -58,3 +58,20 @@ func (s *stripeClient) CreatePaymentSession(ctx context.Context, amount int64, c
 
 	return result.ID, nil
 }
+
+// ExpirePaymentSession expires an open checkout session so it can no longer be
+// paid. Sessions that are already expired or complete are left as they are.
+func (s *stripeClient) ExpirePaymentSession(ctx context.Context, sessionID string) error {
+	current, err := session.Get(sessionID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get the payment session from stripe: %w", err)
+	}
+	if current.Status != stripe.CheckoutSessionStatusOpen {
+		return nil
+	}
+
+	if _, err := session.Expire(sessionID, nil); err != nil {
+		return fmt.Errorf("failed to expire the payment session on stripe: %w", err)
+	}
+	return nil
+}
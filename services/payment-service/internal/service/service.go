@@ -78,3 +78,27 @@ func (s *paymentService) CreatePaymentSession(
 		CreatedAt:       time.Now(),
 	}, nil
 }
+
+// VoidPaymentSession expires the unpaid session of a trip whose saga was
+// compensated, so the rider can no longer pay for it. It is idempotent.
+func (s *paymentService) VoidPaymentSession(ctx context.Context, tripID, sessionID string) error {
+	if sessionID == "" {
+		stored, err := s.sessionStore.Get(ctx, tripID)
+		if err != nil {
+			return fmt.Errorf("failed to check session store: %w", err)
+		}
+		sessionID = stored
+	}
+	if sessionID == "" {
+		// no session was ever created for this trip
+		return nil
+	}
+
+	if err := s.paymentProcessor.ExpirePaymentSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to void payment session: %w", err)
+	}
+	if err := s.sessionStore.Delete(ctx, tripID); err != nil {
+		return fmt.Errorf("failed to forget payment session: %w", err)
+	}
+	return nil
+}
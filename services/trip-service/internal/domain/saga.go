@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "ride-sharing/shared/proto/trip"
+)
+
+// SagaStep is the position of a trip in the accept → pay → complete saga.
+type SagaStep string
+
+const (
+	SagaStepAwaitingSession SagaStep = "awaiting_session" // payment session requested
+	SagaStepAwaitingPayment SagaStep = "awaiting_payment" // rider has a checkout session
+	SagaStepCompleting      SagaStep = "completing"       // rider paid; trip being completed
+	SagaStepCompleted       SagaStep = "completed"
+	SagaStepCompensating    SagaStep = "compensating" // compensations pending or being retried
+	SagaStepCompensated     SagaStep = "compensated"
+)
+
+// Terminal reports whether the saga has no further steps.
+func (s SagaStep) Terminal() bool {
+	return s == SagaStepCompleted || s == SagaStepCompensated
+}
+
+var (
+	ErrSagaExists   = errors.New("saga already exists")
+	ErrSagaConflict = errors.New("saga was updated concurrently")
+)
+
+// TripSagaModel is the persisted state of the saga of one trip. Deadline is
+// when the current step times out (or, while compensating, when failed
+// compensations are retried); it is zero once the saga is terminal.
+type TripSagaModel struct {
+	TripID    string   `bson:"_id"`
+	RiderID   string   `bson:"riderID"`
+	DriverID  string   `bson:"driverID"`
+	SessionID string   `bson:"sessionID,omitempty"`
+	Amount    float64  `bson:"amount"`
+	Currency  string   `bson:"currency"`
+	Step      SagaStep `bson:"step"`
+
+	Deadline time.Time `bson:"deadline"`
+	// CompensationReason says why the saga is compensating, e.g. trip_cancelled.
+	CompensationReason string `bson:"compensationReason,omitempty"`
+	// CancelTrip is set when the saga itself must cancel the trip and notify
+	// both parties; a rider or driver cancellation has already done both.
+	CancelTrip bool   `bson:"cancelTrip,omitempty"`
+	LastError  string `bson:"lastError,omitempty"`
+
+	StartedAt time.Time `bson:"startedAt"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+	Version   int64     `bson:"version"` // optimistic concurrency between replicas
+}
+
+func (s *TripSagaModel) ToProto() *pb.TripSaga {
+	saga := &pb.TripSaga{
+		TripID:             s.TripID,
+		RiderID:            s.RiderID,
+		DriverID:           s.DriverID,
+		SessionID:          s.SessionID,
+		Step:               string(s.Step),
+		CompensationReason: s.CompensationReason,
+		LastError:          s.LastError,
+		StartedAt:          s.StartedAt.UnixMilli(),
+		UpdatedAt:          s.UpdatedAt.UnixMilli(),
+	}
+	if !s.Deadline.IsZero() {
+		saga.Deadline = s.Deadline.UnixMilli()
+	}
+	return saga
+}
+
+type TripSagaRepository interface {
+	// CreateSaga returns ErrSagaExists if the trip already has a saga.
+	CreateSaga(ctx context.Context, saga *TripSagaModel) error
+	// GetSaga returns (nil, nil) when the trip has no saga.
+	GetSaga(ctx context.Context, tripID string) (*TripSagaModel, error)
+	// UpdateSaga stores saga if its Version is still current and increments
+	// it; otherwise it returns ErrSagaConflict.
+	UpdateSaga(ctx context.Context, saga *TripSagaModel) error
+	// ListDueSagas returns non-terminal sagas whose deadline is before now.
+	ListDueSagas(ctx context.Context, now time.Time) ([]*TripSagaModel, error)
+	// ListStuckSagas returns sagas that are overdue or whose last step failed.
+	ListStuckSagas(ctx context.Context, now time.Time) ([]*TripSagaModel, error)
+}
+
+// SagaPublisher publishes the commands and events the saga orchestrates.
+type SagaPublisher interface {
+	PublishPaymentSessionRequest(ctx context.Context, saga *TripSagaModel) error
+	PublishTripCompleted(ctx context.Context, trip *TripModel) error
+	PublishTripCancelled(ctx context.Context, tripID, riderID, driverID string, driverAccepted bool, cancelledBy, reason string) error
+	PublishVoidPaymentSession(ctx context.Context, saga *TripSagaModel) error
+	PublishDriverRelease(ctx context.Context, saga *TripSagaModel) error
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ride-sharing/shared/tripshare"
+)
+
+var (
+	ErrNotTripRider = errors.New("only the trip's rider can share it")
+	ErrTripEnded    = errors.New("trip has ended")
+)
+
+// Ended reports whether the trip was completed or cancelled.
+func (t *TripModel) Ended() bool {
+	return t.Status == "completed" || t.Status == "cancelled"
+}
+
+// TripShareStore mints and revokes public share links of trips.
+// *tripshare.Store implements it.
+type TripShareStore interface {
+	Create(ctx context.Context, tripID, riderID string, ttl time.Duration) (string, tripshare.Share, error)
+	Revoke(ctx context.Context, tripID, shareID string) (bool, error)
+	RevokeTrip(ctx context.Context, tripID string) (int, error)
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tripTypes "ride-sharing/services/trip-service/pkg/types"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/trip"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TripEventModel is one entry of a trip's append-only timeline. Entries are
+// never updated; the trip document can be rebuilt with FoldTripTimeline.
+type TripEventModel struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	TripID        string             `bson:"tripID"`
+	MessageID     string             `bson:"messageID"` // unique; redeliveries are recorded once
+	Type          string             `bson:"type"`      // routing key
+	Actor         string             `bson:"actor"`     // rider:<id>, driver:<id> or service:<name>
+	OwnerID       string             `bson:"ownerID"`   // user the message was addressed to
+	OccurredAt    time.Time          `bson:"occurredAt"`
+	PayloadDigest string             `bson:"payloadDigest"`
+	Payload       string             `bson:"payload"` // JSON message data
+}
+
+func (e *TripEventModel) ToProto() *pb.TripTimelineEvent {
+	return &pb.TripTimelineEvent{
+		MessageID:     e.MessageID,
+		Type:          e.Type,
+		Actor:         e.Actor,
+		OwnerID:       e.OwnerID,
+		OccurredAt:    e.OccurredAt.UnixMilli(),
+		PayloadDigest: e.PayloadDigest,
+	}
+}
+
+type TripTimelineRepository interface {
+	// AppendTripEvent records event unless an event with the same MessageID
+	// was already recorded.
+	AppendTripEvent(ctx context.Context, event *TripEventModel) error
+	// ListTripEvents returns the timeline of tripID, oldest first.
+	ListTripEvents(ctx context.Context, tripID string) ([]*TripEventModel, error)
+}
+
+// FoldTripTimeline rebuilds the trip document from its timeline. Events that
+// do not change the document (offers, declines, payments) are skipped. If an
+// event can't be applied, it returns the trip folded up to that event (nil if
+// the timeline doesn't start with the trip's creation) along with the error.
+func FoldTripTimeline(events []*TripEventModel) (*TripModel, error) {
+	var trip *TripModel
+	for _, event := range events {
+		if trip == nil && event.Type != contracts.TripEventCreated {
+			return nil, fmt.Errorf("timeline of trip %s does not start with %s", event.TripID, contracts.TripEventCreated)
+		}
+
+		switch event.Type {
+		case contracts.TripEventCreated:
+			var data messaging.TripEventData
+			if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+				return trip, fmt.Errorf("decode %s: %w", event.Type, err)
+			}
+			created, err := tripFromProto(data.Trip)
+			if err != nil {
+				return trip, err
+			}
+			trip = created
+		case contracts.TripEventDriverAssigned:
+			var data messaging.TripEventData
+			if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+				return trip, fmt.Errorf("decode %s: %w", event.Type, err)
+			}
+			trip.Status = "accepted"
+			if data.Trip != nil && data.Trip.Driver != nil {
+				trip.Driver = data.Trip.Driver
+			}
+		case contracts.TripEventCompleted:
+			trip.Status = "completed"
+		case contracts.TripEventCancelled:
+			trip.Status = "cancelled"
+		}
+	}
+	if trip == nil {
+		return nil, fmt.Errorf("timeline is empty")
+	}
+	return trip, nil
+}
+
+// tripFromProto is the inverse of TripModel.ToProto.
+func tripFromProto(t *pb.Trip) (*TripModel, error) {
+	if t == nil {
+		return nil, fmt.Errorf("trip payload is nil")
+	}
+	id, err := primitive.ObjectIDFromHex(t.Id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trip id %q: %w", t.Id, err)
+	}
+
+	fare := &RideFareModel{UserID: t.UserID, Route: tripTypes.RouteFromProto(t.Route)}
+	if f := t.SelectedFare; f != nil {
+		fare.PackageSlug = f.PackageSlug
+		fare.TotalPriceInCents = f.TotalPriceInCents
+		if fareID, err := primitive.ObjectIDFromHex(f.Id); err == nil {
+			fare.ID = fareID
+		}
+	}
+
+	return &TripModel{
+		ID:       id,
+		UserID:   t.UserID,
+		Status:   t.Status,
+		RideFare: fare,
+		Driver:   t.Driver,
+	}, nil
+}
@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"time"
+
+	pb "ride-sharing/shared/proto/trip"
+)
+
+// traceScale is the resolution positions are stored at: 1e-6 degrees, about
+// 11 cm.
+const traceScale = 1e6
+
+// TracePoint is one recorded position of a trip's driver.
+type TracePoint struct {
+	Latitude  float64
+	Longitude float64
+	At        time.Time
+	Speed     float64 // m/s
+}
+
+func (p TracePoint) ToProto() *pb.TracePoint {
+	return &pb.TracePoint{
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+		At:        p.At.UnixMilli(),
+		Speed:     p.Speed,
+	}
+}
+
+// TraceChunk is one batch of points as driver-service sent it, encoded with
+// EncodeTracePoints.
+type TraceChunk struct {
+	ID    string `bson:"id"` // time of the first point; a batch sent twice is stored once
+	Count int    `bson:"count"`
+	Data  []byte `bson:"data"`
+}
+
+// TripTraceModel is the path a trip's driver took from accepting the trip to
+// its end. Anonymized traces have no driver and coarsened points.
+type TripTraceModel struct {
+	TripID       string       `bson:"_id"`
+	DriverID     string       `bson:"driverID,omitempty"`
+	Chunks       []TraceChunk `bson:"chunks"`
+	StartedAt    time.Time    `bson:"startedAt"`
+	EndedAt      time.Time    `bson:"endedAt"` // time of the last point stored so far
+	AnonymizedAt *time.Time   `bson:"anonymizedAt,omitempty"`
+}
+
+// Points decodes the trace's points, oldest first. Chunks are stored in the
+// order they arrived, which is not always the order they were recorded in.
+func (t *TripTraceModel) Points() ([]TracePoint, error) {
+	var points []TracePoint
+	for i, chunk := range t.Chunks {
+		decoded, err := DecodeTracePoints(chunk.Data)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d of trace %s: %w", i, t.TripID, err)
+		}
+		points = append(points, decoded...)
+	}
+	slices.SortStableFunc(points, func(a, b TracePoint) int { return a.At.Compare(b.At) })
+	return points, nil
+}
+
+type TripTraceRepository interface {
+	// AppendTraceChunk adds chunk, covering from to to, to the trace of tripID,
+	// creating the trace if needed. A chunk with an ID already stored is
+	// ignored.
+	AppendTraceChunk(ctx context.Context, tripID, driverID string, chunk TraceChunk, from, to time.Time) error
+	// GetTripTrace returns the trace of tripID, or nil if none was recorded.
+	GetTripTrace(ctx context.Context, tripID string) (*TripTraceModel, error)
+	// ListTracesEndedBefore returns up to limit traces that ended before
+	// before and are not anonymized.
+	ListTracesEndedBefore(ctx context.Context, before time.Time, limit int) ([]*TripTraceModel, error)
+	// ReplaceTripTrace overwrites a trace, e.g. with its anonymized version.
+	ReplaceTripTrace(ctx context.Context, trace *TripTraceModel) error
+	// DeleteTracesEndedBefore deletes every trace that ended before before.
+	DeleteTracesEndedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EncodeTracePoints packs points compactly: each point is stored as varint
+// deltas from the previous one (positions at traceScale, times in ms, speeds
+// in cm/s), and the result is DEFLATE-compressed.
+func EncodeTracePoints(points []TracePoint) ([]byte, error) {
+	var raw []byte
+	raw = binary.AppendUvarint(raw, uint64(len(points)))
+	var lat, lng, at int64
+	for _, p := range points {
+		nextLat := int64(math.Round(p.Latitude * traceScale))
+		nextLng := int64(math.Round(p.Longitude * traceScale))
+		nextAt := p.At.UnixMilli()
+		raw = binary.AppendVarint(raw, nextLat-lat)
+		raw = binary.AppendVarint(raw, nextLng-lng)
+		raw = binary.AppendVarint(raw, nextAt-at)
+		raw = binary.AppendUvarint(raw, uint64(math.Round(max(p.Speed, 0)*100)))
+		lat, lng, at = nextLat, nextLng, nextAt
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var errCorruptTrace = errors.New("corrupt trace data")
+
+// DecodeTracePoints is the inverse of EncodeTracePoints.
+func DecodeTracePoints(data []byte) ([]TracePoint, error) {
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorruptTrace, err)
+	}
+	r := bytes.NewReader(raw)
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(len(raw)) {
+		return nil, errCorruptTrace
+	}
+
+	points := make([]TracePoint, 0, count)
+	var lat, lng, at int64
+	for range count {
+		dLat, err1 := binary.ReadVarint(r)
+		dLng, err2 := binary.ReadVarint(r)
+		dAt, err3 := binary.ReadVarint(r)
+		speed, err4 := binary.ReadUvarint(r)
+		if err := errors.Join(err1, err2, err3, err4); err != nil {
+			return nil, errCorruptTrace
+		}
+		lat, lng, at = lat+dLat, lng+dLng, at+dAt
+		points = append(points, TracePoint{
+			Latitude:  float64(lat) / traceScale,
+			Longitude: float64(lng) / traceScale,
+			At:        time.UnixMilli(at).UTC(),
+			Speed:     float64(speed) / 100,
+		})
+	}
+	return points, nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	tripTypes "ride-sharing/services/trip-service/pkg/types"
 	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/tripshare"
 	"ride-sharing/shared/types"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -53,4 +55,6 @@ type TripService interface {
 	CompleteTrip(ctx context.Context, tripID string) (*TripModel, error)
 	UpdateTrip(ctx context.Context, tripID string, status string, driver *pb.TripDriver) error
 	CancelTrip(ctx context.Context, tripID, requesterUserID string) (*TripModel, error)
+	ShareTrip(ctx context.Context, tripID, requesterUserID string, ttl time.Duration) (string, tripshare.Share, error)
+	RevokeTripShare(ctx context.Context, tripID, shareID, requesterUserID string) error
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/service"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
 	pb "ride-sharing/shared/proto/trip"
@@ -14,20 +15,22 @@ import (
 )
 
 type driverConsumer struct {
-	rabbitmq *messaging.RabbitMQ
-	service  domain.TripService
+	broker  messaging.Broker
+	service domain.TripService
+	saga    *service.SagaOrchestrator
 }
 
-func NewDriverConsumer(rabbitmq *messaging.RabbitMQ, service domain.TripService) *driverConsumer {
+func NewDriverConsumer(broker messaging.Broker, tripService domain.TripService, saga *service.SagaOrchestrator) *driverConsumer {
 	return &driverConsumer{
-		rabbitmq: rabbitmq,
-		service:  service,
+		broker:  broker,
+		service: tripService,
+		saga:    saga,
 	}
 }
 func (c *driverConsumer) Listen() error {
-	return c.rabbitmq.ConsumeMessages(messaging.DriverTripResponseQueue, func(ctx context.Context, msg amqp091.Delivery) error {
-		var message contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &message); err != nil {
+	return c.broker.ConsumeMessages(messaging.DriverTripResponseQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		message, err := messaging.DecodeMessage(msg)
+		if err != nil {
 			log.Printf("Failed to unmarshal message: %v", err)
 			return err
 		}
@@ -72,7 +75,7 @@ func (c *driverConsumer) handleTripDeclined(ctx context.Context, tripID, riderID
 		return err
 	}
 
-	if err := c.rabbitmq.PublishMessage(ctx, contracts.TripEventDriverNotInterested,
+	if err := c.broker.PublishMessage(ctx, contracts.TripEventDriverNotInterested,
 		contracts.AmqpMessage{
 			OwnerID: riderID,
 			Data:    marshalledPayload,
@@ -109,36 +112,18 @@ func (c *driverConsumer) handleTripAccepted(ctx context.Context, payload messagi
 		return err
 	}
 
-	// 4. Publish TripEventDriverAssigned with proto JSON so the rider gets lowercase keys
-	marshalledTrip, err := json.Marshal(trip.ToProto())
+	// 4. Publish TripEventDriverAssigned in the same TripEventData shape as every other trip event
+	marshalledTrip, err := json.Marshal(messaging.TripEventData{Trip: trip.ToProto()})
 	if err != nil {
 		return err
 	}
-	if err := c.rabbitmq.PublishMessage(ctx, contracts.TripEventDriverAssigned, contracts.AmqpMessage{
+	if err := c.broker.PublishMessage(ctx, contracts.TripEventDriverAssigned, contracts.AmqpMessage{
 		OwnerID: trip.UserID,
 		Data:    marshalledTrip,
 	}); err != nil {
 		return err
 	}
 
-	// 5. Notify payment service
-	marshalledPayload, err := json.Marshal(messaging.PaymentTripResponseData{
-		TripID:   payload.TripID,
-		UserID:   trip.UserID,
-		DriverID: payload.DriverID,
-		Amount:   trip.RideFare.TotalPriceInCents,
-		Currency: "USD",
-	})
-	if err != nil {
-		return err
-	}
-	if err := c.rabbitmq.PublishMessage(ctx, contracts.PaymentCmdCreateSession,
-		contracts.AmqpMessage{
-			OwnerID: trip.UserID,
-			Data:    marshalledPayload,
-		},
-	); err != nil {
-		return err
-	}
-	return nil
+	// 5. Start the payment saga; it asks payment service for a checkout session
+	return c.saga.Start(ctx, trip)
 }
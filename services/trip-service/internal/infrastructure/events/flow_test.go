@@ -0,0 +1,359 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/infrastructure/repository"
+	"ride-sharing/services/trip-service/internal/service"
+	tripTypes "ride-sharing/services/trip-service/pkg/types"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/retry"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// tripFlow runs trip-service's consumers on an in-memory broker. The driver
+// and payment services are replaced by stand-ins that answer on the same
+// queues the real services consume.
+type tripFlow struct {
+	broker    *messaging.InMemoryBroker
+	svc       *service.TripService
+	timeline  *service.TimelineService
+	traces    *service.TraceService
+	saga      *service.SagaOrchestrator
+	publisher *TripEventPublisher
+}
+
+func newTripFlow(t *testing.T, sagaCfg service.SagaConfig, riderPays bool) *tripFlow {
+	t.Helper()
+
+	broker, err := messaging.NewInMemoryBroker(messaging.DefaultTopology, retry.Config{
+		MaxRetries:  1,
+		InitialWait: time.Millisecond,
+		MaxWait:     time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewInMemoryBroker: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	repo := repository.NewInmemoryRepository()
+	f := &tripFlow{
+		broker:    broker,
+		svc:       service.NewTripService(repo, nil, nil),
+		timeline:  service.NewTimelineService(repo),
+		traces:    service.NewTraceService(repo, service.DefaultTraceRetention()),
+		publisher: NewTripEventPublisher(broker),
+	}
+	f.saga = service.NewSagaOrchestrator(repo, f.svc, f.publisher, sagaCfg)
+
+	if err := NewDriverConsumer(broker, f.svc, f.saga).Listen(); err != nil {
+		t.Fatalf("driver consumer: %v", err)
+	}
+	if err := NewPaymentConsumer(broker, f.saga).Listen(); err != nil {
+		t.Fatalf("payment consumer: %v", err)
+	}
+	if err := NewTimelineConsumer(broker, f.timeline).Listen(); err != nil {
+		t.Fatalf("timeline consumer: %v", err)
+	}
+	if err := NewTraceConsumer(broker, f.traces).Listen(); err != nil {
+		t.Fatalf("trace consumer: %v", err)
+	}
+
+	// driver-service stand-in: the first driver accepts every trip offered.
+	if err := broker.ConsumeMessages(messaging.FindAvailableDriversQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		if msg.ContentType != messaging.ContentTypeProtobuf {
+			t.Errorf("trip event sent as %q, want protobuf", msg.ContentType)
+		}
+		var event messaging.TripEventData
+		if _, err := messaging.DecodePayload(msg, &event); err != nil {
+			return err
+		}
+		data, err := json.Marshal(messaging.DriverTripResponseData{
+			TripID:     event.Trip.Id,
+			RiderID:    event.Trip.UserID,
+			DriverID:   "driver-1",
+			DriverName: "Lewis",
+		})
+		if err != nil {
+			return err
+		}
+		return broker.PublishMessage(ctx, contracts.DriverCmdTripAccept, contracts.AmqpMessage{OwnerID: "driver-1", Data: data})
+	}); err != nil {
+		t.Fatalf("driver stand-in: %v", err)
+	}
+
+	// payment-service stand-in: a session is created as soon as it is
+	// requested and, if riderPays, the rider pays it straight away.
+	if err := broker.ConsumeMessages(messaging.PaymentTripResponseQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var cmd messaging.PaymentTripResponseData
+		if _, err := messaging.DecodePayload(msg, &cmd); err != nil {
+			return err
+		}
+		if cmd.Amount != 1250 {
+			t.Errorf("payment session requested for %v cents, want 1250", cmd.Amount)
+		}
+		session, err := json.Marshal(messaging.PaymentEventSessionCreatedData{
+			TripID:    cmd.TripID,
+			SessionID: "cs_test_1",
+			Amount:    cmd.Amount / 100,
+			Currency:  cmd.Currency,
+		})
+		if err != nil {
+			return err
+		}
+		if err := broker.PublishMessage(ctx, contracts.PaymentEventSessionCreated, contracts.AmqpMessage{OwnerID: cmd.UserID, Data: session}); err != nil {
+			return err
+		}
+		if !riderPays {
+			return nil
+		}
+		data, err := json.Marshal(messaging.PaymentStatusUpdateData{
+			TripID:   cmd.TripID,
+			UserID:   cmd.UserID,
+			DriverID: cmd.DriverID,
+		})
+		if err != nil {
+			return err
+		}
+		return broker.PublishMessage(ctx, contracts.PaymentEventSuccess, contracts.AmqpMessage{OwnerID: cmd.UserID, Data: data})
+	}); err != nil {
+		t.Fatalf("payment stand-in: %v", err)
+	}
+
+	return f
+}
+
+// requestTrip creates a trip for rider-1 and waits until the flow settles.
+func (f *tripFlow) requestTrip(t *testing.T) *domain.TripModel {
+	t.Helper()
+	ctx := context.Background()
+
+	var route tripTypes.OSRMApiResponse
+	if err := json.Unmarshal([]byte(`{"routes":[{"distance":1200,"duration":300,"geometry":{"coordinates":[[13.40,52.52],[13.41,52.53]]}}]}`), &route); err != nil {
+		t.Fatalf("route fixture: %v", err)
+	}
+	trip, err := f.svc.CreateTrip(ctx, &domain.RideFareModel{
+		ID:                primitive.NewObjectID(),
+		UserID:            "rider-1",
+		PackageSlug:       "sedan",
+		TotalPriceInCents: 1250,
+		Route:             &route,
+	})
+	if err != nil {
+		t.Fatalf("CreateTrip: %v", err)
+	}
+	if err := f.publisher.PublishTripCreated(ctx, trip); err != nil {
+		t.Fatalf("PublishTripCreated: %v", err)
+	}
+	f.waitIdle(t)
+	return trip
+}
+
+func (f *tripFlow) waitIdle(t *testing.T) {
+	t.Helper()
+	waitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := f.broker.WaitIdle(waitCtx); err != nil {
+		t.Fatalf("flow did not settle: %v", err)
+	}
+}
+
+// TestTripFlow_AcceptAndPay drives a trip from creation to completion.
+func TestTripFlow_AcceptAndPay(t *testing.T) {
+	ctx := context.Background()
+	f := newTripFlow(t, service.DefaultSagaConfig(), true)
+	trip := f.requestTrip(t)
+
+	got, err := f.svc.GetTripByID(ctx, trip.ID.Hex())
+	if err != nil {
+		t.Fatalf("GetTripByID: %v", err)
+	}
+	if got.Status != "completed" {
+		t.Fatalf("trip status = %q, want completed", got.Status)
+	}
+	if got.Driver == nil || got.Driver.Id != "driver-1" {
+		t.Fatalf("trip driver = %v, want driver-1", got.Driver)
+	}
+
+	if n := len(f.broker.Messages(messaging.NotifyDriverAssignQueue)); n != 1 {
+		t.Fatalf("expected 1 driver_assigned notification, got %d", n)
+	}
+	completed := f.broker.Messages(messaging.NotifyTripCompletedQueue)
+	owners := map[string]bool{}
+	for _, d := range completed {
+		envelope, err := messaging.DecodeMessage(d)
+		if err != nil {
+			t.Fatalf("completed envelope: %v", err)
+		}
+		owners[envelope.OwnerID] = true
+	}
+	if len(completed) != 2 || !owners["rider-1"] || !owners["driver-1"] {
+		t.Fatalf("trip completed should notify rider and driver, got owners %v", owners)
+	}
+
+	timeline, err := f.timeline.GetTimeline(ctx, trip.ID.Hex())
+	if err != nil || timeline == nil || timeline.FoldErr != nil {
+		t.Fatalf("GetTimeline = %+v, %v", timeline, err)
+	}
+	events, folded := timeline.Events, timeline.Trip
+	var types []string
+	actors := map[string]string{}
+	for _, e := range events {
+		types = append(types, e.Type)
+		actors[e.Type] = e.Actor
+	}
+	// one trip.event.completed per participant
+	if len(events) != 8 {
+		t.Fatalf("timeline = %v, want 8 events", types)
+	}
+	if actors[contracts.TripEventCreated] != "rider:rider-1" || actors[contracts.DriverCmdTripAccept] != "driver:driver-1" {
+		t.Fatalf("unexpected timeline actors %v", actors)
+	}
+	if folded.Status != got.Status || folded.Driver.GetId() != got.Driver.Id || folded.UserID != got.UserID {
+		t.Fatalf("folded trip = %+v, want %+v", folded, got)
+	}
+	if fare := folded.ToProto().GetSelectedFare(); fare.GetTotalPriceInCents() != 1250 {
+		t.Fatalf("folded fare = %v, want 1250 cents", fare)
+	}
+
+	if stuck, err := f.saga.ListStuckSagas(ctx); err != nil || len(stuck) != 0 {
+		t.Fatalf("ListStuckSagas = %v, %v; want none", stuck, err)
+	}
+	if dead := f.broker.Messages(messaging.DeadLetterQueue); len(dead) != 0 {
+		t.Fatalf("expected no dead-lettered messages, got %d", len(dead))
+	}
+}
+
+// TestTripFlow_PaymentTimeoutCompensates leaves the checkout session unpaid
+// and checks that the saga cancels the trip, voids the session, releases the
+// driver and notifies both parties.
+func TestTripFlow_PaymentTimeoutCompensates(t *testing.T) {
+	ctx := context.Background()
+	cfg := service.DefaultSagaConfig()
+	cfg.PaymentTimeout = time.Millisecond
+	f := newTripFlow(t, cfg, false)
+	trip := f.requestTrip(t)
+	time.Sleep(5 * time.Millisecond) // let the payment step time out
+
+	stuck, err := f.saga.ListStuckSagas(ctx)
+	if err != nil {
+		t.Fatalf("ListStuckSagas: %v", err)
+	}
+	if len(stuck) != 1 || stuck[0].Step != domain.SagaStepAwaitingPayment || stuck[0].SessionID != "cs_test_1" {
+		t.Fatalf("stuck sagas = %+v, want the unpaid trip awaiting payment", stuck)
+	}
+
+	if err := f.saga.Tick(ctx); err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+	f.waitIdle(t)
+
+	got, err := f.svc.GetTripByID(ctx, trip.ID.Hex())
+	if err != nil {
+		t.Fatalf("GetTripByID: %v", err)
+	}
+	if got.Status != "cancelled" {
+		t.Fatalf("trip status = %q, want cancelled", got.Status)
+	}
+
+	var void messaging.PaymentVoidSessionData
+	voids := f.broker.Messages(messaging.PaymentVoidSessionQueue)
+	if len(voids) != 1 {
+		t.Fatalf("expected 1 void session command, got %d", len(voids))
+	}
+	if _, err := messaging.DecodePayload(voids[0], &void); err != nil || void.SessionID != "cs_test_1" {
+		t.Fatalf("void session = %+v, %v; want cs_test_1", void, err)
+	}
+	if n := len(f.broker.Messages(messaging.DriverReleaseQueue)); n != 1 {
+		t.Fatalf("expected 1 driver release command, got %d", n)
+	}
+	var cancelled messaging.TripCancelledData
+	notices := f.broker.Messages(messaging.NotifyTripCancelledQueue)
+	if len(notices) != 1 {
+		t.Fatalf("expected 1 cancellation notice, got %d", len(notices))
+	}
+	if _, err := messaging.DecodePayload(notices[0], &cancelled); err != nil || cancelled.DriverID != "driver-1" || cancelled.Reason != service.SagaReasonPaymentTimeout {
+		t.Fatalf("cancellation = %+v, %v; want driver-1 notified of %s", cancelled, err, service.SagaReasonPaymentTimeout)
+	}
+
+	if stuck, err := f.saga.ListStuckSagas(ctx); err != nil || len(stuck) != 0 {
+		t.Fatalf("ListStuckSagas after compensation = %v, %v; want none", stuck, err)
+	}
+	if timeline, err := f.timeline.GetTimeline(ctx, trip.ID.Hex()); err != nil || timeline == nil || timeline.Trip == nil || timeline.Trip.Status != "cancelled" {
+		t.Fatalf("timeline = %+v, %v; want a cancelled trip", timeline, err)
+	}
+}
+
+// TestTripFlow_Trace stores driver-service's trace batches, exports them and
+// anonymizes them once the retention period is over.
+func TestTripFlow_Trace(t *testing.T) {
+	ctx := context.Background()
+	f := newTripFlow(t, service.DefaultSagaConfig(), true)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	publish := func(points ...*messaging.TracePoint) {
+		t.Helper()
+		data, err := json.Marshal(messaging.DriverTraceData{TripID: "trip-1", DriverID: "driver-1", Points: points})
+		if err != nil {
+			t.Fatalf("marshal trace: %v", err)
+		}
+		if err := f.broker.PublishMessage(ctx, contracts.DriverEventTrace, contracts.AmqpMessage{OwnerID: "driver-1", Data: data}); err != nil {
+			t.Fatalf("PublishMessage: %v", err)
+		}
+	}
+	first := []*messaging.TracePoint{
+		{Latitude: 52.520008, Longitude: 13.404954, At: start.UnixMilli(), Speed: 8.5},
+		{Latitude: 52.520412, Longitude: 13.405511, At: start.Add(30 * time.Second).UnixMilli(), Speed: 9.25},
+	}
+	publish(first...)
+	publish(first...) // sent again after a crash before the batch was trimmed
+	publish(&messaging.TracePoint{Latitude: 52.521107, Longitude: 13.406843, At: start.Add(time.Minute).UnixMilli()})
+	f.waitIdle(t)
+
+	trace, points, err := f.traces.GetTrace(ctx, "trip-1")
+	if err != nil || trace == nil {
+		t.Fatalf("GetTrace = %v, %v; want a trace", trace, err)
+	}
+	if len(points) != 3 || trace.DriverID != "driver-1" || !trace.EndedAt.Equal(start.Add(time.Minute)) {
+		t.Fatalf("trace = %+v with %d points, want driver-1's 3 points ending at %s", trace, len(points), start.Add(time.Minute))
+	}
+	if p := points[1]; p.Latitude != 52.520412 || p.Longitude != 13.405511 || p.Speed != 9.25 || !p.At.Equal(start.Add(30*time.Second)) {
+		t.Fatalf("second point = %+v, want it as recorded", p)
+	}
+
+	geojson, contentType, err := service.ExportTrace(trace, points, service.TraceFormatGeoJSON)
+	if err != nil || contentType != "application/geo+json" {
+		t.Fatalf("ExportTrace(geojson) = %q, %v", contentType, err)
+	}
+	var feature struct {
+		Geometry struct {
+			Type        string       `json:"type"`
+			Coordinates [][2]float64 `json:"coordinates"`
+		} `json:"geometry"`
+	}
+	if err := json.Unmarshal(geojson, &feature); err != nil || feature.Geometry.Type != "LineString" || len(feature.Geometry.Coordinates) != 3 || feature.Geometry.Coordinates[0] != [2]float64{13.404954, 52.520008} {
+		t.Fatalf("geojson = %s, %v; want a LineString of 3 [lng, lat] points", geojson, err)
+	}
+	if _, _, err := service.ExportTrace(trace, points, "kml"); !errors.Is(err, service.ErrUnknownTraceFormat) {
+		t.Fatalf("ExportTrace(kml) error = %v, want ErrUnknownTraceFormat", err)
+	}
+
+	if err := f.traces.ApplyRetention(ctx, start.Add(91*24*time.Hour)); err != nil {
+		t.Fatalf("ApplyRetention: %v", err)
+	}
+	trace, points, err = f.traces.GetTrace(ctx, "trip-1")
+	if err != nil || trace.AnonymizedAt == nil || trace.DriverID != "" {
+		t.Fatalf("trace after retention = %+v, %v; want it anonymized", trace, err)
+	}
+	if len(points) != 3 || points[0].Latitude != 52.52 || points[0].Longitude != 13.405 {
+		t.Fatalf("anonymized points = %+v, want 3 points coarsened to 1e-3 degrees", points)
+	}
+}
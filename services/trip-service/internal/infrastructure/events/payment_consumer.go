@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"log"
 
-	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/service"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
 
@@ -13,48 +13,46 @@ import (
 )
 
 type paymentConsumer struct {
-	rabbitmq  *messaging.RabbitMQ
-	service   domain.TripService
-	publisher *TripEventPublisher
+	broker messaging.Subscriber
+	saga   *service.SagaOrchestrator
 }
 
-func NewPaymentConsumer(rabbitmq *messaging.RabbitMQ, service domain.TripService, publisher *TripEventPublisher) *paymentConsumer {
+func NewPaymentConsumer(broker messaging.Subscriber, saga *service.SagaOrchestrator) *paymentConsumer {
 	return &paymentConsumer{
-		rabbitmq:  rabbitmq,
-		service:   service,
-		publisher: publisher,
+		broker: broker,
+		saga:   saga,
 	}
 }
 
+// Listen feeds payment events into the trip's saga: the checkout session
+// moves it to awaiting payment, a successful payment completes the trip.
 func (c *paymentConsumer) Listen() error {
-	return c.rabbitmq.ConsumeMessages(messaging.NotifyPaymentSuccessQueue, func(ctx context.Context, msg amqp091.Delivery) error {
-		var message contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &message); err != nil {
-			log.Printf("Failed to unmarshal message: %v", err)
-			return err
-		}
-		// This code snippet is attempting to unmarshal the `Data` field of an `AmqpMessage` struct into a
-		// variable named `payload` of type `messaging.PaymentStatusUpdateData`. The `json.Unmarshal`
-		// function is used to decode the JSON data from the `message.Data` field into the `payload`
-		// variable. If there is an error during the unmarshaling process, it logs the error and returns the
-		// error.
-		var payload messaging.PaymentStatusUpdateData
-		if err := json.Unmarshal(message.Data, &payload); err != nil {
-			log.Printf("Failed to unmarshal payload: %v", err)
-			return err
-		}
-
-		log.Printf("Trip payment succeeded; marking trip completed.")
-
-		trip, err := c.service.CompleteTrip(ctx, payload.TripID)
+	return c.broker.ConsumeMessages(messaging.NotifyPaymentSuccessQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		message, err := messaging.DecodeMessage(msg)
 		if err != nil {
+			log.Printf("Failed to unmarshal message: %v", err)
 			return err
 		}
 
-		if err := c.publisher.PublishTripCompleted(ctx, trip); err != nil {
-			return err
+		switch msg.RoutingKey {
+		case contracts.PaymentEventSessionCreated:
+			var payload messaging.PaymentEventSessionCreatedData
+			if err := json.Unmarshal(message.Data, &payload); err != nil {
+				log.Printf("Failed to unmarshal payload: %v", err)
+				return err
+			}
+			return c.saga.OnSessionCreated(ctx, payload.TripID, payload.SessionID)
+
+		case contracts.PaymentEventSuccess:
+			var payload messaging.PaymentStatusUpdateData
+			if err := json.Unmarshal(message.Data, &payload); err != nil {
+				log.Printf("Failed to unmarshal payload: %v", err)
+				return err
+			}
+
+			log.Printf("Trip payment succeeded; marking trip completed.")
+			return c.saga.OnPaymentSucceeded(ctx, payload.TripID)
 		}
-
 		return nil
 	})
 }
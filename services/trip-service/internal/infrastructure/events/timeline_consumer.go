@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"log"
+
+	"ride-sharing/services/trip-service/internal/service"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// timelineConsumer records every trip event on the trip exchange in the
+// trip's append-only timeline.
+type timelineConsumer struct {
+	broker   messaging.Subscriber
+	timeline *service.TimelineService
+}
+
+func NewTimelineConsumer(broker messaging.Subscriber, timeline *service.TimelineService) *timelineConsumer {
+	return &timelineConsumer{
+		broker:   broker,
+		timeline: timeline,
+	}
+}
+
+func (c *timelineConsumer) Listen() error {
+	return c.broker.ConsumeMessages(messaging.TripTimelineQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		message, err := messaging.DecodeMessage(msg)
+		if err != nil {
+			log.Printf("Failed to decode timeline event: %v", err)
+			return err
+		}
+
+		if err := c.timeline.RecordEvent(ctx, msg.RoutingKey, msg.MessageId, msg.Timestamp, message); err != nil {
+			log.Printf("Failed to record %s on the trip timeline: %v", msg.RoutingKey, err)
+			return err
+		}
+		return nil
+	})
+}
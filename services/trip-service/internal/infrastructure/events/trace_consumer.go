@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"log"
+
+	"ride-sharing/services/trip-service/internal/service"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// traceConsumer stores the batches of trip positions driver-service sends on
+// driver.event.trace.
+type traceConsumer struct {
+	broker messaging.Subscriber
+	traces *service.TraceService
+}
+
+func NewTraceConsumer(broker messaging.Subscriber, traces *service.TraceService) *traceConsumer {
+	return &traceConsumer{
+		broker: broker,
+		traces: traces,
+	}
+}
+
+func (c *traceConsumer) Listen() error {
+	return c.broker.ConsumeMessages(messaging.TripTraceQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		var data messaging.DriverTraceData
+		if _, err := messaging.DecodePayload(msg, &data); err != nil {
+			log.Printf("Failed to decode trace batch: %v", err)
+			return err
+		}
+		if err := data.Validate(); err != nil {
+			log.Printf("Invalid trace batch: %v", err)
+			return err
+		}
+
+		if err := c.traces.RecordBatch(ctx, data); err != nil {
+			log.Printf("Failed to record trace batch of trip %s: %v", data.TripID, err)
+			return err
+		}
+		return nil
+	})
+}
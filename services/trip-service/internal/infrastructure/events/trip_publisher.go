@@ -10,12 +10,12 @@ import (
 )
 
 type TripEventPublisher struct {
-	rabbitMQ *messaging.RabbitMQ
+	publisher messaging.Publisher
 }
 
-func NewTripEventPublisher(rabbitMQ *messaging.RabbitMQ) *TripEventPublisher {
+func NewTripEventPublisher(publisher messaging.Publisher) *TripEventPublisher {
 	return &TripEventPublisher{
-		rabbitMQ: rabbitMQ,
+		publisher: publisher,
 	}
 }
 
@@ -39,7 +39,7 @@ func (p *TripEventPublisher) PublishTripCreated(ctx context.Context, trip *domai
 		return err
 	}
 	log.Printf("Publishing Trip Created event for tripID: %s, userID: %s", trip.ID.Hex(), trip.UserID)
-	return p.rabbitMQ.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{
+	return p.publisher.PublishMessage(ctx, contracts.TripEventCreated, contracts.AmqpMessage{
 		// consumed by driver service to find suitable drivers and notify them of the new trip
 		Data:    tripEventJSON,
 		OwnerID: trip.UserID,
@@ -48,14 +48,14 @@ func (p *TripEventPublisher) PublishTripCreated(ctx context.Context, trip *domai
 
 // PublishTripCompleted notifies both rider and driver that the trip is done.
 func (p *TripEventPublisher) PublishTripCompleted(ctx context.Context, trip *domain.TripModel) error {
-	completedPayload, err := json.Marshal(map[string]string{
-		"tripID": trip.ID.Hex(),
+	completedPayload, err := json.Marshal(messaging.TripCompletedData{
+		TripID: trip.ID.Hex(),
 	})
 	if err != nil {
 		return err
 	}
 
-	if err := p.rabbitMQ.PublishMessage(ctx, contracts.TripEventCompleted, contracts.AmqpMessage{
+	if err := p.publisher.PublishMessage(ctx, contracts.TripEventCompleted, contracts.AmqpMessage{
 		OwnerID: trip.UserID,
 		Data:    completedPayload,
 	}); err != nil {
@@ -63,7 +63,7 @@ func (p *TripEventPublisher) PublishTripCompleted(ctx context.Context, trip *dom
 	}
 
 	if trip.Driver != nil && trip.Driver.Id != "" {
-		if err := p.rabbitMQ.PublishMessage(ctx, contracts.TripEventCompleted, contracts.AmqpMessage{
+		if err := p.publisher.PublishMessage(ctx, contracts.TripEventCompleted, contracts.AmqpMessage{
 			OwnerID: trip.Driver.Id,
 			Data:    completedPayload,
 		}); err != nil {
@@ -77,20 +77,78 @@ func (p *TripEventPublisher) PublishTripCompleted(ctx context.Context, trip *dom
 // PublishTripCancelled notifies both rider and driver (if assigned) about the cancellation.
 // It publishes a single AMQP message whose Data contains the full TripCancelledData so the
 // ws-gateway cancel consumer can fan-out to both parties in one shot.
-func (p *TripEventPublisher) PublishTripCancelled(ctx context.Context, tripID, riderID, driverID string, driverAccepted bool) error {
+func (p *TripEventPublisher) PublishTripCancelled(ctx context.Context, tripID, riderID, driverID string, driverAccepted bool, cancelledBy, reason string) error {
 	payload := &messaging.TripCancelledData{
 		TripID:         tripID,
 		RiderID:        riderID,
 		DriverID:       driverID,
 		DriverAccepted: driverAccepted,
+		CancelledBy:    cancelledBy,
+		Reason:         reason,
 	}
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 	log.Printf("Publishing Trip Cancelled event for tripID: %s riderID: %s driverID: %s driverAccepted: %t", tripID, riderID, driverID, driverAccepted)
-	return p.rabbitMQ.PublishMessage(ctx, contracts.TripEventCancelled, contracts.AmqpMessage{
+	return p.publisher.PublishMessage(ctx, contracts.TripEventCancelled, contracts.AmqpMessage{
 		OwnerID: riderID, // ownerID is not used by the cancel consumer, but required by the envelope
 		Data:    data,
 	})
 }
+
+// PublishPaymentSessionRequest asks payment-service to open a checkout session
+// for the trip of saga.
+func (p *TripEventPublisher) PublishPaymentSessionRequest(ctx context.Context, saga *domain.TripSagaModel) error {
+	data, err := json.Marshal(messaging.PaymentTripResponseData{
+		TripID:   saga.TripID,
+		UserID:   saga.RiderID,
+		DriverID: saga.DriverID,
+		Amount:   saga.Amount,
+		Currency: saga.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	return p.publisher.PublishMessage(ctx, contracts.PaymentCmdCreateSession, contracts.AmqpMessage{
+		OwnerID: saga.RiderID,
+		Data:    data,
+	})
+}
+
+// PublishVoidPaymentSession asks payment-service to expire the checkout
+// session of a compensated saga.
+func (p *TripEventPublisher) PublishVoidPaymentSession(ctx context.Context, saga *domain.TripSagaModel) error {
+	data, err := json.Marshal(messaging.PaymentVoidSessionData{
+		TripID:    saga.TripID,
+		SessionID: saga.SessionID,
+		Reason:    saga.CompensationReason,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Publishing void payment session for tripID: %s sessionID: %s", saga.TripID, saga.SessionID)
+	return p.publisher.PublishMessage(ctx, contracts.PaymentCmdVoidSession, contracts.AmqpMessage{
+		OwnerID: saga.RiderID,
+		Data:    data,
+	})
+}
+
+// PublishDriverRelease asks driver-service to drop the driver's pairing with
+// the trip of a compensated saga.
+func (p *TripEventPublisher) PublishDriverRelease(ctx context.Context, saga *domain.TripSagaModel) error {
+	data, err := json.Marshal(messaging.DriverReleaseData{
+		TripID:   saga.TripID,
+		DriverID: saga.DriverID,
+		RiderID:  saga.RiderID,
+		Reason:   saga.CompensationReason,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Publishing driver release for tripID: %s driverID: %s", saga.TripID, saga.DriverID)
+	return p.publisher.PublishMessage(ctx, contracts.DriverCmdRelease, contracts.AmqpMessage{
+		OwnerID: saga.DriverID,
+		Data:    data,
+	})
+}
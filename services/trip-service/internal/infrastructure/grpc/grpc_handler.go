@@ -4,12 +4,17 @@ import (
 	// "context"
 	// "log"
 	"context"
+	"errors"
 	"log"
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/services/trip-service/internal/infrastructure/events"
+	"ride-sharing/services/trip-service/internal/service"
 	pb "ride-sharing/shared/proto/trip"
 	"ride-sharing/shared/types"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -23,12 +28,18 @@ type gRPCHandler struct {
 	// so we embed UnimplementedTripServiceServer struct which has default implementations of all methods
 	//like CreateTrip and PreviewTrip
 	service   domain.TripService // this is our domain service which has business logic
+	timeline  *service.TimelineService
+	traces    *service.TraceService
+	saga      *service.SagaOrchestrator
 	publisher *events.TripEventPublisher
 }
 
-func NewGRPCHandler(server *grpc.Server, service domain.TripService, publisher *events.TripEventPublisher) *gRPCHandler {
+func NewGRPCHandler(server *grpc.Server, tripService domain.TripService, timeline *service.TimelineService, traces *service.TraceService, saga *service.SagaOrchestrator, publisher *events.TripEventPublisher) *gRPCHandler {
 	handler := &gRPCHandler{
-		service:   service,
+		service:   tripService,
+		timeline:  timeline,
+		traces:    traces,
+		saga:      saga,
 		publisher: publisher,
 	}
 
@@ -111,10 +122,156 @@ func (h *gRPCHandler) CancelTrip(ctx context.Context, req *pb.CancelTripRequest)
 	}
 	driverAccepted := driverID != ""
 
-	if err := h.publisher.PublishTripCancelled(ctx, tripID, trip.UserID, driverID, driverAccepted); err != nil {
+	if err := h.publisher.PublishTripCancelled(ctx, tripID, trip.UserID, driverID, driverAccepted, userID, ""); err != nil {
 		log.Printf("CancelTrip: failed to publish cancellation event for trip %s: %v", tripID, err)
 		// Don't fail the RPC — trip is already marked cancelled in MongoDB.
 	}
 
+	if err := h.saga.OnTripCancelled(ctx, tripID); err != nil {
+		// The saga retries failed compensations on its own.
+		log.Printf("CancelTrip: failed to compensate payment saga for trip %s: %v", tripID, err)
+	}
+
 	return &pb.CancelTripResponse{DriverID: driverID}, nil
 }
+
+func (h *gRPCHandler) GetTrip(ctx context.Context, req *pb.GetTripRequest) (*pb.GetTripResponse, error) {
+	tripID := req.GetTripID()
+	if tripID == "" {
+		return nil, status.Error(codes.InvalidArgument, "tripID is required")
+	}
+
+	trip, err := h.service.GetTripByID(ctx, tripID)
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) || (err == nil && trip == nil) {
+		return nil, status.Errorf(codes.NotFound, "trip %s not found", tripID)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get trip: %v", err)
+	}
+	return &pb.GetTripResponse{Trip: trip.ToProto()}, nil
+}
+
+func (h *gRPCHandler) GetTripTimeline(ctx context.Context, req *pb.GetTripTimelineRequest) (*pb.GetTripTimelineResponse, error) {
+	tripID := req.GetTripID()
+	if tripID == "" {
+		return nil, status.Error(codes.InvalidArgument, "tripID is required")
+	}
+
+	timeline, err := h.timeline.GetTimeline(ctx, tripID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get trip timeline: %v", err)
+	}
+	if timeline == nil {
+		return nil, status.Errorf(codes.NotFound, "no timeline for trip %s", tripID)
+	}
+
+	resp := &pb.GetTripTimelineResponse{
+		Events: make([]*pb.TripTimelineEvent, len(timeline.Events)),
+	}
+	if timeline.Trip != nil {
+		resp.Trip = timeline.Trip.ToProto()
+	}
+	if timeline.FoldErr != nil {
+		resp.FoldError = timeline.FoldErr.Error()
+	}
+	for i, event := range timeline.Events {
+		resp.Events[i] = event.ToProto()
+	}
+	return resp, nil
+}
+
+func (h *gRPCHandler) GetTripTrace(ctx context.Context, req *pb.GetTripTraceRequest) (*pb.GetTripTraceResponse, error) {
+	tripID := req.GetTripID()
+	if tripID == "" {
+		return nil, status.Error(codes.InvalidArgument, "tripID is required")
+	}
+
+	trace, points, err := h.traces.GetTrace(ctx, tripID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get trip trace: %v", err)
+	}
+	if trace == nil {
+		return nil, status.Errorf(codes.NotFound, "no trace for trip %s", tripID)
+	}
+
+	resp := &pb.GetTripTraceResponse{
+		TripID:     trace.TripID,
+		DriverID:   trace.DriverID,
+		StartedAt:  trace.StartedAt.UnixMilli(),
+		EndedAt:    trace.EndedAt.UnixMilli(),
+		Anonymized: trace.AnonymizedAt != nil,
+	}
+	if format := req.GetFormat(); format != "" {
+		resp.Export, resp.ContentType, err = service.ExportTrace(trace, points, format)
+		if errors.Is(err, service.ErrUnknownTraceFormat) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to export trip trace: %v", err)
+		}
+		return resp, nil
+	}
+	resp.Points = make([]*pb.TracePoint, len(points))
+	for i, p := range points {
+		resp.Points[i] = p.ToProto()
+	}
+	return resp, nil
+}
+
+func (h *gRPCHandler) ListStuckSagas(ctx context.Context, req *pb.ListStuckSagasRequest) (*pb.ListStuckSagasResponse, error) {
+	sagas, err := h.saga.ListStuckSagas(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list stuck sagas: %v", err)
+	}
+
+	resp := &pb.ListStuckSagasResponse{Sagas: make([]*pb.TripSaga, len(sagas))}
+	for i, saga := range sagas {
+		resp.Sagas[i] = saga.ToProto()
+	}
+	return resp, nil
+}
+
+func (h *gRPCHandler) ShareTrip(ctx context.Context, req *pb.ShareTripRequest) (*pb.ShareTripResponse, error) {
+	tripID := req.GetTripID()
+	if tripID == "" {
+		return nil, status.Error(codes.InvalidArgument, "tripID is required")
+	}
+	if req.GetTtlSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttlSeconds must not be negative")
+	}
+
+	token, share, err := h.service.ShareTrip(ctx, tripID, req.GetUserID(), time.Duration(req.GetTtlSeconds())*time.Second)
+	if err != nil {
+		return nil, shareError(tripID, err)
+	}
+	return &pb.ShareTripResponse{
+		ShareID:   share.ID,
+		Token:     token,
+		ExpiresAt: share.ExpiresAt.UnixMilli(),
+	}, nil
+}
+
+func (h *gRPCHandler) RevokeTripShare(ctx context.Context, req *pb.RevokeTripShareRequest) (*pb.RevokeTripShareResponse, error) {
+	tripID := req.GetTripID()
+	if tripID == "" || req.GetShareID() == "" {
+		return nil, status.Error(codes.InvalidArgument, "tripID and shareID are required")
+	}
+
+	if err := h.service.RevokeTripShare(ctx, tripID, req.GetShareID(), req.GetUserID()); err != nil {
+		return nil, shareError(tripID, err)
+	}
+	return &pb.RevokeTripShareResponse{}, nil
+}
+
+func shareError(tripID string, err error) error {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments), errors.Is(err, primitive.ErrInvalidHex):
+		return status.Errorf(codes.NotFound, "trip %s not found", tripID)
+	case errors.Is(err, domain.ErrNotTripRider):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, domain.ErrTripEnded):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "failed to share trip: %v", err)
+	}
+}
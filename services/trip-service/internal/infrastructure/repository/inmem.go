@@ -4,13 +4,20 @@ import (
 	"context"
 	"fmt"
 	"ride-sharing/services/trip-service/internal/domain"
-	pbd "ride-sharing/shared/proto/driver"
 	pb "ride-sharing/shared/proto/trip"
+	"sort"
+	"sync"
+	"time"
 )
 
 type InMemoryRepository struct {
-	trips     map[string]*domain.TripModel
-	rideFares map[string]*domain.RideFareModel
+	mu         sync.RWMutex
+	trips      map[string]*domain.TripModel
+	rideFares  map[string]*domain.RideFareModel
+	tripEvents map[string][]*domain.TripEventModel
+	messageIDs map[string]struct{}
+	sagas      map[string]*domain.TripSagaModel
+	traces     map[string]*domain.TripTraceModel
 }
 
 func NewInmemoryRepository() *InMemoryRepository {
@@ -18,20 +25,32 @@ func NewInmemoryRepository() *InMemoryRepository {
 	// it uses in-memory storage for simplicity
 	// in real-world applications, this would be replaced with a database connection
 	return &InMemoryRepository{
-		trips:     make(map[string]*domain.TripModel),
-		rideFares: make(map[string]*domain.RideFareModel),
+		trips:      make(map[string]*domain.TripModel),
+		rideFares:  make(map[string]*domain.RideFareModel),
+		tripEvents: make(map[string][]*domain.TripEventModel),
+		messageIDs: make(map[string]struct{}),
+		sagas:      make(map[string]*domain.TripSagaModel),
+		traces:     make(map[string]*domain.TripTraceModel),
 	}
 }
 
 func (r *InMemoryRepository) GetTripByID(ctx context.Context, id string) (*domain.TripModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	trip, ok := r.trips[id]
 	if !ok {
 		return nil, nil
 	}
-	return trip, nil
+	// hand out a copy so callers never observe concurrent updates
+	cp := *trip
+	return &cp, nil
 }
 
-func (r *InMemoryRepository) UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error {
+func (r *InMemoryRepository) UpdateTrip(ctx context.Context, tripID string, status string, driver *pb.TripDriver) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	trip, ok := r.trips[tripID]
 	if !ok {
 		return fmt.Errorf("trip not found with ID: %s", tripID)
@@ -40,27 +59,31 @@ func (r *InMemoryRepository) UpdateTrip(ctx context.Context, tripID string, stat
 	trip.Status = status
 
 	if driver != nil {
-		trip.Driver = &pb.TripDriver{
-			Id:             driver.Id,
-			Name:           driver.Name,
-			CarPlate:       driver.CarPlate,
-			ProfilePicture: driver.ProfilePicture,
-		}
+		trip.Driver = driver
 	}
 	return nil
 }
 
 func (r *InMemoryRepository) CreateTrip(ctx context.Context, trip *domain.TripModel) (*domain.TripModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.trips[trip.ID.Hex()] = trip
 	return trip, nil
 }
 
 func (r *InMemoryRepository) SaveRideFare(ctx context.Context, f *domain.RideFareModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.rideFares[f.ID.Hex()] = f
 	return nil
 }
 
 func (r *InMemoryRepository) GetRideFareByID(ctx context.Context, id string) (*domain.RideFareModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	fare, exist := r.rideFares[id]
 	if !exist {
 		return nil, fmt.Errorf("fare does not exist with ID: %s", id)
@@ -68,3 +91,173 @@ func (r *InMemoryRepository) GetRideFareByID(ctx context.Context, id string) (*d
 
 	return fare, nil
 }
+
+func (r *InMemoryRepository) AppendTripEvent(ctx context.Context, event *domain.TripEventModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, seen := r.messageIDs[event.MessageID]; seen {
+		return nil
+	}
+	r.messageIDs[event.MessageID] = struct{}{}
+	cp := *event
+	r.tripEvents[event.TripID] = append(r.tripEvents[event.TripID], &cp)
+	return nil
+}
+
+func (r *InMemoryRepository) ListTripEvents(ctx context.Context, tripID string) ([]*domain.TripEventModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]*domain.TripEventModel, 0, len(r.tripEvents[tripID]))
+	for _, event := range r.tripEvents[tripID] {
+		cp := *event
+		events = append(events, &cp)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].OccurredAt.Before(events[j].OccurredAt) })
+	return events, nil
+}
+
+func (r *InMemoryRepository) CreateSaga(ctx context.Context, saga *domain.TripSagaModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sagas[saga.TripID]; exists {
+		return domain.ErrSagaExists
+	}
+	cp := *saga
+	r.sagas[saga.TripID] = &cp
+	return nil
+}
+
+func (r *InMemoryRepository) GetSaga(ctx context.Context, tripID string) (*domain.TripSagaModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	saga, ok := r.sagas[tripID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *saga
+	return &cp, nil
+}
+
+func (r *InMemoryRepository) UpdateSaga(ctx context.Context, saga *domain.TripSagaModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.sagas[saga.TripID]
+	if !ok || stored.Version != saga.Version {
+		return domain.ErrSagaConflict
+	}
+	saga.Version++
+	cp := *saga
+	r.sagas[saga.TripID] = &cp
+	return nil
+}
+
+func (r *InMemoryRepository) ListDueSagas(ctx context.Context, now time.Time) ([]*domain.TripSagaModel, error) {
+	return r.listSagas(func(s *domain.TripSagaModel) bool {
+		return !s.Step.Terminal() && s.Deadline.Before(now)
+	}), nil
+}
+
+func (r *InMemoryRepository) ListStuckSagas(ctx context.Context, now time.Time) ([]*domain.TripSagaModel, error) {
+	return r.listSagas(func(s *domain.TripSagaModel) bool {
+		return (!s.Step.Terminal() && s.Deadline.Before(now)) || s.LastError != ""
+	}), nil
+}
+
+func (r *InMemoryRepository) listSagas(match func(*domain.TripSagaModel) bool) []*domain.TripSagaModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sagas []*domain.TripSagaModel
+	for _, saga := range r.sagas {
+		if match(saga) {
+			cp := *saga
+			sagas = append(sagas, &cp)
+		}
+	}
+	sort.Slice(sagas, func(i, j int) bool { return sagas[i].Deadline.Before(sagas[j].Deadline) })
+	return sagas
+}
+
+func (r *InMemoryRepository) AppendTraceChunk(ctx context.Context, tripID, driverID string, chunk domain.TraceChunk, from, to time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.traces[tripID]
+	if !ok {
+		stored = &domain.TripTraceModel{TripID: tripID, DriverID: driverID, StartedAt: from, EndedAt: to}
+		r.traces[tripID] = stored
+	}
+	for _, c := range stored.Chunks {
+		if c.ID == chunk.ID {
+			return nil
+		}
+	}
+	stored.Chunks = append(stored.Chunks, chunk)
+	if from.Before(stored.StartedAt) {
+		stored.StartedAt = from
+	}
+	if to.After(stored.EndedAt) {
+		stored.EndedAt = to
+	}
+	return nil
+}
+
+func (r *InMemoryRepository) GetTripTrace(ctx context.Context, tripID string) (*domain.TripTraceModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.traces[tripID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *stored
+	cp.Chunks = append([]domain.TraceChunk(nil), stored.Chunks...)
+	return &cp, nil
+}
+
+func (r *InMemoryRepository) ListTracesEndedBefore(ctx context.Context, before time.Time, limit int) ([]*domain.TripTraceModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var traces []*domain.TripTraceModel
+	for _, stored := range r.traces {
+		if stored.AnonymizedAt == nil && stored.EndedAt.Before(before) {
+			cp := *stored
+			cp.Chunks = append([]domain.TraceChunk(nil), stored.Chunks...)
+			traces = append(traces, &cp)
+		}
+	}
+	sort.Slice(traces, func(i, j int) bool { return traces[i].EndedAt.Before(traces[j].EndedAt) })
+	if len(traces) > limit {
+		traces = traces[:limit]
+	}
+	return traces, nil
+}
+
+func (r *InMemoryRepository) ReplaceTripTrace(ctx context.Context, trace *domain.TripTraceModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cp := *trace
+	r.traces[trace.TripID] = &cp
+	return nil
+}
+
+func (r *InMemoryRepository) DeleteTracesEndedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for tripID, stored := range r.traces {
+		if stored.EndedAt.Before(before) {
+			delete(r.traces, tripID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/db"
+	"ride-sharing/shared/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var activeSagaSteps = bson.A{
+	domain.SagaStepAwaitingSession,
+	domain.SagaStepAwaitingPayment,
+	domain.SagaStepCompleting,
+	domain.SagaStepCompensating,
+}
+
+// EnsureSagaIndexes creates the index used to find overdue sagas.
+func (r *mongoRepository) EnsureSagaIndexes(ctx context.Context) error {
+	_, err := r.db.Collection(db.TripSagasCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "step", Value: 1}, {Key: "deadline", Value: 1}},
+	})
+	return err
+}
+
+func (r *mongoRepository) CreateSaga(ctx context.Context, saga *domain.TripSagaModel) error {
+	return tracing.RunInSpan(ctx, "db", "mongodb.trip_sagas.insert", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripSagasCollection),
+		attribute.String("trip.id", saga.TripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		_, err := r.db.Collection(db.TripSagasCollection).InsertOne(ctx, saga)
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrSagaExists
+		}
+		return err
+	})
+}
+
+func (r *mongoRepository) GetSaga(ctx context.Context, tripID string) (*domain.TripSagaModel, error) {
+	var saga *domain.TripSagaModel
+	err := tracing.RunInSpan(ctx, "db", "mongodb.trip_sagas.find_one", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripSagasCollection),
+		attribute.String("trip.id", tripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		var decoded domain.TripSagaModel
+		err := r.db.Collection(db.TripSagasCollection).FindOne(ctx, bson.M{"_id": tripID}).Decode(&decoded)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		saga = &decoded
+		return nil
+	})
+	return saga, err
+}
+
+func (r *mongoRepository) UpdateSaga(ctx context.Context, saga *domain.TripSagaModel) error {
+	return tracing.RunInSpan(ctx, "db", "mongodb.trip_sagas.replace_one", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripSagasCollection),
+		attribute.String("trip.id", saga.TripID),
+		attribute.String("saga.step", string(saga.Step)),
+	), func(ctx context.Context, _ trace.Span) error {
+		expected := saga.Version
+		next := *saga
+		next.Version++
+
+		result, err := r.db.Collection(db.TripSagasCollection).ReplaceOne(ctx,
+			bson.M{"_id": saga.TripID, "version": expected},
+			&next,
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return domain.ErrSagaConflict
+		}
+		saga.Version = next.Version
+		return nil
+	})
+}
+
+func (r *mongoRepository) ListDueSagas(ctx context.Context, now time.Time) ([]*domain.TripSagaModel, error) {
+	return r.findSagas(ctx, "mongodb.trip_sagas.find_due", bson.M{
+		"step":     bson.M{"$in": activeSagaSteps},
+		"deadline": bson.M{"$lt": now},
+	})
+}
+
+func (r *mongoRepository) ListStuckSagas(ctx context.Context, now time.Time) ([]*domain.TripSagaModel, error) {
+	return r.findSagas(ctx, "mongodb.trip_sagas.find_stuck", bson.M{"$or": bson.A{
+		bson.M{"step": bson.M{"$in": activeSagaSteps}, "deadline": bson.M{"$lt": now}},
+		bson.M{"lastError": bson.M{"$exists": true, "$ne": ""}},
+	}})
+}
+
+func (r *mongoRepository) findSagas(ctx context.Context, spanName string, filter bson.M) ([]*domain.TripSagaModel, error) {
+	var sagas []*domain.TripSagaModel
+	err := tracing.RunInSpan(ctx, "db", spanName, tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripSagasCollection),
+	), func(ctx context.Context, _ trace.Span) error {
+		cursor, err := r.db.Collection(db.TripSagasCollection).Find(ctx, filter,
+			options.Find().SetSort(bson.D{{Key: "deadline", Value: 1}}),
+		)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &sagas)
+	})
+	return sagas, err
+}
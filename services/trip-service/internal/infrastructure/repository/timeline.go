@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/db"
+	"ride-sharing/shared/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EnsureTimelineIndexes creates the indexes the trip timeline relies on: the
+// unique message ID that makes appends idempotent, and the per-trip ordering.
+func (r *mongoRepository) EnsureTimelineIndexes(ctx context.Context) error {
+	_, err := r.db.Collection(db.TripEventsCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "messageID", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "tripID", Value: 1}, {Key: "occurredAt", Value: 1}},
+		},
+	})
+	return err
+}
+
+func (r *mongoRepository) AppendTripEvent(ctx context.Context, event *domain.TripEventModel) error {
+	return tracing.RunInSpan(ctx, "db", "mongodb.trip_events.insert", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripEventsCollection),
+		attribute.String("trip.id", event.TripID),
+		attribute.String("trip.event_type", event.Type),
+	), func(ctx context.Context, _ trace.Span) error {
+		_, err := r.db.Collection(db.TripEventsCollection).InsertOne(ctx, event)
+		if mongo.IsDuplicateKeyError(err) {
+			// redelivery of an event that is already on the timeline
+			return nil
+		}
+		return err
+	})
+}
+
+func (r *mongoRepository) ListTripEvents(ctx context.Context, tripID string) ([]*domain.TripEventModel, error) {
+	var events []*domain.TripEventModel
+	err := tracing.RunInSpan(ctx, "db", "mongodb.trip_events.find", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripEventsCollection),
+		attribute.String("trip.id", tripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		cursor, err := r.db.Collection(db.TripEventsCollection).Find(ctx,
+			bson.M{"tripID": tripID},
+			options.Find().SetSort(bson.D{{Key: "occurredAt", Value: 1}, {Key: "_id", Value: 1}}),
+		)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &events)
+	})
+	return events, err
+}
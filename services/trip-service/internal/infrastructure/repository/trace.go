@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/db"
+	"ride-sharing/shared/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EnsureTraceIndexes creates the index the retention policy finds expired
+// traces with.
+func (r *mongoRepository) EnsureTraceIndexes(ctx context.Context) error {
+	_, err := r.db.Collection(db.TripTracesCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "endedAt", Value: 1}},
+	})
+	return err
+}
+
+func (r *mongoRepository) AppendTraceChunk(ctx context.Context, tripID, driverID string, chunk domain.TraceChunk, from, to time.Time) error {
+	return tracing.RunInSpan(ctx, "db", "mongodb.trip_traces.append", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripTracesCollection),
+		attribute.String("trip.id", tripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		// The filter skips traces that already hold the chunk. For those the
+		// upsert tries to insert a second document with the trip's ID and
+		// fails, which means the chunk is stored.
+		_, err := r.db.Collection(db.TripTracesCollection).UpdateOne(ctx,
+			bson.M{"_id": tripID, "chunks.id": bson.M{"$ne": chunk.ID}},
+			bson.M{
+				"$push":        bson.M{"chunks": chunk},
+				"$min":         bson.M{"startedAt": from},
+				"$max":         bson.M{"endedAt": to},
+				"$setOnInsert": bson.M{"driverID": driverID},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (r *mongoRepository) GetTripTrace(ctx context.Context, tripID string) (*domain.TripTraceModel, error) {
+	var tripTrace *domain.TripTraceModel
+	err := tracing.RunInSpan(ctx, "db", "mongodb.trip_traces.find_one", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripTracesCollection),
+		attribute.String("trip.id", tripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		var decoded domain.TripTraceModel
+		err := r.db.Collection(db.TripTracesCollection).FindOne(ctx, bson.M{"_id": tripID}).Decode(&decoded)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		tripTrace = &decoded
+		return nil
+	})
+	return tripTrace, err
+}
+
+func (r *mongoRepository) ListTracesEndedBefore(ctx context.Context, before time.Time, limit int) ([]*domain.TripTraceModel, error) {
+	var traces []*domain.TripTraceModel
+	err := tracing.RunInSpan(ctx, "db", "mongodb.trip_traces.find", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripTracesCollection),
+	), func(ctx context.Context, _ trace.Span) error {
+		cursor, err := r.db.Collection(db.TripTracesCollection).Find(ctx,
+			bson.M{"endedAt": bson.M{"$lt": before}, "anonymizedAt": bson.M{"$exists": false}},
+			options.Find().SetSort(bson.D{{Key: "endedAt", Value: 1}}).SetLimit(int64(limit)),
+		)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &traces)
+	})
+	return traces, err
+}
+
+func (r *mongoRepository) ReplaceTripTrace(ctx context.Context, tripTrace *domain.TripTraceModel) error {
+	return tracing.RunInSpan(ctx, "db", "mongodb.trip_traces.replace", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripTracesCollection),
+		attribute.String("trip.id", tripTrace.TripID),
+	), func(ctx context.Context, _ trace.Span) error {
+		_, err := r.db.Collection(db.TripTracesCollection).ReplaceOne(ctx, bson.M{"_id": tripTrace.TripID}, tripTrace)
+		return err
+	})
+}
+
+func (r *mongoRepository) DeleteTracesEndedBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := tracing.RunInSpan(ctx, "db", "mongodb.trip_traces.delete", tracing.DBSpanAttrs("mongodb",
+		attribute.String("db.collection", db.TripTracesCollection),
+	), func(ctx context.Context, _ trace.Span) error {
+		result, err := r.db.Collection(db.TripTracesCollection).DeleteMany(ctx, bson.M{"endedAt": bson.M{"$lt": before}})
+		if err != nil {
+			return err
+		}
+		deleted = result.DeletedCount
+		return nil
+	})
+	return deleted, err
+}
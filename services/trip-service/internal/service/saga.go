@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ride-sharing/services/trip-service/internal/domain"
+)
+
+// Reasons recorded when a saga compensates.
+const (
+	SagaReasonTripCancelled  = "trip_cancelled"
+	SagaReasonSessionTimeout = "payment_session_timeout"
+	SagaReasonPaymentTimeout = "payment_timeout"
+)
+
+type SagaConfig struct {
+	SessionTimeout time.Duration // payment-service must create a session within this
+	PaymentTimeout time.Duration // the rider must pay within this
+	RetryInterval  time.Duration // delay before failed compensations are retried
+}
+
+func DefaultSagaConfig() SagaConfig {
+	return SagaConfig{
+		SessionTimeout: 2 * time.Minute,
+		PaymentTimeout: 15 * time.Minute,
+		RetryInterval:  30 * time.Second,
+	}
+}
+
+// SagaOrchestrator drives a trip from driver acceptance through payment to
+// completion. Each step is persisted before its command is published, so a
+// restart or a redelivery resumes the saga. When a step times out or the trip
+// is cancelled, the orchestrator voids the payment session, releases the
+// driver and, if nobody else did, cancels the trip and notifies both parties.
+type SagaOrchestrator struct {
+	sagas     domain.TripSagaRepository
+	trips     domain.TripService
+	publisher domain.SagaPublisher
+	cfg       SagaConfig
+}
+
+func NewSagaOrchestrator(sagas domain.TripSagaRepository, trips domain.TripService, publisher domain.SagaPublisher, cfg SagaConfig) *SagaOrchestrator {
+	return &SagaOrchestrator{
+		sagas:     sagas,
+		trips:     trips,
+		publisher: publisher,
+		cfg:       cfg,
+	}
+}
+
+// Start begins the saga of a trip a driver has accepted and asks
+// payment-service for a checkout session. Starting an existing saga re-sends
+// the session request if it is still outstanding.
+func (o *SagaOrchestrator) Start(ctx context.Context, trip *domain.TripModel) error {
+	now := time.Now()
+	saga := &domain.TripSagaModel{
+		TripID:    trip.ID.Hex(),
+		RiderID:   trip.UserID,
+		Amount:    trip.RideFare.TotalPriceInCents,
+		Currency:  "USD",
+		Step:      domain.SagaStepAwaitingSession,
+		Deadline:  now.Add(o.cfg.SessionTimeout),
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+	if trip.Driver != nil {
+		saga.DriverID = trip.Driver.Id
+	}
+
+	err := o.sagas.CreateSaga(ctx, saga)
+	if errors.Is(err, domain.ErrSagaExists) {
+		saga, err = o.sagas.GetSaga(ctx, saga.TripID)
+		if err != nil {
+			return err
+		}
+		if saga == nil || saga.Step != domain.SagaStepAwaitingSession {
+			return nil
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to start saga: %w", err)
+	}
+
+	return o.publisher.PublishPaymentSessionRequest(ctx, saga)
+}
+
+// OnSessionCreated records the rider's checkout session. A session created
+// for a saga that is already compensating or compensated is voided.
+func (o *SagaOrchestrator) OnSessionCreated(ctx context.Context, tripID, sessionID string) error {
+	saga, err := o.sagas.GetSaga(ctx, tripID)
+	if err != nil || saga == nil {
+		return err
+	}
+
+	switch saga.Step {
+	case domain.SagaStepAwaitingSession:
+		saga.SessionID = sessionID
+		saga.Step = domain.SagaStepAwaitingPayment
+		saga.Deadline = time.Now().Add(o.cfg.PaymentTimeout)
+		return o.update(ctx, saga)
+	case domain.SagaStepCompensating, domain.SagaStepCompensated:
+		log.Printf("saga: session %s created for trip %s after compensation, voiding it", sessionID, tripID)
+		saga.SessionID = sessionID
+		if err := o.publisher.PublishVoidPaymentSession(ctx, saga); err != nil {
+			return err
+		}
+		return o.update(ctx, saga)
+	}
+	return nil
+}
+
+// OnPaymentSucceeded completes the trip. Trips accepted before sagas existed
+// have no saga and are completed directly.
+func (o *SagaOrchestrator) OnPaymentSucceeded(ctx context.Context, tripID string) error {
+	saga, err := o.sagas.GetSaga(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	if saga == nil {
+		trip, err := o.trips.CompleteTrip(ctx, tripID)
+		if err != nil {
+			return err
+		}
+		return o.publisher.PublishTripCompleted(ctx, trip)
+	}
+
+	switch saga.Step {
+	case domain.SagaStepCompleted:
+		// redelivery; completion events were already published
+		return nil
+	case domain.SagaStepCompensating, domain.SagaStepCompensated:
+		saga.LastError = "payment received after compensation; refund required"
+		log.Printf("saga: trip %s: %s", tripID, saga.LastError)
+		return o.update(ctx, saga)
+	case domain.SagaStepAwaitingSession, domain.SagaStepAwaitingPayment:
+		// Claim the saga before touching the trip, so a concurrent Tick that
+		// times out the payment loses the race instead of cancelling a paid
+		// trip.
+		saga.Step = domain.SagaStepCompleting
+		saga.Deadline = time.Now().Add(o.cfg.RetryInterval)
+		if err := o.update(ctx, saga); err != nil {
+			return err
+		}
+	}
+	return o.complete(ctx, saga)
+}
+
+// complete finishes a saga claimed for completion. It is re-run by Tick
+// until it succeeds, so a failure after the rider paid is retried rather
+// than compensated.
+func (o *SagaOrchestrator) complete(ctx context.Context, saga *domain.TripSagaModel) error {
+	trip, err := o.trips.CompleteTrip(ctx, saga.TripID)
+	if err == nil {
+		err = o.publisher.PublishTripCompleted(ctx, trip)
+	}
+	if err != nil {
+		saga.LastError = err.Error()
+		saga.Deadline = time.Now().Add(o.cfg.RetryInterval)
+		if updateErr := o.update(ctx, saga); updateErr != nil {
+			log.Printf("saga: failed to record completion error for trip %s: %v", saga.TripID, updateErr)
+		}
+		return err
+	}
+
+	saga.Step = domain.SagaStepCompleted
+	saga.Deadline = time.Time{}
+	saga.LastError = ""
+	return o.update(ctx, saga)
+}
+
+// OnTripCancelled compensates the saga of a trip the rider or driver
+// cancelled. The cancellation was already published, so only the session and
+// the driver are released.
+func (o *SagaOrchestrator) OnTripCancelled(ctx context.Context, tripID string) error {
+	saga, err := o.sagas.GetSaga(ctx, tripID)
+	if err != nil || saga == nil || saga.Step.Terminal() || saga.Step == domain.SagaStepCompensating {
+		return err
+	}
+	if saga.Step == domain.SagaStepCompleting {
+		log.Printf("saga: trip %s cancelled after the rider paid, completing it instead", tripID)
+		return nil
+	}
+	return o.compensate(ctx, saga, SagaReasonTripCancelled, false)
+}
+
+// Run checks for timed out steps and failed compensations every interval
+// until ctx is cancelled.
+func (o *SagaOrchestrator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := o.Tick(ctx); err != nil {
+				log.Printf("saga: tick failed: %v", err)
+			}
+		}
+	}
+}
+
+// Tick times out overdue steps and retries failed compensations once.
+func (o *SagaOrchestrator) Tick(ctx context.Context) error {
+	due, err := o.sagas.ListDueSagas(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to list due sagas: %w", err)
+	}
+
+	var errs []error
+	for _, saga := range due {
+		var err error
+		switch saga.Step {
+		case domain.SagaStepAwaitingSession:
+			err = o.compensate(ctx, saga, SagaReasonSessionTimeout, true)
+		case domain.SagaStepAwaitingPayment:
+			err = o.compensate(ctx, saga, SagaReasonPaymentTimeout, true)
+		case domain.SagaStepCompleting:
+			err = o.complete(ctx, saga)
+		case domain.SagaStepCompensating:
+			err = o.runCompensations(ctx, saga)
+		}
+		if err != nil && !errors.Is(err, domain.ErrSagaConflict) {
+			errs = append(errs, fmt.Errorf("trip %s: %w", saga.TripID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ListStuckSagas returns sagas that are overdue or whose last step failed.
+func (o *SagaOrchestrator) ListStuckSagas(ctx context.Context) ([]*domain.TripSagaModel, error) {
+	return o.sagas.ListStuckSagas(ctx, time.Now())
+}
+
+// compensate claims the saga for compensation and runs the compensations.
+// Claiming first means concurrent replicas never compensate twice.
+func (o *SagaOrchestrator) compensate(ctx context.Context, saga *domain.TripSagaModel, reason string, cancelTrip bool) error {
+	log.Printf("saga: compensating trip %s (%s)", saga.TripID, reason)
+	saga.Step = domain.SagaStepCompensating
+	saga.CompensationReason = reason
+	saga.CancelTrip = cancelTrip
+	saga.Deadline = time.Now().Add(o.cfg.RetryInterval)
+	if err := o.update(ctx, saga); err != nil {
+		return err
+	}
+	return o.runCompensations(ctx, saga)
+}
+
+// runCompensations executes every compensation of a compensating saga. They
+// are idempotent, so all of them are re-run until every one succeeds.
+func (o *SagaOrchestrator) runCompensations(ctx context.Context, saga *domain.TripSagaModel) error {
+	var errs []error
+	if saga.CancelTrip {
+		if err := o.trips.UpdateTrip(ctx, saga.TripID, "cancelled", nil); err != nil {
+			errs = append(errs, fmt.Errorf("cancel trip: %w", err))
+		} else if err := o.publisher.PublishTripCancelled(ctx, saga.TripID, saga.RiderID, saga.DriverID, saga.DriverID != "", "", saga.CompensationReason); err != nil {
+			errs = append(errs, fmt.Errorf("notify parties: %w", err))
+		}
+	}
+	if err := o.publisher.PublishVoidPaymentSession(ctx, saga); err != nil {
+		errs = append(errs, fmt.Errorf("void payment session: %w", err))
+	}
+	if saga.DriverID != "" {
+		if err := o.publisher.PublishDriverRelease(ctx, saga); err != nil {
+			errs = append(errs, fmt.Errorf("release driver: %w", err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		saga.LastError = err.Error()
+		saga.Deadline = time.Now().Add(o.cfg.RetryInterval)
+		if updateErr := o.update(ctx, saga); updateErr != nil {
+			log.Printf("saga: failed to record compensation error for trip %s: %v", saga.TripID, updateErr)
+		}
+		return err
+	}
+
+	saga.Step = domain.SagaStepCompensated
+	saga.Deadline = time.Time{}
+	saga.LastError = ""
+	return o.update(ctx, saga)
+}
+
+func (o *SagaOrchestrator) update(ctx context.Context, saga *domain.TripSagaModel) error {
+	saga.UpdatedAt = time.Now()
+	return o.sagas.UpdateSaga(ctx, saga)
+}
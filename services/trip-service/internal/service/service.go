@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"ride-sharing/services/trip-service/internal/domain"
 	tripTypes "ride-sharing/services/trip-service/pkg/types"
 	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/tripshare"
 	"ride-sharing/shared/types"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -17,10 +20,11 @@ import (
 type TripService struct {
 	repo     domain.TripRepository
 	fareRepo domain.RideFareRepository
+	shares   domain.TripShareStore
 }
 
-func NewTripService(repo domain.TripRepository, fareRepo domain.RideFareRepository) *TripService {
-	return &TripService{repo: repo, fareRepo: fareRepo}
+func NewTripService(repo domain.TripRepository, fareRepo domain.RideFareRepository, shares domain.TripShareStore) *TripService {
+	return &TripService{repo: repo, fareRepo: fareRepo, shares: shares}
 }
 
 // Implement service methods here bcoz NewTripSerice return tripservice --> it should implement all methods of tripService defined in domain
@@ -173,11 +177,18 @@ func (s *TripService) CompleteTrip(ctx context.Context, tripID string) (*domain.
 		return nil, fmt.Errorf("failed to complete trip: %w", err)
 	}
 	trip.Status = "completed"
+	s.revokeShares(ctx, tripID)
 	return trip, nil
 }
 
 func (s *TripService) UpdateTrip(ctx context.Context, tripID string, status string, driver *pb.TripDriver) error {
-	return s.repo.UpdateTrip(ctx, tripID, status, driver)
+	if err := s.repo.UpdateTrip(ctx, tripID, status, driver); err != nil {
+		return err
+	}
+	if status == "completed" || status == "cancelled" {
+		s.revokeShares(ctx, tripID)
+	}
+	return nil
 }
 
 func (s *TripService) CancelTrip(ctx context.Context, tripID, requesterUserID string) (*domain.TripModel, error) {
@@ -206,5 +217,53 @@ func (s *TripService) CancelTrip(ctx context.Context, tripID, requesterUserID st
 		return nil, fmt.Errorf("failed to cancel trip: %w", err)
 	}
 	trip.Status = "cancelled"
+	s.revokeShares(ctx, tripID)
 	return trip, nil
 }
+
+// ShareTrip mints a public share link of an active trip. Only the trip's rider
+// may share it.
+func (s *TripService) ShareTrip(ctx context.Context, tripID, requesterUserID string, ttl time.Duration) (string, tripshare.Share, error) {
+	trip, err := s.repo.GetTripByID(ctx, tripID)
+	if err != nil {
+		return "", tripshare.Share{}, fmt.Errorf("trip not found: %w", err)
+	}
+	if trip.UserID != requesterUserID {
+		return "", tripshare.Share{}, domain.ErrNotTripRider
+	}
+	if trip.Ended() {
+		return "", tripshare.Share{}, domain.ErrTripEnded
+	}
+	return s.shares.Create(ctx, tripID, trip.UserID, ttl)
+}
+
+// RevokeTripShare revokes one share link of a trip of requesterUserID.
+// Revoking a share that is already gone is not an error.
+func (s *TripService) RevokeTripShare(ctx context.Context, tripID, shareID, requesterUserID string) error {
+	trip, err := s.repo.GetTripByID(ctx, tripID)
+	if err != nil {
+		return fmt.Errorf("trip not found: %w", err)
+	}
+	if trip.UserID != requesterUserID {
+		return domain.ErrNotTripRider
+	}
+	_, err = s.shares.Revoke(ctx, tripID, shareID)
+	return err
+}
+
+// revokeShares revokes the share links of a trip that ended. ws-gateway also
+// ends share streams on the trip's completed or cancelled event, so a failure
+// here is only logged.
+func (s *TripService) revokeShares(ctx context.Context, tripID string) {
+	if s.shares == nil {
+		return
+	}
+	n, err := s.shares.RevokeTrip(ctx, tripID)
+	if err != nil {
+		log.Printf("failed to revoke share links of trip %s: %v", tripID, err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Revoked %d share links of ended trip %s", n, tripID)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+)
+
+type TimelineService struct {
+	repo domain.TripTimelineRepository
+}
+
+func NewTimelineService(repo domain.TripTimelineRepository) *TimelineService {
+	return &TimelineService{repo: repo}
+}
+
+// RecordEvent appends a trip event received on routingKey to the timeline of
+// the trip it refers to. Events without a trip ID are ignored.
+func (s *TimelineService) RecordEvent(ctx context.Context, routingKey, messageID string, occurredAt time.Time, message contracts.AmqpMessage) error {
+	var ref struct {
+		TripID string `json:"tripID"`
+		Trip   *struct {
+			ID string `json:"id"`
+		} `json:"trip"`
+	}
+	if err := json.Unmarshal(message.Data, &ref); err != nil {
+		return fmt.Errorf("decode %s payload: %w", routingKey, err)
+	}
+	tripID := ref.TripID
+	if tripID == "" && ref.Trip != nil {
+		tripID = ref.Trip.ID
+	}
+	if tripID == "" {
+		return nil
+	}
+
+	digest := sha256.Sum256(message.Data)
+	payloadDigest := hex.EncodeToString(digest[:])
+	if messageID == "" {
+		// Messages published before IDs were assigned are deduplicated by content.
+		messageID = routingKey + ":" + message.OwnerID + ":" + payloadDigest
+	}
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	return s.repo.AppendTripEvent(ctx, &domain.TripEventModel{
+		TripID:        tripID,
+		MessageID:     messageID,
+		Type:          routingKey,
+		Actor:         eventActor(routingKey, message),
+		OwnerID:       message.OwnerID,
+		OccurredAt:    occurredAt.UTC(),
+		PayloadDigest: payloadDigest,
+		Payload:       string(message.Data),
+	})
+}
+
+// eventActor names who caused the event: the rider or driver when a user
+// action triggered it, otherwise the service that published it.
+func eventActor(routingKey string, message contracts.AmqpMessage) string {
+	switch routingKey {
+	case contracts.TripEventCreated:
+		return "rider:" + message.OwnerID
+	case contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline:
+		var data messaging.DriverTripResponseData
+		if err := json.Unmarshal(message.Data, &data); err == nil && data.DriverID != "" {
+			return "driver:" + data.DriverID
+		}
+	case contracts.TripEventCancelled:
+		var data messaging.TripCancelledData
+		if err := json.Unmarshal(message.Data, &data); err == nil && data.CancelledBy != "" {
+			if data.CancelledBy == data.DriverID {
+				return "driver:" + data.CancelledBy
+			}
+			return "rider:" + data.CancelledBy
+		}
+	}
+	if producers := messaging.DefaultTopology.Producers[routingKey]; len(producers) > 0 {
+		return "service:" + producers[0]
+	}
+	return "service:unknown"
+}
+
+// TripTimeline is the timeline of a trip together with the trip document
+// rebuilt from it.
+type TripTimeline struct {
+	Events []*domain.TripEventModel
+	// Trip is nil or partial when FoldErr is set.
+	Trip    *domain.TripModel
+	FoldErr error
+}
+
+// GetTimeline returns the timeline of tripID, or nil if nothing was recorded
+// for it. A timeline that can't be folded into a trip is still returned, since
+// the raw events are what's needed to debug it.
+func (s *TimelineService) GetTimeline(ctx context.Context, tripID string) (*TripTimeline, error) {
+	events, err := s.repo.ListTripEvents(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trip events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	trip, err := domain.FoldTripTimeline(events)
+	if err != nil {
+		log.Printf("Failed to fold timeline of trip %s: %v", tripID, err)
+	}
+	return &TripTimeline{Events: events, Trip: trip, FoldErr: err}, nil
+}
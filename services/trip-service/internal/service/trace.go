@@ -0,0 +1,251 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/messaging"
+)
+
+// Trace export formats accepted by GetTripTrace.
+const (
+	TraceFormatGeoJSON = "geojson"
+	TraceFormatGPX     = "gpx"
+)
+
+// What happens to a trace once its retention period is over.
+const (
+	TraceRetentionDelete    = "delete"
+	TraceRetentionAnonymize = "anonymize"
+)
+
+// traceAnonymizedScale coarsens anonymized points to 1e-3 degrees, about
+// 110 m, so they no longer pinpoint a home or workplace.
+const traceAnonymizedScale = 1e3
+
+// traceRetentionBatch is how many traces one retention pass anonymizes.
+const traceRetentionBatch = 100
+
+var ErrUnknownTraceFormat = errors.New("unknown trace format")
+
+type TraceRetention struct {
+	Period time.Duration // how long a trace is kept as recorded after it ended
+	Mode   string        // TraceRetentionDelete or TraceRetentionAnonymize
+}
+
+func DefaultTraceRetention() TraceRetention {
+	return TraceRetention{Period: 90 * 24 * time.Hour, Mode: TraceRetentionAnonymize}
+}
+
+// TraceService stores the positions driver-service records during a trip and
+// exports them for disputes, fare audits and safety reviews.
+type TraceService struct {
+	repo      domain.TripTraceRepository
+	retention TraceRetention
+}
+
+func NewTraceService(repo domain.TripTraceRepository, retention TraceRetention) *TraceService {
+	return &TraceService{repo: repo, retention: retention}
+}
+
+// RecordBatch appends a batch of driver.event.trace points to the trace of
+// its trip. Batches delivered or sent twice are stored once.
+func (s *TraceService) RecordBatch(ctx context.Context, data messaging.DriverTraceData) error {
+	points := make([]domain.TracePoint, 0, len(data.Points))
+	for _, p := range data.Points {
+		points = append(points, domain.TracePoint{
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			At:        time.UnixMilli(p.At).UTC(),
+			Speed:     p.Speed,
+		})
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	encoded, err := domain.EncodeTracePoints(points)
+	if err != nil {
+		return fmt.Errorf("failed to encode trace of trip %s: %w", data.TripID, err)
+	}
+	chunk := domain.TraceChunk{ID: strconv.FormatInt(data.Points[0].At, 10), Count: len(points), Data: encoded}
+	return s.repo.AppendTraceChunk(ctx, data.TripID, data.DriverID, chunk, points[0].At, points[len(points)-1].At)
+}
+
+// GetTrace returns the trace of tripID and its points, or a nil trace if none
+// was recorded.
+func (s *TraceService) GetTrace(ctx context.Context, tripID string) (*domain.TripTraceModel, []domain.TracePoint, error) {
+	trace, err := s.repo.GetTripTrace(ctx, tripID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get trace: %w", err)
+	}
+	if trace == nil {
+		return nil, nil, nil
+	}
+	points, err := trace.Points()
+	if err != nil {
+		return nil, nil, err
+	}
+	return trace, points, nil
+}
+
+// ExportTrace renders a trace as GeoJSON or GPX and returns it with its
+// content type.
+func ExportTrace(trace *domain.TripTraceModel, points []domain.TracePoint, format string) ([]byte, string, error) {
+	switch format {
+	case TraceFormatGeoJSON:
+		b, err := exportGeoJSON(trace, points)
+		return b, "application/geo+json", err
+	case TraceFormatGPX:
+		b, err := exportGPX(trace, points)
+		return b, "application/gpx+xml", err
+	}
+	return nil, "", fmt.Errorf("%w %q", ErrUnknownTraceFormat, format)
+}
+
+// exportGeoJSON renders the trace as a LineString Feature. The point times
+// are in the coordTimes property, as most GeoJSON tools expect them.
+func exportGeoJSON(trace *domain.TripTraceModel, points []domain.TracePoint) ([]byte, error) {
+	coordinates := make([][2]float64, len(points))
+	times := make([]string, len(points))
+	for i, p := range points {
+		coordinates[i] = [2]float64{p.Longitude, p.Latitude}
+		times[i] = p.At.Format(time.RFC3339Nano)
+	}
+	properties := map[string]any{
+		"tripID":     trace.TripID,
+		"startedAt":  trace.StartedAt.Format(time.RFC3339Nano),
+		"endedAt":    trace.EndedAt.Format(time.RFC3339Nano),
+		"coordTimes": times,
+		"anonymized": trace.AnonymizedAt != nil,
+	}
+	if trace.DriverID != "" {
+		properties["driverID"] = trace.DriverID
+	}
+	return json.Marshal(map[string]any{
+		"type": "Feature",
+		"geometry": map[string]any{
+			"type":        "LineString",
+			"coordinates": coordinates,
+		},
+		"properties": properties,
+	})
+}
+
+type gpxDocument struct {
+	XMLName xml.Name `xml:"gpx"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Track   struct {
+		Name    string `xml:"name"`
+		Segment struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time"`
+}
+
+func exportGPX(trace *domain.TripTraceModel, points []domain.TracePoint) ([]byte, error) {
+	doc := gpxDocument{Version: "1.1", Creator: "ride-sharing trip-service", XMLNS: "http://www.topografix.com/GPX/1/1"}
+	doc.Track.Name = "trip " + trace.TripID
+	doc.Track.Segment.Points = make([]gpxPoint, len(points))
+	for i, p := range points {
+		doc.Track.Segment.Points[i] = gpxPoint{Lat: p.Latitude, Lon: p.Longitude, Time: p.At.Format(time.RFC3339Nano)}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode gpx: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Run applies the retention policy every interval until ctx is cancelled.
+func (s *TraceService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ApplyRetention(ctx, time.Now()); err != nil {
+				log.Printf("trace: retention failed: %v", err)
+			}
+		}
+	}
+}
+
+// ApplyRetention deletes or anonymizes the traces that ended more than the
+// retention period before now.
+func (s *TraceService) ApplyRetention(ctx context.Context, now time.Time) error {
+	before := now.Add(-s.retention.Period)
+	if s.retention.Mode == TraceRetentionDelete {
+		n, err := s.repo.DeleteTracesEndedBefore(ctx, before)
+		if err != nil {
+			return fmt.Errorf("failed to delete traces: %w", err)
+		}
+		if n > 0 {
+			log.Printf("trace: deleted %d traces that ended before %s", n, before.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	traces, err := s.repo.ListTracesEndedBefore(ctx, before, traceRetentionBatch)
+	if err != nil {
+		return fmt.Errorf("failed to list expired traces: %w", err)
+	}
+	var errs []error
+	for _, trace := range traces {
+		if err := s.anonymize(ctx, trace, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if n := len(traces) - len(errs); n > 0 {
+		log.Printf("trace: anonymized %d traces that ended before %s", n, before.Format(time.RFC3339))
+	}
+	return errors.Join(errs...)
+}
+
+// anonymize drops the driver from trace and coarsens its points.
+func (s *TraceService) anonymize(ctx context.Context, trace *domain.TripTraceModel, now time.Time) error {
+	points, err := trace.Points()
+	if err != nil {
+		return err
+	}
+	for i := range points {
+		points[i].Latitude = math.Round(points[i].Latitude*traceAnonymizedScale) / traceAnonymizedScale
+		points[i].Longitude = math.Round(points[i].Longitude*traceAnonymizedScale) / traceAnonymizedScale
+	}
+	encoded, err := domain.EncodeTracePoints(points)
+	if err != nil {
+		return fmt.Errorf("failed to encode trace of trip %s: %w", trace.TripID, err)
+	}
+
+	anonymizedAt := now.UTC()
+	trace.DriverID = ""
+	trace.Chunks = []domain.TraceChunk{{ID: "anonymized", Count: len(points), Data: encoded}}
+	trace.AnonymizedAt = &anonymizedAt
+	if err := s.repo.ReplaceTripTrace(ctx, trace); err != nil {
+		return fmt.Errorf("failed to anonymize trace of trip %s: %w", trace.TripID, err)
+	}
+	return nil
+}
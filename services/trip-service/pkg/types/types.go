@@ -3,13 +3,35 @@ package types
 import pb "ride-sharing/shared/proto/trip"
 
 type OSRMApiResponse struct {
-	Routes []struct {
-		Distance float64 `json:"distance"`
-		Duration float64 `json:"duration"`
-		Geometry struct {
-			Coordinates [][]float64 `json:"coordinates"`
-		} `json:"geometry"`
-	} `json:"routes"`
+	Routes []OSRMRoute `json:"routes"`
+}
+
+type OSRMRoute struct {
+	Distance float64      `json:"distance"`
+	Duration float64      `json:"duration"`
+	Geometry OSRMGeometry `json:"geometry"`
+}
+
+type OSRMGeometry struct {
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+// RouteFromProto is the inverse of ToProto.
+func RouteFromProto(route *pb.Route) *OSRMApiResponse {
+	if route == nil {
+		return nil
+	}
+	var coordinates [][]float64
+	for _, geometry := range route.Geometry {
+		for _, coord := range geometry.Coordinates {
+			coordinates = append(coordinates, []float64{coord.Latitude, coord.Longitude})
+		}
+	}
+	return &OSRMApiResponse{Routes: []OSRMRoute{{
+		Distance: route.Distance,
+		Duration: route.Duration,
+		Geometry: OSRMGeometry{Coordinates: coordinates},
+	}}}
 }
 
 func (o *OSRMApiResponse) ToProto() *pb.Route {
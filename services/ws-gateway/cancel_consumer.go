@@ -12,18 +12,18 @@ import (
 )
 
 type cancelConsumer struct {
-	rabbitmq    *messaging.RabbitMQ
+	broker      messaging.Subscriber
 	connManager *messaging.RedisConnectionManager
 }
 
-func newCancelConsumer(rabbitmq *messaging.RabbitMQ, connManager *messaging.RedisConnectionManager) *cancelConsumer {
-	return &cancelConsumer{rabbitmq: rabbitmq, connManager: connManager}
+func newCancelConsumer(broker messaging.Subscriber, connManager *messaging.RedisConnectionManager) *cancelConsumer {
+	return &cancelConsumer{broker: broker, connManager: connManager}
 }
 
 func (c *cancelConsumer) Start() error {
-	return c.rabbitmq.ConsumeMessages(messaging.NotifyTripCancelledQueue, func(ctx context.Context, msg amqp091.Delivery) error {
-		var envelope contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+	return c.broker.ConsumeMessages(messaging.NotifyTripCancelledQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		envelope, err := messaging.DecodeMessage(msg)
+		if err != nil {
 			log.Printf("cancelConsumer: failed to unmarshal envelope: %v", err)
 			return err
 		}
@@ -52,9 +52,7 @@ func (c *cancelConsumer) Start() error {
 		wsMsg := contracts.WSMessage{
 			Type:  contracts.TripEventCancelled,
 			Topic: "trip:" + payload.TripID,
-			Data: map[string]string{
-				"tripID": payload.TripID,
-			},
+			Data:  contracts.WSTripRefData{TripID: payload.TripID},
 		}
 
 		shouldTearDownTripStreams := payload.DriverAccepted || driverID != ""
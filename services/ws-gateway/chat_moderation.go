@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/moderation"
+)
+
+// wsErrMessageRejected is the ws.error code for a chat message moderation
+// blocked; the message carries the reason to show the sender.
+const wsErrMessageRejected = "message_rejected"
+
+const defaultClassifierTimeout = 500 * time.Millisecond
+
+// newChatModerator builds the pipeline every chat message passes before it is
+// relayed: contact details are masked first, then the configured word list
+// and classifier may block the message.
+//
+//	CHAT_BLOCKED_WORDS         comma-separated words to block
+//	CHAT_BLOCKED_WORDS_FILE    file with one word per line, # for comments
+//	CHAT_CLASSIFIER_URL        external classifier; a clean stub when unset
+//	CHAT_CLASSIFIER_THRESHOLD  score from which a label blocks (default 0.8)
+//	CHAT_CLASSIFIER_TIMEOUT    how long to wait for the classifier (default 500ms)
+func newChatModerator() *moderation.Pipeline {
+	wordList := moderation.NewWordList(nil)
+	if path := env.GetString("CHAT_BLOCKED_WORDS_FILE", ""); path != "" {
+		list, err := moderation.LoadWordListFile(path)
+		if err != nil {
+			log.Printf("Ignoring CHAT_BLOCKED_WORDS_FILE: %v", err)
+		} else {
+			wordList = list
+		}
+	}
+	if words := env.GetString("CHAT_BLOCKED_WORDS", ""); words != "" {
+		wordList.Add(strings.Split(words, ",")...)
+	}
+
+	var classifier moderation.Classifier = moderation.StubClassifier{}
+	if url := env.GetString("CHAT_CLASSIFIER_URL", ""); url != "" {
+		timeout := defaultClassifierTimeout
+		if d, err := time.ParseDuration(env.GetString("CHAT_CLASSIFIER_TIMEOUT", timeout.String())); err == nil {
+			timeout = d
+		}
+		classifier = moderation.NewHTTPClassifier(url, timeout)
+	}
+	threshold := moderation.DefaultClassifierThreshold
+	if raw := env.GetString("CHAT_CLASSIFIER_THRESHOLD", ""); raw != "" {
+		if t, err := strconv.ParseFloat(raw, 64); err == nil && t > 0 && t <= 1 {
+			threshold = t
+		} else {
+			log.Printf("Ignoring CHAT_CLASSIFIER_THRESHOLD %q: want a number in (0, 1]", raw)
+		}
+	}
+
+	log.Printf("Chat moderation: %d blocked words, classifier %T (threshold %.2f)", wordList.Len(), classifier, threshold)
+	return moderation.NewPipeline(
+		moderation.ContactMasker{},
+		wordList,
+		moderation.ClassifierRule{Classifier: classifier, Threshold: threshold},
+	)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+const wsDrainPollInterval = 500 * time.Millisecond
+
+// gatewayDrain moves this node's clients to other nodes before it shuts down.
+type gatewayDrain struct {
+	connManager *messaging.RedisConnectionManager
+	registry    *nodeRegistry
+	rdb         *redis.Client
+	draining    atomic.Bool
+}
+
+func newGatewayDrain(connManager *messaging.RedisConnectionManager, registry *nodeRegistry, rdb *redis.Client) *gatewayDrain {
+	return &gatewayDrain{connManager: connManager, registry: registry, rdb: rdb}
+}
+
+// Draining reports whether the node is draining. A draining node accepts no
+// new sockets, and drivers disconnecting from it are not unregistered.
+func (d *gatewayDrain) Draining() bool {
+	return d.draining.Load()
+}
+
+// Drain marks the node draining and sends every socket a ws.reconnect frame
+// with a random delay below jitter. It returns once all sockets have closed or
+// timeout passed, after closing the remaining sockets with 1001 (going away).
+func (d *gatewayDrain) Drain(ctx context.Context, jitter, timeout time.Duration) {
+	d.draining.Store(true)
+	if err := d.registry.SetState(ctx, nodeDraining); err != nil {
+		log.Printf("Failed to mark ws node draining: %v", err)
+	}
+	if nodes, err := listNodes(ctx, d.rdb); err == nil {
+		serving := 0
+		for _, node := range nodes {
+			if node.State == nodeServing {
+				serving++
+			}
+		}
+		log.Printf("Draining ws node %s: %d other nodes serving", d.registry.node.ID, serving)
+	}
+
+	socketIDs := d.connManager.LocalSocketIDs()
+	for _, socketID := range socketIDs {
+		var delay time.Duration
+		if jitter > 0 {
+			delay = time.Duration(rand.Int63n(int64(jitter)))
+		}
+		if err := d.connManager.SendToSocket(socketID, contracts.WSMessage{
+			Type: contracts.WSReconnect,
+			Data: contracts.WSReconnectData{DelayMs: delay.Milliseconds(), Reason: "drain"},
+		}); err != nil {
+			log.Printf("Failed to send ws.reconnect to socket %s: %v", socketID, err)
+		}
+	}
+	log.Printf("Sent ws.reconnect to %d sockets", len(socketIDs))
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(wsDrainPollInterval)
+	defer ticker.Stop()
+	for {
+		remaining := len(d.connManager.LocalSocketIDs())
+		if remaining == 0 {
+			log.Println("All sockets migrated")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			log.Printf("Drain timeout: closing %d remaining sockets", remaining)
+			d.connManager.CloseLocal(websocket.CloseGoingAway, "server shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
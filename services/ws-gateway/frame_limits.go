@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const wsErrRateLimited = "rate_limited"
+
+// frameOverflow says what happens to a frame that exceeds its type's limit.
+type frameOverflow int
+
+const (
+	// frameReject answers the frame with a ws.error.
+	frameReject frameOverflow = iota
+	// frameCoalesce holds the frame and sends it once a token is available;
+	// a newer frame with the same coalesce key replaces it.
+	frameCoalesce
+)
+
+// frameLimit is a token bucket refilled at Rate frames per second up to Burst.
+type frameLimit struct {
+	Rate     float64
+	Burst    int
+	Overflow frameOverflow
+}
+
+// defaultFrameLimits apply per user and frame type on each gateway node.
+// Frame types without a limit are not rate limited.
+var defaultFrameLimits = map[string]frameLimit{
+	contracts.DriverCmdLocation:    {Rate: 1, Burst: 5, Overflow: frameCoalesce},
+	WSChatMessageSend:              {Rate: 1, Burst: 10, Overflow: frameReject},
+	WSChatMessageRead:              {Rate: 1, Burst: 5, Overflow: frameCoalesce},
+	WSChatTyping:                   {Rate: 0.5, Burst: 3, Overflow: frameCoalesce},
+	contracts.DriverCmdTripAccept:  {Rate: 1, Burst: 5, Overflow: frameReject},
+	contracts.DriverCmdTripDecline: {Rate: 1, Burst: 5, Overflow: frameReject},
+	contracts.WSRoomJoin:           {Rate: 2, Burst: 10, Overflow: frameReject},
+	contracts.WSTopicSubscribe:     {Rate: 2, Burst: 10, Overflow: frameReject},
+}
+
+// parseFrameLimits overrides defaults with a spec such as
+// "driver.cmd.location=2/10,chat.message.send=0.5/5" (rate per second/burst).
+// The overflow behaviour of a type is kept.
+func parseFrameLimits(spec string, defaults map[string]frameLimit) (map[string]frameLimit, error) {
+	limits := make(map[string]frameLimit, len(defaults))
+	for frameType, limit := range defaults {
+		limits[frameType] = limit
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		frameType, value, ok := strings.Cut(entry, "=")
+		rateStr, burstStr, ok2 := strings.Cut(value, "/")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("frame limit %q: want type=rate/burst", entry)
+		}
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("frame limit %q: invalid rate", entry)
+		}
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("frame limit %q: invalid burst", entry)
+		}
+		limit := limits[frameType]
+		limit.Rate, limit.Burst = rate, burst
+		limits[frameType] = limit
+	}
+	return limits, nil
+}
+
+type tokenBucket struct {
+	limit  frameLimit
+	tokens float64
+	last   time.Time
+}
+
+// take consumes a token if one is available. Otherwise it returns how long
+// until the next one.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// full reports whether the bucket would be full at now.
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst)
+}
+
+// frameLimiter holds the token buckets of every user on this node, shared by
+// all of a user's sockets. It reports ws.frames.limited by frame type and
+// outcome (coalesced or rejected).
+type frameLimiter struct {
+	limits  map[string]frameLimit
+	limited metric.Int64Counter
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // userID|frameType → bucket
+}
+
+func newFrameLimiter(limits map[string]frameLimit) *frameLimiter {
+	l := &frameLimiter{limits: limits, buckets: make(map[string]*tokenBucket)}
+	limited, err := tracing.GetMeter("ride-sharing/ws-gateway").Int64Counter("ws.frames.limited",
+		metric.WithDescription("Client WebSocket frames over their rate limit, by frame type and outcome"))
+	if err != nil {
+		log.Printf("Failed to create frame limit counter: %v", err)
+	}
+	l.limited = limited
+	return l
+}
+
+// allow takes a token for a frame of frameType from userID. When none is
+// left it returns the wait until the next token.
+func (l *frameLimiter) allow(userID, frameType string) (frameLimit, bool, time.Duration) {
+	limit, ok := l.limits[frameType]
+	if !ok {
+		return limit, true, 0
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := userID + "|" + frameType
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	allowed, wait := b.take(now)
+	return limit, allowed, wait
+}
+
+func (l *frameLimiter) record(frameType, outcome string) {
+	if l.limited == nil {
+		return
+	}
+	l.limited.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("ws.frame.type", frameType),
+		attribute.String("outcome", outcome),
+	))
+}
+
+// Run drops buckets that have been idle long enough to be full again, every
+// interval until ctx is cancelled.
+func (l *frameLimiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.mu.Lock()
+			for key, b := range l.buckets {
+				if b.full(now) {
+					delete(l.buckets, key)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// coalesceKey says which held frame msg replaces. Chat read receipts and
+// typing frames are kept per trip, so a frame for one trip never replaces
+// one for another; other frames are kept per type.
+func coalesceKey(msg wsIncomingMessage) string {
+	switch msg.Type {
+	case WSChatMessageRead, WSChatTyping:
+		var data struct {
+			TripID string `json:"tripID"`
+		}
+		// The frame was validated, so the trip is set.
+		_ = json.Unmarshal(msg.Data, &data)
+		return msg.Type + "|" + data.TripID
+	}
+	return msg.Type
+}
+
+// socketFrameLimiter applies the limiter to the frames of one socket and
+// holds its coalesced frames.
+type socketFrameLimiter struct {
+	limiter *frameLimiter
+	userID  string
+
+	mu      sync.Mutex
+	held    map[string]wsIncomingMessage // coalesce key → latest held frame
+	timers  map[string]*time.Timer       // coalesce key → delivery timer
+	sending map[string]struct{}          // coalesce keys whose held frame is being delivered
+	closed  bool
+}
+
+func (l *frameLimiter) forSocket(userID string) *socketFrameLimiter {
+	return &socketFrameLimiter{
+		limiter: l,
+		userID:  userID,
+		held:    make(map[string]wsIncomingMessage),
+		timers:  make(map[string]*time.Timer),
+		sending: make(map[string]struct{}),
+	}
+}
+
+// admit reports whether msg may be handled now. A coalesced frame is held and
+// passed to deliver once a token is available; a rejected frame returns the
+// ws.error to reply with. While a frame is held or being delivered, newer
+// frames with its coalesce key are held after it, so they are never sent out
+// of order.
+func (s *socketFrameLimiter) admit(msg wsIncomingMessage, deliver func(wsIncomingMessage)) (bool, *contracts.WSErrorData) {
+	key := coalesceKey(msg)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return false, nil
+	}
+	_, held := s.held[key]
+	_, sending := s.sending[key]
+	if held || sending {
+		s.held[key] = msg
+		s.mu.Unlock()
+		s.limiter.record(msg.Type, "coalesced")
+		return false, nil
+	}
+	s.mu.Unlock()
+
+	limit, allowed, wait := s.limiter.allow(s.userID, msg.Type)
+	if allowed {
+		return true, nil
+	}
+
+	if limit.Overflow == frameReject {
+		s.limiter.record(msg.Type, "rejected")
+		return false, &contracts.WSErrorData{
+			Code:    wsErrRateLimited,
+			Message: fmt.Sprintf("too many %s frames, retry in %s", msg.Type, wait.Round(time.Millisecond)),
+		}
+	}
+
+	s.limiter.record(msg.Type, "coalesced")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, nil
+	}
+	s.held[key] = msg
+	if _, scheduled := s.timers[key]; !scheduled {
+		s.schedule(key, msg.Type, wait, deliver)
+	}
+	return false, nil
+}
+
+// schedule sends the frame held under key after wait, or waits again if the
+// bucket of frameType is still empty. deliver runs without s.mu, so the read
+// loop is not held up by it; frames with the same key that arrive meanwhile
+// are held and sent after it. The caller holds s.mu.
+func (s *socketFrameLimiter) schedule(key, frameType string, wait time.Duration, deliver func(wsIncomingMessage)) {
+	s.timers[key] = time.AfterFunc(wait, func() {
+		_, allowed, next := s.limiter.allow(s.userID, frameType)
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		if !allowed {
+			s.schedule(key, frameType, next, deliver)
+			s.mu.Unlock()
+			return
+		}
+		msg := s.held[key]
+		delete(s.held, key)
+		delete(s.timers, key)
+		s.sending[key] = struct{}{}
+		s.mu.Unlock()
+
+		deliver(msg)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.sending, key)
+		if _, held := s.held[key]; held && !s.closed {
+			s.schedule(key, frameType, 0, deliver)
+		}
+	})
+}
+
+// close drops held frames and stops their timers.
+func (s *socketFrameLimiter) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, t := range s.timers {
+		t.Stop()
+	}
+	s.held = nil
+}
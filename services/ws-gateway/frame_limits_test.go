@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ride-sharing/shared/contracts"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	b := &tokenBucket{limit: frameLimit{Rate: 2, Burst: 3}, tokens: 3, last: start}
+
+	for i := range 3 {
+		if ok, _ := b.take(start); !ok {
+			t.Fatalf("take %d of the burst was refused", i+1)
+		}
+	}
+	ok, wait := b.take(start)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("take with an empty bucket = %v, %s; want false, 500ms", ok, wait)
+	}
+	if ok, _ := b.take(start.Add(500 * time.Millisecond)); !ok {
+		t.Error("take after the refill was refused")
+	}
+	if b.full(start.Add(1500 * time.Millisecond)) {
+		t.Error("bucket full after 1.5s, want 2s")
+	}
+	if !b.full(start.Add(2 * time.Second)) {
+		t.Error("bucket not full after 2s")
+	}
+}
+
+func TestParseFrameLimits(t *testing.T) {
+	limits, err := parseFrameLimits("driver.cmd.location=2/10, chat.message.send=0.5/5", defaultFrameLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := limits[contracts.DriverCmdLocation]; got != (frameLimit{Rate: 2, Burst: 10, Overflow: frameCoalesce}) {
+		t.Errorf("driver.cmd.location = %+v", got)
+	}
+	if got := limits[WSChatMessageSend]; got != (frameLimit{Rate: 0.5, Burst: 5, Overflow: frameReject}) {
+		t.Errorf("chat.message.send = %+v", got)
+	}
+	if defaultFrameLimits[contracts.DriverCmdLocation].Rate != 1 {
+		t.Error("parseFrameLimits changed the defaults")
+	}
+
+	for _, spec := range []string{"driver.cmd.location", "driver.cmd.location=2", "x=0/1", "x=1/0", "x=a/1"} {
+		if _, err := parseFrameLimits(spec, defaultFrameLimits); err == nil {
+			t.Errorf("parseFrameLimits(%q) succeeded", spec)
+		}
+	}
+}
+
+func typingFrame(tripID string, typing bool) wsIncomingMessage {
+	data, _ := json.Marshal(contracts.WSChatTypingData{TripID: tripID, Typing: typing})
+	return wsIncomingMessage{Type: WSChatTyping, Data: data}
+}
+
+func TestSocketFrameLimiterRejects(t *testing.T) {
+	limiter := newFrameLimiter(map[string]frameLimit{
+		WSChatMessageSend: {Rate: 0.001, Burst: 1, Overflow: frameReject},
+	})
+	s := limiter.forSocket("rider-1")
+	defer s.close()
+	deliver := func(wsIncomingMessage) { t.Error("a rejected frame was delivered") }
+
+	msg := wsIncomingMessage{Type: WSChatMessageSend}
+	if ok, wsErr := s.admit(msg, deliver); !ok || wsErr != nil {
+		t.Fatalf("first frame = %v, %v; want admitted", ok, wsErr)
+	}
+	ok, wsErr := s.admit(msg, deliver)
+	if ok || wsErr == nil || wsErr.Code != wsErrRateLimited {
+		t.Errorf("second frame = %v, %+v; want rate_limited", ok, wsErr)
+	}
+
+	other := limiter.forSocket("rider-2")
+	defer other.close()
+	if ok, _ := other.admit(msg, deliver); !ok {
+		t.Error("another user's frame was limited")
+	}
+}
+
+func TestSocketFrameLimiterCoalesces(t *testing.T) {
+	limiter := newFrameLimiter(map[string]frameLimit{
+		WSChatTyping: {Rate: 20, Burst: 1, Overflow: frameCoalesce},
+	})
+	s := limiter.forSocket("rider-1")
+	defer s.close()
+	delivered := make(chan bool, 10)
+	deliver := func(msg wsIncomingMessage) {
+		var data contracts.WSChatTypingData
+		_ = json.Unmarshal(msg.Data, &data)
+		delivered <- data.Typing
+	}
+
+	if ok, _ := s.admit(typingFrame("trip-1", true), deliver); !ok {
+		t.Fatal("first frame was not admitted")
+	}
+	// Over the limit: the second frame is held and the third replaces it.
+	for _, msg := range []wsIncomingMessage{typingFrame("trip-1", true), typingFrame("trip-1", false)} {
+		if ok, wsErr := s.admit(msg, deliver); ok || wsErr != nil {
+			t.Fatalf("frame over the limit = %v, %v; want held", ok, wsErr)
+		}
+	}
+
+	select {
+	case typing := <-delivered:
+		if typing {
+			t.Error("delivered the replaced frame")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("held frame not delivered")
+	}
+	select {
+	case <-delivered:
+		t.Error("delivered more than the latest held frame")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSocketFrameLimiterHoldsFramesDuringDelivery(t *testing.T) {
+	limiter := newFrameLimiter(map[string]frameLimit{
+		WSChatTyping: {Rate: 50, Burst: 1, Overflow: frameCoalesce},
+	})
+	s := limiter.forSocket("rider-1")
+	defer s.close()
+
+	sending, release := make(chan struct{}), make(chan struct{})
+	delivered := make(chan bool, 10)
+	deliver := func(msg wsIncomingMessage) {
+		var data contracts.WSChatTypingData
+		_ = json.Unmarshal(msg.Data, &data)
+		if data.Typing {
+			close(sending)
+			<-release // a slow publish
+		}
+		delivered <- data.Typing
+	}
+
+	s.admit(typingFrame("trip-1", true), deliver)
+	s.admit(typingFrame("trip-1", true), deliver) // held, delivered once a token is back
+
+	// Wait until the held frame is being delivered, then send another one:
+	// admit must not block on the delivery, and the new frame must not
+	// overtake it even though a token is available again.
+	select {
+	case <-sending:
+	case <-time.After(time.Second):
+		t.Fatal("held frame was not delivered")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if ok, _ := s.admit(typingFrame("trip-1", false), deliver); ok {
+		t.Fatal("a frame overtook the one being delivered")
+	}
+	close(release)
+
+	for _, want := range []bool{true, false} {
+		select {
+		case got := <-delivered:
+			if got != want {
+				t.Fatalf("delivered typing=%v, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("frame typing=%v not delivered", want)
+		}
+	}
+}
+
+func TestSocketFrameLimiterClose(t *testing.T) {
+	limiter := newFrameLimiter(map[string]frameLimit{
+		WSChatTyping: {Rate: 20, Burst: 1, Overflow: frameCoalesce},
+	})
+	s := limiter.forSocket("rider-1")
+	deliver := func(wsIncomingMessage) { t.Error("a frame was delivered after close") }
+
+	s.admit(typingFrame("trip-1", true), deliver)
+	s.admit(typingFrame("trip-1", false), deliver)
+	s.close()
+	if ok, _ := s.admit(typingFrame("trip-1", true), deliver); ok {
+		t.Error("a frame was admitted after close")
+	}
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestSocketFrameLimiterCoalescesPerTrip(t *testing.T) {
+	limiter := newFrameLimiter(map[string]frameLimit{
+		WSChatTyping: {Rate: 20, Burst: 1, Overflow: frameCoalesce},
+	})
+	s := limiter.forSocket("rider-1")
+	defer s.close()
+	delivered := make(chan contracts.WSChatTypingData, 10)
+	deliver := func(msg wsIncomingMessage) {
+		var data contracts.WSChatTypingData
+		_ = json.Unmarshal(msg.Data, &data)
+		delivered <- data
+	}
+
+	s.admit(typingFrame("trip-1", true), deliver)
+	for _, msg := range []wsIncomingMessage{typingFrame("trip-1", true), typingFrame("trip-2", true), typingFrame("trip-1", false)} {
+		if ok, _ := s.admit(msg, deliver); ok {
+			t.Fatal("frame over the limit was admitted")
+		}
+	}
+
+	got := map[string]bool{}
+	for range 2 {
+		select {
+		case data := <-delivered:
+			if _, dup := got[data.TripID]; dup {
+				t.Errorf("trip %s delivered twice", data.TripID)
+			}
+			got[data.TripID] = data.Typing
+		case <-time.After(time.Second):
+			t.Fatalf("held frames not delivered, got %v", got)
+		}
+	}
+	if typing, ok := got["trip-1"]; !ok || typing {
+		t.Errorf("trip-1 delivered %v, %v; want the latest frame (false)", typing, ok)
+	}
+	if typing, ok := got["trip-2"]; !ok || !typing {
+		t.Error("trip-2's frame was replaced by trip-1's")
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/gorilla/websocket"
+)
+
+// Codes sent in ws.error frames for rejected client frames.
+const (
+	wsErrMalformedFrame = "malformed_frame"
+	wsErrUnknownType    = "unknown_type"
+	wsErrInvalidFrame   = "invalid_frame"
+)
+
+const (
+	// wsViolationLimit is how many rejected frames a user may send per
+	// wsViolationWindowSecs before their socket is closed.
+	wsViolationLimit      = 20
+	wsViolationWindowSecs = 60
+)
+
+type clientRole string
+
+const (
+	roleRider  clientRole = "rider"
+	roleDriver clientRole = "driver"
+)
+
+// inboundFrameTypes lists the frame types each role may send. Payloads are
+// validated against shared/schemas/events.schema.json by
+// contracts.ValidateWSData.
+var inboundFrameTypes = map[clientRole]map[string]bool{
+	roleRider: {
+		WSChatMessageSend:            true,
+		WSChatMessageRead:            true,
+		WSChatTyping:                 true,
+		contracts.WSRoomJoin:         true,
+		contracts.WSRoomLeave:        true,
+		contracts.WSTopicSubscribe:   true,
+		contracts.WSTopicUnsubscribe: true,
+		contracts.DriverCmdLocation:  true, // accepted and discarded
+	},
+	roleDriver: {
+		WSChatMessageSend:              true,
+		WSChatMessageRead:              true,
+		WSChatTyping:                   true,
+		contracts.WSRoomJoin:           true,
+		contracts.WSRoomLeave:          true,
+		contracts.WSTopicSubscribe:     true,
+		contracts.WSTopicUnsubscribe:   true,
+		contracts.DriverCmdLocation:    true,
+		contracts.DriverCmdTripAccept:  true,
+		contracts.DriverCmdTripDecline: true,
+	},
+}
+
+// decodeInboundFrame parses a raw client frame and validates it against the
+// role's frame registry. Binary frames are protobuf WSMessages from
+// ride.v1.proto clients. On failure it returns as much of the frame as could
+// be parsed together with the ws.error to reply with.
+func decodeInboundFrame(role clientRole, messageType int, raw []byte) (wsIncomingMessage, *contracts.WSErrorData) {
+	var msg wsIncomingMessage
+	if messageType == websocket.BinaryMessage {
+		frame, err := messaging.TranscodeWSProto(raw)
+		if err != nil {
+			return msg, &contracts.WSErrorData{Code: wsErrMalformedFrame, Message: "binary frame is not a valid " + contracts.WSSubprotocolProto + " WSMessage"}
+		}
+		raw = frame
+	}
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return msg, &contracts.WSErrorData{Code: wsErrMalformedFrame, Message: "frame is not a JSON object with type and data"}
+	}
+	if msg.ID == "" && msg.Type == WSChatMessageSend {
+		var chat struct {
+			MessageID string `json:"messageID"`
+		}
+		_ = json.Unmarshal(msg.Data, &chat)
+		msg.ID = chat.MessageID
+	}
+
+	if !inboundFrameTypes[role][msg.Type] {
+		return msg, &contracts.WSErrorData{
+			Code:      wsErrUnknownType,
+			Message:   fmt.Sprintf("%s clients cannot send %q frames", role, msg.Type),
+			MessageID: msg.ID,
+			FrameType: msg.Type,
+		}
+	}
+	if err := contracts.ValidateWSData(msg.Type, msg.Data); err != nil {
+		return msg, &contracts.WSErrorData{
+			Code:      wsErrInvalidFrame,
+			Message:   err.Error(),
+			MessageID: msg.ID,
+			FrameType: msg.Type,
+		}
+	}
+	return msg, nil
+}
+
+// socketSender sends a frame to one local socket, like
+// messaging.RedisConnectionManager.SendToSocket.
+type socketSender interface {
+	SendToSocket(socketID string, msg contracts.WSMessage) error
+}
+
+// frameRejecter answers rejected frames of one socket and closes the socket
+// once its user exceeds the violation limit.
+type frameRejecter struct {
+	connManager socketSender
+	rl          interface {
+		RecordWsViolation(ctx context.Context, userID string) (int, error)
+	}
+	conn interface {
+		WriteControl(messageType int, data []byte, deadline time.Time) error
+		Close() error
+	}
+	socketID string
+	userID   string
+}
+
+// reject sends a ws.error frame for msg and counts the violation. It returns
+// true if the socket was closed.
+func (f *frameRejecter) reject(ctx context.Context, msg wsIncomingMessage, wsErr contracts.WSErrorData) bool {
+	if wsErr.MessageID == "" {
+		wsErr.MessageID = msg.ID
+	}
+	if wsErr.FrameType == "" {
+		wsErr.FrameType = msg.Type
+	}
+	log.Printf("Rejected frame from user %s socket %s: code=%s type=%q id=%q: %s", f.userID, f.socketID, wsErr.Code, wsErr.FrameType, wsErr.MessageID, wsErr.Message)
+	sendWSError(f.connManager, f.socketID, wsErr)
+	if wsErr.Code == wsErrRoomUnavailable {
+		return false // our failure, not the client's
+	}
+
+	count, err := f.rl.RecordWsViolation(ctx, f.userID)
+	if err != nil {
+		log.Printf("ws violation counter redis error: %v", err)
+		return false
+	}
+	if count <= wsViolationLimit {
+		return false
+	}
+
+	log.Printf("security: closing socket %s of user %s after %d rejected frames in %ds", f.socketID, f.userID, count, wsViolationWindowSecs)
+	_ = f.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many invalid frames"),
+		time.Now().Add(time.Second))
+	_ = f.conn.Close()
+	return true
+}
+
+func sendWSError(connManager socketSender, socketID string, wsErr contracts.WSErrorData) {
+	if err := connManager.SendToSocket(socketID, contracts.WSMessage{
+		Type: contracts.WSError,
+		Data: wsErr,
+	}); err != nil {
+		log.Printf("Failed to send ws.error to socket %s: %v", socketID, err)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ride-sharing/shared/contracts"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDecodeInboundFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     clientRole
+		raw      string
+		wantType string
+		wantID   string
+		wantCode string // empty for an accepted frame
+	}{
+		{
+			name:     "valid chat message",
+			role:     roleRider,
+			raw:      `{"type":"chat.message.send","data":{"tripID":"t1","messageID":"m1","text":"hi"}}`,
+			wantType: WSChatMessageSend,
+			wantID:   "m1",
+		},
+		{
+			name:     "envelope ID wins over the chat message ID",
+			role:     roleDriver,
+			raw:      `{"id":"e1","type":"chat.message.send","data":{"tripID":"t1","messageID":"m1","text":"hi"}}`,
+			wantType: WSChatMessageSend,
+			wantID:   "e1",
+		},
+		{
+			name:     "not JSON",
+			role:     roleRider,
+			raw:      `hello`,
+			wantCode: wsErrMalformedFrame,
+		},
+		{
+			name:     "type the role may not send",
+			role:     roleRider,
+			raw:      `{"type":"driver.cmd.trip_accept","data":{}}`,
+			wantType: contracts.DriverCmdTripAccept,
+			wantCode: wsErrUnknownType,
+		},
+		{
+			name:     "unknown type",
+			role:     roleDriver,
+			raw:      `{"id":"e2","type":"admin.shutdown","data":{}}`,
+			wantType: "admin.shutdown",
+			wantID:   "e2",
+			wantCode: wsErrUnknownType,
+		},
+		{
+			name:     "payload failing the schema",
+			role:     roleRider,
+			raw:      `{"type":"chat.typing","data":{"typing":true}}`,
+			wantType: WSChatTyping,
+			wantCode: wsErrInvalidFrame,
+		},
+		{
+			name:     "payload of the wrong shape",
+			role:     roleDriver,
+			raw:      `{"type":"chat.message.send","data":{"tripID":"t1","messageID":"m2","text":42}}`,
+			wantType: WSChatMessageSend,
+			wantID:   "m2",
+			wantCode: wsErrInvalidFrame,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, wsErr := decodeInboundFrame(tt.role, websocket.TextMessage, []byte(tt.raw))
+			if msg.Type != tt.wantType || msg.ID != tt.wantID {
+				t.Errorf("frame = %q/%q, want %q/%q", msg.Type, msg.ID, tt.wantType, tt.wantID)
+			}
+			if tt.wantCode == "" {
+				if wsErr != nil {
+					t.Fatalf("rejected with %+v", wsErr)
+				}
+				return
+			}
+			if wsErr == nil || wsErr.Code != tt.wantCode {
+				t.Fatalf("error = %+v, want code %s", wsErr, tt.wantCode)
+			}
+			if tt.wantCode != wsErrMalformedFrame && (wsErr.FrameType != tt.wantType || wsErr.MessageID != tt.wantID) {
+				t.Errorf("error names %q/%q, want %q/%q", wsErr.FrameType, wsErr.MessageID, tt.wantType, tt.wantID)
+			}
+		})
+	}
+}
+
+type fakeSocketSender struct{ sent []contracts.WSMessage }
+
+func (f *fakeSocketSender) SendToSocket(_ string, msg contracts.WSMessage) error {
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+type fakeViolations struct{ count int }
+
+func (f *fakeViolations) RecordWsViolation(context.Context, string) (int, error) {
+	f.count++
+	return f.count, nil
+}
+
+type fakeCloser struct{ closeCode int }
+
+func (f *fakeCloser) WriteControl(_ int, data []byte, _ time.Time) error {
+	if len(data) >= 2 {
+		f.closeCode = int(data[0])<<8 | int(data[1])
+	}
+	return nil
+}
+
+func (f *fakeCloser) Close() error { return nil }
+
+func TestFrameRejecter(t *testing.T) {
+	sender, violations, conn := &fakeSocketSender{}, &fakeViolations{}, &fakeCloser{}
+	rejecter := &frameRejecter{connManager: sender, rl: violations, conn: conn, socketID: "s1", userID: "u1"}
+	ctx := context.Background()
+	msg := wsIncomingMessage{ID: "m1", Type: WSChatTyping}
+
+	if rejecter.reject(ctx, msg, contracts.WSErrorData{Code: wsErrInvalidFrame, Message: "bad"}) {
+		t.Fatal("socket closed after the first violation")
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Type != contracts.WSError {
+		t.Fatalf("sent %+v, want one ws.error", sender.sent)
+	}
+	if got := sender.sent[0].Data.(contracts.WSErrorData); got.MessageID != "m1" || got.FrameType != WSChatTyping {
+		t.Errorf("ws.error = %+v, want the frame's ID and type", got)
+	}
+
+	// Our own failures are answered but not counted.
+	rejecter.reject(ctx, msg, contracts.WSErrorData{Code: wsErrRoomUnavailable})
+	if violations.count != 1 {
+		t.Errorf("violations = %d after room_unavailable, want 1", violations.count)
+	}
+
+	for i := 2; i <= wsViolationLimit; i++ {
+		if rejecter.reject(ctx, msg, contracts.WSErrorData{Code: wsErrInvalidFrame}) {
+			t.Fatalf("socket closed after %d violations", i)
+		}
+	}
+	if !rejecter.reject(ctx, msg, contracts.WSErrorData{Code: wsErrInvalidFrame}) {
+		t.Fatal("socket not closed after exceeding the violation limit")
+	}
+	if conn.closeCode != websocket.ClosePolicyViolation {
+		t.Errorf("close code = %d, want %d", conn.closeCode, websocket.ClosePolicyViolation)
+	}
+}
@@ -0,0 +1,43 @@
+package grpc_clients
+
+import (
+	"log"
+	"os"
+
+	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type TripServiceClient struct {
+	Client pb.TripServiceClient
+	conn   *grpc.ClientConn
+}
+
+func NewTripServiceClient() (*TripServiceClient, error) {
+	tripServiceURL := os.Getenv("TRIP_SERVICE_URL")
+	if tripServiceURL == "" {
+		tripServiceURL = "trip-service:9093"
+	}
+	dialOptions := append(
+		tracing.DialOptionsWithTracing(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient(tripServiceURL, dialOptions...)
+	if err != nil {
+		log.Println("failed to connect to trip service:", err)
+		return nil, err
+	}
+	return &TripServiceClient{
+		Client: pb.NewTripServiceClient(conn),
+		conn:   conn,
+	}, nil
+}
+
+func (c *TripServiceClient) Close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
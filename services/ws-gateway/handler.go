@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/moderation"
+	"ride-sharing/shared/presence"
 	pb "ride-sharing/shared/proto/driver"
 
 	"github.com/google/uuid"
@@ -36,6 +39,39 @@ func startWsGateHeartbeat(userID string, rl *RateLimiter) func() {
 	return func() { close(done) }
 }
 
+// startPresenceHeartbeat records presence heartbeats for a driver socket until
+// the returned function is called. Once it returns, no heartbeat of the
+// socket is in flight.
+func startPresenceHeartbeat(store *presence.Store, driverID, packageSlug string) func() {
+	beat := func() {
+		if _, err := store.Heartbeat(context.Background(), driverID, packageSlug); err != nil {
+			log.Printf("Presence heartbeat failed: %v", err)
+		}
+	}
+	beat()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(presence.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				beat()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
 func startWsPingLoop(conn *websocket.Conn) func() {
 	done := make(chan struct{})
 	go func() {
@@ -60,10 +96,18 @@ func startWsPingLoop(conn *websocket.Conn) func() {
 func handleRidersWebSocket(
 	w http.ResponseWriter,
 	r *http.Request,
-	rb *messaging.RabbitMQ,
+	rb messaging.Publisher,
 	connManager *messaging.RedisConnectionManager,
+	auth *roomAuthorizer,
 	rl *RateLimiter,
+	frames *frameLimiter,
+	moderator *moderation.Pipeline,
+	drain *gatewayDrain,
 ) {
+	if drain.Draining() {
+		http.Error(w, "ws-gateway is draining", http.StatusServiceUnavailable)
+		return
+	}
 	conn, err := connManager.Upgrade(w, r)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
@@ -89,8 +133,19 @@ func handleRidersWebSocket(
 	// Dead connections will be cleaned up when ping fails and WS connection is closed, triggering the deferred release and heartbeat stop.
 	defer stopPing()
 
-	connManager.Add(userID, socketID, conn)
+	connManager.Add(userID, socketID, messaging.WSConn(conn), r.URL.Query().Get("lastEventID"))
 	defer connManager.Remove(socketID)
+	rejecter := &frameRejecter{connManager: connManager, rl: rl, conn: conn, socketID: socketID, userID: userID}
+	limits := frames.forSocket(userID)
+	defer limits.close()
+	deliverHeld := func(msg wsIncomingMessage) {
+		if msg.Type == contracts.DriverCmdLocation {
+			return
+		}
+		if err := relayTripChatFrame(r.Context(), connManager, rb, userID, msg); err != nil {
+			log.Printf("Rider chat relay error: %v", err)
+		}
+	}
 
 	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
 	conn.SetPongHandler(func(string) error {
@@ -98,21 +153,36 @@ func handleRidersWebSocket(
 	})
 
 	for {
-		_, message, err := conn.ReadMessage()
+		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			log.Printf("Rider WS read error: %v", err)
 			break
 		}
 
-		var msg wsIncomingMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("Rider message unmarshal error: %v", err)
+		msg, wsErr := decodeInboundFrame(roleRider, messageType, message)
+		if wsErr != nil {
+			if rejecter.reject(r.Context(), msg, *wsErr) {
+				return
+			}
+			continue
+		}
+		// Coalesced chat frames are relayed once the rider's bucket refills;
+		// driver.cmd.location is discarded anyway.
+		if ok, wsErr := limits.admit(msg, deliverHeld); !ok {
+			if wsErr != nil && rejecter.reject(r.Context(), msg, *wsErr) {
+				return
+			}
 			continue
 		}
 
 		switch msg.Type {
 		case WSChatMessageSend:
-			if err := relayTripChatMessage(r.Context(), connManager, rb, socketID, userID, msg.Data); err != nil {
+			if err := relayTripChatMessage(r.Context(), connManager, rb, moderator, socketID, userID, msg.Data); err != nil {
+				log.Printf("Rider chat relay error: %v", err)
+			}
+
+		case WSChatMessageRead, WSChatTyping:
+			if err := relayTripChatFrame(r.Context(), connManager, rb, userID, msg); err != nil {
 				log.Printf("Rider chat relay error: %v", err)
 			}
 
@@ -122,7 +192,9 @@ func handleRidersWebSocket(
 				log.Printf("Rider room join parse error: %v", err)
 				continue
 			}
-			connManager.JoinRoom(socketID, ctrl.RoomID)
+			if wsErr := joinAuthorizedRoom(r.Context(), connManager, auth, socketID, userID, ctrl.RoomID); wsErr != nil && rejecter.reject(r.Context(), msg, *wsErr) {
+				return
+			}
 
 		case contracts.WSRoomLeave:
 			var ctrl contracts.WSRoomControlData
@@ -139,7 +211,9 @@ func handleRidersWebSocket(
 				log.Printf("Rider subscribe parse error: %v", err)
 				continue
 			}
-			connManager.JoinRoom(socketID, ctrl.Topic)
+			if wsErr := joinAuthorizedRoom(r.Context(), connManager, auth, socketID, userID, ctrl.Topic); wsErr != nil && rejecter.reject(r.Context(), msg, *wsErr) {
+				return
+			}
 
 		case contracts.WSTopicUnsubscribe:
 			var ctrl contracts.WSTopicControlData
@@ -162,10 +236,19 @@ func handleRidersWebSocket(
 func handleDriversWebSocket(
 	w http.ResponseWriter,
 	r *http.Request,
-	rb *messaging.RabbitMQ,
+	rb messaging.Publisher,
 	connManager *messaging.RedisConnectionManager,
+	auth *roomAuthorizer,
 	rl *RateLimiter,
+	frames *frameLimiter,
+	moderator *moderation.Pipeline,
+	presenceStore *presence.Store,
+	drain *gatewayDrain,
 ) {
+	if drain.Draining() {
+		http.Error(w, "ws-gateway is draining", http.StatusServiceUnavailable)
+		return
+	}
 	conn, err := connManager.Upgrade(w, r)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
@@ -195,17 +278,44 @@ func handleDriversWebSocket(
 	stopPing := startWsPingLoop(conn)
 	defer stopPing()
 
-	ctx := r.Context()
-
-	connManager.Add(userID, socketID, conn)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	connManager.Add(userID, socketID, messaging.WSConn(conn), r.URL.Query().Get("lastEventID"))
+	rejecter := &frameRejecter{connManager: connManager, rl: rl, conn: conn, socketID: socketID, userID: userID}
+	limits := frames.forSocket(userID)
+	// Coalesced location and chat frames are handled once the driver's
+	// bucket refills.
+	deliverHeld := func(msg wsIncomingMessage) {
+		if msg.Type == contracts.DriverCmdLocation {
+			publishDriverLocation(ctx, rb, userID, packageSlug, msg.Data)
+			return
+		}
+		if err := relayTripChatFrame(ctx, connManager, rb, userID, msg); err != nil {
+			log.Printf("Driver chat relay error: %v", err)
+		}
+	}
 
 	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
 	conn.SetPongHandler(func(string) error {
 		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
 	})
 
+	stopPresence := startPresenceHeartbeat(presenceStore, userID, packageSlug)
+	defer stopPresence()
+
 	defer func() {
+		limits.close()
+		cancel() // before Remove, so joinTripChatOnceAssigned can't join a removed socket
 		connManager.Remove(socketID)
+		stopPresence()
+		closedAt := time.Now()
+		if drain.Draining() {
+			// Planned drain: the driver was asked to reconnect to another node,
+			// and presence expires them if they never do.
+			log.Printf("Skipping driver unregister for %s: node is draining", userID)
+			return
+		}
 		if connManager.HasLocalUser(userID) {
 			log.Printf("Skipping driver unregister for %s: another socket is already active", userID)
 			return
@@ -220,9 +330,18 @@ func handleDriversWebSocket(
 		}
 
 		cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		// on disconnect, attempt to unregister driver to clean up geo state.
+		// on disconnect, attempt to unregister driver to clean up geo state and
+		// mark them offline.
 		// timeout will trigger cleanup on the driver service side even if this call fails, so we won't leak drivers indefinitely.
 		defer cancel()
+		// A socket on another node records heartbeats too; if one did since
+		// this socket closed, the driver reconnected there.
+		if seen, err := presenceStore.Lookup(cleanupCtx, []string{userID}); err != nil {
+			log.Printf("Failed to look up presence of driver %s, unregistering anyway: %v", userID, err)
+		} else if seen[userID].LastSeen.After(closedAt) {
+			log.Printf("Skipping driver unregister for %s: reconnected to another node", userID)
+			return
+		}
 		if _, err := driverClient.Client.UnregisterDriver(cleanupCtx, &pb.RegisterDriverRequest{
 			DriverID:    userID,
 			PackageSlug: packageSlug,
@@ -232,59 +351,39 @@ func handleDriversWebSocket(
 	}()
 
 	for {
-		_, message, err := conn.ReadMessage()
+		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			log.Printf("Driver WS read error: %v", err)
 			break
 		}
 
-		var msg wsIncomingMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("Driver message unmarshal error: %v", err)
+		msg, wsErr := decodeInboundFrame(roleDriver, messageType, message)
+		if wsErr != nil {
+			if rejecter.reject(ctx, msg, *wsErr) {
+				return
+			}
+			continue
+		}
+		if ok, wsErr := limits.admit(msg, deliverHeld); !ok {
+			if wsErr != nil && rejecter.reject(ctx, msg, *wsErr) {
+				return
+			}
 			continue
 		}
 
 		switch msg.Type {
 		case contracts.DriverCmdLocation:
-			var locMsg struct {
-				Location struct {
-					Latitude  float64 `json:"latitude"`
-					Longitude float64 `json:"longitude"`
-				} `json:"location"`
-			}
-			if err := json.Unmarshal(msg.Data, &locMsg); err != nil {
-				log.Printf("Driver location parse error: %v", err)
-				continue
-			}
-			locPayload, _ := json.Marshal(messaging.DriverLocationUpdateData{
-				PackageSlug: packageSlug,
-				Latitude:    locMsg.Location.Latitude,
-				Longitude:   locMsg.Location.Longitude,
-			})
-			if err := rb.PublishMessage(ctx, contracts.DriverCmdLocation, contracts.AmqpMessage{
-				OwnerID: userID,
-				Data:    locPayload,
-			}); err != nil {
-				log.Printf("Error publishing driver location: %v", err)
-			}
+			publishDriverLocation(ctx, rb, userID, packageSlug, msg.Data)
 
 		case contracts.DriverCmdTripAccept:
-			var frontendData struct {
-				TripID  string `json:"tripID"`
-				RiderID string `json:"riderID"`
-			}
+			var frontendData contracts.WSDriverTripResponseData
 			if err := json.Unmarshal(msg.Data, &frontendData); err != nil {
 				log.Printf("Driver trip accept parse error: %v", err)
 				continue
 			}
-			if frontendData.TripID != "" && frontendData.RiderID != "" {
-				// Register trip chat pair so chat is authorised from this moment.
-				if err := connManager.SetTripChatPair(frontendData.TripID, frontendData.RiderID, userID, 2*time.Hour); err != nil {
-					log.Printf("Error setting trip chat pair: %v", err)
-				}
-				// Driver auto-joins the trip chat room.
-				connManager.JoinRoom(socketID, tripChatRoomID(frontendData.TripID))
-			}
+			// The trip and rider IDs come from the client; the driver only
+			// joins the trip chat once trip-service has confirmed the accept.
+			go joinTripChatOnceAssigned(ctx, connManager, socketID, userID, frontendData.TripID)
 			enrichedData, _ := json.Marshal(messaging.DriverTripResponseData{
 				TripID:      frontendData.TripID,
 				RiderID:     frontendData.RiderID,
@@ -300,10 +399,7 @@ func handleDriversWebSocket(
 			}
 
 		case contracts.DriverCmdTripDecline:
-			var frontendData struct {
-				TripID  string `json:"tripID"`
-				RiderID string `json:"riderID"`
-			}
+			var frontendData contracts.WSDriverTripResponseData
 			if err := json.Unmarshal(msg.Data, &frontendData); err != nil {
 				log.Printf("Driver trip decline parse error: %v", err)
 				continue
@@ -323,7 +419,12 @@ func handleDriversWebSocket(
 			}
 
 		case WSChatMessageSend:
-			if err := relayTripChatMessage(ctx, connManager, rb, socketID, userID, msg.Data); err != nil {
+			if err := relayTripChatMessage(ctx, connManager, rb, moderator, socketID, userID, msg.Data); err != nil {
+				log.Printf("Driver chat relay error: %v", err)
+			}
+
+		case WSChatMessageRead, WSChatTyping:
+			if err := relayTripChatFrame(ctx, connManager, rb, userID, msg); err != nil {
 				log.Printf("Driver chat relay error: %v", err)
 			}
 
@@ -333,7 +434,9 @@ func handleDriversWebSocket(
 				log.Printf("Driver room join parse error: %v", err)
 				continue
 			}
-			connManager.JoinRoom(socketID, ctrl.RoomID)
+			if wsErr := joinAuthorizedRoom(ctx, connManager, auth, socketID, userID, ctrl.RoomID); wsErr != nil && rejecter.reject(ctx, msg, *wsErr) {
+				return
+			}
 
 		case contracts.WSRoomLeave:
 			var ctrl contracts.WSRoomControlData
@@ -350,7 +453,9 @@ func handleDriversWebSocket(
 				log.Printf("Driver subscribe parse error: %v", err)
 				continue
 			}
-			connManager.JoinRoom(socketID, ctrl.Topic)
+			if wsErr := joinAuthorizedRoom(ctx, connManager, auth, socketID, userID, ctrl.Topic); wsErr != nil && rejecter.reject(ctx, msg, *wsErr) {
+				return
+			}
 
 		case contracts.WSTopicUnsubscribe:
 			var ctrl contracts.WSTopicControlData
@@ -365,3 +470,29 @@ func handleDriversWebSocket(
 		}
 	}
 }
+
+// publishDriverLocation forwards a driver.cmd.location frame to RabbitMQ.
+func publishDriverLocation(ctx context.Context, rb messaging.Publisher, userID, packageSlug string, data json.RawMessage) {
+	var locMsg contracts.WSDriverLocationData
+	if err := json.Unmarshal(data, &locMsg); err != nil {
+		log.Printf("Driver location parse error: %v", err)
+		return
+	}
+	timestamp := locMsg.Timestamp
+	if timestamp == 0 {
+		timestamp = time.Now().UnixMilli()
+	}
+	locPayload, _ := json.Marshal(messaging.DriverLocationUpdateData{
+		PackageSlug: packageSlug,
+		Latitude:    locMsg.Location.Latitude,
+		Longitude:   locMsg.Location.Longitude,
+		Timestamp:   timestamp,
+		Accuracy:    locMsg.Accuracy,
+	})
+	if err := rb.PublishMessage(ctx, contracts.DriverCmdLocation, contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    locPayload,
+	}); err != nil {
+		log.Printf("Error publishing driver location: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// wsNodesKey is a sorted set of node IDs scored by the unix ms at which
+	// their registration expires.
+	wsNodesKey = "ws:nodes"
+
+	wsNodeTTL               = 30 * time.Second
+	wsNodeHeartbeatInterval = 10 * time.Second
+)
+
+func wsNodeKey(nodeID string) string {
+	return "ws:node:" + nodeID
+}
+
+type nodeState string
+
+const (
+	nodeServing  nodeState = "serving"
+	nodeDraining nodeState = "draining"
+)
+
+// wsNode is a gateway node as registered in Redis.
+type wsNode struct {
+	ID        string
+	Addr      string
+	State     nodeState
+	Sockets   int
+	StartedAt time.Time
+}
+
+// defaultNodeID is the host name (the pod name on Kubernetes) plus a random
+// suffix, so restarted processes never share an ID.
+func defaultNodeID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "ws-gateway"
+	}
+	return host + "-" + uuid.NewString()[:8]
+}
+
+// nodeRegistry keeps this node's entry in Redis: its address, state and socket
+// count, refreshed every wsNodeHeartbeatInterval. Entries of nodes that stop
+// refreshing expire after wsNodeTTL.
+type nodeRegistry struct {
+	rdb     *redis.Client
+	node    wsNode
+	sockets func() int
+	mu      sync.Mutex
+}
+
+func newNodeRegistry(rdb *redis.Client, nodeID, addr string, sockets func() int) *nodeRegistry {
+	return &nodeRegistry{
+		rdb:     rdb,
+		node:    wsNode{ID: nodeID, Addr: addr, State: nodeServing, StartedAt: time.Now()},
+		sockets: sockets,
+	}
+}
+
+// Run registers the node and refreshes its entry until ctx is cancelled.
+func (n *nodeRegistry) Run(ctx context.Context) {
+	if err := n.refresh(ctx); err != nil {
+		log.Printf("Failed to register ws node %s: %v", n.node.ID, err)
+	}
+	ticker := time.NewTicker(wsNodeHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := n.refresh(ctx); err != nil {
+				log.Printf("Failed to refresh ws node %s: %v", n.node.ID, err)
+			}
+		}
+	}
+}
+
+// SetState records a new state for the node right away.
+func (n *nodeRegistry) SetState(ctx context.Context, state nodeState) error {
+	n.mu.Lock()
+	n.node.State = state
+	n.mu.Unlock()
+	return n.refresh(ctx)
+}
+
+func (n *nodeRegistry) refresh(ctx context.Context) error {
+	n.mu.Lock()
+	node := n.node
+	n.mu.Unlock()
+
+	now := time.Now()
+	pipe := n.rdb.TxPipeline()
+	pipe.HSet(ctx, wsNodeKey(node.ID), map[string]any{
+		"addr":      node.Addr,
+		"state":     string(node.State),
+		"sockets":   n.sockets(),
+		"startedAt": node.StartedAt.UnixMilli(),
+	})
+	pipe.Expire(ctx, wsNodeKey(node.ID), wsNodeTTL)
+	pipe.ZAdd(ctx, wsNodesKey, redis.Z{Score: float64(now.Add(wsNodeTTL).UnixMilli()), Member: node.ID})
+	pipe.ZRemRangeByScore(ctx, wsNodesKey, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Deregister removes the node's entry.
+func (n *nodeRegistry) Deregister(ctx context.Context) error {
+	pipe := n.rdb.TxPipeline()
+	pipe.Del(ctx, wsNodeKey(n.node.ID))
+	pipe.ZRem(ctx, wsNodesKey, n.node.ID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// listNodes returns every registered node whose entry has not expired.
+func listNodes(ctx context.Context, rdb *redis.Client) ([]wsNode, error) {
+	ids, err := rdb.ZRangeByScore(ctx, wsNodesKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ws nodes: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, wsNodeKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read ws nodes: %w", err)
+	}
+
+	nodes := make([]wsNode, 0, len(ids))
+	for i, id := range ids {
+		fields := cmds[i].Val()
+		if len(fields) == 0 {
+			continue
+		}
+		sockets, _ := strconv.Atoi(fields["sockets"])
+		startedAt, _ := strconv.ParseInt(fields["startedAt"], 10, 64)
+		nodes = append(nodes, wsNode{
+			ID:        id,
+			Addr:      fields["addr"],
+			State:     nodeState(fields["state"]),
+			Sockets:   sockets,
+			StartedAt: time.UnixMilli(startedAt),
+		})
+	}
+	return nodes, nil
+}
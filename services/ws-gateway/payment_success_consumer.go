@@ -12,25 +12,23 @@ import (
 )
 
 type paymentSuccessConsumer struct {
-	rabbitmq    *messaging.RabbitMQ
+	broker      messaging.Subscriber
 	connManager *messaging.RedisConnectionManager
 }
 
-func newPaymentSuccessConsumer(rabbitmq *messaging.RabbitMQ, connManager *messaging.RedisConnectionManager) *paymentSuccessConsumer {
-	return &paymentSuccessConsumer{rabbitmq: rabbitmq, connManager: connManager}
+func newPaymentSuccessConsumer(broker messaging.Subscriber, connManager *messaging.RedisConnectionManager) *paymentSuccessConsumer {
+	return &paymentSuccessConsumer{broker: broker, connManager: connManager}
 }
 
 func (c *paymentSuccessConsumer) Start() error {
-	return c.rabbitmq.ConsumeMessages(messaging.NotifyTripCompletedQueue, func(ctx context.Context, msg amqp091.Delivery) error {
-		var envelope contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+	return c.broker.ConsumeMessages(messaging.NotifyTripCompletedQueue, func(ctx context.Context, msg amqp091.Delivery) error {
+		envelope, err := messaging.DecodeMessage(msg)
+		if err != nil {
 			log.Printf("paymentSuccessConsumer: failed to unmarshal envelope: %v", err)
 			return err
 		}
 
-		var payload struct {
-			TripID string `json:"tripID"`
-		}
+		var payload messaging.TripCompletedData
 		if err := json.Unmarshal(envelope.Data, &payload); err != nil {
 			log.Printf("paymentSuccessConsumer: failed to unmarshal payload: %v", err)
 			return err
@@ -53,9 +51,7 @@ func (c *paymentSuccessConsumer) Start() error {
 		wsMsg := contracts.WSMessage{
 			Type:  contracts.TripEventCompleted,
 			Topic: tripRoomID,
-			Data: map[string]string{
-				"tripID": payload.TripID,
-			},
+			Data:  contracts.WSTripRefData{TripID: payload.TripID},
 		}
 
 		if err := c.connManager.SendMessage(envelope.OwnerID, wsMsg); err != nil {
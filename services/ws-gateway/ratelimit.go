@@ -90,6 +90,13 @@ func (rl *RateLimiter) RefreshWsConnectionGate(ctx context.Context, userID strin
 	}
 }
 
+// RecordWsViolation counts a rejected WebSocket frame of userID and returns
+// the number counted in the current window.
+func (rl *RateLimiter) RecordWsViolation(ctx context.Context, userID string) (int, error) {
+	key := fmt.Sprintf("ws:violations:%s", userID)
+	return luaRateLimit.Run(ctx, rl.rdb, []string{key}, wsViolationWindowSecs).Int()
+}
+
 func userKey(prefix string) func(*http.Request) string {
 	return func(r *http.Request) string {
 		userID, _ := r.Context().Value(ctxKeyUserID).(string)
@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/moderation"
 
 	"github.com/google/uuid"
 )
@@ -21,21 +23,35 @@ func tripChatRoomID(tripID string) string {
 }
 
 // relayTripChatMessage validates the sender's membership in the trip chat,
-// broadcasts the message to the room (low-latency local + Redis cross-node),
-// and then publishes to the chat-service queue for durable persistence.
+// runs the message through the moderator, broadcasts it to the room
+// (low-latency local + Redis cross-node), and then publishes to the
+// chat-service queue for durable persistence. A message the moderator blocks
+// is not broadcast: the sender gets a message_rejected ws.error and
+// chat-service stores it, flagged, for support.
 func relayTripChatMessage(
 	ctx context.Context,
 	connManager *messaging.RedisConnectionManager,
-	rb *messaging.RabbitMQ,
+	rb messaging.Publisher,
+	moderator *moderation.Pipeline,
 	socketID, senderID string,
 	rawData json.RawMessage,
 ) error {
-	var payload tripChatMessageData
+	var payload contracts.WSChatMessageSendData
 	if err := json.Unmarshal(rawData, &payload); err != nil {
 		return err
 	}
-	if payload.TripID == "" || payload.Text == "" {
-		return nil
+	if err := payload.Validate(); err != nil {
+		return err
+	}
+	if payload.Text == "" && payload.AttachmentID == "" {
+		return fmt.Errorf("chat message needs text or an attachment")
+	}
+	if payload.AttachmentID != "" {
+		// Attachments are stored under chat/<tripID>/<attachmentID>; only IDs
+		// minted by the upload endpoint are accepted.
+		if _, err := uuid.Parse(payload.AttachmentID); err != nil {
+			return fmt.Errorf("invalid attachmentID %q", payload.AttachmentID)
+		}
 	}
 
 	// Ensure sender is an authorised trip participant before broadcasting.
@@ -50,31 +66,41 @@ func relayTripChatMessage(
 	sentAt := time.Now().Unix()
 	roomID := tripChatRoomID(payload.TripID)
 
-	wsMsg := contracts.WSMessage{
-		Type:   WSChatMessageReceived,
-		RoomID: roomID,
-		Data: tripChatMessageReceivedData{
-			TripID:    payload.TripID,
-			RoomID:    roomID,
-			SenderID:  senderID,
+	verdict := moderator.Check(ctx, moderation.Message{
+		TripID:   payload.TripID,
+		SenderID: senderID,
+		Text:     payload.Text,
+	})
+	if verdict.Blocked {
+		log.Printf("Chat message %s of trip %s blocked: %v", msgID, payload.TripID, verdict.Flags)
+		sendWSError(connManager, socketID, contracts.WSErrorData{
+			Code:      wsErrMessageRejected,
+			Message:   verdict.Reason,
 			MessageID: msgID,
-			Text:      payload.Text,
-			SentAt:    sentAt,
-		},
-	}
-
-	// Broadcast to all sockets in the room (sender + peer, local + cross-node).
-	if err := connManager.BroadcastToRoom(roomID, wsMsg); err != nil {
-		log.Printf("BroadcastToRoom %s error: %v", roomID, err)
+			FrameType: WSChatMessageSend,
+		})
+	} else {
+		broadcastTripChatMessage(connManager, contracts.WSChatMessageReceivedData{
+			TripID:       payload.TripID,
+			RoomID:       roomID,
+			SenderID:     senderID,
+			MessageID:    msgID,
+			Text:         verdict.Text,
+			AttachmentID: payload.AttachmentID,
+			SentAt:       sentAt,
+		})
 	}
 
 	// Publish to chat-service for durable storage (fire-and-forget from WS perspective).
 	chatData, _ := json.Marshal(messaging.ChatMessageData{
-		MessageID: msgID,
-		TripID:    payload.TripID,
-		SenderID:  senderID,
-		Text:      payload.Text,
-		SentAt:    sentAt,
+		MessageID:    msgID,
+		TripID:       payload.TripID,
+		SenderID:     senderID,
+		Text:         verdict.Text,
+		AttachmentID: payload.AttachmentID,
+		SentAt:       sentAt,
+		Flags:        verdict.Flags,
+		Blocked:      verdict.Blocked,
 	})
 	if err := rb.PublishMessage(ctx, contracts.ChatCmdSend, contracts.AmqpMessage{
 		OwnerID: senderID,
@@ -85,3 +111,104 @@ func relayTripChatMessage(
 
 	return nil
 }
+
+// broadcastTripChatMessage sends a chat message to all sockets in the trip
+// chat room (sender + peer, local + cross-node).
+func broadcastTripChatMessage(connManager *messaging.RedisConnectionManager, data contracts.WSChatMessageReceivedData) {
+	wsMsg := contracts.WSMessage{
+		Type:   WSChatMessageReceived,
+		RoomID: data.RoomID,
+		Data:   data,
+	}
+	if err := connManager.BroadcastToRoom(data.RoomID, wsMsg); err != nil {
+		log.Printf("BroadcastToRoom %s error: %v", data.RoomID, err)
+	}
+}
+
+// relayTripChatFrame handles the chat frames that are only relayed to the
+// trip chat room and, for read receipts, stored by chat-service.
+func relayTripChatFrame(
+	ctx context.Context,
+	connManager *messaging.RedisConnectionManager,
+	rb messaging.Publisher,
+	userID string,
+	msg wsIncomingMessage,
+) error {
+	switch msg.Type {
+	case WSChatMessageRead:
+		return relayTripChatRead(ctx, connManager, rb, userID, msg.Data)
+	case WSChatTyping:
+		return relayTripChatTyping(connManager, userID, msg.Data)
+	}
+	return nil
+}
+
+// relayTripChatRead validates the reader's membership in the trip chat, tells
+// the room how far they have read and publishes the receipt to chat-service,
+// which keeps the read state of each participant.
+func relayTripChatRead(
+	ctx context.Context,
+	connManager *messaging.RedisConnectionManager,
+	rb messaging.Publisher,
+	readerID string,
+	rawData json.RawMessage,
+) error {
+	var payload contracts.WSChatMessageReadData
+	if err := json.Unmarshal(rawData, &payload); err != nil {
+		return err
+	}
+	if err := payload.Validate(); err != nil {
+		return err
+	}
+	if _, err := connManager.ResolveTripChatPeer(payload.TripID, readerID); err != nil {
+		return err
+	}
+
+	payload.ReaderID = readerID
+	payload.ReadAt = time.Now().Unix()
+	roomID := tripChatRoomID(payload.TripID)
+	if err := connManager.BroadcastToRoom(roomID, contracts.WSMessage{
+		Type:   WSChatMessageRead,
+		RoomID: roomID,
+		Data:   payload,
+	}); err != nil {
+		log.Printf("BroadcastToRoom %s error: %v", roomID, err)
+	}
+
+	readData, _ := json.Marshal(messaging.ChatReadData{
+		TripID:    payload.TripID,
+		ReaderID:  readerID,
+		MessageID: payload.MessageID,
+		ReadAt:    payload.ReadAt,
+	})
+	if err := rb.PublishMessage(ctx, contracts.ChatCmdRead, contracts.AmqpMessage{
+		OwnerID: readerID,
+		Data:    readData,
+	}); err != nil {
+		log.Printf("Failed to publish chat read receipt to persistence queue: %v", err)
+	}
+	return nil
+}
+
+// relayTripChatTyping tells the trip chat room that a participant started or
+// stopped typing. Typing frames are not stored or replayed.
+func relayTripChatTyping(connManager *messaging.RedisConnectionManager, userID string, rawData json.RawMessage) error {
+	var payload contracts.WSChatTypingData
+	if err := json.Unmarshal(rawData, &payload); err != nil {
+		return err
+	}
+	if err := payload.Validate(); err != nil {
+		return err
+	}
+	if _, err := connManager.ResolveTripChatPeer(payload.TripID, userID); err != nil {
+		return err
+	}
+
+	payload.UserID = userID
+	roomID := tripChatRoomID(payload.TripID)
+	return connManager.BroadcastToRoom(roomID, contracts.WSMessage{
+		Type:   WSChatTyping,
+		RoomID: roomID,
+		Data:   payload,
+	})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/trip"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A driver's socket is joined to the trip chat once trip-service confirmed
+// their accept. The confirmation is polled for every tripAssignPollInterval,
+// for at most tripAssignWait.
+const (
+	tripAssignWait         = 30 * time.Second
+	tripAssignPollInterval = 500 * time.Millisecond
+)
+
+// Codes sent in ws.error frames for rejected room joins.
+const (
+	wsErrRoomInvalid     = "room_invalid"
+	wsErrRoomForbidden   = "room_forbidden"
+	wsErrRoomUnavailable = "room_unavailable"
+)
+
+var (
+	errRoomInvalid   = errors.New("invalid room ID")
+	errRoomForbidden = errors.New("user is not a participant of the room's trip")
+)
+
+// roomKind is the resource a room ID refers to.
+type roomKind string
+
+const (
+	roomKindTrip     roomKind = "trip"      // trip:{tripID}
+	roomKindTripChat roomKind = "trip_chat" // trip:{tripID}:chat
+)
+
+type roomResource struct {
+	Kind   roomKind
+	TripID string
+}
+
+// parseRoomID parses the room IDs clients may join. Anything else is rejected.
+func parseRoomID(roomID string) (roomResource, error) {
+	parts := strings.Split(roomID, ":")
+	if len(parts) < 2 || parts[0] != "trip" || parts[1] == "" {
+		return roomResource{}, fmt.Errorf("%w: %q", errRoomInvalid, roomID)
+	}
+	switch {
+	case len(parts) == 2:
+		return roomResource{Kind: roomKindTrip, TripID: parts[1]}, nil
+	case len(parts) == 3 && parts[2] == "chat":
+		return roomResource{Kind: roomKindTripChat, TripID: parts[1]}, nil
+	}
+	return roomResource{}, fmt.Errorf("%w: %q", errRoomInvalid, roomID)
+}
+
+// roomAuthorizer decides whether a user may join a room. Trip and trip chat
+// rooms are open to the trip's rider and driver only. The trip chat pair keys
+// answer once a driver accepted; before that, trip-service is asked.
+type roomAuthorizer struct {
+	pairs interface {
+		TripChatPair(tripID string) (riderID, driverID string, err error)
+	}
+	trips pb.TripServiceClient
+}
+
+func newRoomAuthorizer(connManager *messaging.RedisConnectionManager, trips pb.TripServiceClient) *roomAuthorizer {
+	return &roomAuthorizer{pairs: connManager, trips: trips}
+}
+
+// Authorize returns nil if userID may join roomID, errRoomInvalid or
+// errRoomForbidden if it may not, and any other error if participation could
+// not be checked. Joins are denied in every error case.
+func (a *roomAuthorizer) Authorize(ctx context.Context, userID, roomID string) error {
+	room, err := parseRoomID(roomID)
+	if err != nil {
+		return err
+	}
+
+	riderID, driverID, err := a.pairs.TripChatPair(room.TripID)
+	switch {
+	case err == nil:
+		if userID == riderID || userID == driverID {
+			return nil
+		}
+		return errRoomForbidden
+	case !errors.Is(err, messaging.ErrTripChatPairNotFound):
+		return fmt.Errorf("failed to read trip chat pair: %w", err)
+	}
+
+	resp, err := a.trips.GetTrip(ctx, &pb.GetTripRequest{TripID: room.TripID})
+	if status.Code(err) == codes.NotFound {
+		return errRoomForbidden
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get trip: %w", err)
+	}
+	trip := resp.GetTrip()
+	if trip.GetUserID() == userID || (trip.GetDriver() != nil && trip.GetDriver().GetId() == userID) {
+		return nil
+	}
+	return errRoomForbidden
+}
+
+// joinAuthorizedRoom joins socketID to roomID if the user may join it, and
+// otherwise logs the denied attempt and returns the ws.error to reply with.
+func joinAuthorizedRoom(ctx context.Context, connManager *messaging.RedisConnectionManager, auth *roomAuthorizer, socketID, userID, roomID string) *contracts.WSErrorData {
+	err := auth.Authorize(ctx, userID, roomID)
+	if err == nil {
+		connManager.JoinRoom(socketID, roomID)
+		return nil
+	}
+
+	code, message := wsErrRoomUnavailable, "room access could not be checked, try again"
+	switch {
+	case errors.Is(err, errRoomInvalid):
+		code, message = wsErrRoomInvalid, "unknown room"
+	case errors.Is(err, errRoomForbidden):
+		code, message = wsErrRoomForbidden, "not a participant of this trip"
+	}
+	log.Printf("security: room join denied user=%s socket=%s room=%q code=%s: %v", userID, socketID, roomID, code, err)
+	return &contracts.WSErrorData{
+		Code:    code,
+		Message: message,
+		RoomID:  roomID,
+	}
+}
+
+// joinTripChatOnceAssigned joins a driver's socket to the chat room of a trip
+// they accepted, once trip-service has assigned them. driver-service records
+// the assignment as the trip chat pair, so the pair is polled until it names
+// the driver, names someone else, or tripAssignWait passes. ctx is cancelled
+// before the socket is removed.
+func joinTripChatOnceAssigned(ctx context.Context, connManager *messaging.RedisConnectionManager, socketID, driverID, tripID string) {
+	ticker := time.NewTicker(tripAssignPollInterval)
+	defer ticker.Stop()
+	timeout := time.NewTimer(tripAssignWait)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout.C:
+			log.Printf("Trip %s was not assigned to driver %s within %s, not joining its chat", tripID, driverID, tripAssignWait)
+			return
+		case <-ticker.C:
+		}
+
+		_, assigned, err := connManager.TripChatPair(tripID)
+		if errors.Is(err, messaging.ErrTripChatPairNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to read trip chat pair of trip %s: %v", tripID, err)
+			continue
+		}
+		if assigned != driverID {
+			log.Printf("security: trip %s was assigned to %s, not joining driver %s to its chat", tripID, assigned, driverID)
+			return
+		}
+		roomID := tripChatRoomID(tripID)
+		connManager.JoinRoom(socketID, roomID)
+		if ctx.Err() != nil {
+			// The socket closed while joining.
+			connManager.LeaveRoom(socketID, roomID)
+		}
+		return
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/trip"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeChatPairs map[string][2]string // tripID → rider, driver
+
+func (f fakeChatPairs) TripChatPair(tripID string) (string, string, error) {
+	pair, ok := f[tripID]
+	if !ok {
+		return "", "", messaging.ErrTripChatPairNotFound
+	}
+	return pair[0], pair[1], nil
+}
+
+// fakeTrips answers GetTrip from trips; the other methods are not used.
+type fakeTrips struct {
+	pb.TripServiceClient
+	trips map[string]*pb.Trip
+	err   error
+	calls int
+}
+
+func (f *fakeTrips) GetTrip(_ context.Context, req *pb.GetTripRequest, _ ...grpc.CallOption) (*pb.GetTripResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	trip, ok := f.trips[req.GetTripID()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "trip not found")
+	}
+	return &pb.GetTripResponse{Trip: trip}, nil
+}
+
+func TestParseRoomID(t *testing.T) {
+	tests := []struct {
+		roomID string
+		want   roomResource
+		valid  bool
+	}{
+		{"trip:t1", roomResource{Kind: roomKindTrip, TripID: "t1"}, true},
+		{"trip:t1:chat", roomResource{Kind: roomKindTripChat, TripID: "t1"}, true},
+		{"trip:", roomResource{}, false},
+		{"trip", roomResource{}, false},
+		{"trip::chat", roomResource{}, false},
+		{"trip:x:y", roomResource{}, false},
+		{"trip:t1:chat:x", roomResource{}, false},
+		{"user:x", roomResource{}, false},
+		{"", roomResource{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseRoomID(tt.roomID)
+		if tt.valid && (err != nil || got != tt.want) {
+			t.Errorf("parseRoomID(%q) = %+v, %v; want %+v", tt.roomID, got, err, tt.want)
+		}
+		if !tt.valid && !errors.Is(err, errRoomInvalid) {
+			t.Errorf("parseRoomID(%q) = %+v, %v; want errRoomInvalid", tt.roomID, got, err)
+		}
+	}
+}
+
+func TestRoomAuthorizer(t *testing.T) {
+	trips := &fakeTrips{trips: map[string]*pb.Trip{
+		"requested": {Id: "requested", UserID: "rider-2"},
+		"assigned":  {Id: "assigned", UserID: "rider-3", Driver: &pb.TripDriver{Id: "driver-3"}},
+	}}
+	auth := &roomAuthorizer{
+		pairs: fakeChatPairs{"accepted": {"rider-1", "driver-1"}},
+		trips: trips,
+	}
+
+	tests := []struct {
+		userID, roomID string
+		want           error
+	}{
+		{"rider-1", "trip:accepted", nil},
+		{"driver-1", "trip:accepted:chat", nil},
+		{"driver-2", "trip:accepted:chat", errRoomForbidden},
+		{"rider-2", "trip:requested", nil},
+		{"driver-1", "trip:requested", errRoomForbidden},
+		{"driver-3", "trip:assigned:chat", nil},
+		{"rider-1", "trip:unknown", errRoomForbidden},
+		{"rider-1", "user:rider-1", errRoomInvalid},
+	}
+	for _, tt := range tests {
+		if err := auth.Authorize(context.Background(), tt.userID, tt.roomID); !errors.Is(err, tt.want) {
+			t.Errorf("Authorize(%s, %s) = %v, want %v", tt.userID, tt.roomID, err, tt.want)
+		}
+	}
+
+	// The chat pair answers for accepted trips without asking trip-service.
+	trips.calls = 0
+	_ = auth.Authorize(context.Background(), "rider-1", "trip:accepted")
+	if trips.calls != 0 {
+		t.Errorf("GetTrip called %d times for a trip with a chat pair", trips.calls)
+	}
+
+	trips.err = status.Error(codes.Unavailable, "down")
+	err := auth.Authorize(context.Background(), "rider-2", "trip:requested")
+	if err == nil || errors.Is(err, errRoomForbidden) || errors.Is(err, errRoomInvalid) {
+		t.Errorf("Authorize with trip-service down = %v, want an unavailable error", err)
+	}
+}
+
+func TestJoinAuthorizedRoomErrors(t *testing.T) {
+	trips := &fakeTrips{trips: map[string]*pb.Trip{}}
+	auth := &roomAuthorizer{pairs: fakeChatPairs{"t1": {"rider-1", "driver-1"}}, trips: trips}
+
+	tests := []struct {
+		userID, roomID string
+		code           string
+		setErr         error
+	}{
+		{"rider-1", "lobby", wsErrRoomInvalid, nil},
+		{"rider-2", "trip:t1:chat", wsErrRoomForbidden, nil},
+		{"rider-2", "trip:t2", wsErrRoomUnavailable, status.Error(codes.Unavailable, "down")},
+	}
+	for _, tt := range tests {
+		trips.err = tt.setErr
+		// A denied join never touches the connection manager.
+		wsErr := joinAuthorizedRoom(context.Background(), nil, auth, "socket-1", tt.userID, tt.roomID)
+		if wsErr == nil || wsErr.Code != tt.code || wsErr.RoomID != tt.roomID {
+			t.Errorf("joinAuthorizedRoom(%s, %s) = %+v, want code %s", tt.userID, tt.roomID, wsErr, tt.code)
+		}
+	}
+}
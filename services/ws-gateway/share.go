@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/tripshare"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// shareViewerLimit is how many viewers may follow one share link at once.
+	shareViewerLimit = 10
+	// shareRecheckInterval is how often an open share stream checks that its
+	// link was not revoked.
+	shareRecheckInterval = 15 * time.Second
+
+	tripRoomPrefix = "trip:"
+)
+
+// shareConn relays to a share viewer only the rider's messages about the
+// shared trip: driver locations and ETAs and the trip's status events. Status events
+// carry just the trip ID, so the viewer learns nothing else about the rider.
+// Locations without a trip ID are dropped, as they may belong to another trip.
+// The stream ends after the trip's completed or cancelled event.
+type shareConn struct {
+	*sseConn
+	tripID string
+}
+
+func (c *shareConn) WriteJSON(v any) error {
+	msg, ok := v.(contracts.WSMessage)
+	if !ok {
+		return nil
+	}
+	switch {
+	case msg.Type == contracts.WSReconnect:
+		return c.sseConn.WriteJSON(msg)
+	case msg.Type == contracts.DriverEventLocation && messageTripID(msg) == c.tripID,
+		msg.Type == contracts.DriverEventETAChanged && messageTripID(msg) == c.tripID:
+		return c.sseConn.WriteJSON(msg)
+	case strings.HasPrefix(msg.Type, "trip.event.") && messageTripID(msg) == c.tripID:
+		if err := c.sseConn.WriteJSON(contracts.WSMessage{
+			ID:    msg.ID,
+			Type:  msg.Type,
+			Topic: tripRoomPrefix + c.tripID,
+			Data:  contracts.WSTripRefData{TripID: c.tripID},
+		}); err != nil {
+			return err
+		}
+		if msg.Type == contracts.TripEventCompleted || msg.Type == contracts.TripEventCancelled {
+			return c.Close()
+		}
+	}
+	return nil
+}
+
+// messageTripID returns the trip a message is about: its topic, or the trip ID
+// in its data.
+func messageTripID(msg contracts.WSMessage) string {
+	if tripID, ok := strings.CutPrefix(msg.Topic, tripRoomPrefix); ok {
+		return tripID
+	}
+	raw, ok := msg.Data.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(msg.Data); err != nil {
+			return ""
+		}
+	}
+	var ref struct {
+		ID     string `json:"id"`
+		TripID string `json:"tripID"`
+	}
+	if err := json.Unmarshal(raw, &ref); err != nil {
+		return ""
+	}
+	if ref.TripID != "" {
+		return ref.TripID
+	}
+	return ref.ID
+}
+
+// handleShareStream streams a shared trip to a viewer with no account, as
+// Server-Sent Events. The path's token is minted by trip-service's ShareTrip.
+// The stream is registered as a viewer of the trip's rider, owned by the share
+// rather than the rider, and filtered by shareConn, so it resumes with
+// Last-Event-ID and is drained like any other stream. It ends when the trip ends or the link is revoked or expires.
+func handleShareStream(
+	w http.ResponseWriter,
+	r *http.Request,
+	connManager *messaging.RedisConnectionManager,
+	shares *tripshare.Store,
+	trips pb.TripServiceClient,
+	rl *RateLimiter,
+	drain *gatewayDrain,
+) {
+	if drain.Draining() {
+		http.Error(w, "ws-gateway is draining", http.StatusServiceUnavailable)
+		return
+	}
+
+	share, err := shares.Verify(r.Context(), r.PathValue("token"))
+	switch {
+	case errors.Is(err, tripshare.ErrInvalidToken):
+		http.Error(w, "share link not found", http.StatusNotFound)
+		return
+	case errors.Is(err, tripshare.ErrExpired), errors.Is(err, tripshare.ErrRevoked):
+		http.Error(w, "share link is no longer active", http.StatusGone)
+		return
+	case err != nil:
+		log.Printf("Share link check failed: %v", err)
+		http.Error(w, "share link cannot be checked", http.StatusServiceUnavailable)
+		return
+	}
+	// trip-service revokes links when the trip ends; this covers a revocation
+	// that failed.
+	resp, err := trips.GetTrip(r.Context(), &pb.GetTripRequest{TripID: share.TripID})
+	if err != nil {
+		log.Printf("Share %s: failed to get trip %s: %v", share.ID, share.TripID, err)
+		http.Error(w, "trip cannot be loaded", http.StatusServiceUnavailable)
+		return
+	}
+	if s := resp.GetTrip().GetStatus(); s == "completed" || s == "cancelled" {
+		http.Error(w, "share link is no longer active", http.StatusGone)
+		return
+	}
+
+	gateKey := "share:" + share.ID
+	allowed, release := rl.WsConnectionGate(r.Context(), gateKey, shareViewerLimit)
+	if !allowed {
+		http.Error(w, "too many viewers", http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+	stopHeartbeat := startWsGateHeartbeat(gateKey, rl)
+	defer stopHeartbeat()
+
+	sse, err := openSSE(w)
+	if err != nil {
+		log.Printf("Share stream %s cannot be flushed: %v", share.ID, err)
+		return
+	}
+	conn := &shareConn{sseConn: sse, tripID: share.TripID}
+
+	socketID := uuid.New().String()
+	connManager.AddViewer(share.RiderID, gateKey, socketID, conn, eventCursor(r))
+	defer connManager.Remove(socketID)
+	defer conn.Close()
+	log.Printf("Share %s of trip %s: viewer connected", share.ID, share.TripID)
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	recheck := time.NewTicker(shareRecheckInterval)
+	defer recheck.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-conn.done:
+			return
+		case <-keepAlive.C:
+			if err := conn.comment("keep-alive"); err != nil {
+				return
+			}
+		case <-recheck.C:
+			active, err := shares.Active(r.Context(), share)
+			if err != nil {
+				log.Printf("Share %s: %v", share.ID, err)
+				continue
+			}
+			if !active {
+				log.Printf("Share %s of trip %s revoked or expired: ending stream", share.ID, share.TripID)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+)
+
+func TestShareConnFiltersByTrip(t *testing.T) {
+	rec := httptest.NewRecorder()
+	conn := &shareConn{sseConn: newSSEConn(rec), tripID: "t1"}
+
+	messages := []contracts.WSMessage{
+		{ID: "1", Type: contracts.DriverEventLocation, Data: messaging.DriverLocationEventData{TripID: "t1", Latitude: 1}},
+		{ID: "2", Type: contracts.DriverEventLocation, Data: messaging.DriverLocationEventData{TripID: "t2", Latitude: 2}},
+		{ID: "3", Type: contracts.DriverEventLocation, Data: json.RawMessage(`{"latitude":3,"longitude":0}`)},
+		{ID: "4", Type: contracts.DriverEventETAChanged, Data: messaging.DriverETAChangedData{TripID: "t2"}},
+		{ID: "5", Type: contracts.TripEventCompleted, Topic: "trip:t2"},
+		{ID: "6", Type: contracts.DriverEventETAChanged, Data: messaging.DriverETAChangedData{TripID: "t1"}},
+	}
+	for _, msg := range messages {
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("WriteJSON(%s): %v", msg.ID, err)
+		}
+	}
+
+	var ids []string
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	if strings.Join(ids, ",") != "1,6" {
+		t.Errorf("viewer got events %v, want 1 and 6 of trip t1", ids)
+	}
+	select {
+	case <-conn.done:
+		t.Error("another trip's completion ended the stream")
+	default:
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// sseRetry is the reconnect delay EventSource clients use after an error.
+	sseRetry        = 3 * time.Second
+	sseKeepAlive    = 20 * time.Second
+	pollDefaultWait = 25 * time.Second
+	pollMaxWait     = 55 * time.Second
+	pollBatchLinger = 50 * time.Millisecond
+)
+
+// sseConn writes a socket's messages to a Server-Sent Events response. Each
+// event's data is the same JSON envelope a WebSocket client receives, and
+// stream messages carry their ID as the event id, so EventSource resumes with
+// Last-Event-ID on its own.
+type sseConn struct {
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+	mu   sync.Mutex
+	done chan struct{}
+	once sync.Once
+}
+
+func newSSEConn(w http.ResponseWriter) *sseConn {
+	return &sseConn{w: w, rc: http.NewResponseController(w), done: make(chan struct{})}
+}
+
+func (c *sseConn) WriteJSON(v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	msg, _ := v.(contracts.WSMessage)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		return messaging.ErrConnectionNotFound
+	default:
+	}
+	if msg.ID != "" {
+		fmt.Fprintf(c.w, "id: %s\n", msg.ID)
+	}
+	if reconnect, ok := msg.Data.(contracts.WSReconnectData); ok && msg.Type == contracts.WSReconnect {
+		// EventSource reconnects on its own once the stream ends; retry
+		// makes it wait the jittered delay first.
+		fmt.Fprintf(c.w, "retry: %d\n", reconnect.DelayMs)
+	}
+	if _, err := fmt.Fprintf(c.w, "data: %s\n\n", payload); err != nil {
+		return err
+	}
+	if err := c.rc.Flush(); err != nil {
+		return err
+	}
+	if msg.Type == contracts.WSReconnect {
+		c.once.Do(func() { close(c.done) })
+	}
+	return nil
+}
+
+// comment writes an SSE comment line, which keeps proxies from timing out an
+// idle stream.
+func (c *sseConn) comment(text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	return c.rc.Flush()
+}
+
+func (c *sseConn) SetWriteDeadline(t time.Time) error {
+	if err := c.rc.SetWriteDeadline(t); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// Close ends the stream; the handler returns and the response completes. It
+// waits for a write in progress, and later writes fail, so nothing touches the
+// response after the handler returned.
+func (c *sseConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+// pollConn collects a socket's messages for one long-poll request.
+type pollConn struct {
+	mu       sync.Mutex
+	messages []contracts.WSMessage
+	ready    chan struct{} // closed when the first message arrives
+	once     sync.Once
+}
+
+func newPollConn() *pollConn {
+	return &pollConn{ready: make(chan struct{})}
+}
+
+func (c *pollConn) WriteJSON(v any) error {
+	msg, ok := v.(contracts.WSMessage)
+	if !ok {
+		return fmt.Errorf("unexpected long-poll message %T", v)
+	}
+	c.mu.Lock()
+	c.messages = append(c.messages, msg)
+	c.mu.Unlock()
+	c.once.Do(func() { close(c.ready) })
+	return nil
+}
+
+func (c *pollConn) SetWriteDeadline(time.Time) error { return nil }
+
+// Close ends the poll early, e.g. when the node drains.
+func (c *pollConn) Close() error {
+	c.once.Do(func() { close(c.ready) })
+	return nil
+}
+
+func (c *pollConn) take() []contracts.WSMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	messages := c.messages
+	c.messages = nil
+	return messages
+}
+
+// openSSE starts an event stream response with the reconnect delay.
+func openSSE(w http.ResponseWriter) (*sseConn, error) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+
+	conn := newSSEConn(w)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	return conn, conn.rc.Flush()
+}
+
+// eventCursor returns where a client resumes the user event stream: the
+// Last-Event-ID header EventSource sends on reconnect, else ?lastEventID=.
+func eventCursor(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventID")
+}
+
+// handleRidersSSE streams a rider's events as Server-Sent Events, for
+// networks that block WebSocket upgrades. The stream is registered with
+// connManager like a socket, so it receives the same user-direct messages,
+// replays from Last-Event-ID and is drained like a socket. It is receive-only.
+func handleRidersSSE(
+	w http.ResponseWriter,
+	r *http.Request,
+	connManager *messaging.RedisConnectionManager,
+	rl *RateLimiter,
+	drain *gatewayDrain,
+) {
+	if drain.Draining() {
+		http.Error(w, "ws-gateway is draining", http.StatusServiceUnavailable)
+		return
+	}
+	userID, _ := r.Context().Value(ctxKeyUserID).(string)
+
+	allowed, release := rl.WsConnectionGate(r.Context(), userID, 3)
+	if !allowed {
+		log.Printf("SSE connection rejected for rider %s: too many connections", userID)
+		http.Error(w, "too many connections", http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+	stopHeartbeat := startWsGateHeartbeat(userID, rl)
+	defer stopHeartbeat()
+
+	conn, err := openSSE(w)
+	if err != nil {
+		log.Printf("SSE stream for rider %s cannot be flushed: %v", userID, err)
+		return
+	}
+
+	socketID := uuid.New().String()
+	connManager.Add(userID, socketID, conn, eventCursor(r))
+	defer connManager.Remove(socketID)
+	defer conn.Close()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-conn.done:
+			return
+		case <-keepAlive.C:
+			if err := conn.comment("keep-alive"); err != nil {
+				log.Printf("SSE keep-alive for rider %s failed: %v", userID, err)
+				return
+			}
+		}
+	}
+}
+
+// pollResponse is the body of a long-poll response. Clients pass lastEventID
+// back on the next request.
+type pollResponse struct {
+	Messages    []contracts.WSMessage `json:"messages"`
+	LastEventID string                `json:"lastEventID,omitempty"`
+}
+
+// handleRidersPoll is the long-poll variant of handleRidersSSE for clients
+// that cannot use SSE. It registers a socket for the duration of the request
+// and answers as soon as messages arrive, or with none after ?wait= (default
+// 25s). Stream messages after ?lastEventID= are replayed first; live-only
+// messages such as driver locations are only seen while a poll is open.
+func handleRidersPoll(
+	w http.ResponseWriter,
+	r *http.Request,
+	connManager *messaging.RedisConnectionManager,
+	rl *RateLimiter,
+	drain *gatewayDrain,
+) {
+	if drain.Draining() {
+		http.Error(w, "ws-gateway is draining", http.StatusServiceUnavailable)
+		return
+	}
+	userID, _ := r.Context().Value(ctxKeyUserID).(string)
+
+	wait := pollDefaultWait
+	if v := r.URL.Query().Get("wait"); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil || secs < 0 {
+			http.Error(w, "wait must be a number of seconds", http.StatusBadRequest)
+			return
+		}
+		wait = min(time.Duration(secs)*time.Second, pollMaxWait)
+	}
+
+	allowed, release := rl.WsConnectionGate(r.Context(), userID, 3)
+	if !allowed {
+		log.Printf("Long-poll rejected for rider %s: too many connections", userID)
+		http.Error(w, "too many connections", http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+
+	// A client without a cursor is handed the current end of the stream, read
+	// before the socket opens, so its next poll resumes without a gap even if
+	// this one returns no messages.
+	cursor := eventCursor(r)
+	respCursor := cursor
+	if cursor == "" {
+		last, err := connManager.LastStreamID(r.Context(), userID)
+		if err != nil {
+			log.Printf("Long-poll for rider %s: %v", userID, err)
+			http.Error(w, "event stream unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		respCursor = last
+	}
+	conn := newPollConn()
+	socketID := uuid.New().String()
+	connManager.Add(userID, socketID, conn, cursor)
+	messages := collectPoll(r.Context(), conn, wait)
+	connManager.Remove(socketID)
+	// Messages written before the socket was removed belong to this poll;
+	// anything still queued is replayed on the next one.
+	messages = append(messages, conn.take()...)
+
+	resp := pollResponse{Messages: messages, LastEventID: respCursor}
+	if resp.Messages == nil {
+		resp.Messages = []contracts.WSMessage{}
+	}
+	for _, msg := range messages {
+		if msg.ID != "" {
+			resp.LastEventID = msg.ID
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Long-poll response for rider %s failed: %v", userID, err)
+	}
+}
+
+// collectPoll waits up to wait for the first message, then briefly for more
+// so a replay or burst is returned as one batch.
+func collectPoll(ctx context.Context, conn *pollConn, wait time.Duration) []contracts.WSMessage {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-timer.C:
+		return conn.take()
+	case <-conn.ready:
+	}
+
+	linger := time.NewTimer(pollBatchLinger)
+	defer linger.Stop()
+	select {
+	case <-ctx.Done():
+	case <-linger.C:
+	}
+	return conn.take()
+}
@@ -7,27 +7,14 @@ const (
 	WSChatMessageSend     = "chat.message.send"
 	WSChatMessageReceived = "chat.message.received"
 	WSChatMessageAck      = "chat.message.ack" // delivery receipt sent to sender
+	WSChatMessageRead     = "chat.message.read"
+	WSChatTyping          = "chat.typing"
 )
 
 // wsIncomingMessage is the top-level envelope for every client → server frame.
+// ID is optional and client-generated; it is echoed in ws.error replies.
 type wsIncomingMessage struct {
+	ID   string          `json:"id,omitempty"`
 	Type string          `json:"type"`
 	Data json.RawMessage `json:"data"`
 }
-
-// tripChatMessageData is the payload the client sends with chat.message.send.
-type tripChatMessageData struct {
-	TripID    string `json:"tripID"`
-	MessageID string `json:"messageID,omitempty"` // client-generated idempotency key
-	Text      string `json:"text"`
-}
-
-// tripChatMessageReceivedData is the payload broadcast to room members.
-type tripChatMessageReceivedData struct {
-	TripID    string `json:"tripID"`
-	RoomID    string `json:"roomID"`
-	SenderID  string `json:"senderID"`
-	MessageID string `json:"messageID,omitempty"`
-	Text      string `json:"text"`
-	SentAt    int64  `json:"sentAt"`
-}
@@ -18,12 +18,21 @@ const (
 	TripEventDriverNotInterested = "trip.event.driver_not_interested"
 	TripEventCancelled           = "trip.event.cancelled"
 
+	// Geofence events (driver-service → rider WS and trip-service): the driver
+	// of a trip crossed one of its fences.
+	TripEventDriverNearby      = "trip.event.driver_nearby"
+	TripEventDriverAtPickup    = "trip.event.driver_at_pickup"
+	TripEventDriverAtDropoff   = "trip.event.driver_at_dropoff"
+	TripEventDriverZoneEntered = "trip.event.driver_zone_entered"
+	TripEventDriverZoneExited  = "trip.event.driver_zone_exited"
+
 	// Driver commands (driver.cmd.*)
 	DriverCmdTripRequest = "driver.cmd.trip_request"
 	DriverCmdTripAccept  = "driver.cmd.trip_accept"
 	DriverCmdTripDecline = "driver.cmd.trip_decline"
 	DriverCmdLocation    = "driver.cmd.location"
 	DriverCmdRegister    = "driver.cmd.register"
+	DriverCmdRelease     = "driver.cmd.release" // trip-service → driver-service: drop the driver's trip pairing
 
 	// Payment events (payment.event.*)
 	PaymentEventSessionCreated = "payment.event.session_created"
@@ -33,13 +42,22 @@ const (
 
 	// Payment commands (payment.cmd.*)
 	PaymentCmdCreateSession = "payment.cmd.create_session"
+	PaymentCmdVoidSession   = "payment.cmd.void_session" // trip-service → payment-service: expire an unpaid session
 
 	// Driver events (driver.event.*)
-	DriverEventLocation = "driver.event.location" // driver service → rider WS: real-time position of assigned driver
+	DriverEventLocation        = "driver.event.location"         // driver service → rider WS: real-time position of assigned driver
+	DriverEventPresenceChanged = "driver.event.presence_changed" // driver-service → driver-service: driver went offline, drop from GEO index
+	DriverEventETAChanged      = "driver.event.eta_changed"      // driver-service → rider WS: the driver's ETA moved a lot
+	DriverEventTrace           = "driver.event.trace"            // driver-service → trip-service: down-sampled positions of a trip
 
 	// Chat commands (chat.cmd.*)
 	ChatCmdSend = "chat.cmd.send" // ws-gateway → chat-service: persist + ack
+	ChatCmdRead = "chat.cmd.read" // ws-gateway → chat-service: store a read receipt
 
 	// Chat events (chat.event.*)
 	ChatEventDelivered = "chat.event.delivered" // chat-service → ws-gateway: message stored & delivered
+
+	// Raised by chat-service for support when moderation flagged a chat
+	// message; it is kept in the trip timeline.
+	TripEventChatFlagged = "trip.event.chat_flagged"
 )
@@ -14,25 +14,30 @@ const (
 	WSRoomLeave = "ws.room.leave"
 )
 
+// WSError is sent by the gateway when it rejects a client frame.
+const WSError = "ws.error"
+
+// WSReconnect is sent by a draining gateway node to move its clients to
+// another node.
+const WSReconnect = "ws.reconnect"
+
+// WSSubprotocolProto is the WebSocket subprotocol whose frames are binary
+// protobuf WSMessages (proto/ws.proto). Sockets without a subprotocol use JSON
+// text frames.
+const WSSubprotocolProto = "ride.v1.proto"
+
 // WSMessage is the envelope for every WebSocket message.
 // RoomID optionally scopes the message to a chat room (e.g. "trip:{id}:chat").
+// ID is the Redis stream ID of a user-direct message; clients reconnect with
+// the last ID they saw as lastEventID to receive only what they missed.
 type WSMessage struct {
+	ID     string `json:"id,omitempty"`
 	Type   string `json:"type"`
 	Topic  string `json:"topic,omitempty"`  // legacy – kept for non-chat system events
 	RoomID string `json:"roomID,omitempty"` // room-scoped broadcast
 	Data   any    `json:"data"`
 }
 
-// WSTopicControlData is the payload for legacy subscribe/unsubscribe frames.
-type WSTopicControlData struct {
-	Topic string `json:"topic"`
-}
-
-// WSRoomControlData is the payload for ws.room.join / ws.room.leave frames.
-type WSRoomControlData struct {
-	RoomID string `json:"roomID"`
-}
-
 type WSDriverMessage struct {
 	Type string          `json:"type"`
 	Data json.RawMessage `json:"data"`
@@ -0,0 +1,283 @@
+// Code generated by schemagen from shared/schemas/events.schema.json. DO NOT EDIT.
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"ride-sharing/shared/types"
+)
+
+// WSTopicControlData is the payload for legacy subscribe/unsubscribe frames.
+type WSTopicControlData struct {
+	Topic string `json:"topic"`
+}
+
+// Validate checks d against the WSTopicControlData schema.
+func (d *WSTopicControlData) Validate() error {
+	if d.Topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if utf8.RuneCountInString(d.Topic) > 128 {
+		return fmt.Errorf("topic is longer than 128 characters")
+	}
+	return nil
+}
+
+// WSRoomControlData is the payload for ws.room.join / ws.room.leave frames.
+type WSRoomControlData struct {
+	RoomID string `json:"roomID"`
+}
+
+// Validate checks d against the WSRoomControlData schema.
+func (d *WSRoomControlData) Validate() error {
+	if d.RoomID == "" {
+		return fmt.Errorf("roomID is required")
+	}
+	if utf8.RuneCountInString(d.RoomID) > 128 {
+		return fmt.Errorf("roomID is longer than 128 characters")
+	}
+	return nil
+}
+
+// WSDriverLocationData is the position a driver's client reports.
+type WSDriverLocationData struct {
+	Location  types.Coordinate `json:"location"`
+	Geohash   string           `json:"geohash"`             // precomputed by the client; driver-service derives its own
+	Timestamp int64            `json:"timestamp,omitempty"` // when the position was taken, in ms since the epoch; ws-gateway's receive time when absent
+	Accuracy  float64          `json:"accuracy,omitempty"`  // radius of the position's uncertainty in meters
+}
+
+// Validate checks d against the WSDriverLocationData schema.
+func (d *WSDriverLocationData) Validate() error {
+	if err := d.Location.Validate(); err != nil {
+		return fmt.Errorf("location: %w", err)
+	}
+	if utf8.RuneCountInString(d.Geohash) > 12 {
+		return fmt.Errorf("geohash is longer than 12 characters")
+	}
+	if d.Timestamp < 0 {
+		return fmt.Errorf("timestamp %v is below 0", d.Timestamp)
+	}
+	if d.Accuracy < 0 {
+		return fmt.Errorf("accuracy %v is below 0", d.Accuracy)
+	}
+	return nil
+}
+
+// WSDriverTripResponseData is a driver's accept or decline of a trip offer.
+type WSDriverTripResponseData struct {
+	TripID  string `json:"tripID"`
+	RiderID string `json:"riderID"`
+}
+
+// Validate checks d against the WSDriverTripResponseData schema.
+func (d *WSDriverTripResponseData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if utf8.RuneCountInString(d.TripID) > 64 {
+		return fmt.Errorf("tripID is longer than 64 characters")
+	}
+	if d.RiderID == "" {
+		return fmt.Errorf("riderID is required")
+	}
+	if utf8.RuneCountInString(d.RiderID) > 64 {
+		return fmt.Errorf("riderID is longer than 64 characters")
+	}
+	return nil
+}
+
+// WSChatMessageSendData is the payload the client sends with chat.message.send.
+type WSChatMessageSendData struct {
+	TripID       string `json:"tripID"`
+	MessageID    string `json:"messageID,omitempty"`    // client-generated idempotency key
+	Text         string `json:"text"`                   // may be empty when attachmentID is set
+	AttachmentID string `json:"attachmentID,omitempty"` // returned by POST /chat/attachments
+}
+
+// Validate checks d against the WSChatMessageSendData schema.
+func (d *WSChatMessageSendData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if utf8.RuneCountInString(d.TripID) > 64 {
+		return fmt.Errorf("tripID is longer than 64 characters")
+	}
+	if utf8.RuneCountInString(d.MessageID) > 64 {
+		return fmt.Errorf("messageID is longer than 64 characters")
+	}
+	if utf8.RuneCountInString(d.Text) > 1000 {
+		return fmt.Errorf("text is longer than 1000 characters")
+	}
+	if utf8.RuneCountInString(d.AttachmentID) > 64 {
+		return fmt.Errorf("attachmentID is longer than 64 characters")
+	}
+	return nil
+}
+
+// WSChatMessageReceivedData is the payload broadcast to chat room members.
+type WSChatMessageReceivedData struct {
+	TripID       string `json:"tripID"`
+	RoomID       string `json:"roomID"`
+	SenderID     string `json:"senderID"`
+	MessageID    string `json:"messageID,omitempty"`
+	Text         string `json:"text"`
+	AttachmentID string `json:"attachmentID,omitempty"` // fetch a download URL with GET /chat/attachments/url
+	SentAt       int64  `json:"sentAt"`                 // unix seconds
+}
+
+// Validate checks d against the WSChatMessageReceivedData schema.
+func (d *WSChatMessageReceivedData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.RoomID == "" {
+		return fmt.Errorf("roomID is required")
+	}
+	if d.SenderID == "" {
+		return fmt.Errorf("senderID is required")
+	}
+	return nil
+}
+
+// WSChatMessageReadData is a read receipt. The client sends it with the last
+// message it showed; ws-gateway fills in the reader and broadcasts it to the
+// chat room.
+type WSChatMessageReadData struct {
+	TripID    string `json:"tripID"`
+	MessageID string `json:"messageID"`
+	ReaderID  string `json:"readerID,omitempty"` // set by ws-gateway
+	ReadAt    int64  `json:"readAt,omitempty"`   // unix seconds, set by ws-gateway
+}
+
+// Validate checks d against the WSChatMessageReadData schema.
+func (d *WSChatMessageReadData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if utf8.RuneCountInString(d.TripID) > 64 {
+		return fmt.Errorf("tripID is longer than 64 characters")
+	}
+	if d.MessageID == "" {
+		return fmt.Errorf("messageID is required")
+	}
+	if utf8.RuneCountInString(d.MessageID) > 64 {
+		return fmt.Errorf("messageID is longer than 64 characters")
+	}
+	return nil
+}
+
+// WSChatTypingData tells the chat room a participant started or stopped typing.
+// It is relayed but never stored.
+type WSChatTypingData struct {
+	TripID string `json:"tripID"`
+	Typing bool   `json:"typing"`
+	UserID string `json:"userID,omitempty"` // set by ws-gateway
+}
+
+// Validate checks d against the WSChatTypingData schema.
+func (d *WSChatTypingData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if utf8.RuneCountInString(d.TripID) > 64 {
+		return fmt.Errorf("tripID is longer than 64 characters")
+	}
+	return nil
+}
+
+// WSTripRefData identifies the trip a lifecycle frame refers to.
+type WSTripRefData struct {
+	TripID string `json:"tripID"`
+}
+
+// Validate checks d against the WSTripRefData schema.
+func (d *WSTripRefData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	return nil
+}
+
+// WSErrorData is the payload of the ws.error frame the gateway sends when it
+// rejects a client frame.
+type WSErrorData struct {
+	Code      string `json:"code"` // machine-readable reason, e.g. room_forbidden
+	Message   string `json:"message"`
+	RoomID    string `json:"roomID,omitempty"`
+	MessageID string `json:"messageID,omitempty"` // id of the rejected client frame, if it had one
+	FrameType string `json:"frameType,omitempty"` // type of the rejected client frame
+}
+
+// Validate checks d against the WSErrorData schema.
+func (d *WSErrorData) Validate() error {
+	if d.Code == "" {
+		return fmt.Errorf("code is required")
+	}
+	if d.Message == "" {
+		return fmt.Errorf("message is required")
+	}
+	return nil
+}
+
+// WSReconnectData is the payload of the ws.reconnect frame a draining gateway
+// node sends before it shuts down. The client should reconnect after delayMs,
+// resuming from its lastEventID.
+type WSReconnectData struct {
+	DelayMs int64  `json:"delayMs"` // jittered per socket so clients do not reconnect at once
+	Reason  string `json:"reason"`  // e.g. drain
+}
+
+// Validate checks d against the WSReconnectData schema.
+func (d *WSReconnectData) Validate() error {
+	if d.DelayMs < 0 {
+		return fmt.Errorf("delayMs %v is below 0", d.DelayMs)
+	}
+	if d.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	return nil
+}
+
+type validator interface {
+	Validate() error
+}
+
+var validateWSDataTypes = map[string]func() validator{
+	"ws.topic.subscribe":      func() validator { return new(WSTopicControlData) },
+	"ws.topic.unsubscribe":    func() validator { return new(WSTopicControlData) },
+	"ws.room.join":            func() validator { return new(WSRoomControlData) },
+	"ws.room.leave":           func() validator { return new(WSRoomControlData) },
+	"driver.cmd.location":     func() validator { return new(WSDriverLocationData) },
+	"driver.cmd.trip_accept":  func() validator { return new(WSDriverTripResponseData) },
+	"driver.cmd.trip_decline": func() validator { return new(WSDriverTripResponseData) },
+	"chat.message.send":       func() validator { return new(WSChatMessageSendData) },
+	"chat.message.received":   func() validator { return new(WSChatMessageReceivedData) },
+	"chat.message.read":       func() validator { return new(WSChatMessageReadData) },
+	"chat.typing":             func() validator { return new(WSChatTypingData) },
+	"trip.event.completed":    func() validator { return new(WSTripRefData) },
+	"trip.event.cancelled":    func() validator { return new(WSTripRefData) },
+	"trip.cmd.cancel":         func() validator { return new(WSTripRefData) },
+	"ws.error":                func() validator { return new(WSErrorData) },
+	"ws.reconnect":            func() validator { return new(WSReconnectData) },
+}
+
+// ValidateWSData decodes data as the payload of a WebSocket frame of type
+// msgType and validates it. Frame types without a schema are accepted unchecked.
+func ValidateWSData(msgType string, data []byte) error {
+	newPayload, ok := validateWSDataTypes[msgType]
+	if !ok {
+		return nil
+	}
+	payload := newPayload()
+	if err := json.Unmarshal(data, payload); err != nil {
+		return fmt.Errorf("decode %s payload: %w", msgType, err)
+	}
+	if err := payload.Validate(); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", msgType, err)
+	}
+	return nil
+}
@@ -13,8 +13,11 @@ import (
 )
 
 const (
-	TripsCollection     = "trips"
-	RideFaresCollection = "ride_fares"
+	TripsCollection      = "trips"
+	RideFaresCollection  = "ride_fares"
+	TripEventsCollection = "trip_events"
+	TripSagasCollection  = "trip_sagas"
+	TripTracesCollection = "trip_traces"
 )
 
 // MongoConfig holds MongoDB connection configuration
@@ -0,0 +1,145 @@
+// Package geofence tests positions against circles and named zone polygons.
+package geofence
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"ride-sharing/shared/routing"
+	"ride-sharing/shared/types"
+)
+
+const earthRadiusMeters = 6371000
+
+// Circle is a fence of RadiusMeters around Center.
+type Circle struct {
+	Center       types.Coordinate
+	RadiusMeters float64
+}
+
+func (c Circle) Contains(p types.Coordinate) bool {
+	return routing.DistanceMeters(c.Center, p) <= c.RadiusMeters
+}
+
+// Polygon is a fence bounded by its vertices, in order. The last vertex
+// connects back to the first.
+type Polygon []types.Coordinate
+
+// Contains reports whether p lies inside the polygon, by counting the edges a
+// ray from p crosses.
+func (pg Polygon) Contains(p types.Coordinate) bool {
+	inside := false
+	for i, j := 0, len(pg)-1; i < len(pg); j, i = i, i+1 {
+		a, b := pg[i], pg[j]
+		if (a.Latitude > p.Latitude) != (b.Latitude > p.Latitude) {
+			crossing := a.Longitude + (p.Latitude-a.Latitude)/(b.Latitude-a.Latitude)*(b.Longitude-a.Longitude)
+			if p.Longitude < crossing {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// DistanceMeters returns how far p is from the polygon's boundary. Zones are
+// small enough to measure on a flat projection around p.
+func (pg Polygon) DistanceMeters(p types.Coordinate) float64 {
+	scaleY := earthRadiusMeters * math.Pi / 180
+	scaleX := scaleY * math.Cos(p.Latitude*math.Pi/180)
+	project := func(c types.Coordinate) (float64, float64) {
+		return (c.Longitude - p.Longitude) * scaleX, (c.Latitude - p.Latitude) * scaleY
+	}
+
+	best := math.Inf(1)
+	for i, j := 0, len(pg)-1; i < len(pg); j, i = i, i+1 {
+		ax, ay := project(pg[j])
+		bx, by := project(pg[i])
+		// Closest point to the origin (p) on the segment a-b.
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = max(0, min(1, -(ax*dx+ay*dy)/length))
+		}
+		best = min(best, math.Hypot(ax+t*dx, ay+t*dy))
+	}
+	return best
+}
+
+// Zone is a named area drivers are tracked in and out of, such as an airport
+// or a restricted area.
+type Zone struct {
+	ID      string
+	Name    string
+	Kind    string
+	Polygon Polygon
+}
+
+// LoadZones reads zones from a GeoJSON FeatureCollection of Polygon features
+// with "id", "name" and "kind" properties. Only the outer ring of each
+// polygon is used.
+func LoadZones(r io.Reader) ([]Zone, error) {
+	var collection struct {
+		Features []struct {
+			Geometry struct {
+				Type        string         `json:"type"`
+				Coordinates [][][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+				Kind string `json:"kind"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("failed to decode zones: %w", err)
+	}
+
+	zones := make([]Zone, 0, len(collection.Features))
+	seen := make(map[string]struct{}, len(collection.Features))
+	for i, feature := range collection.Features {
+		props := feature.Properties
+		if props.ID == "" {
+			return nil, fmt.Errorf("zone %d has no id", i)
+		}
+		if _, ok := seen[props.ID]; ok {
+			return nil, fmt.Errorf("zone %s is defined twice", props.ID)
+		}
+		seen[props.ID] = struct{}{}
+		if feature.Geometry.Type != "Polygon" || len(feature.Geometry.Coordinates) == 0 {
+			return nil, fmt.Errorf("zone %s is a %q, want a Polygon", props.ID, feature.Geometry.Type)
+		}
+
+		ring := feature.Geometry.Coordinates[0]
+		if n := len(ring); n > 1 && ring[0] == ring[n-1] {
+			ring = ring[:n-1] // GeoJSON rings repeat their first position
+		}
+		if len(ring) < 3 {
+			return nil, fmt.Errorf("zone %s has fewer than 3 corners", props.ID)
+		}
+		polygon := make(Polygon, len(ring))
+		for j, position := range ring {
+			polygon[j] = types.Coordinate{Latitude: position[1], Longitude: position[0]}
+		}
+
+		name := props.Name
+		if name == "" {
+			name = props.ID
+		}
+		zones = append(zones, Zone{ID: props.ID, Name: name, Kind: props.Kind, Polygon: polygon})
+	}
+	return zones, nil
+}
+
+// LoadZonesFile reads zones from the GeoJSON file at path.
+func LoadZonesFile(path string) ([]Zone, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadZones(f)
+}
@@ -0,0 +1,278 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/tracing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BreakerState is the state of a consumer circuit breaker.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // consuming normally
+	BreakerHalfOpen                     // consuming a single probe delivery
+	BreakerOpen                         // consumer cancelled, messages stay in the queue
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive transient failures that open the breaker
+	OpenTimeout      time.Duration // how long to stay open before probing
+}
+
+// DefaultBreakerConfig returns a BreakerConfig with sensible default values
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+}
+
+// TransientError marks a failure caused by an unavailable dependency (MongoDB,
+// Redis, another service). Consumers requeue such messages instead of
+// dead-lettering them and count them towards the queue's circuit breaker.
+type TransientError struct {
+	Dependency string
+	Err        error
+}
+
+func (e *TransientError) Error() string {
+	return fmt.Sprintf("%s unavailable: %v", e.Dependency, e.Err)
+}
+
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// Transient wraps err as a TransientError for dependency. It returns nil for a nil err.
+func Transient(dependency string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TransientError{Dependency: dependency, Err: err}
+}
+
+// TransientDependency reports whether err is transient and which dependency
+// caused it. Errors not wrapped with Transient are still recognised when they
+// are MongoDB network errors or timeouts, or network errors in general.
+func TransientDependency(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	var transient *TransientError
+	if errors.As(err, &transient) {
+		return transient.Dependency, true
+	}
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return "mongodb", true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		if opErr.Addr != nil {
+			return opErr.Addr.String(), true
+		}
+		return opErr.Net, true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "network", true
+	}
+	return "", false
+}
+
+// circuitBreaker tracks one dependency of one queue.
+type circuitBreaker struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// queueBreaker holds the breakers of a single queue. The queue is paused while
+// any of its dependencies is open.
+type queueBreaker struct {
+	queue string
+	cfg   BreakerConfig
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func newQueueBreaker(queue string, cfg BreakerConfig) *queueBreaker {
+	return &queueBreaker{queue: queue, cfg: cfg, breakers: make(map[string]*circuitBreaker)}
+}
+
+// State returns the most severe state across the queue's dependencies.
+func (q *queueBreaker) State() BreakerState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stateLocked()
+}
+
+func (q *queueBreaker) stateLocked() BreakerState {
+	state := BreakerClosed
+	for _, b := range q.breakers {
+		if b.state > state {
+			state = b.state
+		}
+	}
+	return state
+}
+
+// Success closes every breaker of the queue: the delivery needed all of them.
+func (q *queueBreaker) Success() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for dep, b := range q.breakers {
+		if b.state != BreakerClosed {
+			log.Printf("Circuit for queue %s (%s) closed", q.queue, dep)
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+	}
+}
+
+// Failure records a transient failure of dependency and returns the resulting state.
+func (q *queueBreaker) Failure(dependency string) BreakerState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	b, ok := q.breakers[dependency]
+	if !ok {
+		b = &circuitBreaker{}
+		q.breakers[dependency] = b
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= q.cfg.FailureThreshold {
+		if b.state != BreakerOpen {
+			log.Printf("Circuit for queue %s opened after %d transient %s failures", q.queue, b.failures, dependency)
+		}
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+	return q.stateLocked()
+}
+
+// WaitHalfOpen blocks until every open breaker has cooled down, then moves
+// them to half-open so the consumer can take a single probe delivery.
+func (q *queueBreaker) WaitHalfOpen(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		var wait time.Duration
+		for _, b := range q.breakers {
+			if b.state != BreakerOpen {
+				continue
+			}
+			if left := q.cfg.OpenTimeout - time.Since(b.openedAt); left > wait {
+				wait = left
+			}
+		}
+		if wait <= 0 {
+			for dep, b := range q.breakers {
+				if b.state == BreakerOpen {
+					log.Printf("Circuit for queue %s (%s) half-open, probing", q.queue, dep)
+					b.state = BreakerHalfOpen
+				}
+			}
+			q.mu.Unlock()
+			return nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// breakerRegistry owns the queue breakers of one broker and reports their
+// state as the messaging.consumer.breaker.state gauge (0 closed, 1 half-open, 2 open).
+type breakerRegistry struct {
+	cfg BreakerConfig
+
+	mu     sync.Mutex
+	queues map[string]*queueBreaker
+}
+
+func newBreakerRegistry(cfg BreakerConfig) *breakerRegistry {
+	r := &breakerRegistry{cfg: cfg, queues: make(map[string]*queueBreaker)}
+
+	meter := tracing.GetMeter("ride-sharing/messaging")
+	gauge, err := meter.Int64ObservableGauge("messaging.consumer.breaker.state",
+		metric.WithDescription("Consumer circuit breaker state per queue and dependency: 0 closed, 1 half-open, 2 open"))
+	if err != nil {
+		log.Printf("Failed to create breaker state gauge: %v", err)
+		return r
+	}
+	if _, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		r.observe(func(queue, dependency string, state BreakerState) {
+			o.ObserveInt64(gauge, int64(state), metric.WithAttributes(
+				attribute.String("messaging.destination", queue),
+				attribute.String("dependency", dependency),
+			))
+		})
+		return nil
+	}, gauge); err != nil {
+		log.Printf("Failed to register breaker state gauge: %v", err)
+	}
+	return r
+}
+
+func (r *breakerRegistry) forQueue(queue string) *queueBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q, ok := r.queues[queue]
+	if !ok {
+		q = newQueueBreaker(queue, r.cfg)
+		r.queues[queue] = q
+	}
+	return q
+}
+
+func (r *breakerRegistry) observe(fn func(queue, dependency string, state BreakerState)) {
+	r.mu.Lock()
+	queues := make([]*queueBreaker, 0, len(r.queues))
+	for _, q := range r.queues {
+		queues = append(queues, q)
+	}
+	r.mu.Unlock()
+
+	for _, q := range queues {
+		q.mu.Lock()
+		for dep, b := range q.breakers {
+			fn(q.queue, dep, b.state)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// annotateBreakerState records the queue's breaker state on the consume span.
+func annotateBreakerState(ctx context.Context, state BreakerState) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("messaging.consumer.breaker_state", state.String()))
+}
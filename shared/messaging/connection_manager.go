@@ -4,7 +4,9 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"sync"
+	"time"
 
 	"ride-sharing/shared/contracts"
 
@@ -15,12 +17,58 @@ var (
 	ErrConnectionNotFound = errors.New("connection not found")
 )
 
-// connRecord holds a WebSocket connection together with its owning userID.
-// The per-record mutex ensures only one goroutine writes to the connection at a time.
+// Conn is the client connection a socket's messages are written to.
+// *websocket.Conn implements it, WSConn wraps it for protobuf clients, and
+// ws-gateway also registers Server-Sent Events streams and long-poll requests
+// as sockets.
+type Conn interface {
+	WriteJSON(v any) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+// connRecord holds a client connection together with its owning userID.
+// Messages are queued on the record and written by its own writer goroutine,
+// so a slow client never blocks the goroutine delivering to it. mu guards the
+// queue and the delivery state; cond is signalled whenever the queue changes.
+//
+// replayedTo is the stream ID up to which the socket has the user-direct
+// stream: the cursor it resumed from, advanced by every replayed entry. While
+// the socket is replaying, live messages are held in pending and queued once
+// the replay has caught up.
 type connRecord struct {
-	conn   *websocket.Conn
+	conn   Conn
 	userID string
-	mu     sync.Mutex
+	cfg    SendQueueConfig
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	queue      []contracts.WSMessage
+	closed     bool
+	replayedTo string
+	replaying  bool
+	pending    []contracts.WSMessage
+}
+
+func newConnRecord(conn Conn, userID, cursor string, cfg SendQueueConfig) *connRecord {
+	rec := &connRecord{
+		conn:       conn,
+		userID:     userID,
+		cfg:        cfg,
+		replayedTo: cursor,
+		replaying:  cursor != "",
+	}
+	rec.cond = sync.NewCond(&rec.mu)
+	return rec
+}
+
+// replayed reports whether msg falls within the stream range the socket
+// resumed from or was replayed. Live messages are only checked against that
+// range, never against each other: publishers on different nodes can deliver
+// stream entries out of order, and each one must still reach the socket. The
+// caller holds rec.mu.
+func (rec *connRecord) replayed(msg contracts.WSMessage) bool {
+	return msg.ID != "" && rec.replayedTo != "" && !streamIDAfter(msg.ID, rec.replayedTo)
 }
 
 // ConnectionManager tracks connections by socketID (one unique ID per WebSocket
@@ -29,20 +77,29 @@ type connRecord struct {
 type ConnectionManager struct {
 	bySocket map[string]*connRecord         // socketID → record
 	byUser   map[string]map[string]struct{} // userID  → set of socketIDs
+	cfg      SendQueueConfig
+	metrics  *sendQueueMetrics
 	mu       sync.RWMutex
 }
 
+// upgrader negotiates the protobuf subprotocol and permessage-deflate when the
+// client offers them; clients offering neither get uncompressed JSON frames.
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
+	Subprotocols:      []string{contracts.WSSubprotocolProto},
+	EnableCompression: true,
 }
 
-func NewConnectionManager() *ConnectionManager {
-	return &ConnectionManager{
+func NewConnectionManager(cfg SendQueueConfig) *ConnectionManager {
+	cm := &ConnectionManager{
 		bySocket: make(map[string]*connRecord),
 		byUser:   make(map[string]map[string]struct{}),
+		cfg:      cfg,
 	}
+	cm.metrics = newSendQueueMetrics(cm)
+	return cm
 }
 
 func (cm *ConnectionManager) Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
@@ -50,11 +107,19 @@ func (cm *ConnectionManager) Upgrade(w http.ResponseWriter, r *http.Request) (*w
 }
 
 // Add registers a new WebSocket connection under socketID (unique per connection)
-// and links it to the owning userID for multi-device fan-out.
-func (cm *ConnectionManager) Add(socketID, userID string, conn *websocket.Conn) {
+// and links it to the owning userID for multi-device fan-out. A non-empty
+// cursor puts the socket in replay mode until EndReplay is called. The
+// socket's writer runs until the socket is removed or a write fails.
+func (cm *ConnectionManager) Add(socketID, userID string, conn Conn, cursor string) {
+	rec := newConnRecord(conn, userID, cursor, cm.cfg)
+	go rec.writeLoop(func(err error) {
+		log.Printf("Write to socket %s (user %s) failed: %v", socketID, userID, err)
+		cm.dropStale(socketID, rec)
+	})
+
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
-	cm.bySocket[socketID] = &connRecord{conn: conn, userID: userID}
+	cm.bySocket[socketID] = rec
 	if _, ok := cm.byUser[userID]; !ok {
 		cm.byUser[userID] = make(map[string]struct{})
 	}
@@ -62,7 +127,8 @@ func (cm *ConnectionManager) Add(socketID, userID string, conn *websocket.Conn)
 	log.Printf("Added socket %s for user %s", socketID, userID)
 }
 
-// Remove removes a socket and returns the owning userID (empty string if not found).
+// Remove removes a socket, stops its writer and returns the owning userID
+// (empty string if not found).
 func (cm *ConnectionManager) Remove(socketID string) string {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
@@ -70,6 +136,7 @@ func (cm *ConnectionManager) Remove(socketID string) string {
 	if !ok {
 		return ""
 	}
+	rec.close()
 	userID := rec.userID
 	delete(cm.bySocket, socketID)
 	if sockets, ok := cm.byUser[userID]; ok {
@@ -88,23 +155,144 @@ func (cm *ConnectionManager) HasUser(userID string) bool {
 	return len(cm.byUser[userID]) > 0
 }
 
-// SendToSocket writes a message to a specific socket connection.
-func (cm *ConnectionManager) SendToSocket(socketID string, msg contracts.WSMessage) error {
+// SocketIDs returns the IDs of all sockets on this node.
+func (cm *ConnectionManager) SocketIDs() []string {
 	cm.mu.RLock()
-	rec, ok := cm.bySocket[socketID]
-	cm.mu.RUnlock()
+	defer cm.mu.RUnlock()
+	ids := make([]string, 0, len(cm.bySocket))
+	for socketID := range cm.bySocket {
+		ids = append(ids, socketID)
+	}
+	return ids
+}
+
+// CloseAll closes every socket, sending WebSocket clients a close frame with
+// code and text. The sockets' handlers then fail and remove them.
+func (cm *ConnectionManager) CloseAll(code int, text string) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	deadline := time.Now().Add(time.Second)
+	for _, rec := range cm.bySocket {
+		if ws, ok := rec.conn.(interface {
+			WriteControl(messageType int, data []byte, deadline time.Time) error
+		}); ok {
+			_ = ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), deadline)
+		}
+		_ = rec.conn.Close()
+	}
+}
+
+// SendToSocket queues a message for a specific socket connection. Stream
+// messages the socket has already seen are skipped, and those arriving while
+// it replays are held behind the replay. When the socket's queue is full the
+// overflow policy applies; ErrSlowConsumer means the socket was disconnected.
+func (cm *ConnectionManager) SendToSocket(socketID string, msg contracts.WSMessage) error {
+	rec, ok := cm.record(socketID)
 	if !ok {
 		return ErrConnectionNotFound
 	}
 	rec.mu.Lock()
-	err := rec.conn.WriteJSON(msg)
+	outcome := overflowNone
+	switch {
+	case rec.closed:
+		rec.mu.Unlock()
+		return ErrConnectionNotFound
+	case rec.replaying && msg.ID != "":
+		rec.pending = append(rec.pending, msg)
+	case !rec.replayed(msg):
+		outcome = rec.enqueue(msg)
+	}
 	rec.mu.Unlock()
-	if err != nil {
-		// Best-effort stale socket cleanup so reconnecting users are not blocked by dead entries.
-		_ = rec.conn.Close()
-		cm.Remove(socketID)
+
+	if outcome == overflowNone {
+		return nil
+	}
+	cm.metrics.recordOverflow(outcome, msg.Type)
+	if outcome != overflowDisconnected {
+		return nil
+	}
+	log.Printf("Send queue of socket %s (user %s) overflowed, disconnecting", socketID, rec.userID)
+	cm.dropStale(socketID, rec)
+	return ErrSlowConsumer
+}
+
+// Replay queues a missed stream message for a replaying socket, bypassing the
+// pending messages and waiting for room in the queue instead of overflowing.
+func (cm *ConnectionManager) Replay(socketID string, msg contracts.WSMessage) error {
+	rec, ok := cm.record(socketID)
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.replayed(msg) {
+		return nil
+	}
+	rec.replayedTo = msg.ID
+	return rec.enqueueWait(msg)
+}
+
+// EndReplay queues the live messages held during the replay, in stream order,
+// and switches the socket back to live delivery.
+func (cm *ConnectionManager) EndReplay(socketID string) error {
+	rec, ok := cm.record(socketID)
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	// enqueueWait releases mu while it waits, so live messages may still be
+	// appended to pending until it is empty.
+	for len(rec.pending) > 0 {
+		slices.SortStableFunc(rec.pending, func(a, b contracts.WSMessage) int {
+			return compareStreamIDs(a.ID, b.ID)
+		})
+		msg := rec.pending[0]
+		rec.pending = rec.pending[1:]
+		if rec.replayed(msg) {
+			continue
+		}
+		if err := rec.enqueueWait(msg); err != nil {
+			return err
+		}
+	}
+	rec.pending = nil
+	rec.replaying = false
+	return nil
+}
+
+func (cm *ConnectionManager) record(socketID string) (*connRecord, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	rec, ok := cm.bySocket[socketID]
+	return rec, ok
+}
+
+// dropStale closes a socket that failed or fell behind. Best-effort stale
+// socket cleanup so reconnecting users are not blocked by dead entries.
+func (cm *ConnectionManager) dropStale(socketID string, rec *connRecord) {
+	_ = rec.conn.Close()
+	cm.Remove(socketID)
+}
+
+// queueDepth returns the number of messages queued on all sockets and on the
+// deepest one.
+func (cm *ConnectionManager) queueDepth() (total, deepest int) {
+	cm.mu.RLock()
+	recs := make([]*connRecord, 0, len(cm.bySocket))
+	for _, rec := range cm.bySocket {
+		recs = append(recs, rec)
+	}
+	cm.mu.RUnlock()
+
+	for _, rec := range recs {
+		rec.mu.Lock()
+		n := len(rec.queue)
+		rec.mu.Unlock()
+		total += n
+		deepest = max(deepest, n)
 	}
-	return err
+	return total, deepest
 }
 
 // SendMessage delivers a message to every socket owned by userID.
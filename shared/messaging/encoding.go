@@ -0,0 +1,138 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"ride-sharing/shared/contracts"
+	pbe "ride-sharing/shared/proto/events"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types of AMQP message bodies.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// protoPayload is implemented by payloads that have a protobuf wire form.
+type protoPayload interface {
+	validator
+	marshalProto() ([]byte, error)
+	unmarshalProto([]byte) error
+}
+
+// newProtoPayload returns an empty payload for routingKey if its schema type
+// can be sent as protobuf.
+func newProtoPayload(routingKey string) (protoPayload, bool) {
+	newPayload, ok := validatePayloadTypes[routingKey]
+	if !ok {
+		return nil, false
+	}
+	payload, ok := newPayload().(protoPayload)
+	return payload, ok
+}
+
+func (d *TripEventData) marshalProto() ([]byte, error) {
+	return proto.Marshal(&pbe.TripEvent{Trip: d.Trip, PickupLat: d.PickupLat, PickupLng: d.PickupLng})
+}
+
+func (d *TripEventData) unmarshalProto(b []byte) error {
+	var event pbe.TripEvent
+	if err := proto.Unmarshal(b, &event); err != nil {
+		return err
+	}
+	*d = TripEventData{Trip: event.Trip, PickupLat: event.PickupLat, PickupLng: event.PickupLng}
+	return nil
+}
+
+// encodeMessage renders message as the body of a contentType message.
+// message.Data is always JSON; for protobuf it is transcoded through the
+// routing key's payload type.
+func encodeMessage(contentType, routingKey string, message contracts.AmqpMessage) ([]byte, error) {
+	if contentType != ContentTypeProtobuf {
+		body, err := json.Marshal(message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal message: %v", err)
+		}
+		return body, nil
+	}
+
+	payload, ok := newProtoPayload(routingKey)
+	if !ok {
+		return nil, fmt.Errorf("routing key %s has no protobuf payload", routingKey)
+	}
+	if err := json.Unmarshal(message.Data, payload); err != nil {
+		return nil, fmt.Errorf("decode %s payload: %w", routingKey, err)
+	}
+	data, err := payload.marshalProto()
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", routingKey, err)
+	}
+	return proto.Marshal(&pbe.Envelope{OwnerID: message.OwnerID, Data: data})
+}
+
+// printableBody renders the body of d for logs.
+func printableBody(d amqp.Delivery) string {
+	if d.ContentType == ContentTypeProtobuf {
+		return fmt.Sprintf("<%d bytes %s>", len(d.Body), d.ContentType)
+	}
+	return string(d.Body)
+}
+
+// DecodeMessage decodes the envelope of d according to its content type.
+// Protobuf payloads are transcoded to JSON, so handlers that unmarshal Data
+// work whichever encoding the publisher chose.
+func DecodeMessage(d amqp.Delivery) (contracts.AmqpMessage, error) {
+	if d.ContentType != ContentTypeProtobuf {
+		var message contracts.AmqpMessage
+		if err := json.Unmarshal(d.Body, &message); err != nil {
+			return contracts.AmqpMessage{}, fmt.Errorf("decode message: %w", err)
+		}
+		return message, nil
+	}
+
+	var envelope pbe.Envelope
+	if err := proto.Unmarshal(d.Body, &envelope); err != nil {
+		return contracts.AmqpMessage{}, fmt.Errorf("decode protobuf envelope: %w", err)
+	}
+	payload, ok := newProtoPayload(d.RoutingKey)
+	if !ok {
+		return contracts.AmqpMessage{}, fmt.Errorf("routing key %s has no protobuf payload", d.RoutingKey)
+	}
+	if err := payload.unmarshalProto(envelope.Data); err != nil {
+		return contracts.AmqpMessage{}, fmt.Errorf("decode %s payload: %w", d.RoutingKey, err)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return contracts.AmqpMessage{}, err
+	}
+	return contracts.AmqpMessage{OwnerID: envelope.OwnerID, Data: data}, nil
+}
+
+// DecodePayload decodes the payload of d into payload and returns the owner ID
+// of the message. Protobuf payloads are decoded directly when payload has a
+// protobuf form.
+func DecodePayload(d amqp.Delivery, payload any) (string, error) {
+	if p, ok := payload.(protoPayload); ok && d.ContentType == ContentTypeProtobuf {
+		var envelope pbe.Envelope
+		if err := proto.Unmarshal(d.Body, &envelope); err != nil {
+			return "", fmt.Errorf("decode protobuf envelope: %w", err)
+		}
+		if err := p.unmarshalProto(envelope.Data); err != nil {
+			return "", fmt.Errorf("decode %s payload: %w", d.RoutingKey, err)
+		}
+		return envelope.OwnerID, nil
+	}
+
+	message, err := DecodeMessage(d)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(message.Data, payload); err != nil {
+		return "", fmt.Errorf("decode %s payload: %w", d.RoutingKey, err)
+	}
+	return message.OwnerID, nil
+}
@@ -1,15 +1,17 @@
 package messaging
 
-import (
-	pbd "ride-sharing/shared/proto/driver"
-	pb "ride-sharing/shared/proto/trip"
-)
+//go:generate go run ../../tools/schemagen -root ../..
 
 const (
-	FindAvailableDriversQueue        = "find_available_drivers"
+	// The dispatch queues are priority queues. The broker cannot add
+	// x-max-priority to an existing queue, so they were renamed with a _v2
+	// suffix and replace the FIFO queues of the same name (see
+	// QueueSpec.Replaces).
+	FindAvailableDriversQueue = "find_available_drivers_v2"
+	DriverCmdTripRequestQueue = "driver_cmd_trip_request_v2"
+	DriverTripResponseQueue   = "driver_trip_response_v2"
+
 	NotifyTripCreatedQueue           = "notify_trip_created"
-	DriverCmdTripRequestQueue        = "driver_cmd_trip_request"
-	DriverTripResponseQueue          = "driver_trip_response"
 	NotifyDriverNoDriversFoundQueue  = "notify_driver_no_drivers_found"
 	NotifyDriverAssignQueue          = "notify_driver_assign"
 	NotifyTripCompletedQueue         = "notify_trip_completed"
@@ -20,85 +22,29 @@ const (
 	DriverLocationUpdateQueue        = "driver_location_update"
 	DriverTripAssignedQueue          = "driver_trip_assigned"
 	NotifyRiderDriverLocationQueue   = "notify_rider_driver_location"
+	NotifyRiderETAChangedQueue       = "notify_rider_eta_changed"
+	NotifyRiderGeofenceQueue         = "notify_rider_geofence"
 
 	// Chat queues — ws-gateway publishes, chat-service consumes (and vice-versa for acks).
 	ChatCmdSendQueue        = "chat_cmd_send"
+	ChatCmdReadQueue        = "chat_cmd_read"
 	ChatEventDeliveredQueue = "chat_event_delivered"
 
 	// Cancel queue — trip-service publishes, ws-gateway cancels both rider and driver.
 	NotifyTripCancelledQueue = "notify_trip_cancelled"
-)
-
-type TripEventData struct {
-	Trip      *pb.Trip `json:"trip"`
-	PickupLat float64  `json:"pickupLat"`
-	PickupLng float64  `json:"pickupLng"`
-}
-
-type DriverTripResponseData struct {
-	Driver      *pbd.Driver `json:"driver"` // kept for backward compat but may be nil
-	TripID      string      `json:"tripID"`
-	RiderID     string      `json:"riderID"`
-	DriverID    string      `json:"driverID"`
-	DriverName  string      `json:"driverName"`
-	PackageSlug string      `json:"packageSlug"`
-}
 
-type PaymentEventSessionCreatedData struct {
-	TripID    string  `json:"tripID"`
-	SessionID string  `json:"sessionID"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-}
+	// Saga compensation queues — trip-service publishes when a trip cannot be completed.
+	PaymentVoidSessionQueue = "payment_void_session"
+	DriverReleaseQueue      = "driver_release"
 
-type PaymentTripResponseData struct {
-	TripID   string  `json:"tripID"`
-	UserID   string  `json:"userID"`
-	DriverID string  `json:"driverID"`
-	Amount   float64 `json:"amount"`
-	Currency string  `json:"currency"`
-}
+	// Presence queues — driver-service drops offline drivers from the GEO index
+	// and frees busy drivers when their trip ends.
+	DriverPresenceChangedQueue = "driver_presence_changed"
+	DriverTripEndedQueue       = "driver_trip_ended"
 
-type PaymentStatusUpdateData struct {
-	TripID   string `json:"tripID"`
-	UserID   string `json:"userID"`
-	DriverID string `json:"driverID"`
-}
+	// Timeline queue — trip-service records every trip event in the trip's timeline.
+	TripTimelineQueue = "trip_timeline"
 
-type DriverLocationUpdateData struct {
-	PackageSlug string  `json:"packageSlug"`
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-}
-
-type DriverLocationEventData struct {
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-}
-
-// TripCancelledData is the payload published on trip.event.cancelled.
-// It carries both riderID and driverID so the ws-gateway cancel consumer can
-// fan-out to both parties and clean up Redis state in one shot.
-type TripCancelledData struct {
-	TripID         string `json:"tripID"`
-	RiderID        string `json:"riderID"`
-	DriverID       string `json:"driverID"`
-	DriverAccepted bool   `json:"driverAccepted"`
-}
-
-// ChatMessageData is the payload published to ChatCmdSendQueue by ws-gateway
-// and consumed by chat-service for persistence and delivery acknowledgement.
-type ChatMessageData struct {
-	MessageID string `json:"messageID"`
-	TripID    string `json:"tripID"`
-	SenderID  string `json:"senderID"`
-	Text      string `json:"text"`
-	SentAt    int64  `json:"sentAt"`
-}
-
-// ChatDeliveredData is the payload published by chat-service once a message
-// has been persisted, allowing ws-gateway to emit a delivery receipt.
-type ChatDeliveredData struct {
-	MessageID string `json:"messageID"`
-	TripID    string `json:"tripID"`
-}
+	// Trace queue — trip-service stores the positions driver-service records during a trip.
+	TripTraceQueue = "trip_trace"
+)
@@ -0,0 +1,589 @@
+// Code generated by schemagen from shared/schemas/events.schema.json. DO NOT EDIT.
+
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pb "ride-sharing/shared/proto/trip"
+)
+
+// TripEventData is the payload of every trip event that carries the trip
+// itself.
+type TripEventData struct {
+	Trip      *pb.Trip `json:"trip"`
+	PickupLat float64  `json:"pickupLat"`
+	PickupLng float64  `json:"pickupLng"`
+}
+
+// Validate checks d against the TripEventData schema.
+func (d *TripEventData) Validate() error {
+	if d.Trip == nil {
+		return fmt.Errorf("trip is required")
+	}
+	if d.PickupLat < -90 || d.PickupLat > 90 {
+		return fmt.Errorf("pickupLat %v out of range [-90, 90]", d.PickupLat)
+	}
+	if d.PickupLng < -180 || d.PickupLng > 180 {
+		return fmt.Errorf("pickupLng %v out of range [-180, 180]", d.PickupLng)
+	}
+	return nil
+}
+
+// DriverTripResponseData is the driver's answer to a trip offer, enriched by
+// ws-gateway with the driver's identity.
+type DriverTripResponseData struct {
+	TripID      string `json:"tripID"`
+	RiderID     string `json:"riderID"`
+	DriverID    string `json:"driverID"`
+	DriverName  string `json:"driverName"`
+	PackageSlug string `json:"packageSlug"`
+}
+
+// Validate checks d against the DriverTripResponseData schema.
+func (d *DriverTripResponseData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.RiderID == "" {
+		return fmt.Errorf("riderID is required")
+	}
+	if d.DriverID == "" {
+		return fmt.Errorf("driverID is required")
+	}
+	return nil
+}
+
+// PaymentEventSessionCreatedData tells the rider which checkout session to pay.
+type PaymentEventSessionCreatedData struct {
+	TripID    string  `json:"tripID"`
+	SessionID string  `json:"sessionID"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+}
+
+// Validate checks d against the PaymentEventSessionCreatedData schema.
+func (d *PaymentEventSessionCreatedData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.SessionID == "" {
+		return fmt.Errorf("sessionID is required")
+	}
+	if d.Amount < 0 {
+		return fmt.Errorf("amount %v is below 0", d.Amount)
+	}
+	if d.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+	return nil
+}
+
+// PaymentTripResponseData asks payment-service to open a checkout session for
+// an accepted trip.
+type PaymentTripResponseData struct {
+	TripID   string  `json:"tripID"`
+	UserID   string  `json:"userID"`
+	DriverID string  `json:"driverID"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// Validate checks d against the PaymentTripResponseData schema.
+func (d *PaymentTripResponseData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.UserID == "" {
+		return fmt.Errorf("userID is required")
+	}
+	if d.DriverID == "" {
+		return fmt.Errorf("driverID is required")
+	}
+	if d.Amount < 0 {
+		return fmt.Errorf("amount %v is below 0", d.Amount)
+	}
+	if d.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+	return nil
+}
+
+// PaymentStatusUpdateData reports a successful payment from the Stripe webhook.
+type PaymentStatusUpdateData struct {
+	TripID   string `json:"tripID"`
+	UserID   string `json:"userID"`
+	DriverID string `json:"driverID"`
+}
+
+// Validate checks d against the PaymentStatusUpdateData schema.
+func (d *PaymentStatusUpdateData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	return nil
+}
+
+// DriverLocationUpdateData is a driver position forwarded by ws-gateway to
+// driver-service.
+type DriverLocationUpdateData struct {
+	PackageSlug string  `json:"packageSlug"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Timestamp   int64   `json:"timestamp"`          // when the client took the position, in ms since the epoch
+	Accuracy    float64 `json:"accuracy,omitempty"` // meters; driver-service assumes a default when absent
+}
+
+// Validate checks d against the DriverLocationUpdateData schema.
+func (d *DriverLocationUpdateData) Validate() error {
+	if d.Latitude < -90 || d.Latitude > 90 {
+		return fmt.Errorf("latitude %v out of range [-90, 90]", d.Latitude)
+	}
+	if d.Longitude < -180 || d.Longitude > 180 {
+		return fmt.Errorf("longitude %v out of range [-180, 180]", d.Longitude)
+	}
+	if d.Timestamp < 0 {
+		return fmt.Errorf("timestamp %v is below 0", d.Timestamp)
+	}
+	if d.Accuracy < 0 {
+		return fmt.Errorf("accuracy %v is below 0", d.Accuracy)
+	}
+	return nil
+}
+
+// RouteETA is the driver's estimated time and route distance to the pickup, or
+// to the destination once the rider is picked up.
+type RouteETA struct {
+	Target         string  `json:"target"` // pickup or destination
+	ETASeconds     int64   `json:"etaSeconds"`
+	DistanceMeters float64 `json:"distanceMeters"`
+}
+
+// Validate checks d against the RouteETA schema.
+func (d *RouteETA) Validate() error {
+	if d.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+	if d.ETASeconds < 0 {
+		return fmt.Errorf("etaSeconds %v is below 0", d.ETASeconds)
+	}
+	if d.DistanceMeters < 0 {
+		return fmt.Errorf("distanceMeters %v is below 0", d.DistanceMeters)
+	}
+	return nil
+}
+
+// DriverLocationEventData is the assigned driver's position sent to the rider.
+type DriverLocationEventData struct {
+	TripID    string    `json:"tripID,omitempty"` // the trip the driver is on; absent while driver-service does not know it
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Bearing   float64   `json:"bearing,omitempty"` // degrees clockwise from north; absent while the driver stands still
+	Speed     float64   `json:"speed,omitempty"`   // meters per second
+	ETA       *RouteETA `json:"eta,omitempty"`     // absent when no ETA could be computed
+}
+
+// Validate checks d against the DriverLocationEventData schema.
+func (d *DriverLocationEventData) Validate() error {
+	if d.Latitude < -90 || d.Latitude > 90 {
+		return fmt.Errorf("latitude %v out of range [-90, 90]", d.Latitude)
+	}
+	if d.Longitude < -180 || d.Longitude > 180 {
+		return fmt.Errorf("longitude %v out of range [-180, 180]", d.Longitude)
+	}
+	if d.Bearing < 0 || d.Bearing > 360 {
+		return fmt.Errorf("bearing %v out of range [0, 360]", d.Bearing)
+	}
+	if d.Speed < 0 {
+		return fmt.Errorf("speed %v is below 0", d.Speed)
+	}
+	if d.ETA != nil {
+		if err := d.ETA.Validate(); err != nil {
+			return fmt.Errorf("eta: %w", err)
+		}
+	}
+	return nil
+}
+
+// DriverETAChangedData is sent to the rider when the ETA moves by more than
+// driver-service's threshold since the last one announced, and when its target
+// changes.
+type DriverETAChangedData struct {
+	TripID             string    `json:"tripID"`
+	ETA                *RouteETA `json:"eta"`
+	PreviousETASeconds int64     `json:"previousEtaSeconds,omitempty"` // the last announced ETA, counted down to now; absent for a new target
+}
+
+// Validate checks d against the DriverETAChangedData schema.
+func (d *DriverETAChangedData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.ETA == nil {
+		return fmt.Errorf("eta is required")
+	}
+	if d.ETA != nil {
+		if err := d.ETA.Validate(); err != nil {
+			return fmt.Errorf("eta: %w", err)
+		}
+	}
+	if d.PreviousETASeconds < 0 {
+		return fmt.Errorf("previousEtaSeconds %v is below 0", d.PreviousETASeconds)
+	}
+	return nil
+}
+
+// TracePoint is one position of a driver's trace.
+type TracePoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	At        int64   `json:"at"`              // ms since the epoch
+	Speed     float64 `json:"speed,omitempty"` // meters per second
+}
+
+// Validate checks d against the TracePoint schema.
+func (d *TracePoint) Validate() error {
+	if d.Latitude < -90 || d.Latitude > 90 {
+		return fmt.Errorf("latitude %v out of range [-90, 90]", d.Latitude)
+	}
+	if d.Longitude < -180 || d.Longitude > 180 {
+		return fmt.Errorf("longitude %v out of range [-180, 180]", d.Longitude)
+	}
+	if d.At < 0 {
+		return fmt.Errorf("at %v is below 0", d.At)
+	}
+	if d.Speed < 0 {
+		return fmt.Errorf("speed %v is below 0", d.Speed)
+	}
+	return nil
+}
+
+// DriverTraceData is a batch of the down-sampled positions of a driver on a
+// trip, sent by driver-service for trip-service to store.
+type DriverTraceData struct {
+	TripID   string        `json:"tripID"`
+	DriverID string        `json:"driverID"`
+	Points   []*TracePoint `json:"points"`
+}
+
+// Validate checks d against the DriverTraceData schema.
+func (d *DriverTraceData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.DriverID == "" {
+		return fmt.Errorf("driverID is required")
+	}
+	if len(d.Points) == 0 {
+		return fmt.Errorf("points is required")
+	}
+	if len(d.Points) > 500 {
+		return fmt.Errorf("points has more than 500 items")
+	}
+	for i, item := range d.Points {
+		if item == nil {
+			return fmt.Errorf("points[%d] is required", i)
+		}
+		if err := item.Validate(); err != nil {
+			return fmt.Errorf("points[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// TripGeofenceData is sent to the rider and trip-service when the driver of a
+// trip crosses one of its geofences: the circles around its pickup and dropoff,
+// or a named zone.
+type TripGeofenceData struct {
+	TripID         string  `json:"tripID"`
+	DriverID       string  `json:"driverID"`
+	Fence          string  `json:"fence"` // nearby, pickup or dropoff, or the ID of a zone
+	ZoneName       string  `json:"zoneName,omitempty"`
+	ZoneKind       string  `json:"zoneKind,omitempty"` // e.g. airport or restricted
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	DistanceMeters float64 `json:"distanceMeters,omitempty"` // to the pickup or dropoff
+	At             int64   `json:"at"`                       // ms since the epoch of the position that crossed the fence
+}
+
+// Validate checks d against the TripGeofenceData schema.
+func (d *TripGeofenceData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.DriverID == "" {
+		return fmt.Errorf("driverID is required")
+	}
+	if d.Fence == "" {
+		return fmt.Errorf("fence is required")
+	}
+	if d.Latitude < -90 || d.Latitude > 90 {
+		return fmt.Errorf("latitude %v out of range [-90, 90]", d.Latitude)
+	}
+	if d.Longitude < -180 || d.Longitude > 180 {
+		return fmt.Errorf("longitude %v out of range [-180, 180]", d.Longitude)
+	}
+	if d.DistanceMeters < 0 {
+		return fmt.Errorf("distanceMeters %v is below 0", d.DistanceMeters)
+	}
+	if d.At < 0 {
+		return fmt.Errorf("at %v is below 0", d.At)
+	}
+	return nil
+}
+
+// TripCompletedData is published once per participant when a trip is paid.
+type TripCompletedData struct {
+	TripID string `json:"tripID"`
+}
+
+// Validate checks d against the TripCompletedData schema.
+func (d *TripCompletedData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	return nil
+}
+
+// TripCancelledData is the payload published on trip.event.cancelled. It
+// carries both riderID and driverID so the ws-gateway cancel consumer can
+// fan-out to both parties and clean up Redis state in one shot.
+type TripCancelledData struct {
+	TripID         string `json:"tripID"`
+	RiderID        string `json:"riderID"`
+	DriverID       string `json:"driverID"`
+	DriverAccepted bool   `json:"driverAccepted"`
+	CancelledBy    string `json:"cancelledBy"` // user ID of the rider or driver who cancelled
+	Reason         string `json:"reason"`      // set when trip-service cancels the trip itself, e.g. payment_timeout
+}
+
+// Validate checks d against the TripCancelledData schema.
+func (d *TripCancelledData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.RiderID == "" {
+		return fmt.Errorf("riderID is required")
+	}
+	return nil
+}
+
+// PaymentVoidSessionData asks payment-service to expire the unpaid checkout
+// session of a trip.
+type PaymentVoidSessionData struct {
+	TripID    string `json:"tripID"`
+	SessionID string `json:"sessionID"` // looked up by trip when empty
+	Reason    string `json:"reason"`
+}
+
+// Validate checks d against the PaymentVoidSessionData schema.
+func (d *PaymentVoidSessionData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	return nil
+}
+
+// DriverReleaseData asks driver-service to drop the pairing of a driver with a
+// trip that will not be completed.
+type DriverReleaseData struct {
+	TripID   string `json:"tripID"`
+	DriverID string `json:"driverID"`
+	RiderID  string `json:"riderID"`
+	Reason   string `json:"reason"`
+}
+
+// Validate checks d against the DriverReleaseData schema.
+func (d *DriverReleaseData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.DriverID == "" {
+		return fmt.Errorf("driverID is required")
+	}
+	if d.RiderID == "" {
+		return fmt.Errorf("riderID is required")
+	}
+	return nil
+}
+
+// DriverPresenceChangedData is published by driver-service when a driver goes
+// offline, either on disconnect or because their heartbeats stopped.
+// driver-service removes the driver from the GEO index when it consumes it.
+type DriverPresenceChangedData struct {
+	DriverID      string `json:"driverID"`
+	State         string `json:"state"` // online, busy or offline
+	PreviousState string `json:"previousState"`
+	PackageSlug   string `json:"packageSlug"` // GEO index the driver was last registered in
+	LastSeen      int64  `json:"lastSeen"`    // unix milliseconds of the last heartbeat
+	Reason        string `json:"reason"`      // disconnected or heartbeat_expired
+}
+
+// Validate checks d against the DriverPresenceChangedData schema.
+func (d *DriverPresenceChangedData) Validate() error {
+	if d.DriverID == "" {
+		return fmt.Errorf("driverID is required")
+	}
+	if d.State == "" {
+		return fmt.Errorf("state is required")
+	}
+	if d.PreviousState == "" {
+		return fmt.Errorf("previousState is required")
+	}
+	return nil
+}
+
+// ChatMessageData is the payload published to ChatCmdSendQueue by ws-gateway
+// and consumed by chat-service for persistence and delivery acknowledgement.
+type ChatMessageData struct {
+	MessageID    string   `json:"messageID"`
+	TripID       string   `json:"tripID"`
+	SenderID     string   `json:"senderID"`
+	Text         string   `json:"text"` // as relayed, with contact details masked
+	AttachmentID string   `json:"attachmentID,omitempty"`
+	SentAt       int64    `json:"sentAt"`            // unix seconds
+	Flags        []string `json:"flags,omitempty"`   // moderation flags, e.g. contact or abuse
+	Blocked      bool     `json:"blocked,omitempty"` // moderation kept the message from the peer
+}
+
+// Validate checks d against the ChatMessageData schema.
+func (d *ChatMessageData) Validate() error {
+	if d.MessageID == "" {
+		return fmt.Errorf("messageID is required")
+	}
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.SenderID == "" {
+		return fmt.Errorf("senderID is required")
+	}
+	if len(d.Flags) > 16 {
+		return fmt.Errorf("flags has more than 16 items")
+	}
+	return nil
+}
+
+// ChatFlaggedData is the support event chat-service publishes once it stored a
+// message moderation flagged. The text stays in chat-service.
+type ChatFlaggedData struct {
+	TripID    string   `json:"tripID"`
+	MessageID string   `json:"messageID"`
+	SenderID  string   `json:"senderID"`
+	Flags     []string `json:"flags"`
+	Blocked   bool     `json:"blocked,omitempty"`
+	SentAt    int64    `json:"sentAt"` // unix seconds
+}
+
+// Validate checks d against the ChatFlaggedData schema.
+func (d *ChatFlaggedData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.MessageID == "" {
+		return fmt.Errorf("messageID is required")
+	}
+	if d.SenderID == "" {
+		return fmt.Errorf("senderID is required")
+	}
+	if len(d.Flags) == 0 {
+		return fmt.Errorf("flags is required")
+	}
+	if len(d.Flags) > 16 {
+		return fmt.Errorf("flags has more than 16 items")
+	}
+	return nil
+}
+
+// ChatDeliveredData is the payload published by chat-service once a message has
+// been persisted, allowing ws-gateway to emit a delivery receipt.
+type ChatDeliveredData struct {
+	MessageID string `json:"messageID"`
+	TripID    string `json:"tripID"`
+}
+
+// Validate checks d against the ChatDeliveredData schema.
+func (d *ChatDeliveredData) Validate() error {
+	if d.MessageID == "" {
+		return fmt.Errorf("messageID is required")
+	}
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	return nil
+}
+
+// ChatReadData is the payload published to ChatCmdReadQueue by ws-gateway when
+// a participant has read a trip chat up to a message.
+type ChatReadData struct {
+	TripID    string `json:"tripID"`
+	ReaderID  string `json:"readerID"`
+	MessageID string `json:"messageID"` // last message read; earlier ones count as read too
+	ReadAt    int64  `json:"readAt"`    // unix seconds
+}
+
+// Validate checks d against the ChatReadData schema.
+func (d *ChatReadData) Validate() error {
+	if d.TripID == "" {
+		return fmt.Errorf("tripID is required")
+	}
+	if d.ReaderID == "" {
+		return fmt.Errorf("readerID is required")
+	}
+	if d.MessageID == "" {
+		return fmt.Errorf("messageID is required")
+	}
+	return nil
+}
+
+type validator interface {
+	Validate() error
+}
+
+var validatePayloadTypes = map[string]func() validator{
+	"trip.event.created":               func() validator { return new(TripEventData) },
+	"trip.event.driver_assigned":       func() validator { return new(TripEventData) },
+	"trip.event.driver_not_interested": func() validator { return new(TripEventData) },
+	"trip.event.no_drivers_found":      func() validator { return new(TripEventData) },
+	"driver.cmd.trip_request":          func() validator { return new(TripEventData) },
+	"driver.cmd.trip_accept":           func() validator { return new(DriverTripResponseData) },
+	"driver.cmd.trip_decline":          func() validator { return new(DriverTripResponseData) },
+	"payment.event.session_created":    func() validator { return new(PaymentEventSessionCreatedData) },
+	"payment.cmd.create_session":       func() validator { return new(PaymentTripResponseData) },
+	"payment.event.success":            func() validator { return new(PaymentStatusUpdateData) },
+	"driver.cmd.location":              func() validator { return new(DriverLocationUpdateData) },
+	"driver.event.location":            func() validator { return new(DriverLocationEventData) },
+	"driver.event.eta_changed":         func() validator { return new(DriverETAChangedData) },
+	"driver.event.trace":               func() validator { return new(DriverTraceData) },
+	"trip.event.driver_nearby":         func() validator { return new(TripGeofenceData) },
+	"trip.event.driver_at_pickup":      func() validator { return new(TripGeofenceData) },
+	"trip.event.driver_at_dropoff":     func() validator { return new(TripGeofenceData) },
+	"trip.event.driver_zone_entered":   func() validator { return new(TripGeofenceData) },
+	"trip.event.driver_zone_exited":    func() validator { return new(TripGeofenceData) },
+	"trip.event.completed":             func() validator { return new(TripCompletedData) },
+	"trip.event.cancelled":             func() validator { return new(TripCancelledData) },
+	"payment.cmd.void_session":         func() validator { return new(PaymentVoidSessionData) },
+	"driver.cmd.release":               func() validator { return new(DriverReleaseData) },
+	"driver.event.presence_changed":    func() validator { return new(DriverPresenceChangedData) },
+	"chat.cmd.send":                    func() validator { return new(ChatMessageData) },
+	"trip.event.chat_flagged":          func() validator { return new(ChatFlaggedData) },
+	"chat.event.delivered":             func() validator { return new(ChatDeliveredData) },
+	"chat.cmd.read":                    func() validator { return new(ChatReadData) },
+}
+
+// ValidatePayload decodes data as the payload of routingKey and validates it.
+// Routing keys without a schema are accepted unchecked.
+func ValidatePayload(routingKey string, data []byte) error {
+	newPayload, ok := validatePayloadTypes[routingKey]
+	if !ok {
+		return nil
+	}
+	payload := newPayload()
+	if err := json.Unmarshal(data, payload); err != nil {
+		return fmt.Errorf("decode %s payload: %w", routingKey, err)
+	}
+	if err := payload.Validate(); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", routingKey, err)
+	}
+	return nil
+}
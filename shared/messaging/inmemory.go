@@ -0,0 +1,339 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/retry"
+	"ride-sharing/shared/tracing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// InMemoryBroker is a process-local Broker for hermetic tests. Messages are
+// routed with the bindings of a Topology, consumed through the same retry and
+// dead-letter path as RabbitMQ, and settled deliveries are removed from the
+// queue. Queues without a consumer keep their messages so tests can inspect
+// them with Messages, e.g. to assert that something reached the DLQ.
+type InMemoryBroker struct {
+	topology Topology
+	retry    retry.Config
+	breakers *breakerRegistry
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queues map[string]*memoryQueue
+	tag    uint64
+	closed bool
+}
+
+type memoryQueue struct {
+	spec      QueueSpec
+	pending   []memoryMessage
+	consumers int
+	inFlight  int
+	paused    int // consumers waiting for the circuit breaker to half-open
+}
+
+// enqueue appends m, or on a priority queue places it after every message of
+// the same or higher priority, like RabbitMQ does.
+func (q *memoryQueue) enqueue(m memoryMessage) {
+	if q.spec.MaxPriority == 0 {
+		q.pending = append(q.pending, m)
+		return
+	}
+
+	priority := min(m.delivery.Priority, q.spec.MaxPriority)
+	i := len(q.pending)
+	for i > 0 && min(q.pending[i-1].delivery.Priority, q.spec.MaxPriority) < priority {
+		i--
+	}
+	q.pending = slices.Insert(q.pending, i, m)
+}
+
+type memoryMessage struct {
+	delivery   amqp.Delivery
+	enqueuedAt time.Time
+}
+
+// NewInMemoryBroker creates a broker for topology. cfg controls the consumer
+// retries; tests usually pass a config with millisecond waits.
+func NewInMemoryBroker(topology Topology, cfg retry.Config) (*InMemoryBroker, error) {
+	if err := topology.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid messaging topology: %w", err)
+	}
+
+	b := &InMemoryBroker{
+		topology: topology,
+		retry:    cfg,
+		breakers: newBreakerRegistry(DefaultBreakerConfig()),
+		queues:   make(map[string]*memoryQueue, len(topology.Queues)),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	for _, spec := range topology.Queues {
+		b.queues[spec.Name] = &memoryQueue{spec: spec}
+	}
+	return b, nil
+}
+
+// SetBreakerConfig changes the circuit breaker settings for queues consumed
+// after the call.
+func (b *InMemoryBroker) SetBreakerConfig(cfg BreakerConfig) {
+	b.breakers.mu.Lock()
+	defer b.breakers.mu.Unlock()
+	b.breakers.cfg = cfg
+}
+
+func (b *InMemoryBroker) PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage, opts ...PublishOption) error {
+	msg, err := newPublishing(b.topology, routingKey, message, opts)
+	if err != nil {
+		return err
+	}
+
+	return tracing.TracedPublisher(ctx, TripExchange, routingKey, msg, b.publish)
+}
+
+// publish routes msg to every queue bound to routingKey on exchange. Like an
+// unroutable publish on RabbitMQ, a message without a matching queue is dropped.
+func (b *InMemoryBroker) publish(_ context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return fmt.Errorf("in-memory broker is closed")
+	}
+
+	for _, spec := range b.topology.QueuesFor(exchange, routingKey) {
+		q := b.queues[spec.Name]
+		b.tag++
+
+		d := amqp.Delivery{
+			Headers:       copyTable(msg.Headers),
+			ContentType:   msg.ContentType,
+			DeliveryMode:  msg.DeliveryMode,
+			Priority:      msg.Priority,
+			CorrelationId: msg.CorrelationId,
+			MessageId:     msg.MessageId,
+			Timestamp:     msg.Timestamp,
+			Type:          msg.Type,
+			DeliveryTag:   b.tag,
+			Exchange:      exchange,
+			RoutingKey:    routingKey,
+			Body:          msg.Body,
+		}
+		d.Acknowledger = &memoryAcknowledger{broker: b, queue: q, delivery: d}
+
+		// RabbitMQ's default overflow behaviour drops the oldest message.
+		if spec.MaxLength > 0 && int64(len(q.pending)) >= spec.MaxLength {
+			q.pending = q.pending[1:]
+		}
+		q.enqueue(memoryMessage{delivery: d, enqueuedAt: time.Now()})
+	}
+
+	b.cond.Broadcast()
+	return nil
+}
+
+func (b *InMemoryBroker) ConsumeMessages(queueName string, handler MessageHandler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queueName]
+	if !ok {
+		return fmt.Errorf("queue %q is not declared in the topology", queueName)
+	}
+	q.consumers++
+	breaker := b.breakers.forQueue(queueName)
+
+	go func() {
+		for {
+			d, ok := b.next(q)
+			if !ok {
+				return
+			}
+			if err := tracing.TracedConsumer(d, func(ctx context.Context, d amqp.Delivery) error {
+				return handleDelivery(ctx, d, handler, b.retry, b.publish, breaker)
+			}); err != nil {
+				log.Printf("Error processing message: %v", err)
+			}
+
+			b.mu.Lock()
+			q.inFlight--
+			open := breaker.State() == BreakerOpen
+			if open {
+				q.paused++
+			}
+			b.cond.Broadcast()
+			b.mu.Unlock()
+
+			if open {
+				// like a cancelled RabbitMQ consumer: leave the queue alone until the probe
+				_ = breaker.WaitHalfOpen(context.Background())
+				b.mu.Lock()
+				q.paused--
+				b.mu.Unlock()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// next blocks until q has a live message (prefetch 1) or the broker closes.
+// Messages past the queue's TTL are dead-lettered instead of delivered.
+func (b *InMemoryBroker) next(q *memoryQueue) (amqp.Delivery, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for {
+		for len(q.pending) == 0 && !b.closed {
+			b.cond.Wait()
+		}
+		if b.closed {
+			return amqp.Delivery{}, false
+		}
+
+		m := q.pending[0]
+		q.pending = q.pending[1:]
+		if ttl := q.spec.MessageTTL; ttl > 0 && time.Since(m.enqueuedAt) > ttl {
+			b.deadLetterLocked(q, m.delivery, "expired")
+			continue
+		}
+		q.inFlight++
+		return m.delivery, true
+	}
+}
+
+// deadLetterLocked applies the queue's dead-letter policy the way the broker
+// would for rejected or expired messages. b.mu must be held.
+func (b *InMemoryBroker) deadLetterLocked(q *memoryQueue, d amqp.Delivery, reason string) {
+	policy := q.spec.DeadLetter
+	if policy == nil {
+		return
+	}
+
+	routingKey := policy.RoutingKey
+	if routingKey == "" {
+		routingKey = d.RoutingKey
+	}
+
+	headers := copyTable(d.Headers)
+	headers["x-first-death-reason"] = reason
+	headers["x-first-death-queue"] = q.spec.Name
+	headers["x-first-death-exchange"] = d.Exchange
+
+	b.mu.Unlock()
+	_ = b.publish(context.Background(), policy.Exchange, routingKey, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  d.DeliveryMode,
+		Priority:      d.Priority,
+		CorrelationId: d.CorrelationId,
+		MessageId:     d.MessageId,
+		Timestamp:     d.Timestamp,
+		Type:          d.Type,
+		Body:          d.Body,
+	})
+	b.mu.Lock()
+}
+
+// Messages returns a snapshot of the messages waiting in queueName.
+func (b *InMemoryBroker) Messages(queueName string) []amqp.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queueName]
+	if !ok {
+		return nil
+	}
+	out := make([]amqp.Delivery, 0, len(q.pending))
+	for _, m := range q.pending {
+		out = append(out, m.delivery)
+	}
+	return out
+}
+
+// WaitIdle blocks until every queue with a consumer is empty and no delivery
+// is being handled, i.e. until a published flow has run to completion. Queues
+// whose consumers are paused by an open circuit breaker count as idle.
+func (b *InMemoryBroker) WaitIdle(ctx context.Context) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if b.idle() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *InMemoryBroker) idle() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, q := range b.queues {
+		if q.inFlight > 0 || (q.consumers > q.paused && len(q.pending) > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Close stops all consumers. Pending messages are discarded.
+func (b *InMemoryBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	b.cond.Broadcast()
+}
+
+// memoryAcknowledger settles a single in-memory delivery. Requeued messages go
+// back to the head of their queue; rejected ones follow the dead-letter policy.
+type memoryAcknowledger struct {
+	broker   *InMemoryBroker
+	queue    *memoryQueue
+	delivery amqp.Delivery
+}
+
+func (a *memoryAcknowledger) Ack(uint64, bool) error { return nil }
+
+func (a *memoryAcknowledger) Nack(tag uint64, _ bool, requeue bool) error {
+	return a.Reject(tag, requeue)
+}
+
+func (a *memoryAcknowledger) Reject(_ uint64, requeue bool) error {
+	b := a.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !requeue {
+		b.deadLetterLocked(a.queue, a.delivery, "rejected")
+		return nil
+	}
+
+	d := a.delivery
+	d.Redelivered = true
+	d.Acknowledger = a
+	a.queue.pending = append([]memoryMessage{{delivery: d, enqueuedAt: time.Now()}}, a.queue.pending...)
+	b.cond.Broadcast()
+	return nil
+}
+
+func copyTable(t amqp.Table) amqp.Table {
+	out := make(amqp.Table, len(t))
+	for k, v := range t {
+		out[k] = v
+	}
+	return out
+}
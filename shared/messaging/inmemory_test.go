@@ -0,0 +1,231 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/retry"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	tripCreatedData = json.RawMessage(`{"trip":{"id":"t1","userID":"u1","status":"pending"}}`)
+	tripAcceptData  = json.RawMessage(`{"tripID":"t1","riderID":"u1","driverID":"d1"}`)
+)
+
+func newTestBroker(t *testing.T) *InMemoryBroker {
+	t.Helper()
+	b, err := NewInMemoryBroker(DefaultTopology, retry.Config{
+		MaxRetries:  2,
+		InitialWait: time.Millisecond,
+		MaxWait:     time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewInMemoryBroker: %v", err)
+	}
+	t.Cleanup(b.Close)
+	return b
+}
+
+func waitIdle(t *testing.T, b *InMemoryBroker) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.WaitIdle(ctx); err != nil {
+		t.Fatalf("broker did not become idle: %v", err)
+	}
+}
+
+func TestInMemoryBroker_RoutesByTopologyBindings(t *testing.T) {
+	b := newTestBroker(t)
+
+	var mu sync.Mutex
+	received := map[string][]string{}
+	for _, queue := range []string{FindAvailableDriversQueue, NotifyDriverNoDriversFoundQueue} {
+		if err := b.ConsumeMessages(queue, func(_ context.Context, d amqp091.Delivery) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received[queue] = append(received[queue], d.RoutingKey)
+			return nil
+		}); err != nil {
+			t.Fatalf("ConsumeMessages(%s): %v", queue, err)
+		}
+	}
+
+	ctx := context.Background()
+	for _, key := range []string{contracts.TripEventCreated, contracts.TripEventNoDriversFound} {
+		if err := b.PublishMessage(ctx, key, contracts.AmqpMessage{OwnerID: "u1", Data: tripCreatedData}); err != nil {
+			t.Fatalf("PublishMessage(%s): %v", key, err)
+		}
+	}
+	waitIdle(t, b)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for queue, want := range map[string]string{
+		FindAvailableDriversQueue:       contracts.TripEventCreated,
+		NotifyDriverNoDriversFoundQueue: contracts.TripEventNoDriversFound,
+	} {
+		if got := received[queue]; len(got) != 1 || got[0] != want {
+			t.Fatalf("queue %s received %v, want [%s]", queue, got, want)
+		}
+	}
+}
+
+func TestInMemoryBroker_RejectsPayloadsFailingTheSchema(t *testing.T) {
+	b := newTestBroker(t)
+
+	err := b.PublishMessage(context.Background(), contracts.DriverCmdTripAccept, contracts.AmqpMessage{
+		OwnerID: "d1",
+		Data:    json.RawMessage(`{"tripID":"t1","riderID":"u1"}`),
+	})
+	if err == nil || !strings.Contains(err.Error(), "driverID is required") {
+		t.Fatalf("expected missing driverID to be rejected, got %v", err)
+	}
+	if n := len(b.Messages(DriverTripResponseQueue)); n != 0 {
+		t.Fatalf("rejected payload must not be enqueued, got %d", n)
+	}
+}
+
+func TestInMemoryBroker_DecodesProtobufForJSONHandlers(t *testing.T) {
+	b := newTestBroker(t)
+
+	if err := b.PublishMessage(context.Background(), contracts.TripEventCreated, contracts.AmqpMessage{OwnerID: "u1", Data: tripCreatedData}); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	queued := b.Messages(FindAvailableDriversQueue)
+	if len(queued) != 1 || queued[0].ContentType != ContentTypeProtobuf {
+		t.Fatalf("expected one protobuf message, got %v", queued)
+	}
+
+	message, err := DecodeMessage(queued[0])
+	if err != nil {
+		t.Fatalf("DecodeMessage: %v", err)
+	}
+	var event TripEventData
+	if err := json.Unmarshal(message.Data, &event); err != nil {
+		t.Fatalf("transcoded data is not JSON: %v", err)
+	}
+	if message.OwnerID != "u1" || event.Trip.GetId() != "t1" || event.Trip.GetUserID() != "u1" {
+		t.Fatalf("unexpected decoded message: owner=%s trip=%v", message.OwnerID, event.Trip)
+	}
+}
+
+func TestInMemoryBroker_RetriesThenDeadLetters(t *testing.T) {
+	b := newTestBroker(t)
+
+	var attempts atomic.Int32
+	if err := b.ConsumeMessages(FindAvailableDriversQueue, func(context.Context, amqp091.Delivery) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	}); err != nil {
+		t.Fatalf("ConsumeMessages: %v", err)
+	}
+
+	if err := b.PublishMessage(context.Background(), contracts.TripEventCreated, contracts.AmqpMessage{OwnerID: "u1", Data: tripCreatedData}); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	waitIdle(t, b)
+
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("expected 1 attempt + 2 retries, got %d", got)
+	}
+
+	dead := b.Messages(DeadLetterQueue)
+	if len(dead) != 1 {
+		t.Fatalf("expected 1 message in %s, got %d", DeadLetterQueue, len(dead))
+	}
+	headers := dead[0].Headers
+	if headers["x-death-reason"] != "boom" {
+		t.Fatalf("unexpected x-death-reason %v", headers["x-death-reason"])
+	}
+	if headers["x-origin-exchange"] != TripExchange || headers["x-original-routing-key"] != contracts.TripEventCreated {
+		t.Fatalf("missing origin headers: %v", headers)
+	}
+}
+
+func TestInMemoryBroker_BreakerPausesOnTransientFailures(t *testing.T) {
+	b := newTestBroker(t)
+	b.SetBreakerConfig(BreakerConfig{FailureThreshold: 2, OpenTimeout: 50 * time.Millisecond})
+
+	var healthy atomic.Bool
+	var handled atomic.Int32
+	if err := b.ConsumeMessages(FindAvailableDriversQueue, func(context.Context, amqp091.Delivery) error {
+		if !healthy.Load() {
+			return Transient("mongodb", errors.New("connection refused"))
+		}
+		handled.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("ConsumeMessages: %v", err)
+	}
+
+	if err := b.PublishMessage(context.Background(), contracts.TripEventCreated, contracts.AmqpMessage{OwnerID: "u1", Data: tripCreatedData}); err != nil {
+		t.Fatalf("PublishMessage: %v", err)
+	}
+	waitIdle(t, b)
+
+	breaker := b.breakers.forQueue(FindAvailableDriversQueue)
+	if state := breaker.State(); state != BreakerOpen {
+		t.Fatalf("breaker state = %s, want open", state)
+	}
+	if n := len(b.Messages(FindAvailableDriversQueue)); n != 1 {
+		t.Fatalf("expected the message to stay in the queue, got %d", n)
+	}
+	if n := len(b.Messages(DeadLetterQueue)); n != 0 {
+		t.Fatalf("transient failures must not be dead-lettered, got %d", n)
+	}
+
+	healthy.Store(true)
+	deadline := time.Now().Add(5 * time.Second)
+	for handled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitIdle(t, b)
+
+	if handled.Load() != 1 {
+		t.Fatalf("expected the half-open probe to process the message")
+	}
+	if state := breaker.State(); state != BreakerClosed {
+		t.Fatalf("breaker state = %s, want closed", state)
+	}
+}
+
+func TestInMemoryBroker_DeliversHigherPriorityFirst(t *testing.T) {
+	b := newTestBroker(t)
+	ctx := context.Background()
+
+	// No consumer yet, so the queue order can be inspected directly.
+	publish := func(owner string, opts ...PublishOption) {
+		t.Helper()
+		if err := b.PublishMessage(ctx, contracts.DriverCmdTripAccept, contracts.AmqpMessage{OwnerID: owner, Data: tripAcceptData}, opts...); err != nil {
+			t.Fatalf("PublishMessage: %v", err)
+		}
+	}
+	publish("bulk", WithPriority(PriorityBulk))
+	publish("normal", WithPriority(PriorityNormal))
+	publish("dispatch-1")
+	publish("dispatch-2")
+
+	var got []string
+	for _, d := range b.Messages(DriverTripResponseQueue) {
+		var envelope contracts.AmqpMessage
+		if err := json.Unmarshal(d.Body, &envelope); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		got = append(got, envelope.OwnerID)
+	}
+
+	want := []string{"dispatch-1", "dispatch-2", "normal", "bulk"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("queue order = %v, want %v", got, want)
+	}
+}
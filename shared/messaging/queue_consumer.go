@@ -13,7 +13,7 @@ import (
 )
 
 type QueueConsumer struct {
-	rb        *RabbitMQ
+	rb        Subscriber
 	connMgr   *RedisConnectionManager
 	queueName string
 }
@@ -37,8 +37,14 @@ var wsCanonicalizers = map[string]wsCanonicalizer{
 var wsTopicResolvers = map[string]wsTopicResolver{
 	contracts.TripEventDriverNotInterested: resolveTopicFromWrappedTrip,
 	contracts.TripEventNoDriversFound:      resolveTopicFromWrappedTrip,
-	contracts.TripEventDriverAssigned:      resolveTopicFromFlatTrip,
-	contracts.PaymentEventSessionCreated:   resolveTopicFromPayment,
+	contracts.TripEventDriverAssigned:      resolveTopicFromWrappedTrip,
+	contracts.PaymentEventSessionCreated:   resolveTopicFromTripID,
+	contracts.DriverEventETAChanged:        resolveTopicFromTripID,
+	contracts.TripEventDriverNearby:        resolveTopicFromTripID,
+	contracts.TripEventDriverAtPickup:      resolveTopicFromTripID,
+	contracts.TripEventDriverAtDropoff:     resolveTopicFromTripID,
+	contracts.TripEventDriverZoneEntered:   resolveTopicFromTripID,
+	contracts.TripEventDriverZoneExited:    resolveTopicFromTripID,
 }
 
 func sanitizeTripForWS(trip *pb.Trip) error {
@@ -63,34 +69,20 @@ func sanitizeTripForWS(trip *pb.Trip) error {
 	return nil
 }
 
+// canonicalTripJSON unwraps the trip from a TripEventData payload; clients
+// receive the trip itself as frame data.
 func canonicalTripJSON(payload json.RawMessage) (json.RawMessage, error) {
-	var wrapped struct {
-		Trip *pb.Trip `json:"trip"`
+	var event TripEventData
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("unable to parse trip payload: %w", err)
 	}
-	if err := json.Unmarshal(payload, &wrapped); err == nil && wrapped.Trip != nil {
-		if err := sanitizeTripForWS(wrapped.Trip); err != nil {
-			return nil, err
-		}
-		b, err := json.Marshal(wrapped.Trip)
-		if err != nil {
-			return nil, err
-		}
-		return b, nil
+	if err := event.Validate(); err != nil {
+		return nil, err
 	}
-
-	var trip pb.Trip
-	if err := json.Unmarshal(payload, &trip); err == nil && trip.Id != "" {
-		if err := sanitizeTripForWS(&trip); err != nil {
-			return nil, err
-		}
-		b, err := json.Marshal(&trip)
-		if err != nil {
-			return nil, err
-		}
-		return b, nil
+	if err := sanitizeTripForWS(event.Trip); err != nil {
+		return nil, err
 	}
-
-	return nil, fmt.Errorf("unable to parse trip payload")
+	return json.Marshal(event.Trip)
 }
 
 func canonicalizeTripPayload(payload json.RawMessage) (any, error) {
@@ -106,8 +98,8 @@ func canonicalizePaymentSessionCreated(payload json.RawMessage) (any, error) {
 	if err := json.Unmarshal(payload, &payment); err != nil {
 		return nil, fmt.Errorf("invalid payment session payload: %w", err)
 	}
-	if payment.TripID == "" || payment.SessionID == "" {
-		return nil, fmt.Errorf("invalid payment session payload: tripID and sessionID are required")
+	if err := payment.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid payment session payload: %w", err)
 	}
 	return payment, nil
 }
@@ -117,6 +109,9 @@ func canonicalizeDriverLocation(payload json.RawMessage) (any, error) {
 	if err := json.Unmarshal(payload, &location); err != nil {
 		return nil, fmt.Errorf("invalid driver location payload: %w", err)
 	}
+	if err := location.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid driver location payload: %w", err)
+	}
 	return location, nil
 }
 
@@ -157,22 +152,12 @@ func resolveTopicFromWrappedTrip(payload json.RawMessage) string {
 	return ""
 }
 
-func resolveTopicFromFlatTrip(payload json.RawMessage) string {
-	var trip struct {
-		ID string `json:"id"`
-	}
-	if err := json.Unmarshal(payload, &trip); err == nil {
-		return tripTopic(trip.ID)
-	}
-	return ""
-}
-
-func resolveTopicFromPayment(payload json.RawMessage) string {
-	var payment struct {
+func resolveTopicFromTripID(payload json.RawMessage) string {
+	var ref struct {
 		TripID string `json:"tripID"`
 	}
-	if err := json.Unmarshal(payload, &payment); err == nil {
-		return tripTopic(payment.TripID)
+	if err := json.Unmarshal(payload, &ref); err == nil {
+		return tripTopic(ref.TripID)
 	}
 	return ""
 }
@@ -185,7 +170,7 @@ func deriveTripTopic(routingKey string, payload json.RawMessage) string {
 	return resolver(payload)
 }
 
-func NewQueueConsumer(rb *RabbitMQ, connMgr *RedisConnectionManager, queueName string) *QueueConsumer {
+func NewQueueConsumer(rb Subscriber, connMgr *RedisConnectionManager, queueName string) *QueueConsumer {
 	return &QueueConsumer{
 		rb:        rb,
 		connMgr:   connMgr,
@@ -195,9 +180,9 @@ func NewQueueConsumer(rb *RabbitMQ, connMgr *RedisConnectionManager, queueName s
 
 func (qc *QueueConsumer) Start() error {
 	return qc.rb.ConsumeMessages(qc.queueName, func(ctx context.Context, msg amqp091.Delivery) error {
-		var amqpMsg contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &amqpMsg); err != nil {
-			log.Println("Failed to unmarshal AMQP message:", err)
+		amqpMsg, err := DecodeMessage(msg)
+		if err != nil {
+			log.Println("Failed to decode AMQP message:", err)
 			return err
 		}
 
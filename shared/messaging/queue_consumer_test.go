@@ -2,6 +2,7 @@ package messaging
 
 import (
 	"encoding/json"
+	"slices"
 	"testing"
 
 	"ride-sharing/shared/contracts"
@@ -43,6 +44,14 @@ func TestCanonicalizeWSData_TripEnvelopeStripsIncompleteDriver(t *testing.T) {
 	}
 }
 
+func TestCanonicalizeWSData_RejectsFlatTrip(t *testing.T) {
+	payload := []byte(`{"id":"trip-1","userID":"rider-1","status":"accepted"}`)
+
+	if _, _, err := canonicalizeWSData(contracts.TripEventDriverAssigned, payload); err == nil {
+		t.Fatalf("expected a trip not wrapped in TripEventData to be rejected")
+	}
+}
+
 func TestCanonicalizeWSData_SkipsChatDelivered(t *testing.T) {
 	data, skip, err := canonicalizeWSData(contracts.ChatEventDelivered, []byte(`{"messageID":"m1","tripID":"t1"}`))
 	if err != nil {
@@ -65,3 +74,20 @@ func TestCanonicalizeWSData_RejectsInvalidPaymentPayload(t *testing.T) {
 		t.Fatalf("expected skip=false for invalid payment payload")
 	}
 }
+
+func TestGeofenceEvents_ReachRiderTopicAndTimeline(t *testing.T) {
+	payload := []byte(`{"tripID":"trip-1","driverID":"driver-1","fence":"pickup","latitude":52.52,"longitude":13.405,"at":1}`)
+
+	for _, key := range []string{contracts.TripEventDriverNearby, contracts.TripEventDriverAtPickup, contracts.TripEventDriverZoneExited} {
+		if topic := deriveTripTopic(key, payload); topic != "trip:trip-1" {
+			t.Fatalf("%s topic = %q, want trip:trip-1", key, topic)
+		}
+		var queues []string
+		for _, q := range DefaultTopology.QueuesFor(TripExchange, key) {
+			queues = append(queues, q.Name)
+		}
+		if !slices.Contains(queues, NotifyRiderGeofenceQueue) || !slices.Contains(queues, TripTimelineQueue) {
+			t.Fatalf("%s is routed to %v, want the rider and the trip timeline", key, queues)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package messaging
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"ride-sharing/shared/contracts"
@@ -10,8 +9,10 @@ import (
 	"ride-sharing/shared/tracing"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -21,13 +22,20 @@ const (
 )
 
 type RabbitMQ struct {
-	conn    *amqp.Connection
-	Channel *amqp.Channel
-	uri     string
-	mu      sync.Mutex
+	conn     *amqp.Connection
+	Channel  *amqp.Channel
+	uri      string
+	topology Topology
+	breakers *breakerRegistry
+	mu       sync.Mutex
+	tags     atomic.Uint64
 }
 
 func NewRabbitMQ(uri string) (*RabbitMQ, error) {
+	if err := DefaultTopology.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid messaging topology: %w", err)
+	}
+
 	conn, err := amqp.Dial(uri)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %v", err)
@@ -40,9 +48,11 @@ func NewRabbitMQ(uri string) (*RabbitMQ, error) {
 	}
 
 	rmq := &RabbitMQ{
-		conn:    conn,
-		Channel: ch,
-		uri:     uri,
+		conn:     conn,
+		Channel:  ch,
+		uri:      uri,
+		topology: DefaultTopology,
+		breakers: newBreakerRegistry(DefaultBreakerConfig()),
 	}
 
 	if err := rmq.setupExchangesAndQueues(); err != nil {
@@ -56,115 +66,228 @@ func NewRabbitMQ(uri string) (*RabbitMQ, error) {
 
 type MessageHandler func(context.Context, amqp.Delivery) error
 
+type publishFunc func(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error
+
+// Publisher publishes envelope messages on the trip exchange.
+type Publisher interface {
+	PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage, opts ...PublishOption) error
+}
+
+// PublishOption adjusts a message before it is published.
+type PublishOption func(*amqp.Publishing)
+
+// WithPriority overrides the routing key's default priority class.
+func WithPriority(priority uint8) PublishOption {
+	return func(msg *amqp.Publishing) {
+		msg.Priority = priority
+	}
+}
+
+// newPublishing validates the payload against the routingKey's event schema and
+// builds the persistent message, encoded as negotiated by topology and with
+// its default priority, then applies opts. Every message gets a unique ID and
+// its publish time so consumers can deduplicate and order redeliveries.
+func newPublishing(topology Topology, routingKey string, message contracts.AmqpMessage, opts []PublishOption) (amqp.Publishing, error) {
+	if err := ValidatePayload(routingKey, message.Data); err != nil {
+		return amqp.Publishing{}, err
+	}
+
+	contentType := topology.ContentTypeFor(routingKey)
+	body, err := encodeMessage(contentType, routingKey, message)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+
+	msg := amqp.Publishing{
+		DeliveryMode: amqp.Persistent,
+		ContentType:  contentType,
+		Priority:     topology.PriorityFor(routingKey),
+		MessageId:    uuid.NewString(),
+		Timestamp:    time.Now(),
+		Body:         body,
+	}
+	for _, opt := range opts {
+		opt(&msg)
+	}
+	return msg, nil
+}
+
+// Subscriber consumes a queue, retrying failed deliveries with backoff and
+// dead-lettering them once the retries are exhausted.
+type Subscriber interface {
+	ConsumeMessages(queueName string, handler MessageHandler) error
+}
+
+// Broker is implemented by RabbitMQ and by the InMemoryBroker used in tests.
+type Broker interface {
+	Publisher
+	Subscriber
+}
+
 func (r *RabbitMQ) ConsumeMessages(queueName string, handler MessageHandler) error {
+	consumerTag := fmt.Sprintf("%s-%d", queueName, r.tags.Add(1))
+	msgs, err := r.consume(queueName, consumerTag)
+	if err != nil {
+		return err
+	}
+
+	go r.consumeLoop(queueName, consumerTag, msgs, handler, r.breakers.forQueue(queueName))
+	return nil
+}
+
+func (r *RabbitMQ) consume(queueName, consumerTag string) (<-chan amqp.Delivery, error) {
 	err := r.Channel.Qos(
 		1,     // prefetchCount: Limit to 1 unacknowledged message per consumer
 		0,     // prefetchSize: No specific limit on message size
 		false, // global: Apply prefetchCount to each consumer individually
 	)
 	if err != nil {
-		return fmt.Errorf("failed to set QoS: %v", err)
+		return nil, fmt.Errorf("failed to set QoS: %v", err)
 	}
 
-	msgs, err := r.Channel.Consume(
-		queueName, // queue
-		"",        // consumer
-		false,     // auto-ack
-		false,     // exclusive
-		false,     // no-local
-		false,     // no-wait
-		nil,       // args
+	return r.Channel.Consume(
+		queueName,   // queue
+		consumerTag, // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
 	)
-	if err != nil {
-		return err
-	}
+}
 
-	go func() {
+// consumeLoop processes deliveries until the channel closes. When the queue's
+// breaker opens the consumer is cancelled so the remaining messages stay in
+// the queue; once the breaker turns half-open consumption resumes with a probe.
+func (r *RabbitMQ) consumeLoop(queueName, consumerTag string, msgs <-chan amqp.Delivery, handler MessageHandler, breaker *queueBreaker) {
+	for {
 		for msg := range msgs {
+			if breaker.State() == BreakerOpen {
+				// delivered before the cancel took effect, hand it back untouched
+				_ = msg.Nack(false, true)
+				continue
+			}
+
 			if err := tracing.TracedConsumer(msg, func(ctx context.Context, d amqp.Delivery) error {
-				log.Printf("Received a message: %s", msg.Body)
-
-				cfg := retry.DefaultConfig()
-				err := retry.WithBackoff(ctx, cfg, handler, d)
-				if err != nil {
-					log.Printf("Message processing failed after %d retries for message ID: %s, err: %v", cfg.MaxRetries, d.MessageId, err)
-
-					// Add failure context before sending to the DLQ.
-					// We must republish explicitly because mutating delivery headers and rejecting
-					// does not persist those custom headers into the dead-lettered copy.
-					headers := amqp.Table{}
-					if d.Headers != nil {
-						headers = d.Headers
-					}
+				return handleDelivery(ctx, d, handler, retry.DefaultConfig(), r.publish, breaker)
+			}); err != nil {
+				log.Printf("Error processing message: %v", err)
+			}
 
-					originExchange := d.Exchange
-					if originExchange == "" {
-						originExchange = TripExchange
-					}
+			if breaker.State() == BreakerOpen {
+				log.Printf("Pausing consumer %s until the circuit half-opens", consumerTag)
+				if err := r.Channel.Cancel(consumerTag, false); err != nil {
+					log.Printf("ERROR: Failed to cancel consumer %s: %v", consumerTag, err)
+				}
+			}
+		}
 
-					headers["x-death-reason"] = err.Error()
-					headers["x-origin-exchange"] = originExchange
-					headers["x-original-routing-key"] = d.RoutingKey
-					headers["x-retry-count"] = cfg.MaxRetries
-
-					dlqMsg := amqp.Publishing{
-						Headers:       headers,
-						DeliveryMode:  amqp.Persistent,
-						ContentType:   d.ContentType,
-						Body:          d.Body,
-						MessageId:     d.MessageId,
-						CorrelationId: d.CorrelationId,
-						Type:          d.Type,
-						Timestamp:     time.Now(),
-					}
+		if breaker.State() != BreakerOpen {
+			return // channel closed for another reason
+		}
+		_ = breaker.WaitHalfOpen(context.Background())
 
-					if dlqMsg.ContentType == "" {
-						dlqMsg.ContentType = "application/json"
-					}
+		for {
+			var err error
+			if msgs, err = r.consume(queueName, consumerTag); err == nil {
+				break
+			}
+			log.Printf("ERROR: Failed to resume consumer %s: %v", consumerTag, err)
+			time.Sleep(5 * time.Second)
+		}
+		log.Printf("Resumed consumer %s", consumerTag)
+	}
+}
 
-					if pubErr := r.publish(ctx, DeadLetterExchange, d.RoutingKey, dlqMsg); pubErr != nil {
-						log.Printf("ERROR: Failed to republish failed message to DLQ, falling back to reject. message ID: %s, err: %v", d.MessageId, pubErr)
-						_ = d.Reject(false) // reject without requeueing, since we can't even get it to the DLQ
-						return err
-					}
+// handleDelivery runs handler with retries and acks the delivery. When every
+// retry fails the message is republished to the dead letter exchange with the
+// failure context attached, so the DLQ worker can route it back later. Transient
+// failures (see TransientDependency) are requeued instead and counted by the
+// queue's breaker. It is shared by every Subscriber implementation to keep
+// retry/DLQ semantics equal.
+func handleDelivery(ctx context.Context, d amqp.Delivery, handler MessageHandler, cfg retry.Config, publish publishFunc, breaker *queueBreaker) error {
+	log.Printf("Received a message: %s", printableBody(d))
+
+	err := retry.WithBackoff(ctx, cfg, handler, d)
+	if dependency, transient := TransientDependency(err); transient {
+		state := breaker.Failure(dependency)
+		annotateBreakerState(ctx, state)
+		log.Printf("Transient %s failure for message ID: %s, requeueing (breaker %s): %v", dependency, d.MessageId, state, err)
+		if nackErr := d.Nack(false, true); nackErr != nil {
+			log.Printf("ERROR: Failed to Nack message: %v. Message body: %s", nackErr, printableBody(d))
+		}
+		return err
+	}
 
-					if ackErr := d.Ack(false); ackErr != nil {
-						log.Printf("ERROR: Failed to Ack message after DLQ republish: %v. Message body: %s", ackErr, d.Body)
-					}
-					return err
-				}
+	if err != nil {
+		log.Printf("Message processing failed after %d retries for message ID: %s, err: %v", cfg.MaxRetries, d.MessageId, err)
+
+		// Add failure context before sending to the DLQ.
+		// We must republish explicitly because mutating delivery headers and rejecting
+		// does not persist those custom headers into the dead-lettered copy.
+		headers := amqp.Table{}
+		if d.Headers != nil {
+			headers = d.Headers
+		}
 
-				// Only Ack if the handler succeeds
-				if ackErr := msg.Ack(false); ackErr != nil {
-					// Ack(false) means we're acknowledging this single message. If true it would acknowledge all messages up to and including this one.
-					log.Printf("ERROR: Failed to Ack message: %v. Message body: %s", ackErr, msg.Body)
-				}
+		originExchange := d.Exchange
+		if originExchange == "" {
+			originExchange = TripExchange
+		}
 
-				return nil
-			}); err != nil {
-				log.Printf("Error processing message: %v", err)
-			}
+		headers["x-death-reason"] = err.Error()
+		headers["x-origin-exchange"] = originExchange
+		headers["x-original-routing-key"] = d.RoutingKey
+		headers["x-retry-count"] = cfg.MaxRetries
+
+		dlqMsg := amqp.Publishing{
+			Headers:       headers,
+			DeliveryMode:  amqp.Persistent,
+			Priority:      d.Priority,
+			ContentType:   d.ContentType,
+			Body:          d.Body,
+			MessageId:     d.MessageId,
+			CorrelationId: d.CorrelationId,
+			Type:          d.Type,
+			Timestamp:     time.Now(),
+		}
+
+		if dlqMsg.ContentType == "" {
+			dlqMsg.ContentType = ContentTypeJSON
+		}
+
+		if pubErr := publish(ctx, DeadLetterExchange, d.RoutingKey, dlqMsg); pubErr != nil {
+			log.Printf("ERROR: Failed to republish failed message to DLQ, falling back to reject. message ID: %s, err: %v", d.MessageId, pubErr)
+			_ = d.Reject(false) // reject without requeueing, since we can't even get it to the DLQ
+			return err
+		}
+
+		if ackErr := d.Ack(false); ackErr != nil {
+			log.Printf("ERROR: Failed to Ack message after DLQ republish: %v. Message body: %s", ackErr, printableBody(d))
 		}
-	}()
+		return err
+	}
+
+	breaker.Success()
+	annotateBreakerState(ctx, BreakerClosed)
+
+	// Only Ack if the handler succeeds
+	if ackErr := d.Ack(false); ackErr != nil {
+		// Ack(false) means we're acknowledging this single message. If true it would acknowledge all messages up to and including this one.
+		log.Printf("ERROR: Failed to Ack message: %v. Message body: %s", ackErr, printableBody(d))
+	}
 
 	return nil
 }
 
-func (r *RabbitMQ) PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage) error {
-
-	jsonMsg, err := json.Marshal(message) // converts go struct into JSON encoded [] byte
+func (r *RabbitMQ) PublishMessage(ctx context.Context, routingKey string, message contracts.AmqpMessage, opts ...PublishOption) error {
+	msg, err := newPublishing(r.topology, routingKey, message, opts)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %v", err)
+		return err
 	}
 
 	log.Printf("Publishing message in queue %s for owner %s", routingKey, message.OwnerID)
 
-	msg := amqp.Publishing{
-		DeliveryMode: amqp.Persistent,
-		ContentType:  "application/json",
-		Body:         jsonMsg,
-	}
-
 	return tracing.TracedPublisher(ctx, TripExchange, routingKey, msg, r.publish)
 }
 
@@ -227,232 +350,92 @@ func (r *RabbitMQ) publish(ctx context.Context, exchange, routingKey string, msg
 	return err
 }
 
-func (r *RabbitMQ) setupDeadLetterExchange() error {
-	// Declare the dead letter exchange
-	err := r.Channel.ExchangeDeclare(
-		DeadLetterExchange,
-		"topic",
-		true,  // durable
-		false, // auto-deleted
-		false, // internal
-		false, // no-wait
-		nil,   // arguments
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare dead letter exchange: %v", err)
-	}
-
-	// Declare the dead letter queue
-	q, err := r.Channel.QueueDeclare(
-		DeadLetterQueue,
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare dead letter queue: %v", err)
+// setupExchangesAndQueues declares every exchange, queue and binding in the
+// topology table. It is idempotent and re-run after every reconnect.
+func (r *RabbitMQ) setupExchangesAndQueues() error {
+	for _, ex := range r.topology.Exchanges {
+		if err := r.Channel.ExchangeDeclare(
+			ex.Name, // name
+			ex.Kind, // type
+			true,    // durable
+			false,   // auto-deleted
+			false,   // internal
+			false,   // no-wait
+			nil,     // arguments
+		); err != nil {
+			return fmt.Errorf("failed to declare exchange: %s: %v", ex.Name, err)
+		}
 	}
 
-	// Bind the queue to the exchange with a wildcard routing key
-	err = r.Channel.QueueBind(
-		q.Name,
-		"#", // wildcard routing key to catch all messages
-		DeadLetterExchange,
-		false,
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to bind dead letter queue: %v", err)
+	for _, q := range r.topology.Queues {
+		if err := r.declareAndBindQueue(q); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (r *RabbitMQ) setupExchangesAndQueues() error {
-	// First setup the DLQ exchange and queue
-	if err := r.setupDeadLetterExchange(); err != nil {
-		return err
-	}
-
-	err := r.Channel.ExchangeDeclare(
-		TripExchange, // name
-		"topic",      // type
-		true,         // durable
-		false,        // auto-deleted
-		false,        // internal
-		false,        // no-wait
-		nil,          // arguments
+func (r *RabbitMQ) declareAndBindQueue(spec QueueSpec) error {
+	q, err := r.Channel.QueueDeclare(
+		spec.Name,        // name
+		true,             // durable
+		false,            // delete when unused
+		false,            // exclusive
+		false,            // no-wait
+		spec.Arguments(), // per-queue policy (type, length, TTL, DLX)
 	)
 	if err != nil {
-		return fmt.Errorf("failed to declare exchange: %s: %v", TripExchange, err)
-	}
-
-	if err := r.declareAndBindQueue(
-		FindAvailableDriversQueue,
-		[]string{
-			contracts.TripEventCreated, contracts.TripEventDriverNotInterested,
-		},
-		TripExchange,
-	); err != nil {
-		return err
+		return fmt.Errorf("failed to declare queue %s: %v", spec.Name, err)
 	}
 
-	if err := r.declareAndBindQueue(
-		NotifyTripCreatedQueue,
-		[]string{contracts.TripEventCreated},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		DriverCmdTripRequestQueue,
-		[]string{contracts.DriverCmdTripRequest},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		DriverTripResponseQueue,
-		[]string{contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyDriverNoDriversFoundQueue,
-		[]string{contracts.TripEventNoDriversFound},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyDriverAssignQueue,
-		[]string{contracts.TripEventDriverAssigned},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyTripCompletedQueue,
-		[]string{contracts.TripEventCompleted},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		PaymentTripResponseQueue,
-		[]string{contracts.PaymentCmdCreateSession},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyPaymentSessionCreatedQueue,
-		[]string{contracts.PaymentEventSessionCreated},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyPaymentSuccessQueue,
-		[]string{contracts.PaymentEventSuccess},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		DriverLocationUpdateQueue,
-		[]string{contracts.DriverCmdLocation},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		DriverTripAssignedQueue,
-		[]string{contracts.TripEventDriverAssigned},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		NotifyRiderDriverLocationQueue,
-		[]string{contracts.DriverEventLocation},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		ChatCmdSendQueue,
-		[]string{contracts.ChatCmdSend},
-		TripExchange,
-	); err != nil {
-		return err
-	}
-
-	if err := r.declareAndBindQueue(
-		ChatEventDeliveredQueue,
-		[]string{contracts.ChatEventDelivered},
-		TripExchange,
-	); err != nil {
-		return err
+	for _, key := range spec.Bindings {
+		if err := r.Channel.QueueBind(
+			q.Name,        // queue name
+			key,           // routing key
+			spec.Exchange, // exchange
+			false,
+			nil,
+		); err != nil {
+			return fmt.Errorf("failed to bind queue to %s: %v", spec.Name, err)
+		}
 	}
 
-	if err := r.declareAndBindQueue(
-		NotifyTripCancelledQueue,
-		[]string{contracts.TripEventCancelled},
-		TripExchange,
-	); err != nil {
-		return err
+	if spec.Replaces != "" {
+		r.retireQueue(spec)
 	}
 
 	return nil
 }
 
-func (r *RabbitMQ) declareAndBindQueue(queueName string, messageTypes []string, exchange string) error {
-	// Add dead letter configuration
-	args := amqp.Table{
-		"x-dead-letter-exchange": DeadLetterExchange,
-	}
-
-	q, err := r.Channel.QueueDeclare(
-		queueName, // name
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		args,      // arguments with DLX config
-	)
+// retireQueue moves traffic off the queue spec replaces: it removes the old
+// queue's bindings, so new messages only reach spec.Name, and deletes it once
+// it is empty and no consumer is left on it. Until then the previous release
+// keeps draining it; the next startup or reconnect tries again. It runs on a
+// throwaway channel because the broker closes a channel on any failed
+// queue operation, e.g. when the old queue is already gone.
+func (r *RabbitMQ) retireQueue(spec QueueSpec) {
+	ch, err := r.conn.Channel()
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("Failed to open channel to retire queue %s: %v", spec.Replaces, err)
+		return
 	}
+	defer ch.Close()
 
-	for _, msg := range messageTypes {
-		if err := r.Channel.QueueBind(
-			q.Name,   // queue name
-			msg,      // routing key
-			exchange, // exchange
-			false,
-			nil,
-		); err != nil {
-			return fmt.Errorf("failed to bind queue to %s: %v", queueName, err)
+	if _, err := ch.QueueDeclarePassive(spec.Replaces, true, false, false, false, nil); err != nil {
+		return // already retired
+	}
+	for _, key := range spec.Bindings {
+		if err := ch.QueueUnbind(spec.Replaces, key, spec.Exchange, nil); err != nil {
+			log.Printf("Failed to unbind retired queue %s from %s: %v", spec.Replaces, key, err)
+			return
 		}
 	}
-
-	return nil
+	if n, err := ch.QueueDelete(spec.Replaces, true, true, false); err != nil {
+		log.Printf("Retired queue %s still has messages or consumers, keeping it until it drains: %v", spec.Replaces, err)
+	} else {
+		log.Printf("Deleted retired queue %s (replaced by %s, %d messages)", spec.Replaces, spec.Name, n)
+	}
 }
 
 func (rmq *RabbitMQ) StartDLQConsumer(ctx context.Context) error {
@@ -485,7 +468,7 @@ func (rmq *RabbitMQ) StartDLQConsumer(ctx context.Context) error {
 				reason, _ := headers["x-death-reason"].(string)
 
 				if originExchange == "" || originalRoutingKey == "" {
-					log.Printf("⚠️ Missing origin info, discarding DLQ msg: %s", msg.Body)
+					log.Printf("⚠️ Missing origin info, discarding DLQ msg: %s", printableBody(msg))
 					msg.Ack(false)
 					continue
 				}
@@ -540,6 +523,7 @@ func (r *RabbitMQ) retryDLQMessage(exchange string, routingKey string, msg amqp.
 		ContentType:  msg.ContentType,
 		Body:         msg.Body,
 		DeliveryMode: amqp.Persistent,
+		Priority:     msg.Priority,
 		MessageId:    msg.MessageId,
 		Timestamp:    time.Now(),
 	}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,10 +29,24 @@ var (
 )
 
 const (
-	userEventStreamTTL    = 2 * time.Hour
-	userEventStreamMaxLen = int64(500)
+	// userEventStreamRetention is how long a user-direct message can be
+	// resumed. Entries older than this are trimmed on every append, and the
+	// stream of an idle user expires after the same period.
+	userEventStreamRetention = 2 * time.Hour
+	userEventStreamPageSize  = int64(100)
+	// firstConnectReplayWindow is how far back a socket connecting without a
+	// lastEventID is replayed, so a page load still picks up a trip offer or
+	// assignment published just before it connected.
+	firstConnectReplayWindow = 2 * time.Minute
 )
 
+// ephemeralWSTypes are superseded by the next message of the same type, so
+// they are delivered live only and never stored for resumption.
+var ephemeralWSTypes = map[string]struct{}{
+	contracts.DriverCmdLocation:   {},
+	contracts.DriverEventLocation: {},
+}
+
 // RedisConnectionManager manages WebSocket connections across multiple gateway
 // nodes using Redis Pub/Sub for cross-node message delivery.
 //
@@ -41,13 +57,19 @@ const (
 //     by publishing to the "room:{roomID}" Redis channel.
 //
 // User-direct model (for system/trip/payment events):
-//   - Each user has a dedicated "user:{userID}:events" Redis channel.
-//   - All sockets of the same user on this node receive the message.
+//   - Every message is first appended to the user's "user:{userID}:stream"
+//     Redis stream and carries the entry ID as its WSMessage.ID.
+//   - It is then published on the user's "user:{userID}:events" channel, so
+//     all sockets of the user on every node receive it.
+//   - A socket that connects with a lastEventID first replays the stream
+//     entries after that ID; entries expire by retention, not on delivery.
 type RedisConnectionManager struct {
 	localCM     *ConnectionManager
 	rdb         *redis.Client
 	ctx         context.Context
 	userSubs    map[string]*redis.PubSub       // userID  → user-direct channel sub
+	socketUsers map[string]string              // socketID → userID, kept after a stale drop
+	viewers     map[string]map[string]struct{} // userID  → set of local viewer socketIDs
 	roomSubs    map[string]*redis.PubSub       // roomID  → room broadcast sub
 	socketRooms map[string]map[string]struct{} // socketID → set of roomIDs
 	roomSockets map[string]map[string]struct{} // roomID   → set of local socketIDs
@@ -58,12 +80,16 @@ type RedisConnectionManager struct {
 	mu          sync.Mutex
 }
 
-func NewRedisConnectionManager(rdb *redis.Client) *RedisConnectionManager {
+// NewRedisConnectionManager creates the manager; cfg bounds the outbound queue
+// of every local socket.
+func NewRedisConnectionManager(rdb *redis.Client, cfg SendQueueConfig) *RedisConnectionManager {
 	return &RedisConnectionManager{
-		localCM:     NewConnectionManager(),
+		localCM:     NewConnectionManager(cfg),
 		rdb:         rdb,
 		ctx:         context.Background(),
 		userSubs:    make(map[string]*redis.PubSub),
+		socketUsers: make(map[string]string),
+		viewers:     make(map[string]map[string]struct{}),
 		roomSubs:    make(map[string]*redis.PubSub),
 		socketRooms: make(map[string]map[string]struct{}),
 		roomSockets: make(map[string]map[string]struct{}),
@@ -71,14 +97,47 @@ func NewRedisConnectionManager(rdb *redis.Client) *RedisConnectionManager {
 	}
 }
 
-// Add registers a new WebSocket connection. socketID must be unique per
+// Add registers a new client connection. socketID must be unique per
 // connection (e.g. a UUID generated at upgrade time). The node subscribes to the
 // user's direct Redis channel once — subsequent connections for the same user
 // reuse the existing subscription.
-func (rcm *RedisConnectionManager) Add(userID, socketID string, conn *websocket.Conn) {
-	rcm.localCM.Add(socketID, userID, conn)
+//
+// lastEventID is the stream ID of the last message the client received. When
+// set, the socket first receives every retained message after it ("0" replays
+// the whole retained stream); when empty, it first receives the messages of
+// the last firstConnectReplayWindow.
+func (rcm *RedisConnectionManager) Add(userID, socketID string, conn Conn, lastEventID string) {
+	rcm.add(userID, userID, socketID, conn, lastEventID)
+	log.Printf("RedisConnectionManager: added socket %s for user %s", socketID, userID)
+}
+
+// AddViewer registers a socket that receives userID's messages, replayed as in
+// Add, without being one of the user's sockets: it is owned by ownerID, so
+// HasLocalUser and LeaveUserFromRoom for userID ignore it. conn decides which
+// of the user's messages the viewer sees.
+func (rcm *RedisConnectionManager) AddViewer(userID, ownerID, socketID string, conn Conn, lastEventID string) {
+	rcm.add(userID, ownerID, socketID, conn, lastEventID)
+	log.Printf("RedisConnectionManager: added socket %s of %s viewing user %s", socketID, ownerID, userID)
+}
+
+func (rcm *RedisConnectionManager) add(userID, ownerID, socketID string, conn Conn, lastEventID string) {
+	if lastEventID != "" && !validStreamID(lastEventID) {
+		log.Printf("Ignoring invalid lastEventID %q for user %s", lastEventID, userID)
+		lastEventID = ""
+	}
+	if lastEventID == "" {
+		lastEventID = strconv.FormatInt(time.Now().Add(-firstConnectReplayWindow).UnixMilli(), 10)
+	}
+	rcm.localCM.Add(socketID, ownerID, conn, lastEventID)
 
 	rcm.mu.Lock()
+	rcm.socketUsers[socketID] = userID
+	if ownerID != userID {
+		if rcm.viewers[userID] == nil {
+			rcm.viewers[userID] = make(map[string]struct{})
+		}
+		rcm.viewers[userID][socketID] = struct{}{}
+	}
 	if _, already := rcm.userSubs[userID]; !already {
 		pubsub := rcm.rdb.Subscribe(rcm.ctx, "user:"+userID+":events")
 		rcm.userSubs[userID] = pubsub
@@ -86,19 +145,18 @@ func (rcm *RedisConnectionManager) Add(userID, socketID string, conn *websocket.
 	}
 	rcm.mu.Unlock()
 
-	// Always replay the user's pending stream on a fresh socket so a refresh
-	// can recover rides or other missed direct events even when the Redis pubsub
-	// subscription was already active on this node.
-	go rcm.replayUserStream(userID)
-
-	log.Printf("RedisConnectionManager: added socket %s for user %s", socketID, userID)
+	// Live messages published while the replay runs are queued on the socket
+	// and written after it, so the client sees the stream in order.
+	go rcm.replayUserStream(userID, socketID, lastEventID)
 }
 
 func userEventStreamKey(userID string) string {
 	return "user:" + userID + ":stream"
 }
 
-func (rcm *RedisConnectionManager) persistUserMessage(userID string, msg contracts.WSMessage) error {
+// appendUserMessage adds msg to the user's stream, trimming entries older than
+// the retention period, and returns the entry ID.
+func (rcm *RedisConnectionManager) appendUserMessage(userID string, msg contracts.WSMessage) (string, error) {
 	ctx, span := rcm.tracer.Start(rcm.ctx, "rcm.stream.persist",
 		trace.WithAttributes(
 			attribute.String("messaging.user_id", userID),
@@ -111,113 +169,150 @@ func (rcm *RedisConnectionManager) persistUserMessage(userID string, msg contrac
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return err
+		return "", err
 	}
 
 	key := userEventStreamKey(userID)
-	if err := rcm.rdb.XAdd(ctx, &redis.XAddArgs{
+	minID := strconv.FormatInt(time.Now().Add(-userEventStreamRetention).UnixMilli(), 10)
+	id, err := rcm.rdb.XAdd(ctx, &redis.XAddArgs{
 		Stream: key,
-		MaxLen: userEventStreamMaxLen,
+		MinID:  minID,
 		Approx: true,
 		Values: map[string]any{
 			"payload": string(payload),
 		},
-	}).Err(); err != nil {
+	}).Result()
+	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return err
+		return "", err
 	}
 
-	_ = rcm.rdb.Expire(ctx, key, userEventStreamTTL).Err()
+	_ = rcm.rdb.Expire(ctx, key, userEventStreamRetention).Err()
 	total := rcm.persisted.Add(1)
 	span.SetAttributes(
-		attribute.Int("stream.persisted.count", 1),
+		attribute.String("stream.entry_id", id),
 		attribute.Int64("stream.persisted.total", int64(total)),
 	)
-	log.Printf("stream persisted count=1 total=%d user=%s stream=%s", total, userID, key)
-	return nil
+	return id, nil
+}
+
+// LastStreamID returns the ID of the newest retained message of userID, or
+// "0" if none is retained. A client resuming from it receives every message
+// appended later.
+func (rcm *RedisConnectionManager) LastStreamID(ctx context.Context, userID string) (string, error) {
+	entries, err := rcm.rdb.XRevRangeN(ctx, userEventStreamKey(userID), "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the stream of user %s: %w", userID, err)
+	}
+	if len(entries) == 0 {
+		return "0", nil
+	}
+	return entries[0].ID, nil
 }
 
-func (rcm *RedisConnectionManager) replayUserStream(userID string) {
+// replayUserStream writes the retained stream entries after cursor to one
+// socket, page by page, then releases the live messages queued meanwhile.
+func (rcm *RedisConnectionManager) replayUserStream(userID, socketID, cursor string) {
 	ctx, span := rcm.tracer.Start(rcm.ctx, "rcm.stream.replay",
 		trace.WithAttributes(
 			attribute.String("messaging.user_id", userID),
 			attribute.String("messaging.destination", userEventStreamKey(userID)),
+			attribute.String("stream.cursor", cursor),
 		),
 	)
 	defer span.End()
 
 	key := userEventStreamKey(userID)
-	entries, err := rcm.rdb.XRange(ctx, key, "-", "+").Result()
-	if err != nil {
-		rcm.replayFails.Add(1)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		span.SetAttributes(attribute.Int64("stream.replay.failures.total", int64(rcm.replayFails.Load())))
-		log.Printf("Failed to read pending stream for user %s: %v", userID, err)
-		return
-	}
-	span.SetAttributes(attribute.Int("stream.replay.pending", len(entries)))
-	if len(entries) == 0 {
-		log.Printf("replayed count=0 replay failures=0 user=%s stream=%s", userID, key)
-		return
-	}
-
-	acked := make([]string, 0, len(entries))
 	replayedDelta := 0
-	failuresDelta := 0
-	for _, entry := range entries {
-		raw, ok := entry.Values["payload"]
-		if !ok {
-			acked = append(acked, entry.ID)
-			continue
-		}
-
-		payload, ok := raw.(string)
-		if !ok {
-			acked = append(acked, entry.ID)
-			continue
+	var replayErr error
+	for start := "(" + cursor; replayErr == nil; {
+		entries, err := rcm.rdb.XRangeN(ctx, key, start, "+", userEventStreamPageSize).Result()
+		if err != nil {
+			replayErr = fmt.Errorf("read stream: %w", err)
+			break
 		}
-
-		var wsMsg contracts.WSMessage
-		if err := json.Unmarshal([]byte(payload), &wsMsg); err != nil {
-			acked = append(acked, entry.ID)
-			continue
+		for _, entry := range entries {
+			payload, _ := entry.Values["payload"].(string)
+			var wsMsg contracts.WSMessage
+			if err := json.Unmarshal([]byte(payload), &wsMsg); err != nil {
+				log.Printf("Skipping malformed stream entry %s for user %s: %v", entry.ID, userID, err)
+				continue
+			}
+			wsMsg.ID = entry.ID
+			if err := rcm.localCM.Replay(socketID, wsMsg); err != nil {
+				replayErr = fmt.Errorf("deliver %s: %w", entry.ID, err)
+				break
+			}
+			replayedDelta++
 		}
-
-		if err := rcm.localCM.SendMessage(userID, wsMsg); err != nil {
-			failuresDelta++
-			rcm.replayFails.Add(1)
-			span.RecordError(err)
-			log.Printf("Replay paused for user %s due to delivery error: %v", userID, err)
+		if int64(len(entries)) < userEventStreamPageSize {
 			break
 		}
-
-		replayedDelta++
-		acked = append(acked, entry.ID)
+		start = "(" + entries[len(entries)-1].ID
 	}
 
-	if len(acked) > 0 {
-		if err := rcm.rdb.XDel(ctx, key, acked...).Err(); err != nil {
-			failuresDelta++
-			rcm.replayFails.Add(1)
-			span.RecordError(err)
-			log.Printf("Failed to ack replayed stream events for user %s: %v", userID, err)
-		}
+	if replayErr == nil {
+		replayErr = rcm.localCM.EndReplay(socketID)
 	}
 
 	totalReplayed := rcm.replayed.Add(uint64(replayedDelta))
-	totalFailures := rcm.replayFails.Load()
 	span.SetAttributes(
 		attribute.Int("stream.replayed.count", replayedDelta),
 		attribute.Int64("stream.replayed.total", int64(totalReplayed)),
-		attribute.Int("stream.replay.failures", failuresDelta),
-		attribute.Int64("stream.replay.failures.total", int64(totalFailures)),
 	)
-	if failuresDelta > 0 {
-		span.SetStatus(codes.Error, "replay completed with failures")
+	if replayErr != nil {
+		totalFailures := rcm.replayFails.Add(1)
+		span.RecordError(replayErr)
+		span.SetStatus(codes.Error, replayErr.Error())
+		span.SetAttributes(attribute.Int64("stream.replay.failures.total", int64(totalFailures)))
+		log.Printf("Replay failed for user %s socket %s after %d messages: %v", userID, socketID, replayedDelta, replayErr)
+		return
 	}
-	log.Printf("replayed count=%d total=%d replay failures=%d total_failures=%d user=%s stream=%s", replayedDelta, totalReplayed, failuresDelta, totalFailures, userID, key)
+	log.Printf("replayed count=%d total=%d user=%s socket=%s stream=%s", replayedDelta, totalReplayed, userID, socketID, key)
+}
+
+// validStreamID reports whether id is a Redis stream ID ("<ms>-<seq>" or a
+// bare "<ms>").
+func validStreamID(id string) bool {
+	_, _, ok := parseStreamID(id)
+	return ok
+}
+
+func parseStreamID(id string) (ms, seq uint64, ok bool) {
+	msPart, seqPart, hasSeq := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if hasSeq {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return ms, seq, true
+}
+
+// streamIDAfter reports whether stream ID a is strictly after b. Unparseable
+// IDs are never after anything.
+func streamIDAfter(a, b string) bool {
+	aMs, aSeq, okA := parseStreamID(a)
+	bMs, bSeq, okB := parseStreamID(b)
+	if !okA || !okB {
+		return false
+	}
+	return aMs > bMs || (aMs == bMs && aSeq > bSeq)
+}
+
+// compareStreamIDs orders two valid stream IDs like cmp.Compare.
+func compareStreamIDs(a, b string) int {
+	switch {
+	case streamIDAfter(a, b):
+		return 1
+	case streamIDAfter(b, a):
+		return -1
+	}
+	return 0
 }
 
 func (rcm *RedisConnectionManager) runUserSubscription(userID string, pubsub *redis.PubSub) {
@@ -227,19 +322,41 @@ func (rcm *RedisConnectionManager) runUserSubscription(userID string, pubsub *re
 			log.Printf("Invalid Redis message for user %s: %v", userID, err)
 			continue
 		}
-		if err := rcm.localCM.SendMessage(userID, wsMsg); err != nil {
+		rcm.mu.Lock()
+		viewers := make([]string, 0, len(rcm.viewers[userID]))
+		for socketID := range rcm.viewers[userID] {
+			viewers = append(viewers, socketID)
+		}
+		rcm.mu.Unlock()
+		if err := rcm.localCM.SendMessage(userID, wsMsg); err != nil && !(errors.Is(err, ErrConnectionNotFound) && len(viewers) > 0) {
 			log.Printf("Error delivering user message to %s: %v", userID, err)
 		}
+		for _, socketID := range viewers {
+			if err := rcm.localCM.SendToSocket(socketID, wsMsg); err != nil {
+				log.Printf("Error delivering user message of %s to viewer %s: %v", userID, socketID, err)
+			}
+		}
 	}
 }
 
 // Remove tears down all state for a socket: leaves every room it was in and,
-// when the user's last socket on this node disconnects, closes the user's Redis sub.
+// when the last socket or viewer of the user on this node disconnects, closes
+// the user's Redis sub.
 func (rcm *RedisConnectionManager) Remove(socketID string) {
-	userID := rcm.localCM.Remove(socketID)
+	rcm.localCM.Remove(socketID)
 
 	rcm.mu.Lock()
 	defer rcm.mu.Unlock()
+	// The local manager forgets a socket it dropped as stale, so the user is
+	// looked up here to still close their subscription.
+	userID := rcm.socketUsers[socketID]
+	delete(rcm.socketUsers, socketID)
+	if viewers, ok := rcm.viewers[userID]; ok {
+		delete(viewers, socketID)
+		if len(viewers) == 0 {
+			delete(rcm.viewers, userID)
+		}
+	}
 
 	// Leave all rooms this socket was in.
 	for roomID := range rcm.socketRooms[socketID] {
@@ -257,7 +374,7 @@ func (rcm *RedisConnectionManager) Remove(socketID string) {
 	delete(rcm.socketRooms, socketID)
 
 	// Close the user-direct sub when their last socket on this node is gone.
-	if userID != "" && !rcm.localCM.HasUser(userID) {
+	if userID != "" && !rcm.localCM.HasUser(userID) && len(rcm.viewers[userID]) == 0 {
 		if sub, ok := rcm.userSubs[userID]; ok {
 			sub.Close()
 			delete(rcm.userSubs, userID)
@@ -356,32 +473,33 @@ func (rcm *RedisConnectionManager) BroadcastToRoom(roomID string, msg contracts.
 	return rcm.rdb.Publish(rcm.ctx, "room:"+roomID, b).Err()
 }
 
-// SendMessage delivers a message to all sock ets of a user on this node.
-// If the user has no local connection, it falls back to publishing on their
-// Redis channel so another node can deliver it. This is the backward-compatible
-// API used by QueueConsumer and other system-notification paths.
+// SendMessage appends a message to the user's stream and publishes it, stamped
+// with its stream ID, on the user's Redis channel. Location updates skip the
+// stream and carry no ID. Every node with a socket of
+// the user (including this one) delivers it from its subscription; sockets
+// that connect later pick it up by resuming from an earlier lastEventID. This
+// is the API used by QueueConsumer and other system-notification paths.
 func (rcm *RedisConnectionManager) SendMessage(userID string, msg contracts.WSMessage) error {
-	log.Printf("Attempting to send message to user %s: %+v", userID, msg)
-	if err := rcm.localCM.SendMessage(userID, msg); err == nil {
-		return nil
+	if _, ephemeral := ephemeralWSTypes[msg.Type]; !ephemeral {
+		id, err := rcm.appendUserMessage(userID, msg)
+		if err != nil {
+			return fmt.Errorf("failed to append to stream of user %s: %w", userID, err)
+		}
+		msg.ID = id
 	}
 
 	b, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
-	log.Printf("Publishing to Redis channel for user %s", userID)
-	receivedBy, err := rcm.rdb.Publish(rcm.ctx, "user:"+userID+":events", b).Result()
-	if err != nil {
-		return err
-	}
-	if receivedBy == 0 {
-		if err := rcm.persistUserMessage(userID, msg); err != nil {
-			return err
-		}
-		log.Printf("No active subscriber for user %s; persisted message to stream", userID)
-	}
-	return nil
+	log.Printf("Publishing %s (%s) to Redis channel for user %s", msg.Type, msg.ID, userID)
+	return rcm.rdb.Publish(rcm.ctx, "user:"+userID+":events", b).Err()
+}
+
+// SendToSocket delivers a message to one local socket, e.g. a reply to a
+// frame the socket sent.
+func (rcm *RedisConnectionManager) SendToSocket(socketID string, msg contracts.WSMessage) error {
+	return rcm.localCM.SendToSocket(socketID, msg)
 }
 
 // Upgrade upgrades an HTTP connection to WebSocket.
@@ -409,6 +527,30 @@ func (rcm *RedisConnectionManager) SetTripChatPair(tripID, riderID, driverID str
 	return err
 }
 
+// TripChatPair returns the rider and driver registered for a trip, or
+// ErrTripChatPairNotFound when the pair has expired or was never set.
+func (rcm *RedisConnectionManager) TripChatPair(tripID string) (riderID, driverID string, err error) {
+	return LoadTripChatPair(rcm.ctx, rcm.rdb, tripID)
+}
+
+// LoadTripChatPair is TripChatPair for services without a connection manager,
+// such as chat-service checking who may read a trip's chat.
+func LoadTripChatPair(ctx context.Context, rdb redis.Cmdable, tripID string) (riderID, driverID string, err error) {
+	if tripID == "" {
+		return "", "", fmt.Errorf("tripID is required")
+	}
+	values, err := rdb.MGet(ctx, tripChatRiderKey(tripID), tripChatDriverKey(tripID)).Result()
+	if err != nil {
+		return "", "", err
+	}
+	riderID, riderOk := values[0].(string)
+	driverID, driverOk := values[1].(string)
+	if !riderOk || !driverOk || riderID == "" || driverID == "" {
+		return "", "", ErrTripChatPairNotFound
+	}
+	return riderID, driverID, nil
+}
+
 // ResolveTripChatPeer returns the peer userID for the sender in a trip chat.
 // Returns ErrTripChatPairNotFound when the pair has expired or was never set, and
 // ErrTripChatUnauthorized when senderID is not one of the registered participants.
@@ -416,15 +558,10 @@ func (rcm *RedisConnectionManager) ResolveTripChatPeer(tripID, senderID string)
 	if tripID == "" || senderID == "" {
 		return "", fmt.Errorf("tripID and senderID are required")
 	}
-	values, err := rcm.rdb.MGet(rcm.ctx, tripChatRiderKey(tripID), tripChatDriverKey(tripID)).Result()
+	riderID, driverID, err := rcm.TripChatPair(tripID)
 	if err != nil {
 		return "", err
 	}
-	riderID, riderOk := values[0].(string)
-	driverID, driverOk := values[1].(string)
-	if !riderOk || !driverOk || riderID == "" || driverID == "" {
-		return "", ErrTripChatPairNotFound
-	}
 	switch senderID {
 	case riderID:
 		return driverID, nil
@@ -508,3 +645,13 @@ func (rcm *RedisConnectionManager) HasLocalUser(userID string) bool {
 	}
 	return rcm.localCM.HasUser(userID)
 }
+
+// LocalSocketIDs returns the IDs of all sockets on this gateway node.
+func (rcm *RedisConnectionManager) LocalSocketIDs() []string {
+	return rcm.localCM.SocketIDs()
+}
+
+// CloseLocal closes every socket on this gateway node with a close frame.
+func (rcm *RedisConnectionManager) CloseLocal(code int, text string) {
+	rcm.localCM.CloseAll(code, text)
+}
@@ -0,0 +1,24 @@
+package messaging
+
+import "testing"
+
+func TestStreamIDAfter(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1700000000000-1", "1700000000000-0", true},
+		{"1700000000001-0", "1700000000000-9", true},
+		{"1700000000000-0", "1700000000000-0", false},
+		{"999-5", "1000-0", false},
+		{"1700000000000-0", "0", true},
+		{"1700000000000", "1700000000000-0", false},
+		{"not-an-id", "0", false},
+		{"1700000000000-0", "bogus", false},
+	}
+	for _, tt := range tests {
+		if got := streamIDAfter(tt.a, tt.b); got != tt.want {
+			t.Errorf("streamIDAfter(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrSlowConsumer is returned when a socket's send queue overflowed and the
+// socket was disconnected.
+var ErrSlowConsumer = errors.New("websocket send queue overflowed")
+
+// OverflowPolicy decides what happens to a message sent to a socket whose
+// send queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest drops the oldest queued message that has no stream ID.
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowCoalesce replaces a queued location update with the newer one
+	// for the same type and room, and otherwise drops like OverflowDropOldest.
+	OverflowCoalesce
+	// OverflowDisconnect closes the socket; the client reconnects and resumes
+	// from its lastEventID.
+	OverflowDisconnect
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowCoalesce:
+		return "coalesce"
+	case OverflowDisconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseOverflowPolicy parses the String form of a policy.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	for _, p := range []OverflowPolicy{OverflowDropOldest, OverflowCoalesce, OverflowDisconnect} {
+		if strings.EqualFold(s, p.String()) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overflow policy %q", s)
+}
+
+// SendQueueConfig bounds the outbound queue of each socket.
+type SendQueueConfig struct {
+	Size         int            // messages queued per socket before Overflow applies
+	WriteTimeout time.Duration  // deadline for writing one frame
+	Overflow     OverflowPolicy // what to do when the queue is full
+}
+
+func DefaultSendQueueConfig() SendQueueConfig {
+	return SendQueueConfig{
+		Size:         64,
+		WriteTimeout: 10 * time.Second,
+		Overflow:     OverflowCoalesce,
+	}
+}
+
+type overflowOutcome string
+
+const (
+	overflowNone         overflowOutcome = ""
+	overflowDropped      overflowOutcome = "dropped"
+	overflowCoalesced    overflowOutcome = "coalesced"
+	overflowDisconnected overflowOutcome = "disconnected"
+)
+
+// coalesceKey returns the key under which msg supersedes an older queued
+// message, or "" if it never does.
+func coalesceKey(msg contracts.WSMessage) string {
+	if _, ok := ephemeralWSTypes[msg.Type]; !ok || msg.ID != "" {
+		return ""
+	}
+	return msg.Type + "|" + msg.RoomID
+}
+
+// enqueue adds msg to the socket's queue without blocking, applying the
+// overflow policy when it is full. Messages with a stream ID are never
+// dropped: the client would advance its cursor past them. When only such
+// messages are queued, the socket must be disconnected instead so the client
+// resumes the gap. The caller holds rec.mu.
+func (rec *connRecord) enqueue(msg contracts.WSMessage) overflowOutcome {
+	if len(rec.queue) < rec.cfg.Size {
+		rec.push(msg)
+		return overflowNone
+	}
+
+	switch rec.cfg.Overflow {
+	case OverflowCoalesce:
+		if key := coalesceKey(msg); key != "" {
+			for i, queued := range rec.queue {
+				if coalesceKey(queued) == key {
+					rec.queue[i] = msg
+					return overflowCoalesced
+				}
+			}
+		}
+		fallthrough
+	case OverflowDropOldest:
+		for i, queued := range rec.queue {
+			if queued.ID == "" {
+				rec.queue = append(rec.queue[:i], rec.queue[i+1:]...)
+				rec.push(msg)
+				return overflowDropped
+			}
+		}
+	}
+	return overflowDisconnected
+}
+
+// enqueueWait adds msg to the socket's queue, waiting for room. It is used
+// for replays, which must not overflow. The caller holds rec.mu.
+func (rec *connRecord) enqueueWait(msg contracts.WSMessage) error {
+	for len(rec.queue) >= rec.cfg.Size && !rec.closed {
+		rec.cond.Wait()
+	}
+	if rec.closed {
+		return ErrConnectionNotFound
+	}
+	rec.push(msg)
+	return nil
+}
+
+func (rec *connRecord) push(msg contracts.WSMessage) {
+	rec.queue = append(rec.queue, msg)
+	rec.cond.Broadcast()
+}
+
+// writeLoop writes queued messages until the record is closed or a write
+// fails. It is the only goroutine writing data frames to the connection.
+func (rec *connRecord) writeLoop(onError func(error)) {
+	for {
+		rec.mu.Lock()
+		for len(rec.queue) == 0 && !rec.closed {
+			rec.cond.Wait()
+		}
+		if rec.closed {
+			rec.mu.Unlock()
+			return
+		}
+		msg := rec.queue[0]
+		rec.queue[0] = contracts.WSMessage{}
+		rec.queue = rec.queue[1:]
+		rec.cond.Broadcast()
+		rec.mu.Unlock()
+
+		_ = rec.conn.SetWriteDeadline(time.Now().Add(rec.cfg.WriteTimeout))
+		if err := rec.conn.WriteJSON(msg); err != nil {
+			onError(err)
+			return
+		}
+	}
+}
+
+// close stops the writer and wakes anyone waiting for queue space.
+func (rec *connRecord) close() {
+	rec.mu.Lock()
+	rec.closed = true
+	rec.queue = nil
+	rec.cond.Broadcast()
+	rec.mu.Unlock()
+}
+
+// sendQueueMetrics reports messaging.ws.send_queue.depth (messages queued on
+// all sockets of the node) and messaging.ws.send_queue.overflow (messages
+// dropped or coalesced and sockets disconnected, by outcome).
+type sendQueueMetrics struct {
+	overflow metric.Int64Counter
+}
+
+func newSendQueueMetrics(cm *ConnectionManager) *sendQueueMetrics {
+	m := &sendQueueMetrics{}
+	meter := tracing.GetMeter("ride-sharing/messaging")
+
+	overflow, err := meter.Int64Counter("messaging.ws.send_queue.overflow",
+		metric.WithDescription("WebSocket send queue overflows by outcome: dropped, coalesced or disconnected"))
+	if err != nil {
+		log.Printf("Failed to create send queue overflow counter: %v", err)
+	}
+	m.overflow = overflow
+
+	depth, err := meter.Int64ObservableGauge("messaging.ws.send_queue.depth",
+		metric.WithDescription("Messages waiting in WebSocket send queues on this node"))
+	if err != nil {
+		log.Printf("Failed to create send queue depth gauge: %v", err)
+		return m
+	}
+	if _, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		total, deepest := cm.queueDepth()
+		o.ObserveInt64(depth, int64(total), metric.WithAttributes(attribute.String("aggregation", "sum")))
+		o.ObserveInt64(depth, int64(deepest), metric.WithAttributes(attribute.String("aggregation", "max")))
+		return nil
+	}, depth); err != nil {
+		log.Printf("Failed to register send queue depth gauge: %v", err)
+	}
+	return m
+}
+
+func (m *sendQueueMetrics) recordOverflow(outcome overflowOutcome, msgType string) {
+	if m.overflow == nil {
+		return
+	}
+	m.overflow.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("outcome", string(outcome)),
+		attribute.String("messaging.message.type", msgType),
+	))
+}
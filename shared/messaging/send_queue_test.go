@@ -0,0 +1,114 @@
+package messaging
+
+import (
+	"slices"
+	"testing"
+
+	"ride-sharing/shared/contracts"
+)
+
+func TestSendQueueOverflow(t *testing.T) {
+	location := func(lat float64) contracts.WSMessage {
+		return contracts.WSMessage{Type: contracts.DriverEventLocation, Data: lat}
+	}
+	event := func(id string) contracts.WSMessage {
+		return contracts.WSMessage{ID: id, Type: contracts.TripEventDriverAssigned}
+	}
+
+	tests := []struct {
+		name   string
+		policy OverflowPolicy
+		queued []contracts.WSMessage
+		send   contracts.WSMessage
+		want   overflowOutcome
+		queue  []contracts.WSMessage
+	}{
+		{
+			name:   "coalesce replaces queued location",
+			policy: OverflowCoalesce,
+			queued: []contracts.WSMessage{event("1-0"), location(1)},
+			send:   location(2),
+			want:   overflowCoalesced,
+			queue:  []contracts.WSMessage{event("1-0"), location(2)},
+		},
+		{
+			name:   "coalesce falls back to dropping the oldest message without ID",
+			policy: OverflowCoalesce,
+			queued: []contracts.WSMessage{event("1-0"), {Type: "chat"}},
+			send:   event("2-0"),
+			want:   overflowDropped,
+			queue:  []contracts.WSMessage{event("1-0"), event("2-0")},
+		},
+		{
+			name:   "drop oldest never drops stream messages",
+			policy: OverflowDropOldest,
+			queued: []contracts.WSMessage{event("1-0"), event("2-0")},
+			send:   location(1),
+			want:   overflowDisconnected,
+			queue:  []contracts.WSMessage{event("1-0"), event("2-0")},
+		},
+		{
+			name:   "disconnect",
+			policy: OverflowDisconnect,
+			queued: []contracts.WSMessage{location(1), location(2)},
+			send:   location(3),
+			want:   overflowDisconnected,
+			queue:  []contracts.WSMessage{location(1), location(2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := newConnRecord(nil, "user-1", "", SendQueueConfig{Size: 2, Overflow: tt.policy})
+			rec.queue = append(rec.queue, tt.queued...)
+
+			if got := rec.enqueue(tt.send); got != tt.want {
+				t.Fatalf("enqueue = %q, want %q", got, tt.want)
+			}
+			if len(rec.queue) != len(tt.queue) {
+				t.Fatalf("queue = %+v, want %+v", rec.queue, tt.queue)
+			}
+			for i := range tt.queue {
+				if rec.queue[i].ID != tt.queue[i].ID || rec.queue[i].Type != tt.queue[i].Type || rec.queue[i].Data != tt.queue[i].Data {
+					t.Fatalf("queue = %+v, want %+v", rec.queue, tt.queue)
+				}
+			}
+		})
+	}
+}
+
+func TestConnRecordDeliversOutOfOrderLiveMessages(t *testing.T) {
+	cm := NewConnectionManager(SendQueueConfig{Size: 8, Overflow: OverflowDisconnect})
+	event := func(id string) contracts.WSMessage {
+		return contracts.WSMessage{ID: id, Type: contracts.TripEventDriverAssigned}
+	}
+
+	rec := newConnRecord(nil, "rider-1", "5-0", cm.cfg)
+	cm.bySocket["s1"] = rec
+	for _, id := range []string{"9-0", "7-0", "4-0"} {
+		if err := cm.SendToSocket("s1", event(id)); err != nil {
+			t.Fatalf("SendToSocket(%s): %v", id, err)
+		}
+	}
+	if err := cm.Replay("s1", event("6-0")); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if err := cm.EndReplay("s1"); err != nil {
+		t.Fatalf("EndReplay: %v", err)
+	}
+	// Live after the replay: an entry overtaken by a later one still arrives.
+	for _, id := range []string{"11-0", "10-0", "6-0"} {
+		if err := cm.SendToSocket("s1", event(id)); err != nil {
+			t.Fatalf("SendToSocket(%s): %v", id, err)
+		}
+	}
+
+	var got []string
+	for _, msg := range rec.queue {
+		got = append(got, msg.ID)
+	}
+	want := []string{"6-0", "7-0", "9-0", "11-0", "10-0"}
+	if !slices.Equal(got, want) {
+		t.Errorf("queued %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,610 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/contracts"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// QueueType selects the RabbitMQ queue implementation backing a queue.
+type QueueType string
+
+const (
+	QueueTypeClassic QueueType = "classic"
+	QueueTypeQuorum  QueueType = "quorum"
+)
+
+// Services that own (consume) or produce on the messaging topology. They are
+// only used for documentation, validation and the topology diagram.
+const (
+	ServiceAPIGateway     = "api-gateway"
+	ServiceWSGateway      = "ws-gateway"
+	ServiceTripService    = "trip-service"
+	ServiceDriverService  = "driver-service"
+	ServicePaymentService = "payment-service"
+	ServiceChatService    = "chat-service"
+	ServiceDLQWorker      = "dlq-worker"
+)
+
+// ExchangeSpec describes an exchange declared at startup.
+type ExchangeSpec struct {
+	Name string
+	Kind string // topic, direct, fanout, headers
+}
+
+// DeadLetterPolicy controls where rejected or expired messages of a queue go.
+type DeadLetterPolicy struct {
+	Exchange   string
+	RoutingKey string // optional; keeps the original routing key when empty
+	// AtLeastOnce enables at-least-once dead-lettering (quorum queues only).
+	AtLeastOnce bool
+}
+
+// QueueSpec is one row of the declarative topology table.
+type QueueSpec struct {
+	Name     string
+	Exchange string
+	Bindings []string // routing keys bound on Exchange
+	Owner    string   // service consuming the queue
+
+	Type       QueueType
+	Lazy       bool          // classic queues only; keeps messages on disk
+	MaxLength  int64         // 0 = unbounded
+	MessageTTL time.Duration // 0 = no expiry
+	// MaxPriority enables a priority queue (classic queues only); messages
+	// with a higher priority are delivered first. 0 = FIFO.
+	MaxPriority uint8
+	// Accepts lists the content types the owner can decode besides JSON.
+	// Publishers only use protobuf for a routing key when every queue it is
+	// routed to accepts it.
+	Accepts []string
+
+	DeadLetter *DeadLetterPolicy // nil = messages are dropped on reject
+
+	// Replaces names a queue this one supersedes, e.g. one declared with
+	// different arguments that the broker won't change in place. Its
+	// bindings are removed on startup, so new messages only reach this
+	// queue, and it is deleted once its old consumers have drained it.
+	Replaces string
+}
+
+// Arguments renders the x-arguments passed to QueueDeclare. Only non-default
+// settings are emitted so existing queues keep declaring with identical
+// arguments and the broker does not reject the redeclare.
+func (q QueueSpec) Arguments() amqp.Table {
+	args := amqp.Table{}
+	if q.Type == QueueTypeQuorum {
+		args["x-queue-type"] = string(QueueTypeQuorum)
+	}
+	if q.Lazy {
+		args["x-queue-mode"] = "lazy"
+	}
+	if q.MaxLength > 0 {
+		args["x-max-length"] = q.MaxLength
+	}
+	if q.MessageTTL > 0 {
+		args["x-message-ttl"] = q.MessageTTL.Milliseconds()
+	}
+	if q.MaxPriority > 0 {
+		args["x-max-priority"] = int32(q.MaxPriority)
+	}
+	if q.DeadLetter != nil {
+		args["x-dead-letter-exchange"] = q.DeadLetter.Exchange
+		if q.DeadLetter.RoutingKey != "" {
+			args["x-dead-letter-routing-key"] = q.DeadLetter.RoutingKey
+		}
+		if q.DeadLetter.AtLeastOnce {
+			args["x-dead-letter-strategy"] = "at-least-once"
+			// at-least-once dead-lettering requires reject-publish overflow.
+			args["x-overflow"] = "reject-publish"
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+// Priority classes for published messages. They only reorder messages inside
+// queues declared with MaxPriority.
+const (
+	PriorityBulk     uint8 = 0 // high-frequency traffic that tolerates delay (locations)
+	PriorityNormal   uint8 = 1 // default for routing keys without a class
+	PriorityDispatch uint8 = 2 // trip offers and driver responses, always first
+)
+
+// Topology is the single declarative description of every exchange, queue and
+// binding on the broker, plus which services publish each routing key.
+type Topology struct {
+	Exchanges  []ExchangeSpec
+	Queues     []QueueSpec
+	Producers  map[string][]string // routing key → publishing services
+	Priorities map[string]uint8    // routing key → default priority class
+}
+
+// dlxPolicy is the default dead-letter policy shared by all work queues.
+var dlxPolicy = &DeadLetterPolicy{Exchange: DeadLetterExchange}
+
+// DefaultTopology is the topology every service declares at startup.
+var DefaultTopology = Topology{
+	Exchanges: []ExchangeSpec{
+		{Name: DeadLetterExchange, Kind: "topic"},
+		{Name: TripExchange, Kind: "topic"},
+	},
+	Queues: []QueueSpec{
+		{
+			Name:     DeadLetterQueue,
+			Exchange: DeadLetterExchange,
+			Bindings: []string{"#"}, // catch everything dead-lettered
+			Owner:    ServiceDLQWorker,
+		},
+		{
+			Name:        FindAvailableDriversQueue,
+			Replaces:    "find_available_drivers",
+			Exchange:    TripExchange,
+			Bindings:    []string{contracts.TripEventCreated, contracts.TripEventDriverNotInterested},
+			Owner:       ServiceDriverService,
+			DeadLetter:  dlxPolicy,
+			Accepts:     []string{ContentTypeProtobuf},
+			MaxPriority: PriorityDispatch,
+		},
+		{
+			Name:       NotifyTripCreatedQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.TripEventCreated},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+			Accepts:    []string{ContentTypeProtobuf},
+		},
+		{
+			Name:        DriverCmdTripRequestQueue,
+			Replaces:    "driver_cmd_trip_request",
+			Exchange:    TripExchange,
+			Bindings:    []string{contracts.DriverCmdTripRequest},
+			Owner:       ServiceWSGateway,
+			DeadLetter:  dlxPolicy,
+			Accepts:     []string{ContentTypeProtobuf},
+			MaxPriority: PriorityDispatch,
+		},
+		{
+			Name:        DriverTripResponseQueue,
+			Replaces:    "driver_trip_response",
+			Exchange:    TripExchange,
+			Bindings:    []string{contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline},
+			Owner:       ServiceTripService,
+			DeadLetter:  dlxPolicy,
+			MaxPriority: PriorityDispatch,
+		},
+		{
+			Name:       NotifyDriverNoDriversFoundQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.TripEventNoDriversFound},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+			Accepts:    []string{ContentTypeProtobuf},
+		},
+		{
+			Name:       NotifyDriverAssignQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.TripEventDriverAssigned},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+			Accepts:    []string{ContentTypeProtobuf},
+		},
+		{
+			Name:       NotifyTripCompletedQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.TripEventCompleted},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       PaymentTripResponseQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.PaymentCmdCreateSession},
+			Owner:      ServicePaymentService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       NotifyPaymentSessionCreatedQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.PaymentEventSessionCreated},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			// Feeds the trip-service payment saga: session created, then paid.
+			Name:       NotifyPaymentSuccessQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.PaymentEventSessionCreated, contracts.PaymentEventSuccess},
+			Owner:      ServiceTripService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       DriverLocationUpdateQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.DriverCmdLocation},
+			Owner:      ServiceDriverService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       DriverTripAssignedQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.TripEventDriverAssigned},
+			Owner:      ServiceDriverService,
+			DeadLetter: dlxPolicy,
+			Accepts:    []string{ContentTypeProtobuf},
+		},
+		{
+			Name:       NotifyRiderDriverLocationQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.DriverEventLocation},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       NotifyRiderETAChangedQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.DriverEventETAChanged},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:     NotifyRiderGeofenceQueue,
+			Exchange: TripExchange,
+			Bindings: []string{
+				contracts.TripEventDriverNearby,
+				contracts.TripEventDriverAtPickup,
+				contracts.TripEventDriverAtDropoff,
+				contracts.TripEventDriverZoneEntered,
+				contracts.TripEventDriverZoneExited,
+			},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       ChatCmdSendQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.ChatCmdSend},
+			Owner:      ServiceChatService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       ChatCmdReadQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.ChatCmdRead},
+			Owner:      ServiceChatService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       ChatEventDeliveredQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.ChatEventDelivered},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       NotifyTripCancelledQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.TripEventCancelled},
+			Owner:      ServiceWSGateway,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       PaymentVoidSessionQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.PaymentCmdVoidSession},
+			Owner:      ServicePaymentService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       DriverReleaseQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.DriverCmdRelease},
+			Owner:      ServiceDriverService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       DriverPresenceChangedQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.DriverEventPresenceChanged},
+			Owner:      ServiceDriverService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:       DriverTripEndedQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.TripEventCompleted, contracts.TripEventCancelled},
+			Owner:      ServiceDriverService,
+			DeadLetter: dlxPolicy,
+		},
+		{
+			Name:     TripTimelineQueue,
+			Exchange: TripExchange,
+			Bindings: []string{
+				"trip.event.*",
+				contracts.DriverCmdTripRequest,
+				contracts.DriverCmdTripAccept,
+				contracts.DriverCmdTripDecline,
+				contracts.DriverCmdRelease,
+				"payment.#",
+			},
+			Owner:      ServiceTripService,
+			Lazy:       true, // audit trail; tolerates lag, may grow during outages
+			DeadLetter: dlxPolicy,
+			Accepts:    []string{ContentTypeProtobuf},
+		},
+		{
+			Name:       TripTraceQueue,
+			Exchange:   TripExchange,
+			Bindings:   []string{contracts.DriverEventTrace},
+			Owner:      ServiceTripService,
+			Lazy:       true, // batches are stored when trip-service gets to them
+			DeadLetter: dlxPolicy,
+		},
+	},
+	Producers: map[string][]string{
+		contracts.TripEventCreated:             {ServiceTripService},
+		contracts.TripEventDriverAssigned:      {ServiceTripService},
+		contracts.TripEventCompleted:           {ServiceTripService},
+		contracts.TripEventNoDriversFound:      {ServiceDriverService},
+		contracts.TripEventDriverNotInterested: {ServiceTripService},
+		contracts.TripEventCancelled:           {ServiceTripService},
+		contracts.TripEventDriverNearby:        {ServiceDriverService},
+		contracts.TripEventDriverAtPickup:      {ServiceDriverService},
+		contracts.TripEventDriverAtDropoff:     {ServiceDriverService},
+		contracts.TripEventDriverZoneEntered:   {ServiceDriverService},
+		contracts.TripEventDriverZoneExited:    {ServiceDriverService},
+		contracts.DriverCmdTripRequest:         {ServiceDriverService},
+		contracts.DriverCmdTripAccept:          {ServiceWSGateway},
+		contracts.DriverCmdTripDecline:         {ServiceWSGateway},
+		contracts.DriverCmdLocation:            {ServiceWSGateway},
+		contracts.DriverEventLocation:          {ServiceDriverService},
+		contracts.DriverEventPresenceChanged:   {ServiceDriverService},
+		contracts.DriverEventETAChanged:        {ServiceDriverService},
+		contracts.DriverEventTrace:             {ServiceDriverService},
+		contracts.PaymentCmdCreateSession:      {ServiceTripService},
+		contracts.PaymentCmdVoidSession:        {ServiceTripService},
+		contracts.DriverCmdRelease:             {ServiceTripService},
+		contracts.PaymentEventSessionCreated:   {ServicePaymentService},
+		contracts.PaymentEventSuccess:          {ServiceAPIGateway},
+		contracts.ChatCmdSend:                  {ServiceWSGateway},
+		contracts.ChatCmdRead:                  {ServiceWSGateway},
+		contracts.ChatEventDelivered:           {ServiceChatService},
+		contracts.TripEventChatFlagged:         {ServiceChatService},
+	},
+	Priorities: map[string]uint8{
+		contracts.TripEventCreated:             PriorityDispatch,
+		contracts.TripEventDriverNotInterested: PriorityDispatch,
+		contracts.DriverCmdTripRequest:         PriorityDispatch,
+		contracts.DriverCmdTripAccept:          PriorityDispatch,
+		contracts.DriverCmdTripDecline:         PriorityDispatch,
+		contracts.DriverCmdLocation:            PriorityBulk,
+		contracts.DriverEventLocation:          PriorityBulk,
+	},
+}
+
+// Queue returns the spec for the named queue.
+func (t Topology) Queue(name string) (QueueSpec, bool) {
+	for _, q := range t.Queues {
+		if q.Name == name {
+			return q, true
+		}
+	}
+	return QueueSpec{}, false
+}
+
+// PriorityFor returns the default priority class of routingKey.
+func (t Topology) PriorityFor(routingKey string) uint8 {
+	if p, ok := t.Priorities[routingKey]; ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// ContentTypeFor negotiates the encoding of routingKey: protobuf when its
+// payload has a protobuf form and every queue it is routed to accepts it,
+// JSON otherwise.
+func (t Topology) ContentTypeFor(routingKey string) string {
+	if _, ok := newProtoPayload(routingKey); !ok {
+		return ContentTypeJSON
+	}
+	queues := t.QueuesFor(TripExchange, routingKey)
+	if len(queues) == 0 {
+		return ContentTypeJSON
+	}
+	for _, q := range queues {
+		if !slices.Contains(q.Accepts, ContentTypeProtobuf) {
+			return ContentTypeJSON
+		}
+	}
+	return ContentTypeProtobuf
+}
+
+// QueuesFor returns the queues on exchange that would receive a message
+// published with routingKey, honouring topic wildcards.
+func (t Topology) QueuesFor(exchange, routingKey string) []QueueSpec {
+	var matched []QueueSpec
+	for _, q := range t.Queues {
+		if q.Exchange != exchange {
+			continue
+		}
+		for _, pattern := range q.Bindings {
+			if TopicMatch(pattern, routingKey) {
+				matched = append(matched, q)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Validate checks the table for mistakes that would otherwise only surface as
+// broker errors (or silently unrouted messages) at runtime.
+func (t Topology) Validate() error {
+	var errs []error
+
+	exchanges := make(map[string]struct{}, len(t.Exchanges))
+	for _, ex := range t.Exchanges {
+		if ex.Name == "" {
+			errs = append(errs, fmt.Errorf("exchange with empty name"))
+			continue
+		}
+		if _, dup := exchanges[ex.Name]; dup {
+			errs = append(errs, fmt.Errorf("exchange %q declared twice", ex.Name))
+		}
+		switch ex.Kind {
+		case "topic", "direct", "fanout", "headers":
+		default:
+			errs = append(errs, fmt.Errorf("exchange %q: unknown kind %q", ex.Name, ex.Kind))
+		}
+		exchanges[ex.Name] = struct{}{}
+	}
+
+	bound := make(map[string]struct{})
+	queues := make(map[string]struct{}, len(t.Queues))
+	for _, q := range t.Queues {
+		if q.Name == "" {
+			errs = append(errs, fmt.Errorf("queue with empty name"))
+			continue
+		}
+		if _, dup := queues[q.Name]; dup {
+			errs = append(errs, fmt.Errorf("queue %q declared twice", q.Name))
+		}
+		queues[q.Name] = struct{}{}
+
+		if q.Owner == "" {
+			errs = append(errs, fmt.Errorf("queue %q: owner service is required", q.Name))
+		}
+		if q.Replaces != "" && slices.ContainsFunc(t.Queues, func(other QueueSpec) bool { return other.Name == q.Replaces }) {
+			errs = append(errs, fmt.Errorf("queue %q replaces %q, which is still declared", q.Name, q.Replaces))
+		}
+		if _, ok := exchanges[q.Exchange]; !ok {
+			errs = append(errs, fmt.Errorf("queue %q: exchange %q is not declared", q.Name, q.Exchange))
+		}
+		if len(q.Bindings) == 0 {
+			errs = append(errs, fmt.Errorf("queue %q: at least one binding is required", q.Name))
+		}
+		for _, key := range q.Bindings {
+			if key == "" {
+				errs = append(errs, fmt.Errorf("queue %q: empty binding key", q.Name))
+			}
+			if q.Exchange == TripExchange {
+				bound[key] = struct{}{}
+			}
+		}
+
+		switch q.Type {
+		case "", QueueTypeClassic:
+			if q.DeadLetter != nil && q.DeadLetter.AtLeastOnce {
+				errs = append(errs, fmt.Errorf("queue %q: at-least-once dead-lettering requires a quorum queue", q.Name))
+			}
+		case QueueTypeQuorum:
+			if q.Lazy {
+				errs = append(errs, fmt.Errorf("queue %q: lazy mode is not supported by quorum queues", q.Name))
+			}
+			if q.MaxPriority > 0 {
+				errs = append(errs, fmt.Errorf("queue %q: x-max-priority is not supported by quorum queues", q.Name))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("queue %q: unknown queue type %q", q.Name, q.Type))
+		}
+
+		if q.MaxLength < 0 {
+			errs = append(errs, fmt.Errorf("queue %q: max length must not be negative", q.Name))
+		}
+		if q.MessageTTL < 0 {
+			errs = append(errs, fmt.Errorf("queue %q: message TTL must not be negative", q.Name))
+		} else if q.MessageTTL > 0 && q.MessageTTL < time.Millisecond {
+			errs = append(errs, fmt.Errorf("queue %q: message TTL below 1ms", q.Name))
+		}
+
+		for _, contentType := range q.Accepts {
+			switch contentType {
+			case ContentTypeJSON:
+			case ContentTypeProtobuf:
+				if !slices.ContainsFunc(q.Bindings, func(key string) bool { _, ok := newProtoPayload(key); return ok }) {
+					errs = append(errs, fmt.Errorf("queue %q: accepts %s but none of its bindings has a protobuf payload", q.Name, contentType))
+				}
+			default:
+				errs = append(errs, fmt.Errorf("queue %q: unknown content type %q", q.Name, contentType))
+			}
+		}
+
+		if q.DeadLetter != nil {
+			if _, ok := exchanges[q.DeadLetter.Exchange]; !ok {
+				errs = append(errs, fmt.Errorf("queue %q: dead-letter exchange %q is not declared", q.Name, q.DeadLetter.Exchange))
+			}
+			if q.DeadLetter.Exchange == q.Exchange {
+				errs = append(errs, fmt.Errorf("queue %q: dead-letter exchange must differ from its source exchange", q.Name))
+			}
+		}
+	}
+
+	// Every produced routing key must land in at least one queue, otherwise the
+	// broker silently drops it.
+	for key, producers := range t.Producers {
+		if len(producers) == 0 {
+			errs = append(errs, fmt.Errorf("routing key %q: no producers listed", key))
+		}
+		if len(t.QueuesFor(TripExchange, key)) == 0 {
+			errs = append(errs, fmt.Errorf("routing key %q is produced but not bound to any queue", key))
+		}
+	}
+
+	// A priority class above the lowest one must reach at least one priority
+	// queue, and must not exceed the maximum of any queue it is routed to.
+	for key, priority := range t.Priorities {
+		if priority == 0 {
+			continue
+		}
+		effective := false
+		for _, q := range t.QueuesFor(TripExchange, key) {
+			if q.MaxPriority == 0 {
+				continue
+			}
+			effective = true
+			if priority > q.MaxPriority {
+				errs = append(errs, fmt.Errorf("routing key %q: priority %d exceeds max priority %d of queue %q", key, priority, q.MaxPriority, q.Name))
+			}
+		}
+		if !effective {
+			errs = append(errs, fmt.Errorf("routing key %q: priority %d has no effect, no bound queue sets MaxPriority", key, priority))
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// TopicMatch reports whether routingKey matches an AMQP topic binding pattern,
+// where "*" matches exactly one word and "#" matches zero or more words.
+func TopicMatch(pattern, routingKey string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+func matchWords(pattern, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if matchWords(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(key) > 0 && matchWords(pattern[1:], key[1:])
+	default:
+		return len(key) > 0 && pattern[0] == key[0] && matchWords(pattern[1:], key[1:])
+	}
+}
@@ -0,0 +1,127 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ride-sharing/shared/contracts"
+)
+
+func TestDefaultTopology_IsValid(t *testing.T) {
+	if err := DefaultTopology.Validate(); err != nil {
+		t.Fatalf("expected default topology to be valid, got %v", err)
+	}
+}
+
+func TestTopologyValidate_RejectsLazyQuorumAndUnboundProducer(t *testing.T) {
+	topology := Topology{
+		Exchanges: []ExchangeSpec{{Name: TripExchange, Kind: "topic"}},
+		Queues: []QueueSpec{{
+			Name:     "q1",
+			Exchange: TripExchange,
+			Bindings: []string{contracts.TripEventCreated},
+			Owner:    ServiceTripService,
+			Type:     QueueTypeQuorum,
+			Lazy:     true,
+		}},
+		Producers: map[string][]string{
+			contracts.TripEventCancelled: {ServiceTripService},
+		},
+	}
+
+	err := topology.Validate()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, want := range []string{"lazy mode is not supported", "not bound to any queue"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error to mention %q, got %v", want, err)
+		}
+	}
+}
+
+func TestQueueSpecArguments_OnlyEmitsNonDefaults(t *testing.T) {
+	if args := (QueueSpec{Name: "plain"}).Arguments(); args != nil {
+		t.Fatalf("expected nil arguments for default queue, got %v", args)
+	}
+
+	args := QueueSpec{
+		Type:       QueueTypeQuorum,
+		MaxLength:  1000,
+		MessageTTL: 30 * time.Second,
+		DeadLetter: &DeadLetterPolicy{Exchange: DeadLetterExchange, AtLeastOnce: true},
+	}.Arguments()
+
+	if args["x-queue-type"] != "quorum" || args["x-max-length"] != int64(1000) ||
+		args["x-message-ttl"] != int64(30000) || args["x-dead-letter-strategy"] != "at-least-once" {
+		t.Fatalf("unexpected arguments: %v", args)
+	}
+}
+
+func TestTopologyValidate_PriorityClassNeedsPriorityQueue(t *testing.T) {
+	topology := Topology{
+		Exchanges: []ExchangeSpec{{Name: TripExchange, Kind: "topic"}},
+		Queues: []QueueSpec{{
+			Name:     "fifo",
+			Exchange: TripExchange,
+			Bindings: []string{contracts.DriverCmdTripRequest},
+			Owner:    ServiceWSGateway,
+		}},
+		Priorities: map[string]uint8{contracts.DriverCmdTripRequest: PriorityDispatch},
+	}
+
+	err := topology.Validate()
+	if err == nil || !strings.Contains(err.Error(), "has no effect") {
+		t.Fatalf("expected priority without priority queue to be rejected, got %v", err)
+	}
+
+	topology.Queues[0].MaxPriority = PriorityDispatch
+	if err := topology.Validate(); err != nil {
+		t.Fatalf("expected valid topology, got %v", err)
+	}
+	if args := topology.Queues[0].Arguments(); args["x-max-priority"] != int32(PriorityDispatch) {
+		t.Fatalf("unexpected arguments: %v", args)
+	}
+	if got := topology.PriorityFor(contracts.ChatCmdSend); got != PriorityNormal {
+		t.Fatalf("PriorityFor(unclassified) = %d, want %d", got, PriorityNormal)
+	}
+}
+
+func TestTopologyContentTypeFor_NegotiatesPerQueue(t *testing.T) {
+	queue := func(name string, accepts ...string) QueueSpec {
+		return QueueSpec{Name: name, Exchange: TripExchange, Bindings: []string{contracts.TripEventCreated}, Owner: ServiceDriverService, Accepts: accepts}
+	}
+	migrated := Topology{Queues: []QueueSpec{queue("a", ContentTypeProtobuf), queue("b", ContentTypeProtobuf)}}
+	mixed := Topology{Queues: []QueueSpec{queue("a", ContentTypeProtobuf), queue("b")}}
+
+	if got := migrated.ContentTypeFor(contracts.TripEventCreated); got != ContentTypeProtobuf {
+		t.Fatalf("all queues accept protobuf: got %s", got)
+	}
+	if got := mixed.ContentTypeFor(contracts.TripEventCreated); got != ContentTypeJSON {
+		t.Fatalf("one queue has not migrated: got %s, want JSON fallback", got)
+	}
+	if got := DefaultTopology.ContentTypeFor(contracts.TripEventCancelled); got != ContentTypeJSON {
+		t.Fatalf("payload without protobuf form: got %s", got)
+	}
+}
+
+func TestTopicMatch(t *testing.T) {
+	cases := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"trip.event.created", "trip.event.created", true},
+		{"trip.event.*", "trip.event.created", true},
+		{"trip.*", "trip.event.created", false},
+		{"trip.#", "trip.event.created", true},
+		{"#", "driver.cmd.location", true},
+		{"#.location", "driver.cmd.location", true},
+		{"driver.cmd.trip_accept", "driver.cmd.trip_decline", false},
+	}
+	for _, c := range cases {
+		if got := TopicMatch(c.pattern, c.key); got != c.want {
+			t.Fatalf("TopicMatch(%q, %q) = %v, want %v", c.pattern, c.key, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"ride-sharing/shared/contracts"
+	pbw "ride-sharing/shared/proto/ws"
+	"ride-sharing/shared/types"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// wsCompressMinBytes is the smallest binary frame worth compressing; protobuf
+// location frames are a few dozen bytes and deflate only makes them larger.
+const wsCompressMinBytes = 256
+
+// WSConn returns the Conn a WebSocket's messages are written to: conn itself
+// for JSON clients, or a protobuf writer if the client negotiated
+// contracts.WSSubprotocolProto.
+func WSConn(conn *websocket.Conn) Conn {
+	if conn.Subprotocol() == contracts.WSSubprotocolProto {
+		return &protoConn{Conn: conn}
+	}
+	return conn
+}
+
+// protoConn writes WSMessages as binary protobuf frames.
+type protoConn struct {
+	*websocket.Conn
+}
+
+func (c *protoConn) WriteJSON(v any) error {
+	msg, ok := v.(contracts.WSMessage)
+	if !ok {
+		return fmt.Errorf("unexpected %s message %T", contracts.WSSubprotocolProto, v)
+	}
+	frame, err := EncodeWSProto(msg)
+	if err != nil {
+		return err
+	}
+	// Only the socket's writer goroutine writes data frames, so toggling
+	// compression per frame is safe. It is a no-op unless the client
+	// negotiated permessage-deflate.
+	c.EnableWriteCompression(len(frame) >= wsCompressMinBytes)
+	return c.WriteMessage(websocket.BinaryMessage, frame)
+}
+
+// EncodeWSProto renders msg as a protobuf WSMessage. Driver locations are
+// sent as DriverLocation; all other data is embedded as JSON.
+func EncodeWSProto(msg contracts.WSMessage) ([]byte, error) {
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s data: %w", msg.Type, err)
+	}
+	frame := &pbw.WSMessage{Id: msg.ID, Type: msg.Type, Topic: msg.Topic, RoomID: msg.RoomID}
+	var location DriverLocationEventData
+	if msg.Type == contracts.DriverEventLocation && json.Unmarshal(data, &location) == nil {
+		payload := &pbw.DriverLocation{
+			Latitude:  location.Latitude,
+			Longitude: location.Longitude,
+			Bearing:   location.Bearing,
+			Speed:     location.Speed,
+		}
+		if eta := location.ETA; eta != nil {
+			payload.Eta = &pbw.RouteETA{Target: eta.Target, EtaSeconds: eta.ETASeconds, DistanceMeters: eta.DistanceMeters}
+		}
+		frame.Payload = &pbw.WSMessage_DriverLocation{DriverLocation: payload}
+	} else {
+		frame.Payload = &pbw.WSMessage_Json{Json: data}
+	}
+	return proto.Marshal(frame)
+}
+
+// TranscodeWSProto decodes a binary frame sent by a ride.v1.proto client and
+// returns the equivalent JSON frame, so inbound frames are validated and
+// handled the same whichever encoding the client chose.
+func TranscodeWSProto(raw []byte) ([]byte, error) {
+	var frame pbw.WSMessage
+	if err := proto.Unmarshal(raw, &frame); err != nil {
+		return nil, fmt.Errorf("decode protobuf frame: %w", err)
+	}
+
+	var data json.RawMessage
+	switch payload := frame.Payload.(type) {
+	case *pbw.WSMessage_Json:
+		data = payload.Json
+	case *pbw.WSMessage_DriverLocation:
+		if frame.Type != contracts.DriverCmdLocation {
+			return nil, fmt.Errorf("%s frames cannot carry a driver location", frame.Type)
+		}
+		encoded, err := json.Marshal(contracts.WSDriverLocationData{
+			Location: types.Coordinate{
+				Latitude:  payload.DriverLocation.GetLatitude(),
+				Longitude: payload.DriverLocation.GetLongitude(),
+			},
+			Timestamp: payload.DriverLocation.GetTimestamp(),
+			Accuracy:  payload.DriverLocation.GetAccuracy(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		data = encoded
+	}
+	return json.Marshal(struct {
+		ID   string          `json:"id,omitempty"`
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data,omitempty"`
+	}{ID: frame.Id, Type: frame.Type, Data: data})
+}
@@ -0,0 +1,105 @@
+package messaging
+
+import (
+	"encoding/json"
+	"testing"
+
+	"ride-sharing/shared/contracts"
+	pbw "ride-sharing/shared/proto/ws"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEncodeWSProto_DriverLocationIsTyped(t *testing.T) {
+	frame, err := EncodeWSProto(contracts.WSMessage{
+		Type: contracts.DriverEventLocation,
+		Data: json.RawMessage(`{"latitude":52.52,"longitude":13.405,"eta":{"target":"pickup","etaSeconds":240,"distanceMeters":1800}}`),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var msg pbw.WSMessage
+	if err := proto.Unmarshal(frame, &msg); err != nil {
+		t.Fatalf("unmarshal frame: %v", err)
+	}
+	location := msg.GetDriverLocation()
+	if location == nil {
+		t.Fatalf("expected a DriverLocation payload, got %T", msg.Payload)
+	}
+	if location.Latitude != 52.52 || location.Longitude != 13.405 {
+		t.Fatalf("unexpected location: %v", location)
+	}
+	if eta := location.GetEta(); eta.GetTarget() != "pickup" || eta.GetEtaSeconds() != 240 || eta.GetDistanceMeters() != 1800 {
+		t.Fatalf("unexpected eta: %v", eta)
+	}
+}
+
+func TestEncodeWSProto_OtherDataIsJSON(t *testing.T) {
+	frame, err := EncodeWSProto(contracts.WSMessage{
+		ID:   "1700000000000-0",
+		Type: contracts.WSError,
+		Data: contracts.WSErrorData{Code: "invalid_frame", Message: "bad"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var msg pbw.WSMessage
+	if err := proto.Unmarshal(frame, &msg); err != nil {
+		t.Fatalf("unmarshal frame: %v", err)
+	}
+	if msg.Id != "1700000000000-0" || msg.Type != contracts.WSError {
+		t.Fatalf("unexpected envelope: id=%q type=%q", msg.Id, msg.Type)
+	}
+	var data contracts.WSErrorData
+	if err := json.Unmarshal(msg.GetJson(), &data); err != nil {
+		t.Fatalf("unmarshal json payload: %v", err)
+	}
+	if data.Code != "invalid_frame" {
+		t.Fatalf("unexpected data: %+v", data)
+	}
+}
+
+func TestTranscodeWSProto_DriverLocation(t *testing.T) {
+	raw, err := proto.Marshal(&pbw.WSMessage{
+		Id:   "f1",
+		Type: contracts.DriverCmdLocation,
+		Payload: &pbw.WSMessage_DriverLocation{DriverLocation: &pbw.DriverLocation{
+			Latitude:  52.52,
+			Longitude: 13.405,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("marshal frame: %v", err)
+	}
+
+	frame, err := TranscodeWSProto(raw)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var msg struct {
+		ID   string                         `json:"id"`
+		Type string                         `json:"type"`
+		Data contracts.WSDriverLocationData `json:"data"`
+	}
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		t.Fatalf("unmarshal transcoded frame: %v", err)
+	}
+	if msg.ID != "f1" || msg.Type != contracts.DriverCmdLocation {
+		t.Fatalf("unexpected envelope: %s", frame)
+	}
+	if msg.Data.Location.Latitude != 52.52 || msg.Data.Location.Longitude != 13.405 {
+		t.Fatalf("unexpected location: %s", frame)
+	}
+}
+
+func TestTranscodeWSProto_RejectsLocationOnOtherTypes(t *testing.T) {
+	raw, _ := proto.Marshal(&pbw.WSMessage{
+		Type:    contracts.WSRoomJoin,
+		Payload: &pbw.WSMessage_DriverLocation{DriverLocation: &pbw.DriverLocation{}},
+	})
+	if _, err := TranscodeWSProto(raw); err == nil {
+		t.Fatalf("expected a driver location on %s to be rejected", contracts.WSRoomJoin)
+	}
+}
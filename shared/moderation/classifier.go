@@ -0,0 +1,108 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultClassifierThreshold is the score from which a classifier label
+// blocks a message.
+const DefaultClassifierThreshold = 0.8
+
+// Classifier scores a message, e.g. with an external moderation service.
+// It returns a score from 0 to 1 per label, such as "harassment".
+type Classifier interface {
+	Classify(ctx context.Context, text string) (map[string]float64, error)
+}
+
+// StubClassifier is the local stand-in for an external classifier. It
+// scores every message as clean.
+type StubClassifier struct{}
+
+func (StubClassifier) Classify(context.Context, string) (map[string]float64, error) {
+	return nil, nil
+}
+
+// HTTPClassifier asks a moderation service over HTTP. It posts
+// {"text": …} to the URL and expects {"labels": {"<label>": <score>, …}}.
+type HTTPClassifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPClassifier returns a classifier that gives up after timeout, so a
+// slow service delays a chat message by at most that long.
+func NewHTTPClassifier(url string, timeout time.Duration) *HTTPClassifier {
+	return &HTTPClassifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (c *HTTPClassifier) Classify(ctx context.Context, text string) (map[string]float64, error) {
+	body, _ := json.Marshal(map[string]string{"text": text})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify message: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to classify message: classifier returned %s", resp.Status)
+	}
+
+	var result struct {
+		Labels map[string]float64 `json:"labels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode classification: %w", err)
+	}
+	return result.Labels, nil
+}
+
+// ClassifierRule blocks messages a Classifier scores at or above Threshold
+// for any label. It runs after ContactMasker in the default pipeline, so
+// contact details never leave the platform.
+type ClassifierRule struct {
+	Classifier Classifier
+	Threshold  float64
+}
+
+func (r ClassifierRule) Name() string { return "classifier" }
+
+func (r ClassifierRule) Check(ctx context.Context, msg Message) (Verdict, error) {
+	labels, err := r.Classifier.Classify(ctx, msg.Text)
+	if err != nil {
+		return Verdict{}, err
+	}
+	var flags []string
+	for label, score := range labels {
+		if score >= r.Threshold {
+			flags = append(flags, "classifier:"+label)
+		}
+	}
+	if len(flags) == 0 {
+		return Verdict{}, nil
+	}
+	sort.Strings(flags)
+	return Verdict{
+		Flags:  flags,
+		Block:  true,
+		Reason: "Your message was blocked as " + strings.Join(labelsOf(flags), ", ") + ".",
+	}, nil
+}
+
+func labelsOf(flags []string) []string {
+	labels := make([]string, len(flags))
+	for i, flag := range flags {
+		labels[i] = strings.TrimPrefix(flag, "classifier:")
+	}
+	return labels
+}
@@ -0,0 +1,92 @@
+// Package moderation checks trip chat messages before ws-gateway relays them.
+// A Pipeline runs a list of rules in order; each rule may mask parts of the
+// text, flag the message or block it.
+package moderation
+
+import (
+	"context"
+	"log"
+	"slices"
+)
+
+// Flags raised by the built-in rules. Classifier flags are "classifier:"
+// followed by the label.
+const (
+	FlagContact = "contact" // phone number or email address, masked
+	FlagAbuse   = "abuse"   // word on the blocked word list
+)
+
+// Message is a chat message to check.
+type Message struct {
+	TripID   string
+	SenderID string
+	Text     string
+}
+
+// Verdict is what a rule decided about a message.
+type Verdict struct {
+	Text   string   // the text to pass on, e.g. with contact details masked
+	Flags  []string // why the message was flagged; empty when clean
+	Block  bool     // the message must not be relayed
+	Reason string   // tells the sender why the message was blocked
+}
+
+// Rule checks one aspect of a message. It sees the text as the rules before
+// it left it.
+type Rule interface {
+	Name() string
+	Check(ctx context.Context, msg Message) (Verdict, error)
+}
+
+// Result is the combined verdict of a pipeline.
+type Result struct {
+	Text    string
+	Flags   []string
+	Blocked bool
+	Reason  string // reason of the first rule that blocked the message
+}
+
+// Flagged reports whether any rule flagged the message.
+func (r Result) Flagged() bool { return len(r.Flags) > 0 }
+
+// Pipeline runs rules in order. A rule that fails is logged and skipped, so
+// an unavailable classifier does not stop chat.
+type Pipeline struct {
+	rules []Rule
+}
+
+func NewPipeline(rules ...Rule) *Pipeline {
+	return &Pipeline{rules: rules}
+}
+
+// Check runs every rule on msg. Once a rule blocks the message the
+// remaining rules are skipped. A message without text, such as a bare
+// attachment, passes unchecked.
+func (p *Pipeline) Check(ctx context.Context, msg Message) Result {
+	result := Result{Text: msg.Text}
+	if msg.Text == "" {
+		return result
+	}
+	for _, rule := range p.rules {
+		msg.Text = result.Text
+		verdict, err := rule.Check(ctx, msg)
+		if err != nil {
+			log.Printf("moderation: rule %s failed for trip %s: %v", rule.Name(), msg.TripID, err)
+			continue
+		}
+		if verdict.Text != "" {
+			result.Text = verdict.Text
+		}
+		for _, flag := range verdict.Flags {
+			if !slices.Contains(result.Flags, flag) {
+				result.Flags = append(result.Flags, flag)
+			}
+		}
+		if verdict.Block {
+			result.Blocked = true
+			result.Reason = verdict.Reason
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeClassifier struct {
+	labels map[string]float64
+	err    error
+}
+
+func (f fakeClassifier) Classify(context.Context, string) (map[string]float64, error) {
+	return f.labels, f.err
+}
+
+func TestPipeline(t *testing.T) {
+	words := NewWordList([]string{"Idiot"})
+	tests := []struct {
+		name        string
+		rules       []Rule
+		text        string
+		wantText    string
+		wantFlags   []string
+		wantBlocked bool
+	}{
+		{
+			name:     "clean message passes",
+			rules:    []Rule{ContactMasker{}, words, ClassifierRule{Classifier: StubClassifier{}, Threshold: DefaultClassifierThreshold}},
+			text:     "I'm at the gate, see you in 5 min for $12.50",
+			wantText: "I'm at the gate, see you in 5 min for $12.50",
+		},
+		{
+			name:      "contact details are masked",
+			rules:     []Rule{ContactMasker{}},
+			text:      "call me on +1 (415) 555-0123 or mail jo.doe@example.com",
+			wantText:  "call me on " + maskedPhone + " or mail " + maskedEmail,
+			wantFlags: []string{FlagContact},
+		},
+		{
+			name:        "listed words block",
+			rules:       []Rule{ContactMasker{}, words},
+			text:        "you IDIOT, text 5550123456",
+			wantText:    "you IDIOT, text " + maskedPhone,
+			wantFlags:   []string{FlagContact, FlagAbuse},
+			wantBlocked: true,
+		},
+		{
+			name:     "listed words only match whole",
+			rules:    []Rule{words},
+			text:     "idiotic traffic",
+			wantText: "idiotic traffic",
+		},
+		{
+			name:        "classifier labels over the threshold block",
+			rules:       []Rule{ClassifierRule{Classifier: fakeClassifier{labels: map[string]float64{"harassment": 0.93, "spam": 0.2}}, Threshold: 0.8}},
+			text:        "…",
+			wantText:    "…",
+			wantFlags:   []string{"classifier:harassment"},
+			wantBlocked: true,
+		},
+		{
+			name:     "failing rules are skipped",
+			rules:    []Rule{ClassifierRule{Classifier: fakeClassifier{err: errors.New("timeout")}, Threshold: 0.8}, words},
+			text:     "on my way",
+			wantText: "on my way",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPipeline(tt.rules...).Check(context.Background(), Message{TripID: "trip-1", SenderID: "rider-1", Text: tt.text})
+			if got.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", got.Text, tt.wantText)
+			}
+			if !slices.Equal(got.Flags, tt.wantFlags) {
+				t.Errorf("flags = %v, want %v", got.Flags, tt.wantFlags)
+			}
+			if got.Blocked != tt.wantBlocked {
+				t.Errorf("blocked = %t, want %t", got.Blocked, tt.wantBlocked)
+			}
+			if got.Blocked && got.Reason == "" {
+				t.Error("blocked without a reason")
+			}
+		})
+	}
+}
+
+func TestLoadWordList(t *testing.T) {
+	words, err := LoadWordList(strings.NewReader("# insults\nidiot\n\n  Moron  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if words.Len() != 2 {
+		t.Fatalf("loaded %d words, want 2", words.Len())
+	}
+}
@@ -0,0 +1,111 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// emailPattern matches email addresses.
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	// phonePattern matches 7 to 15 digits, optionally after a +, separated
+	// by spaces, dots, dashes or parentheses. Shorter numbers, such as prices
+	// or times, are left alone.
+	phonePattern = regexp.MustCompile(`\+?\(?\d(?:[\s.\-()]*\d){6,14}`)
+)
+
+// Masks put in place of contact details.
+const (
+	maskedEmail = "[email hidden]"
+	maskedPhone = "[phone hidden]"
+)
+
+// ContactMasker masks phone numbers and email addresses, which riders and
+// drivers exchange to take trips off the platform. The message is still
+// relayed, with FlagContact.
+type ContactMasker struct{}
+
+func (ContactMasker) Name() string { return "contact" }
+
+func (ContactMasker) Check(_ context.Context, msg Message) (Verdict, error) {
+	text := emailPattern.ReplaceAllString(msg.Text, maskedEmail)
+	text = phonePattern.ReplaceAllString(text, maskedPhone)
+	if text == msg.Text {
+		return Verdict{}, nil
+	}
+	return Verdict{Text: text, Flags: []string{FlagContact}}, nil
+}
+
+// WordList blocks messages that contain one of its words. Words match whole
+// and regardless of case.
+type WordList struct {
+	words map[string]struct{}
+}
+
+func NewWordList(words []string) *WordList {
+	w := &WordList{words: make(map[string]struct{}, len(words))}
+	w.Add(words...)
+	return w
+}
+
+// Add adds words to the list.
+func (w *WordList) Add(words ...string) {
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			w.words[word] = struct{}{}
+		}
+	}
+}
+
+// LoadWordList reads one word per line. Empty lines and lines starting with
+// # are skipped.
+func LoadWordList(r io.Reader) (*WordList, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewWordList(words), nil
+}
+
+// LoadWordListFile reads a word list from the file at path.
+func LoadWordListFile(path string) (*WordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadWordList(f)
+}
+
+func (w *WordList) Len() int { return len(w.words) }
+
+func (w *WordList) Name() string { return "wordlist" }
+
+func (w *WordList) Check(_ context.Context, msg Message) (Verdict, error) {
+	fields := strings.FieldsFunc(strings.ToLower(msg.Text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, field := range fields {
+		if _, ok := w.words[field]; ok {
+			return Verdict{
+				Flags:  []string{FlagAbuse},
+				Block:  true,
+				Reason: "Your message contains language that is not allowed.",
+			}, nil
+		}
+	}
+	return Verdict{}, nil
+}
@@ -0,0 +1,313 @@
+// Package presence tracks whether drivers are connected and free to take
+// trips. ws-gateway records heartbeats; driver-service reads presence in
+// batches for matching and expires drivers whose heartbeats stopped.
+//
+// All keys share the {drivers:presence} hash tag, so the scripts below touch a
+// single slot and work on Redis Cluster.
+package presence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// State is a driver's presence state.
+type State string
+
+const (
+	StateOffline State = "offline"
+	StateOnline  State = "online" // connected and free to take a trip
+	StateBusy    State = "busy"   // connected and serving a trip
+)
+
+const (
+	// DefaultTTL is how long a driver stays present after their last heartbeat.
+	DefaultTTL = 60 * time.Second
+	// HeartbeatInterval is how often ws-gateway records a heartbeat for each
+	// connected driver.
+	HeartbeatInterval = 20 * time.Second
+	// BusyTTL is how long a driver stays busy unless their trip ends first,
+	// so a lost trip end does not keep them out of matching for good. It
+	// matches how long driver-service keeps the state of a trip.
+	BusyTTL = 2 * time.Hour
+)
+
+const (
+	stateKey     = "{drivers:presence}:state"     // hash driverID → State
+	lastSeenKey  = "{drivers:presence}:last_seen" // hash driverID → unix ms
+	packageKey   = "{drivers:presence}:package"   // hash driverID → package slug
+	deadlinesKey = "{drivers:presence}:deadlines" // zset driverID → expiry unix ms, present drivers only
+	// busyKey holds the drivers serving a trip until BusyTTL passes. It is
+	// kept while they are offline, so a driver who reconnects mid-trip is busy
+	// again, not online.
+	busyKey = "{drivers:presence}:busy_until" // zset driverID → busy until unix ms
+)
+
+// luaHeartbeat marks an offline driver busy if they are serving a trip and
+// online otherwise, leaves online and busy drivers as they are, and pushes
+// their expiry forward. It returns the previous state.
+var luaHeartbeat = redis.NewScript(`
+local prev = redis.call('HGET', KEYS[1], ARGV[1])
+if not prev or prev == 'offline' then
+    local state = 'online'
+    local busyUntil = redis.call('ZSCORE', KEYS[5], ARGV[1])
+    if busyUntil and tonumber(busyUntil) > tonumber(ARGV[2]) then
+        state = 'busy'
+    end
+    redis.call('HSET', KEYS[1], ARGV[1], state)
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+if ARGV[4] ~= '' then
+    redis.call('HSET', KEYS[3], ARGV[1], ARGV[4])
+end
+redis.call('ZADD', KEYS[4], ARGV[3], ARGV[1])
+return prev or ''
+`)
+
+// luaSetBusy records that the driver serves a trip until ARGV[2] and moves
+// them from online to busy. It returns 1 if their state changed.
+var luaSetBusy = redis.NewScript(`
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= 'online' then
+    return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], 'busy')
+return 1
+`)
+
+// luaSetAvailable records that the driver's trip ended and moves them from
+// busy to online. It returns 1 if their state changed.
+var luaSetAvailable = redis.NewScript(`
+redis.call('ZREM', KEYS[2], ARGV[1])
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= 'busy' then
+    return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], 'online')
+return 1
+`)
+
+// luaOffline marks the driver offline. With ARGV[2] set it only does so if
+// their expiry is not after ARGV[2], so a heartbeat racing the sweep wins. It
+// returns {previous state, package slug, last seen} if the state changed.
+var luaOffline = redis.NewScript(`
+local deadline = redis.call('ZSCORE', KEYS[2], ARGV[1])
+if ARGV[2] ~= '' and deadline and tonumber(deadline) > tonumber(ARGV[2]) then
+    return false
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+local prev = redis.call('HGET', KEYS[1], ARGV[1])
+if not prev or prev == 'offline' then
+    return false
+end
+redis.call('HSET', KEYS[1], ARGV[1], 'offline')
+return {prev, redis.call('HGET', KEYS[3], ARGV[1]) or '', redis.call('HGET', KEYS[4], ARGV[1]) or '0'}
+`)
+
+// luaBusyExpired forgets the trip of a driver whose busy deadline is not after
+// ARGV[2] and moves them from busy to online. It returns 1 if their state
+// changed.
+var luaBusyExpired = redis.NewScript(`
+local busyUntil = redis.call('ZSCORE', KEYS[2], ARGV[1])
+if not busyUntil or tonumber(busyUntil) > tonumber(ARGV[2]) then
+    return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= 'busy' then
+    return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], 'online')
+return 1
+`)
+
+// Presence is a driver's presence as last recorded.
+type Presence struct {
+	DriverID    string
+	State       State
+	PackageSlug string
+	LastSeen    time.Time
+}
+
+// Available reports whether the driver may be offered a trip.
+func (p Presence) Available() bool {
+	return p.State == StateOnline
+}
+
+// Change is a transition to offline returned by Store.MarkOffline and
+// Store.Expire.
+type Change struct {
+	Presence
+	Previous State
+}
+
+// Store reads and writes driver presence in Redis.
+type Store struct {
+	rdb redis.UniversalClient
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewStore(rdb redis.UniversalClient, ttl time.Duration) *Store {
+	return &Store{rdb: rdb, ttl: ttl, now: time.Now}
+}
+
+// Heartbeat records that driverID is connected. A driver that was offline (or
+// unknown) becomes online, or busy if they are still serving a trip; a busy
+// driver stays busy. It returns the previous state, StateOffline for unknown
+// drivers.
+func (s *Store) Heartbeat(ctx context.Context, driverID, packageSlug string) (State, error) {
+	now := s.now()
+	prev, err := luaHeartbeat.Run(ctx, s.rdb,
+		[]string{stateKey, lastSeenKey, packageKey, deadlinesKey, busyKey},
+		driverID, now.UnixMilli(), now.Add(s.ttl).UnixMilli(), packageSlug,
+	).Text()
+	if err != nil {
+		return "", fmt.Errorf("failed to record heartbeat for driver %s: %w", driverID, err)
+	}
+	if prev == "" {
+		return StateOffline, nil
+	}
+	return State(prev), nil
+}
+
+// SetBusy records that driverID serves a trip and marks them busy if they
+// are online. An offline driver becomes busy on their next heartbeat. Unless
+// SetAvailable is called first, the driver is made available again by Expire
+// after BusyTTL. It reports whether the state changed.
+func (s *Store) SetBusy(ctx context.Context, driverID string) (bool, error) {
+	return s.transition(ctx, luaSetBusy, driverID, StateBusy, s.now().Add(BusyTTL).UnixMilli())
+}
+
+// SetAvailable records that driverID's trip ended and marks them online if
+// they are busy. It reports whether the state changed.
+func (s *Store) SetAvailable(ctx context.Context, driverID string) (bool, error) {
+	return s.transition(ctx, luaSetAvailable, driverID, StateOnline)
+}
+
+func (s *Store) transition(ctx context.Context, script *redis.Script, driverID string, to State, args ...any) (bool, error) {
+	changed, err := script.Run(ctx, s.rdb, []string{stateKey, busyKey}, append([]any{driverID}, args...)...).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to set driver %s %s: %w", driverID, to, err)
+	}
+	return changed == 1, nil
+}
+
+// MarkOffline marks driverID offline. It returns the change, or false if the
+// driver was not present.
+func (s *Store) MarkOffline(ctx context.Context, driverID string) (Change, bool, error) {
+	return s.markOffline(ctx, driverID, "")
+}
+
+func (s *Store) markOffline(ctx context.Context, driverID, notAfter string) (Change, bool, error) {
+	res, err := luaOffline.Run(ctx, s.rdb,
+		[]string{stateKey, deadlinesKey, packageKey, lastSeenKey},
+		driverID, notAfter,
+	).StringSlice()
+	if errors.Is(err, redis.Nil) {
+		return Change{}, false, nil
+	}
+	if err != nil {
+		return Change{}, false, fmt.Errorf("failed to mark driver %s offline: %w", driverID, err)
+	}
+	if len(res) != 3 {
+		return Change{}, false, fmt.Errorf("failed to mark driver %s offline: unexpected reply %q", driverID, res)
+	}
+	return Change{
+		Presence: Presence{
+			DriverID:    driverID,
+			State:       StateOffline,
+			PackageSlug: res[1],
+			LastSeen:    parseMillis(res[2]),
+		},
+		Previous: State(res[0]),
+	}, true, nil
+}
+
+// Expire marks offline every driver whose last heartbeat is older than the
+// TTL, and returns those it changed. Concurrent callers never report the same
+// change twice. It also makes drivers busy for longer than BusyTTL available
+// again.
+func (s *Store) Expire(ctx context.Context) ([]Change, error) {
+	cutoff := strconv.FormatInt(s.now().UnixMilli(), 10)
+	if err := s.expireBusy(ctx, cutoff); err != nil {
+		return nil, err
+	}
+	ids, err := s.rdb.ZRangeByScore(ctx, deadlinesKey, &redis.ZRangeBy{Min: "-inf", Max: cutoff}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired drivers: %w", err)
+	}
+
+	var changes []Change
+	for _, id := range ids {
+		change, changed, err := s.markOffline(ctx, id, cutoff)
+		if err != nil {
+			return changes, err
+		}
+		if changed {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (s *Store) expireBusy(ctx context.Context, cutoff string) error {
+	ids, err := s.rdb.ZRangeByScore(ctx, busyKey, &redis.ZRangeBy{Min: "-inf", Max: cutoff}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list drivers busy too long: %w", err)
+	}
+	for _, id := range ids {
+		if err := luaBusyExpired.Run(ctx, s.rdb, []string{stateKey, busyKey}, id, cutoff).Err(); err != nil {
+			return fmt.Errorf("failed to expire busy driver %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the presence of each of driverIDs in one round trip. Drivers
+// never seen are offline, and so are drivers whose heartbeats stopped but who
+// have not been expired yet.
+func (s *Store) Lookup(ctx context.Context, driverIDs []string) (map[string]Presence, error) {
+	result := make(map[string]Presence, len(driverIDs))
+	if len(driverIDs) == 0 {
+		return result, nil
+	}
+
+	pipe := s.rdb.Pipeline()
+	states := pipe.HMGet(ctx, stateKey, driverIDs...)
+	lastSeen := pipe.HMGet(ctx, lastSeenKey, driverIDs...)
+	packages := pipe.HMGet(ctx, packageKey, driverIDs...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to look up driver presence: %w", err)
+	}
+
+	stale := s.now().Add(-s.ttl)
+	for i, id := range driverIDs {
+		p := Presence{DriverID: id, State: StateOffline}
+		if v, ok := states.Val()[i].(string); ok {
+			p.State = State(v)
+		}
+		if v, ok := lastSeen.Val()[i].(string); ok {
+			p.LastSeen = parseMillis(v)
+		}
+		if v, ok := packages.Val()[i].(string); ok {
+			p.PackageSlug = v
+		}
+		if p.LastSeen.Before(stale) {
+			p.State = StateOffline
+		}
+		result[id] = p
+	}
+	return result, nil
+}
+
+func parseMillis(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,150 @@
+package presence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestStore returns a Store on a fresh in-memory Redis and a pointer to
+// its clock.
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	now := time.Unix(1_700_000_000, 0)
+	s := NewStore(rdb, DefaultTTL)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func stateOf(t *testing.T, s *Store, driverID string) State {
+	t.Helper()
+	found, err := s.Lookup(context.Background(), []string{driverID})
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	return found[driverID].State
+}
+
+func TestStoreTransitions(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t)
+
+	if prev, err := s.Heartbeat(ctx, "d1", "sedan"); err != nil || prev != StateOffline {
+		t.Fatalf("first Heartbeat = %q, %v; want offline", prev, err)
+	}
+	if got := stateOf(t, s, "d1"); got != StateOnline {
+		t.Fatalf("state after heartbeat = %s, want online", got)
+	}
+
+	if changed, err := s.SetBusy(ctx, "d1"); err != nil || !changed {
+		t.Fatalf("SetBusy = %v, %v; want changed", changed, err)
+	}
+	if prev, _ := s.Heartbeat(ctx, "d1", "sedan"); prev != StateBusy || stateOf(t, s, "d1") != StateBusy {
+		t.Fatalf("heartbeat did not keep the driver busy")
+	}
+
+	// Disconnecting mid-trip and reconnecting keeps the driver busy.
+	change, changed, err := s.MarkOffline(ctx, "d1")
+	if err != nil || !changed || change.Previous != StateBusy || change.PackageSlug != "sedan" {
+		t.Fatalf("MarkOffline = %+v, %v, %v", change, changed, err)
+	}
+	if _, changed, _ := s.MarkOffline(ctx, "d1"); changed {
+		t.Error("second MarkOffline reported a change")
+	}
+	s.Heartbeat(ctx, "d1", "sedan")
+	if got := stateOf(t, s, "d1"); got != StateBusy {
+		t.Fatalf("state after reconnecting mid-trip = %s, want busy", got)
+	}
+
+	if changed, err := s.SetAvailable(ctx, "d1"); err != nil || !changed {
+		t.Fatalf("SetAvailable = %v, %v; want changed", changed, err)
+	}
+	if changed, _ := s.SetAvailable(ctx, "d1"); changed {
+		t.Error("second SetAvailable reported a change")
+	}
+	if got := stateOf(t, s, "d1"); got != StateOnline {
+		t.Fatalf("state after the trip = %s, want online", got)
+	}
+
+	// A driver assigned while offline is busy once they reconnect, and
+	// online again after a reconnect that follows the trip's end.
+	s.MarkOffline(ctx, "d1")
+	if changed, _ := s.SetBusy(ctx, "d1"); changed {
+		t.Error("SetBusy changed an offline driver")
+	}
+	s.Heartbeat(ctx, "d1", "sedan")
+	if got := stateOf(t, s, "d1"); got != StateBusy {
+		t.Fatalf("state after reconnecting = %s, want busy", got)
+	}
+	s.MarkOffline(ctx, "d1")
+	s.SetAvailable(ctx, "d1")
+	s.Heartbeat(ctx, "d1", "sedan")
+	if got := stateOf(t, s, "d1"); got != StateOnline {
+		t.Fatalf("state after the trip ended offline = %s, want online", got)
+	}
+}
+
+func TestStoreExpire(t *testing.T) {
+	ctx := context.Background()
+	s, now := newTestStore(t)
+
+	s.Heartbeat(ctx, "d1", "sedan")
+	s.Heartbeat(ctx, "d2", "suv")
+	*now = now.Add(DefaultTTL / 2)
+	s.Heartbeat(ctx, "d2", "suv")
+	*now = now.Add(DefaultTTL/2 + time.Second)
+
+	changes, err := s.Expire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].DriverID != "d1" || changes[0].Previous != StateOnline {
+		t.Fatalf("Expire = %+v, want d1 going offline", changes)
+	}
+	if changes, _ := s.Expire(ctx); len(changes) != 0 {
+		t.Errorf("second Expire = %+v, want nothing", changes)
+	}
+	if got := stateOf(t, s, "d2"); got != StateOnline {
+		t.Errorf("d2 = %s, want online", got)
+	}
+}
+
+func TestStoreBusyExpires(t *testing.T) {
+	ctx := context.Background()
+	s, now := newTestStore(t)
+
+	// d1 stays connected; d2 is offline when their busy deadline passes.
+	s.Heartbeat(ctx, "d1", "sedan")
+	s.SetBusy(ctx, "d1")
+	s.Heartbeat(ctx, "d2", "sedan")
+	s.SetBusy(ctx, "d2")
+	s.MarkOffline(ctx, "d2")
+
+	*now = now.Add(BusyTTL - time.Minute)
+	s.Heartbeat(ctx, "d1", "sedan")
+	if _, err := s.Expire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := stateOf(t, s, "d1"); got != StateBusy {
+		t.Fatalf("d1 before BusyTTL = %s, want busy", got)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	s.Heartbeat(ctx, "d1", "sedan")
+	if _, err := s.Expire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := stateOf(t, s, "d1"); got != StateOnline {
+		t.Errorf("d1 after BusyTTL = %s, want online", got)
+	}
+	s.Heartbeat(ctx, "d2", "sedan")
+	if got := stateOf(t, s, "d2"); got != StateOnline {
+		t.Errorf("d2 reconnecting after BusyTTL = %s, want online", got)
+	}
+}
@@ -21,6 +21,914 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ShareTripRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	UserID        string                 `protobuf:"bytes,2,opt,name=userID,proto3" json:"userID,omitempty"`
+	TtlSeconds    int64                  `protobuf:"varint,3,opt,name=ttlSeconds,proto3" json:"ttlSeconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareTripRequest) Reset() {
+	*x = ShareTripRequest{}
+	mi := &file_trip_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareTripRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareTripRequest) ProtoMessage() {}
+
+func (x *ShareTripRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareTripRequest.ProtoReflect.Descriptor instead.
+func (*ShareTripRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ShareTripRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *ShareTripRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+func (x *ShareTripRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+type ShareTripResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShareID       string                 `protobuf:"bytes,1,opt,name=shareID,proto3" json:"shareID,omitempty"`
+	Token         string                 `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	ExpiresAt     int64                  `protobuf:"varint,3,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareTripResponse) Reset() {
+	*x = ShareTripResponse{}
+	mi := &file_trip_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareTripResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareTripResponse) ProtoMessage() {}
+
+func (x *ShareTripResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareTripResponse.ProtoReflect.Descriptor instead.
+func (*ShareTripResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ShareTripResponse) GetShareID() string {
+	if x != nil {
+		return x.ShareID
+	}
+	return ""
+}
+
+func (x *ShareTripResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ShareTripResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type RevokeTripShareRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	ShareID       string                 `protobuf:"bytes,2,opt,name=shareID,proto3" json:"shareID,omitempty"`
+	UserID        string                 `protobuf:"bytes,3,opt,name=userID,proto3" json:"userID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeTripShareRequest) Reset() {
+	*x = RevokeTripShareRequest{}
+	mi := &file_trip_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeTripShareRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeTripShareRequest) ProtoMessage() {}
+
+func (x *RevokeTripShareRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeTripShareRequest.ProtoReflect.Descriptor instead.
+func (*RevokeTripShareRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RevokeTripShareRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *RevokeTripShareRequest) GetShareID() string {
+	if x != nil {
+		return x.ShareID
+	}
+	return ""
+}
+
+func (x *RevokeTripShareRequest) GetUserID() string {
+	if x != nil {
+		return x.UserID
+	}
+	return ""
+}
+
+type RevokeTripShareResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeTripShareResponse) Reset() {
+	*x = RevokeTripShareResponse{}
+	mi := &file_trip_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeTripShareResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeTripShareResponse) ProtoMessage() {}
+
+func (x *RevokeTripShareResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeTripShareResponse.ProtoReflect.Descriptor instead.
+func (*RevokeTripShareResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{3}
+}
+
+type ListStuckSagasRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStuckSagasRequest) Reset() {
+	*x = ListStuckSagasRequest{}
+	mi := &file_trip_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStuckSagasRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStuckSagasRequest) ProtoMessage() {}
+
+func (x *ListStuckSagasRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStuckSagasRequest.ProtoReflect.Descriptor instead.
+func (*ListStuckSagasRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{4}
+}
+
+type ListStuckSagasResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sagas         []*TripSaga            `protobuf:"bytes,1,rep,name=sagas,proto3" json:"sagas,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStuckSagasResponse) Reset() {
+	*x = ListStuckSagasResponse{}
+	mi := &file_trip_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStuckSagasResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStuckSagasResponse) ProtoMessage() {}
+
+func (x *ListStuckSagasResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStuckSagasResponse.ProtoReflect.Descriptor instead.
+func (*ListStuckSagasResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListStuckSagasResponse) GetSagas() []*TripSaga {
+	if x != nil {
+		return x.Sagas
+	}
+	return nil
+}
+
+type TripSaga struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	TripID             string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	RiderID            string                 `protobuf:"bytes,2,opt,name=riderID,proto3" json:"riderID,omitempty"`
+	DriverID           string                 `protobuf:"bytes,3,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	SessionID          string                 `protobuf:"bytes,4,opt,name=sessionID,proto3" json:"sessionID,omitempty"`
+	Step               string                 `protobuf:"bytes,5,opt,name=step,proto3" json:"step,omitempty"`
+	Deadline           int64                  `protobuf:"varint,6,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	CompensationReason string                 `protobuf:"bytes,7,opt,name=compensationReason,proto3" json:"compensationReason,omitempty"`
+	LastError          string                 `protobuf:"bytes,8,opt,name=lastError,proto3" json:"lastError,omitempty"`
+	StartedAt          int64                  `protobuf:"varint,9,opt,name=startedAt,proto3" json:"startedAt,omitempty"`
+	UpdatedAt          int64                  `protobuf:"varint,10,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *TripSaga) Reset() {
+	*x = TripSaga{}
+	mi := &file_trip_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TripSaga) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TripSaga) ProtoMessage() {}
+
+func (x *TripSaga) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TripSaga.ProtoReflect.Descriptor instead.
+func (*TripSaga) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TripSaga) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *TripSaga) GetRiderID() string {
+	if x != nil {
+		return x.RiderID
+	}
+	return ""
+}
+
+func (x *TripSaga) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *TripSaga) GetSessionID() string {
+	if x != nil {
+		return x.SessionID
+	}
+	return ""
+}
+
+func (x *TripSaga) GetStep() string {
+	if x != nil {
+		return x.Step
+	}
+	return ""
+}
+
+func (x *TripSaga) GetDeadline() int64 {
+	if x != nil {
+		return x.Deadline
+	}
+	return 0
+}
+
+func (x *TripSaga) GetCompensationReason() string {
+	if x != nil {
+		return x.CompensationReason
+	}
+	return ""
+}
+
+func (x *TripSaga) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *TripSaga) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *TripSaga) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type GetTripRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTripRequest) Reset() {
+	*x = GetTripRequest{}
+	mi := &file_trip_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTripRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTripRequest) ProtoMessage() {}
+
+func (x *GetTripRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTripRequest.ProtoReflect.Descriptor instead.
+func (*GetTripRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetTripRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+type GetTripResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Trip          *Trip                  `protobuf:"bytes,1,opt,name=trip,proto3" json:"trip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTripResponse) Reset() {
+	*x = GetTripResponse{}
+	mi := &file_trip_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTripResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTripResponse) ProtoMessage() {}
+
+func (x *GetTripResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTripResponse.ProtoReflect.Descriptor instead.
+func (*GetTripResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetTripResponse) GetTrip() *Trip {
+	if x != nil {
+		return x.Trip
+	}
+	return nil
+}
+
+type GetTripTimelineRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTripTimelineRequest) Reset() {
+	*x = GetTripTimelineRequest{}
+	mi := &file_trip_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTripTimelineRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTripTimelineRequest) ProtoMessage() {}
+
+func (x *GetTripTimelineRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTripTimelineRequest.ProtoReflect.Descriptor instead.
+func (*GetTripTimelineRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetTripTimelineRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+type GetTripTimelineResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*TripTimelineEvent   `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Trip          *Trip                  `protobuf:"bytes,2,opt,name=trip,proto3" json:"trip,omitempty"`
+	FoldError     string                 `protobuf:"bytes,3,opt,name=foldError,proto3" json:"foldError,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTripTimelineResponse) Reset() {
+	*x = GetTripTimelineResponse{}
+	mi := &file_trip_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTripTimelineResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTripTimelineResponse) ProtoMessage() {}
+
+func (x *GetTripTimelineResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTripTimelineResponse.ProtoReflect.Descriptor instead.
+func (*GetTripTimelineResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetTripTimelineResponse) GetEvents() []*TripTimelineEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *GetTripTimelineResponse) GetTrip() *Trip {
+	if x != nil {
+		return x.Trip
+	}
+	return nil
+}
+
+func (x *GetTripTimelineResponse) GetFoldError() string {
+	if x != nil {
+		return x.FoldError
+	}
+	return ""
+}
+
+type TripTimelineEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageID     string                 `protobuf:"bytes,1,opt,name=messageID,proto3" json:"messageID,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Actor         string                 `protobuf:"bytes,3,opt,name=actor,proto3" json:"actor,omitempty"`
+	OwnerID       string                 `protobuf:"bytes,4,opt,name=ownerID,proto3" json:"ownerID,omitempty"`
+	OccurredAt    int64                  `protobuf:"varint,5,opt,name=occurredAt,proto3" json:"occurredAt,omitempty"`
+	PayloadDigest string                 `protobuf:"bytes,6,opt,name=payloadDigest,proto3" json:"payloadDigest,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TripTimelineEvent) Reset() {
+	*x = TripTimelineEvent{}
+	mi := &file_trip_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TripTimelineEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TripTimelineEvent) ProtoMessage() {}
+
+func (x *TripTimelineEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TripTimelineEvent.ProtoReflect.Descriptor instead.
+func (*TripTimelineEvent) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TripTimelineEvent) GetMessageID() string {
+	if x != nil {
+		return x.MessageID
+	}
+	return ""
+}
+
+func (x *TripTimelineEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TripTimelineEvent) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *TripTimelineEvent) GetOwnerID() string {
+	if x != nil {
+		return x.OwnerID
+	}
+	return ""
+}
+
+func (x *TripTimelineEvent) GetOccurredAt() int64 {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return 0
+}
+
+func (x *TripTimelineEvent) GetPayloadDigest() string {
+	if x != nil {
+		return x.PayloadDigest
+	}
+	return ""
+}
+
+type GetTripTraceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	Format        string                 `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTripTraceRequest) Reset() {
+	*x = GetTripTraceRequest{}
+	mi := &file_trip_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTripTraceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTripTraceRequest) ProtoMessage() {}
+
+func (x *GetTripTraceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTripTraceRequest.ProtoReflect.Descriptor instead.
+func (*GetTripTraceRequest) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetTripTraceRequest) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *GetTripTraceRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+type GetTripTraceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	DriverID      string                 `protobuf:"bytes,2,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Points        []*TracePoint          `protobuf:"bytes,3,rep,name=points,proto3" json:"points,omitempty"`
+	StartedAt     int64                  `protobuf:"varint,4,opt,name=startedAt,proto3" json:"startedAt,omitempty"`
+	EndedAt       int64                  `protobuf:"varint,5,opt,name=endedAt,proto3" json:"endedAt,omitempty"`
+	Anonymized    bool                   `protobuf:"varint,6,opt,name=anonymized,proto3" json:"anonymized,omitempty"`
+	Export        []byte                 `protobuf:"bytes,7,opt,name=export,proto3" json:"export,omitempty"`
+	ContentType   string                 `protobuf:"bytes,8,opt,name=contentType,proto3" json:"contentType,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTripTraceResponse) Reset() {
+	*x = GetTripTraceResponse{}
+	mi := &file_trip_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTripTraceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTripTraceResponse) ProtoMessage() {}
+
+func (x *GetTripTraceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTripTraceResponse.ProtoReflect.Descriptor instead.
+func (*GetTripTraceResponse) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetTripTraceResponse) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *GetTripTraceResponse) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *GetTripTraceResponse) GetPoints() []*TracePoint {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+func (x *GetTripTraceResponse) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *GetTripTraceResponse) GetEndedAt() int64 {
+	if x != nil {
+		return x.EndedAt
+	}
+	return 0
+}
+
+func (x *GetTripTraceResponse) GetAnonymized() bool {
+	if x != nil {
+		return x.Anonymized
+	}
+	return false
+}
+
+func (x *GetTripTraceResponse) GetExport() []byte {
+	if x != nil {
+		return x.Export
+	}
+	return nil
+}
+
+func (x *GetTripTraceResponse) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+type TracePoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Latitude      float64                `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64                `protobuf:"fixed64,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	At            int64                  `protobuf:"varint,3,opt,name=at,proto3" json:"at,omitempty"`
+	Speed         float64                `protobuf:"fixed64,4,opt,name=speed,proto3" json:"speed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TracePoint) Reset() {
+	*x = TracePoint{}
+	mi := &file_trip_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TracePoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TracePoint) ProtoMessage() {}
+
+func (x *TracePoint) ProtoReflect() protoreflect.Message {
+	mi := &file_trip_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TracePoint.ProtoReflect.Descriptor instead.
+func (*TracePoint) Descriptor() ([]byte, []int) {
+	return file_trip_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TracePoint) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *TracePoint) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *TracePoint) GetAt() int64 {
+	if x != nil {
+		return x.At
+	}
+	return 0
+}
+
+func (x *TracePoint) GetSpeed() float64 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
 type CancelTripRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TripID        string                 `protobuf:"bytes,1,opt,name=tripID,proto3" json:"tripID,omitempty"`
@@ -31,7 +939,7 @@ type CancelTripRequest struct {
 
 func (x *CancelTripRequest) Reset() {
 	*x = CancelTripRequest{}
-	mi := &file_trip_proto_msgTypes[0]
+	mi := &file_trip_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -43,7 +951,7 @@ func (x *CancelTripRequest) String() string {
 func (*CancelTripRequest) ProtoMessage() {}
 
 func (x *CancelTripRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[0]
+	mi := &file_trip_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -56,7 +964,7 @@ func (x *CancelTripRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelTripRequest.ProtoReflect.Descriptor instead.
 func (*CancelTripRequest) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{0}
+	return file_trip_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *CancelTripRequest) GetTripID() string {
@@ -82,7 +990,7 @@ type CancelTripResponse struct {
 
 func (x *CancelTripResponse) Reset() {
 	*x = CancelTripResponse{}
-	mi := &file_trip_proto_msgTypes[1]
+	mi := &file_trip_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -94,7 +1002,7 @@ func (x *CancelTripResponse) String() string {
 func (*CancelTripResponse) ProtoMessage() {}
 
 func (x *CancelTripResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[1]
+	mi := &file_trip_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -107,7 +1015,7 @@ func (x *CancelTripResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelTripResponse.ProtoReflect.Descriptor instead.
 func (*CancelTripResponse) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{1}
+	return file_trip_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *CancelTripResponse) GetDriverID() string {
@@ -127,7 +1035,7 @@ type CreateTripRequest struct {
 
 func (x *CreateTripRequest) Reset() {
 	*x = CreateTripRequest{}
-	mi := &file_trip_proto_msgTypes[2]
+	mi := &file_trip_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -139,7 +1047,7 @@ func (x *CreateTripRequest) String() string {
 func (*CreateTripRequest) ProtoMessage() {}
 
 func (x *CreateTripRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[2]
+	mi := &file_trip_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -152,7 +1060,7 @@ func (x *CreateTripRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateTripRequest.ProtoReflect.Descriptor instead.
 func (*CreateTripRequest) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{2}
+	return file_trip_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *CreateTripRequest) GetRideFareID() string {
@@ -179,7 +1087,7 @@ type CreateTripResponse struct {
 
 func (x *CreateTripResponse) Reset() {
 	*x = CreateTripResponse{}
-	mi := &file_trip_proto_msgTypes[3]
+	mi := &file_trip_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -191,7 +1099,7 @@ func (x *CreateTripResponse) String() string {
 func (*CreateTripResponse) ProtoMessage() {}
 
 func (x *CreateTripResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[3]
+	mi := &file_trip_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -204,7 +1112,7 @@ func (x *CreateTripResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateTripResponse.ProtoReflect.Descriptor instead.
 func (*CreateTripResponse) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{3}
+	return file_trip_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *CreateTripResponse) GetTripID() string {
@@ -235,7 +1143,7 @@ type Trip struct {
 
 func (x *Trip) Reset() {
 	*x = Trip{}
-	mi := &file_trip_proto_msgTypes[4]
+	mi := &file_trip_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -247,7 +1155,7 @@ func (x *Trip) String() string {
 func (*Trip) ProtoMessage() {}
 
 func (x *Trip) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[4]
+	mi := &file_trip_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -260,7 +1168,7 @@ func (x *Trip) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Trip.ProtoReflect.Descriptor instead.
 func (*Trip) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{4}
+	return file_trip_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *Trip) GetId() string {
@@ -317,7 +1225,7 @@ type TripDriver struct {
 
 func (x *TripDriver) Reset() {
 	*x = TripDriver{}
-	mi := &file_trip_proto_msgTypes[5]
+	mi := &file_trip_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -329,7 +1237,7 @@ func (x *TripDriver) String() string {
 func (*TripDriver) ProtoMessage() {}
 
 func (x *TripDriver) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[5]
+	mi := &file_trip_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -342,7 +1250,7 @@ func (x *TripDriver) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TripDriver.ProtoReflect.Descriptor instead.
 func (*TripDriver) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{5}
+	return file_trip_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *TripDriver) GetId() string {
@@ -384,7 +1292,7 @@ type PreviewTripRequest struct {
 
 func (x *PreviewTripRequest) Reset() {
 	*x = PreviewTripRequest{}
-	mi := &file_trip_proto_msgTypes[6]
+	mi := &file_trip_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -396,7 +1304,7 @@ func (x *PreviewTripRequest) String() string {
 func (*PreviewTripRequest) ProtoMessage() {}
 
 func (x *PreviewTripRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[6]
+	mi := &file_trip_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -409,7 +1317,7 @@ func (x *PreviewTripRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PreviewTripRequest.ProtoReflect.Descriptor instead.
 func (*PreviewTripRequest) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{6}
+	return file_trip_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *PreviewTripRequest) GetUserID() string {
@@ -444,7 +1352,7 @@ type PreviewTripResponse struct {
 
 func (x *PreviewTripResponse) Reset() {
 	*x = PreviewTripResponse{}
-	mi := &file_trip_proto_msgTypes[7]
+	mi := &file_trip_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -456,7 +1364,7 @@ func (x *PreviewTripResponse) String() string {
 func (*PreviewTripResponse) ProtoMessage() {}
 
 func (x *PreviewTripResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[7]
+	mi := &file_trip_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -469,7 +1377,7 @@ func (x *PreviewTripResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PreviewTripResponse.ProtoReflect.Descriptor instead.
 func (*PreviewTripResponse) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{7}
+	return file_trip_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *PreviewTripResponse) GetTripID() string {
@@ -503,7 +1411,7 @@ type Coordinate struct {
 
 func (x *Coordinate) Reset() {
 	*x = Coordinate{}
-	mi := &file_trip_proto_msgTypes[8]
+	mi := &file_trip_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -515,7 +1423,7 @@ func (x *Coordinate) String() string {
 func (*Coordinate) ProtoMessage() {}
 
 func (x *Coordinate) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[8]
+	mi := &file_trip_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -528,7 +1436,7 @@ func (x *Coordinate) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Coordinate.ProtoReflect.Descriptor instead.
 func (*Coordinate) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{8}
+	return file_trip_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *Coordinate) GetLatitude() float64 {
@@ -554,7 +1462,7 @@ type Geometry struct {
 
 func (x *Geometry) Reset() {
 	*x = Geometry{}
-	mi := &file_trip_proto_msgTypes[9]
+	mi := &file_trip_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -566,7 +1474,7 @@ func (x *Geometry) String() string {
 func (*Geometry) ProtoMessage() {}
 
 func (x *Geometry) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[9]
+	mi := &file_trip_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -579,7 +1487,7 @@ func (x *Geometry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Geometry.ProtoReflect.Descriptor instead.
 func (*Geometry) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{9}
+	return file_trip_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *Geometry) GetCoordinates() []*Coordinate {
@@ -600,7 +1508,7 @@ type Route struct {
 
 func (x *Route) Reset() {
 	*x = Route{}
-	mi := &file_trip_proto_msgTypes[10]
+	mi := &file_trip_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -612,7 +1520,7 @@ func (x *Route) String() string {
 func (*Route) ProtoMessage() {}
 
 func (x *Route) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[10]
+	mi := &file_trip_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -625,7 +1533,7 @@ func (x *Route) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Route.ProtoReflect.Descriptor instead.
 func (*Route) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{10}
+	return file_trip_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *Route) GetGeometry() []*Geometry {
@@ -660,7 +1568,7 @@ type RideFare struct {
 
 func (x *RideFare) Reset() {
 	*x = RideFare{}
-	mi := &file_trip_proto_msgTypes[11]
+	mi := &file_trip_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -672,7 +1580,7 @@ func (x *RideFare) String() string {
 func (*RideFare) ProtoMessage() {}
 
 func (x *RideFare) ProtoReflect() protoreflect.Message {
-	mi := &file_trip_proto_msgTypes[11]
+	mi := &file_trip_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -685,7 +1593,7 @@ func (x *RideFare) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RideFare.ProtoReflect.Descriptor instead.
 func (*RideFare) Descriptor() ([]byte, []int) {
-	return file_trip_proto_rawDescGZIP(), []int{11}
+	return file_trip_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *RideFare) GetId() string {
@@ -714,7 +1622,78 @@ var File_trip_proto protoreflect.FileDescriptor
 const file_trip_proto_rawDesc = "" +
 	"\n" +
 	"\n" +
-	"trip.proto\x12\x04trip\"C\n" +
+	"trip.proto\x12\x04trip\"b\n" +
+	"\x10ShareTripRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06userID\x18\x02 \x01(\tR\x06userID\x12\x1e\n" +
+	"\n" +
+	"ttlSeconds\x18\x03 \x01(\x03R\n" +
+	"ttlSeconds\"a\n" +
+	"\x11ShareTripResponse\x12\x18\n" +
+	"\ashareID\x18\x01 \x01(\tR\ashareID\x12\x14\n" +
+	"\x05token\x18\x02 \x01(\tR\x05token\x12\x1c\n" +
+	"\texpiresAt\x18\x03 \x01(\x03R\texpiresAt\"b\n" +
+	"\x16RevokeTripShareRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x18\n" +
+	"\ashareID\x18\x02 \x01(\tR\ashareID\x12\x16\n" +
+	"\x06userID\x18\x03 \x01(\tR\x06userID\"\x19\n" +
+	"\x17RevokeTripShareResponse\"\x17\n" +
+	"\x15ListStuckSagasRequest\">\n" +
+	"\x16ListStuckSagasResponse\x12$\n" +
+	"\x05sagas\x18\x01 \x03(\v2\x0e.trip.TripSagaR\x05sagas\"\xb0\x02\n" +
+	"\bTripSaga\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x18\n" +
+	"\ariderID\x18\x02 \x01(\tR\ariderID\x12\x1a\n" +
+	"\bdriverID\x18\x03 \x01(\tR\bdriverID\x12\x1c\n" +
+	"\tsessionID\x18\x04 \x01(\tR\tsessionID\x12\x12\n" +
+	"\x04step\x18\x05 \x01(\tR\x04step\x12\x1a\n" +
+	"\bdeadline\x18\x06 \x01(\x03R\bdeadline\x12.\n" +
+	"\x12compensationReason\x18\a \x01(\tR\x12compensationReason\x12\x1c\n" +
+	"\tlastError\x18\b \x01(\tR\tlastError\x12\x1c\n" +
+	"\tstartedAt\x18\t \x01(\x03R\tstartedAt\x12\x1c\n" +
+	"\tupdatedAt\x18\n" +
+	" \x01(\x03R\tupdatedAt\"(\n" +
+	"\x0eGetTripRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\"1\n" +
+	"\x0fGetTripResponse\x12\x1e\n" +
+	"\x04trip\x18\x01 \x01(\v2\n" +
+	".trip.TripR\x04trip\"0\n" +
+	"\x16GetTripTimelineRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\"\x88\x01\n" +
+	"\x17GetTripTimelineResponse\x12/\n" +
+	"\x06events\x18\x01 \x03(\v2\x17.trip.TripTimelineEventR\x06events\x12\x1e\n" +
+	"\x04trip\x18\x02 \x01(\v2\n" +
+	".trip.TripR\x04trip\x12\x1c\n" +
+	"\tfoldError\x18\x03 \x01(\tR\tfoldError\"\xbb\x01\n" +
+	"\x11TripTimelineEvent\x12\x1c\n" +
+	"\tmessageID\x18\x01 \x01(\tR\tmessageID\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
+	"\x05actor\x18\x03 \x01(\tR\x05actor\x12\x18\n" +
+	"\aownerID\x18\x04 \x01(\tR\aownerID\x12\x1e\n" +
+	"\n" +
+	"occurredAt\x18\x05 \x01(\x03R\n" +
+	"occurredAt\x12$\n" +
+	"\rpayloadDigest\x18\x06 \x01(\tR\rpayloadDigest\"E\n" +
+	"\x13GetTripTraceRequest\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
+	"\x06format\x18\x02 \x01(\tR\x06format\"\x86\x02\n" +
+	"\x14GetTripTraceResponse\x12\x16\n" +
+	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x1a\n" +
+	"\bdriverID\x18\x02 \x01(\tR\bdriverID\x12(\n" +
+	"\x06points\x18\x03 \x03(\v2\x10.trip.TracePointR\x06points\x12\x1c\n" +
+	"\tstartedAt\x18\x04 \x01(\x03R\tstartedAt\x12\x18\n" +
+	"\aendedAt\x18\x05 \x01(\x03R\aendedAt\x12\x1e\n" +
+	"\n" +
+	"anonymized\x18\x06 \x01(\bR\n" +
+	"anonymized\x12\x16\n" +
+	"\x06export\x18\a \x01(\fR\x06export\x12 \n" +
+	"\vcontentType\x18\b \x01(\tR\vcontentType\"l\n" +
+	"\n" +
+	"TracePoint\x12\x1a\n" +
+	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\x12\x0e\n" +
+	"\x02at\x18\x03 \x01(\x03R\x02at\x12\x14\n" +
+	"\x05speed\x18\x04 \x01(\x01R\x05speed\"C\n" +
 	"\x11CancelTripRequest\x12\x16\n" +
 	"\x06tripID\x18\x01 \x01(\tR\x06tripID\x12\x16\n" +
 	"\x06userID\x18\x02 \x01(\tR\x06userID\"0\n" +
@@ -763,13 +1742,19 @@ const file_trip_proto_rawDesc = "" +
 	"\bRideFare\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12 \n" +
 	"\vpackageSlug\x18\x03 \x01(\tR\vpackageSlug\x12,\n" +
-	"\x11totalPriceInCents\x18\x04 \x01(\x01R\x11totalPriceInCents2\xd3\x01\n" +
+	"\x11totalPriceInCents\x18\x04 \x01(\x01R\x11totalPriceInCents2\xfd\x04\n" +
 	"\vTripService\x12B\n" +
 	"\vPreviewTrip\x12\x18.trip.PreviewTripRequest\x1a\x19.trip.PreviewTripResponse\x12?\n" +
 	"\n" +
 	"CreateTrip\x12\x17.trip.CreateTripRequest\x1a\x18.trip.CreateTripResponse\x12?\n" +
 	"\n" +
-	"CancelTrip\x12\x17.trip.CancelTripRequest\x1a\x18.trip.CancelTripResponseB\x18Z\x16shared/proto/trip;tripb\x06proto3"
+	"CancelTrip\x12\x17.trip.CancelTripRequest\x1a\x18.trip.CancelTripResponse\x126\n" +
+	"\aGetTrip\x12\x14.trip.GetTripRequest\x1a\x15.trip.GetTripResponse\x12N\n" +
+	"\x0fGetTripTimeline\x12\x1c.trip.GetTripTimelineRequest\x1a\x1d.trip.GetTripTimelineResponse\x12E\n" +
+	"\fGetTripTrace\x12\x19.trip.GetTripTraceRequest\x1a\x1a.trip.GetTripTraceResponse\x12K\n" +
+	"\x0eListStuckSagas\x12\x1b.trip.ListStuckSagasRequest\x1a\x1c.trip.ListStuckSagasResponse\x12<\n" +
+	"\tShareTrip\x12\x16.trip.ShareTripRequest\x1a\x17.trip.ShareTripResponse\x12N\n" +
+	"\x0fRevokeTripShare\x12\x1c.trip.RevokeTripShareRequest\x1a\x1d.trip.RevokeTripShareResponseB\x18Z\x16shared/proto/trip;tripb\x06proto3"
 
 var (
 	file_trip_proto_rawDescOnce sync.Once
@@ -783,43 +1768,75 @@ func file_trip_proto_rawDescGZIP() []byte {
 	return file_trip_proto_rawDescData
 }
 
-var file_trip_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_trip_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
 var file_trip_proto_goTypes = []any{
-	(*CancelTripRequest)(nil),   // 0: trip.CancelTripRequest
-	(*CancelTripResponse)(nil),  // 1: trip.CancelTripResponse
-	(*CreateTripRequest)(nil),   // 2: trip.CreateTripRequest
-	(*CreateTripResponse)(nil),  // 3: trip.CreateTripResponse
-	(*Trip)(nil),                // 4: trip.Trip
-	(*TripDriver)(nil),          // 5: trip.TripDriver
-	(*PreviewTripRequest)(nil),  // 6: trip.PreviewTripRequest
-	(*PreviewTripResponse)(nil), // 7: trip.PreviewTripResponse
-	(*Coordinate)(nil),          // 8: trip.Coordinate
-	(*Geometry)(nil),            // 9: trip.Geometry
-	(*Route)(nil),               // 10: trip.Route
-	(*RideFare)(nil),            // 11: trip.RideFare
+	(*ShareTripRequest)(nil),        // 0: trip.ShareTripRequest
+	(*ShareTripResponse)(nil),       // 1: trip.ShareTripResponse
+	(*RevokeTripShareRequest)(nil),  // 2: trip.RevokeTripShareRequest
+	(*RevokeTripShareResponse)(nil), // 3: trip.RevokeTripShareResponse
+	(*ListStuckSagasRequest)(nil),   // 4: trip.ListStuckSagasRequest
+	(*ListStuckSagasResponse)(nil),  // 5: trip.ListStuckSagasResponse
+	(*TripSaga)(nil),                // 6: trip.TripSaga
+	(*GetTripRequest)(nil),          // 7: trip.GetTripRequest
+	(*GetTripResponse)(nil),         // 8: trip.GetTripResponse
+	(*GetTripTimelineRequest)(nil),  // 9: trip.GetTripTimelineRequest
+	(*GetTripTimelineResponse)(nil), // 10: trip.GetTripTimelineResponse
+	(*TripTimelineEvent)(nil),       // 11: trip.TripTimelineEvent
+	(*GetTripTraceRequest)(nil),     // 12: trip.GetTripTraceRequest
+	(*GetTripTraceResponse)(nil),    // 13: trip.GetTripTraceResponse
+	(*TracePoint)(nil),              // 14: trip.TracePoint
+	(*CancelTripRequest)(nil),       // 15: trip.CancelTripRequest
+	(*CancelTripResponse)(nil),      // 16: trip.CancelTripResponse
+	(*CreateTripRequest)(nil),       // 17: trip.CreateTripRequest
+	(*CreateTripResponse)(nil),      // 18: trip.CreateTripResponse
+	(*Trip)(nil),                    // 19: trip.Trip
+	(*TripDriver)(nil),              // 20: trip.TripDriver
+	(*PreviewTripRequest)(nil),      // 21: trip.PreviewTripRequest
+	(*PreviewTripResponse)(nil),     // 22: trip.PreviewTripResponse
+	(*Coordinate)(nil),              // 23: trip.Coordinate
+	(*Geometry)(nil),                // 24: trip.Geometry
+	(*Route)(nil),                   // 25: trip.Route
+	(*RideFare)(nil),                // 26: trip.RideFare
 }
 var file_trip_proto_depIdxs = []int32{
-	4,  // 0: trip.CreateTripResponse.trip:type_name -> trip.Trip
-	11, // 1: trip.Trip.selectedFare:type_name -> trip.RideFare
-	10, // 2: trip.Trip.route:type_name -> trip.Route
-	5,  // 3: trip.Trip.driver:type_name -> trip.TripDriver
-	8,  // 4: trip.PreviewTripRequest.startLocation:type_name -> trip.Coordinate
-	8,  // 5: trip.PreviewTripRequest.endLocation:type_name -> trip.Coordinate
-	10, // 6: trip.PreviewTripResponse.route:type_name -> trip.Route
-	11, // 7: trip.PreviewTripResponse.rideFares:type_name -> trip.RideFare
-	8,  // 8: trip.Geometry.coordinates:type_name -> trip.Coordinate
-	9,  // 9: trip.Route.geometry:type_name -> trip.Geometry
-	6,  // 10: trip.TripService.PreviewTrip:input_type -> trip.PreviewTripRequest
-	2,  // 11: trip.TripService.CreateTrip:input_type -> trip.CreateTripRequest
-	0,  // 12: trip.TripService.CancelTrip:input_type -> trip.CancelTripRequest
-	7,  // 13: trip.TripService.PreviewTrip:output_type -> trip.PreviewTripResponse
-	3,  // 14: trip.TripService.CreateTrip:output_type -> trip.CreateTripResponse
-	1,  // 15: trip.TripService.CancelTrip:output_type -> trip.CancelTripResponse
-	13, // [13:16] is the sub-list for method output_type
-	10, // [10:13] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	6,  // 0: trip.ListStuckSagasResponse.sagas:type_name -> trip.TripSaga
+	19, // 1: trip.GetTripResponse.trip:type_name -> trip.Trip
+	11, // 2: trip.GetTripTimelineResponse.events:type_name -> trip.TripTimelineEvent
+	19, // 3: trip.GetTripTimelineResponse.trip:type_name -> trip.Trip
+	14, // 4: trip.GetTripTraceResponse.points:type_name -> trip.TracePoint
+	19, // 5: trip.CreateTripResponse.trip:type_name -> trip.Trip
+	26, // 6: trip.Trip.selectedFare:type_name -> trip.RideFare
+	25, // 7: trip.Trip.route:type_name -> trip.Route
+	20, // 8: trip.Trip.driver:type_name -> trip.TripDriver
+	23, // 9: trip.PreviewTripRequest.startLocation:type_name -> trip.Coordinate
+	23, // 10: trip.PreviewTripRequest.endLocation:type_name -> trip.Coordinate
+	25, // 11: trip.PreviewTripResponse.route:type_name -> trip.Route
+	26, // 12: trip.PreviewTripResponse.rideFares:type_name -> trip.RideFare
+	23, // 13: trip.Geometry.coordinates:type_name -> trip.Coordinate
+	24, // 14: trip.Route.geometry:type_name -> trip.Geometry
+	21, // 15: trip.TripService.PreviewTrip:input_type -> trip.PreviewTripRequest
+	17, // 16: trip.TripService.CreateTrip:input_type -> trip.CreateTripRequest
+	15, // 17: trip.TripService.CancelTrip:input_type -> trip.CancelTripRequest
+	7,  // 18: trip.TripService.GetTrip:input_type -> trip.GetTripRequest
+	9,  // 19: trip.TripService.GetTripTimeline:input_type -> trip.GetTripTimelineRequest
+	12, // 20: trip.TripService.GetTripTrace:input_type -> trip.GetTripTraceRequest
+	4,  // 21: trip.TripService.ListStuckSagas:input_type -> trip.ListStuckSagasRequest
+	0,  // 22: trip.TripService.ShareTrip:input_type -> trip.ShareTripRequest
+	2,  // 23: trip.TripService.RevokeTripShare:input_type -> trip.RevokeTripShareRequest
+	22, // 24: trip.TripService.PreviewTrip:output_type -> trip.PreviewTripResponse
+	18, // 25: trip.TripService.CreateTrip:output_type -> trip.CreateTripResponse
+	16, // 26: trip.TripService.CancelTrip:output_type -> trip.CancelTripResponse
+	8,  // 27: trip.TripService.GetTrip:output_type -> trip.GetTripResponse
+	10, // 28: trip.TripService.GetTripTimeline:output_type -> trip.GetTripTimelineResponse
+	13, // 29: trip.TripService.GetTripTrace:output_type -> trip.GetTripTraceResponse
+	5,  // 30: trip.TripService.ListStuckSagas:output_type -> trip.ListStuckSagasResponse
+	1,  // 31: trip.TripService.ShareTrip:output_type -> trip.ShareTripResponse
+	3,  // 32: trip.TripService.RevokeTripShare:output_type -> trip.RevokeTripShareResponse
+	24, // [24:33] is the sub-list for method output_type
+	15, // [15:24] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_trip_proto_init() }
@@ -833,7 +1850,7 @@ func file_trip_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_trip_proto_rawDesc), len(file_trip_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   12,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
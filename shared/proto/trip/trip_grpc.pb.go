@@ -19,9 +19,15 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	TripService_PreviewTrip_FullMethodName = "/trip.TripService/PreviewTrip"
-	TripService_CreateTrip_FullMethodName  = "/trip.TripService/CreateTrip"
-	TripService_CancelTrip_FullMethodName  = "/trip.TripService/CancelTrip"
+	TripService_PreviewTrip_FullMethodName     = "/trip.TripService/PreviewTrip"
+	TripService_CreateTrip_FullMethodName      = "/trip.TripService/CreateTrip"
+	TripService_CancelTrip_FullMethodName      = "/trip.TripService/CancelTrip"
+	TripService_GetTrip_FullMethodName         = "/trip.TripService/GetTrip"
+	TripService_GetTripTimeline_FullMethodName = "/trip.TripService/GetTripTimeline"
+	TripService_GetTripTrace_FullMethodName    = "/trip.TripService/GetTripTrace"
+	TripService_ListStuckSagas_FullMethodName  = "/trip.TripService/ListStuckSagas"
+	TripService_ShareTrip_FullMethodName       = "/trip.TripService/ShareTrip"
+	TripService_RevokeTripShare_FullMethodName = "/trip.TripService/RevokeTripShare"
 )
 
 // TripServiceClient is the client API for TripService service.
@@ -31,6 +37,12 @@ type TripServiceClient interface {
 	PreviewTrip(ctx context.Context, in *PreviewTripRequest, opts ...grpc.CallOption) (*PreviewTripResponse, error)
 	CreateTrip(ctx context.Context, in *CreateTripRequest, opts ...grpc.CallOption) (*CreateTripResponse, error)
 	CancelTrip(ctx context.Context, in *CancelTripRequest, opts ...grpc.CallOption) (*CancelTripResponse, error)
+	GetTrip(ctx context.Context, in *GetTripRequest, opts ...grpc.CallOption) (*GetTripResponse, error)
+	GetTripTimeline(ctx context.Context, in *GetTripTimelineRequest, opts ...grpc.CallOption) (*GetTripTimelineResponse, error)
+	GetTripTrace(ctx context.Context, in *GetTripTraceRequest, opts ...grpc.CallOption) (*GetTripTraceResponse, error)
+	ListStuckSagas(ctx context.Context, in *ListStuckSagasRequest, opts ...grpc.CallOption) (*ListStuckSagasResponse, error)
+	ShareTrip(ctx context.Context, in *ShareTripRequest, opts ...grpc.CallOption) (*ShareTripResponse, error)
+	RevokeTripShare(ctx context.Context, in *RevokeTripShareRequest, opts ...grpc.CallOption) (*RevokeTripShareResponse, error)
 }
 
 type tripServiceClient struct {
@@ -71,6 +83,66 @@ func (c *tripServiceClient) CancelTrip(ctx context.Context, in *CancelTripReques
 	return out, nil
 }
 
+func (c *tripServiceClient) GetTrip(ctx context.Context, in *GetTripRequest, opts ...grpc.CallOption) (*GetTripResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTripResponse)
+	err := c.cc.Invoke(ctx, TripService_GetTrip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) GetTripTimeline(ctx context.Context, in *GetTripTimelineRequest, opts ...grpc.CallOption) (*GetTripTimelineResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTripTimelineResponse)
+	err := c.cc.Invoke(ctx, TripService_GetTripTimeline_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) GetTripTrace(ctx context.Context, in *GetTripTraceRequest, opts ...grpc.CallOption) (*GetTripTraceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTripTraceResponse)
+	err := c.cc.Invoke(ctx, TripService_GetTripTrace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) ListStuckSagas(ctx context.Context, in *ListStuckSagasRequest, opts ...grpc.CallOption) (*ListStuckSagasResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListStuckSagasResponse)
+	err := c.cc.Invoke(ctx, TripService_ListStuckSagas_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) ShareTrip(ctx context.Context, in *ShareTripRequest, opts ...grpc.CallOption) (*ShareTripResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ShareTripResponse)
+	err := c.cc.Invoke(ctx, TripService_ShareTrip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tripServiceClient) RevokeTripShare(ctx context.Context, in *RevokeTripShareRequest, opts ...grpc.CallOption) (*RevokeTripShareResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeTripShareResponse)
+	err := c.cc.Invoke(ctx, TripService_RevokeTripShare_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TripServiceServer is the server API for TripService service.
 // All implementations must embed UnimplementedTripServiceServer
 // for forward compatibility.
@@ -78,6 +150,12 @@ type TripServiceServer interface {
 	PreviewTrip(context.Context, *PreviewTripRequest) (*PreviewTripResponse, error)
 	CreateTrip(context.Context, *CreateTripRequest) (*CreateTripResponse, error)
 	CancelTrip(context.Context, *CancelTripRequest) (*CancelTripResponse, error)
+	GetTrip(context.Context, *GetTripRequest) (*GetTripResponse, error)
+	GetTripTimeline(context.Context, *GetTripTimelineRequest) (*GetTripTimelineResponse, error)
+	GetTripTrace(context.Context, *GetTripTraceRequest) (*GetTripTraceResponse, error)
+	ListStuckSagas(context.Context, *ListStuckSagasRequest) (*ListStuckSagasResponse, error)
+	ShareTrip(context.Context, *ShareTripRequest) (*ShareTripResponse, error)
+	RevokeTripShare(context.Context, *RevokeTripShareRequest) (*RevokeTripShareResponse, error)
 	mustEmbedUnimplementedTripServiceServer()
 }
 
@@ -97,6 +175,24 @@ func (UnimplementedTripServiceServer) CreateTrip(context.Context, *CreateTripReq
 func (UnimplementedTripServiceServer) CancelTrip(context.Context, *CancelTripRequest) (*CancelTripResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method CancelTrip not implemented")
 }
+func (UnimplementedTripServiceServer) GetTrip(context.Context, *GetTripRequest) (*GetTripResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetTrip not implemented")
+}
+func (UnimplementedTripServiceServer) GetTripTimeline(context.Context, *GetTripTimelineRequest) (*GetTripTimelineResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetTripTimeline not implemented")
+}
+func (UnimplementedTripServiceServer) GetTripTrace(context.Context, *GetTripTraceRequest) (*GetTripTraceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetTripTrace not implemented")
+}
+func (UnimplementedTripServiceServer) ListStuckSagas(context.Context, *ListStuckSagasRequest) (*ListStuckSagasResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListStuckSagas not implemented")
+}
+func (UnimplementedTripServiceServer) ShareTrip(context.Context, *ShareTripRequest) (*ShareTripResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ShareTrip not implemented")
+}
+func (UnimplementedTripServiceServer) RevokeTripShare(context.Context, *RevokeTripShareRequest) (*RevokeTripShareResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RevokeTripShare not implemented")
+}
 func (UnimplementedTripServiceServer) mustEmbedUnimplementedTripServiceServer() {}
 func (UnimplementedTripServiceServer) testEmbeddedByValue()                     {}
 
@@ -172,6 +268,114 @@ func _TripService_CancelTrip_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TripService_GetTrip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTripRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).GetTrip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_GetTrip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).GetTrip(ctx, req.(*GetTripRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_GetTripTimeline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTripTimelineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).GetTripTimeline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_GetTripTimeline_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).GetTripTimeline(ctx, req.(*GetTripTimelineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_GetTripTrace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTripTraceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).GetTripTrace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_GetTripTrace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).GetTripTrace(ctx, req.(*GetTripTraceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_ListStuckSagas_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStuckSagasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).ListStuckSagas(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_ListStuckSagas_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).ListStuckSagas(ctx, req.(*ListStuckSagasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_ShareTrip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShareTripRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).ShareTrip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_ShareTrip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).ShareTrip(ctx, req.(*ShareTripRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TripService_RevokeTripShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeTripShareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TripServiceServer).RevokeTripShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TripService_RevokeTripShare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TripServiceServer).RevokeTripShare(ctx, req.(*RevokeTripShareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TripService_ServiceDesc is the grpc.ServiceDesc for TripService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -191,6 +395,30 @@ var TripService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CancelTrip",
 			Handler:    _TripService_CancelTrip_Handler,
 		},
+		{
+			MethodName: "GetTrip",
+			Handler:    _TripService_GetTrip_Handler,
+		},
+		{
+			MethodName: "GetTripTimeline",
+			Handler:    _TripService_GetTripTimeline_Handler,
+		},
+		{
+			MethodName: "GetTripTrace",
+			Handler:    _TripService_GetTripTrace_Handler,
+		},
+		{
+			MethodName: "ListStuckSagas",
+			Handler:    _TripService_ListStuckSagas_Handler,
+		},
+		{
+			MethodName: "ShareTrip",
+			Handler:    _TripService_ShareTrip_Handler,
+		},
+		{
+			MethodName: "RevokeTripShare",
+			Handler:    _TripService_RevokeTripShare_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "trip.proto",
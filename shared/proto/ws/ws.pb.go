@@ -0,0 +1,372 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v7.34.1
+// source: ws.proto
+
+package ws
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WSMessage struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type   string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Topic  string                 `protobuf:"bytes,3,opt,name=topic,proto3" json:"topic,omitempty"`
+	RoomID string                 `protobuf:"bytes,4,opt,name=roomID,proto3" json:"roomID,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*WSMessage_Json
+	//	*WSMessage_DriverLocation
+	Payload       isWSMessage_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WSMessage) Reset() {
+	*x = WSMessage{}
+	mi := &file_ws_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WSMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WSMessage) ProtoMessage() {}
+
+func (x *WSMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WSMessage.ProtoReflect.Descriptor instead.
+func (*WSMessage) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WSMessage) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *WSMessage) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *WSMessage) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *WSMessage) GetRoomID() string {
+	if x != nil {
+		return x.RoomID
+	}
+	return ""
+}
+
+func (x *WSMessage) GetPayload() isWSMessage_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *WSMessage) GetJson() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*WSMessage_Json); ok {
+			return x.Json
+		}
+	}
+	return nil
+}
+
+func (x *WSMessage) GetDriverLocation() *DriverLocation {
+	if x != nil {
+		if x, ok := x.Payload.(*WSMessage_DriverLocation); ok {
+			return x.DriverLocation
+		}
+	}
+	return nil
+}
+
+type isWSMessage_Payload interface {
+	isWSMessage_Payload()
+}
+
+type WSMessage_Json struct {
+	Json []byte `protobuf:"bytes,5,opt,name=json,proto3,oneof"`
+}
+
+type WSMessage_DriverLocation struct {
+	DriverLocation *DriverLocation `protobuf:"bytes,6,opt,name=driverLocation,proto3,oneof"`
+}
+
+func (*WSMessage_Json) isWSMessage_Payload() {}
+
+func (*WSMessage_DriverLocation) isWSMessage_Payload() {}
+
+type DriverLocation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Latitude      float64                `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64                `protobuf:"fixed64,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Eta           *RouteETA              `protobuf:"bytes,3,opt,name=eta,proto3" json:"eta,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Accuracy      float64                `protobuf:"fixed64,5,opt,name=accuracy,proto3" json:"accuracy,omitempty"`
+	Bearing       float64                `protobuf:"fixed64,6,opt,name=bearing,proto3" json:"bearing,omitempty"`
+	Speed         float64                `protobuf:"fixed64,7,opt,name=speed,proto3" json:"speed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DriverLocation) Reset() {
+	*x = DriverLocation{}
+	mi := &file_ws_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverLocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverLocation) ProtoMessage() {}
+
+func (x *DriverLocation) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverLocation.ProtoReflect.Descriptor instead.
+func (*DriverLocation) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DriverLocation) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *DriverLocation) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *DriverLocation) GetEta() *RouteETA {
+	if x != nil {
+		return x.Eta
+	}
+	return nil
+}
+
+func (x *DriverLocation) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *DriverLocation) GetAccuracy() float64 {
+	if x != nil {
+		return x.Accuracy
+	}
+	return 0
+}
+
+func (x *DriverLocation) GetBearing() float64 {
+	if x != nil {
+		return x.Bearing
+	}
+	return 0
+}
+
+func (x *DriverLocation) GetSpeed() float64 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
+type RouteETA struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Target         string                 `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	EtaSeconds     int64                  `protobuf:"varint,2,opt,name=etaSeconds,proto3" json:"etaSeconds,omitempty"`
+	DistanceMeters float64                `protobuf:"fixed64,3,opt,name=distanceMeters,proto3" json:"distanceMeters,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RouteETA) Reset() {
+	*x = RouteETA{}
+	mi := &file_ws_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteETA) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteETA) ProtoMessage() {}
+
+func (x *RouteETA) ProtoReflect() protoreflect.Message {
+	mi := &file_ws_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteETA.ProtoReflect.Descriptor instead.
+func (*RouteETA) Descriptor() ([]byte, []int) {
+	return file_ws_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RouteETA) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *RouteETA) GetEtaSeconds() int64 {
+	if x != nil {
+		return x.EtaSeconds
+	}
+	return 0
+}
+
+func (x *RouteETA) GetDistanceMeters() float64 {
+	if x != nil {
+		return x.DistanceMeters
+	}
+	return 0
+}
+
+var File_ws_proto protoreflect.FileDescriptor
+
+const file_ws_proto_rawDesc = "" +
+	"\n" +
+	"\bws.proto\x12\x02ws\"\xbc\x01\n" +
+	"\tWSMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
+	"\x05topic\x18\x03 \x01(\tR\x05topic\x12\x16\n" +
+	"\x06roomID\x18\x04 \x01(\tR\x06roomID\x12\x14\n" +
+	"\x04json\x18\x05 \x01(\fH\x00R\x04json\x12<\n" +
+	"\x0edriverLocation\x18\x06 \x01(\v2\x12.ws.DriverLocationH\x00R\x0edriverLocationB\t\n" +
+	"\apayload\"\xd4\x01\n" +
+	"\x0eDriverLocation\x12\x1a\n" +
+	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\x12\x1e\n" +
+	"\x03eta\x18\x03 \x01(\v2\f.ws.RouteETAR\x03eta\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x1a\n" +
+	"\baccuracy\x18\x05 \x01(\x01R\baccuracy\x12\x18\n" +
+	"\abearing\x18\x06 \x01(\x01R\abearing\x12\x14\n" +
+	"\x05speed\x18\a \x01(\x01R\x05speed\"j\n" +
+	"\bRouteETA\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12\x1e\n" +
+	"\n" +
+	"etaSeconds\x18\x02 \x01(\x03R\n" +
+	"etaSeconds\x12&\n" +
+	"\x0edistanceMeters\x18\x03 \x01(\x01R\x0edistanceMetersB\x14Z\x12shared/proto/ws;wsb\x06proto3"
+
+var (
+	file_ws_proto_rawDescOnce sync.Once
+	file_ws_proto_rawDescData []byte
+)
+
+func file_ws_proto_rawDescGZIP() []byte {
+	file_ws_proto_rawDescOnce.Do(func() {
+		file_ws_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ws_proto_rawDesc), len(file_ws_proto_rawDesc)))
+	})
+	return file_ws_proto_rawDescData
+}
+
+var file_ws_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_ws_proto_goTypes = []any{
+	(*WSMessage)(nil),      // 0: ws.WSMessage
+	(*DriverLocation)(nil), // 1: ws.DriverLocation
+	(*RouteETA)(nil),       // 2: ws.RouteETA
+}
+var file_ws_proto_depIdxs = []int32{
+	1, // 0: ws.WSMessage.driverLocation:type_name -> ws.DriverLocation
+	2, // 1: ws.DriverLocation.eta:type_name -> ws.RouteETA
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_ws_proto_init() }
+func file_ws_proto_init() {
+	if File_ws_proto != nil {
+		return
+	}
+	file_ws_proto_msgTypes[0].OneofWrappers = []any{
+		(*WSMessage_Json)(nil),
+		(*WSMessage_DriverLocation)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ws_proto_rawDesc), len(file_ws_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_ws_proto_goTypes,
+		DependencyIndexes: file_ws_proto_depIdxs,
+		MessageInfos:      file_ws_proto_msgTypes,
+	}.Build()
+	File_ws_proto = out.File
+	file_ws_proto_goTypes = nil
+	file_ws_proto_depIdxs = nil
+}
@@ -0,0 +1,134 @@
+// Package routing asks a road router how far and how long the drive between
+// two points is.
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/types"
+)
+
+// DefaultOSRMURL is the public OSRM demo server trip-service also plans
+// routes with.
+const DefaultOSRMURL = "http://router.project-osrm.org"
+
+const earthRadiusMeters = 6371000
+
+var ErrNoRoute = errors.New("no route found")
+
+// Route is the driving distance and duration between two points.
+type Route struct {
+	DistanceMeters  float64
+	DurationSeconds float64
+}
+
+// Provider finds the driving route between two points.
+type Provider interface {
+	Route(ctx context.Context, from, to types.Coordinate) (Route, error)
+}
+
+// Snapper moves a position onto the nearest road.
+type Snapper interface {
+	Snap(ctx context.Context, position types.Coordinate) (types.Coordinate, error)
+}
+
+// OSRM is a Provider and Snapper backed by an OSRM server's route and
+// nearest services.
+type OSRM struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewOSRM(baseURL string) *OSRM {
+	return &OSRM{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (o *OSRM) Route(ctx context.Context, from, to types.Coordinate) (Route, error) {
+	url := fmt.Sprintf("%s/route/v1/driving/%f,%f;%f,%f?overview=false",
+		o.baseURL, from.Longitude, from.Latitude, to.Longitude, to.Latitude)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Route{}, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return Route{}, fmt.Errorf("failed to get route: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Route{}, fmt.Errorf("failed to get route: osrm returned %s", resp.Status)
+	}
+
+	var body struct {
+		Routes []struct {
+			Distance float64 `json:"distance"`
+			Duration float64 `json:"duration"`
+		} `json:"routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Route{}, fmt.Errorf("failed to decode route: %w", err)
+	}
+	if len(body.Routes) == 0 {
+		return Route{}, ErrNoRoute
+	}
+	return Route{DistanceMeters: body.Routes[0].Distance, DurationSeconds: body.Routes[0].Duration}, nil
+}
+
+func (o *OSRM) Snap(ctx context.Context, position types.Coordinate) (types.Coordinate, error) {
+	url := fmt.Sprintf("%s/nearest/v1/driving/%f,%f?number=1", o.baseURL, position.Longitude, position.Latitude)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return types.Coordinate{}, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return types.Coordinate{}, fmt.Errorf("failed to snap position: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return types.Coordinate{}, fmt.Errorf("failed to snap position: osrm returned %s", resp.Status)
+	}
+
+	var body struct {
+		Waypoints []struct {
+			Location []float64 `json:"location"` // [longitude, latitude]
+		} `json:"waypoints"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return types.Coordinate{}, fmt.Errorf("failed to decode nearest road: %w", err)
+	}
+	if len(body.Waypoints) == 0 || len(body.Waypoints[0].Location) != 2 {
+		return types.Coordinate{}, ErrNoRoute
+	}
+	location := body.Waypoints[0].Location
+	return types.Coordinate{Latitude: location[1], Longitude: location[0]}, nil
+}
+
+// DistanceMeters returns the great-circle distance between a and b.
+func DistanceMeters(a, b types.Coordinate) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BearingDegrees returns the initial bearing from a to b, in degrees clockwise
+// from north.
+func BearingDegrees(a, b types.Coordinate) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// metaSuffix names the file next to each blob that holds its content type.
+const metaSuffix = ".meta.json"
+
+// FileStore keeps blobs as files under a root directory. It is meant for
+// development and single-replica setups; replicas do not share it.
+type FileStore struct {
+	root string
+}
+
+func NewFileStore(root string) (*FileStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory %s: %w", root, err)
+	}
+	return &FileStore{root: root}, nil
+}
+
+type fileMeta struct {
+	ContentType string `json:"contentType"`
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the blob to a temporary file and renames it into place, so a
+// reader never sees a partial object.
+func (s *FileStore) Put(_ context.Context, obj Object, body io.Reader) error {
+	path, err := s.path(obj.Key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to store %s: %w", obj.Key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to store %s: %w", obj.Key, err)
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store %s: %w", obj.Key, err)
+	}
+	if obj.Size > 0 && n != obj.Size {
+		return fmt.Errorf("failed to store %s: got %d bytes, want %d", obj.Key, n, obj.Size)
+	}
+
+	meta, _ := json.Marshal(fileMeta{ContentType: obj.ContentType})
+	if err := os.WriteFile(path+metaSuffix, meta, 0o640); err != nil {
+		return fmt.Errorf("failed to store %s: %w", obj.Key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store %s: %w", obj.Key, err)
+	}
+	return nil
+}
+
+func (s *FileStore) Get(_ context.Context, key string) (io.ReadCloser, Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Object{}, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+
+	obj := Object{Key: key, Size: info.Size(), ContentType: "application/octet-stream"}
+	if raw, err := os.ReadFile(path + metaSuffix); err == nil {
+		var meta fileMeta
+		if json.Unmarshal(raw, &meta) == nil && meta.ContentType != "" {
+			obj.ContentType = meta.ContentType
+		}
+	}
+	return f, obj, nil
+}
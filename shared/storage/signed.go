@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// signingContext separates URL signatures from anything else signed with the
+// same secret.
+const signingContext = "storage.url.v1:"
+
+var (
+	ErrInvalidSignature = errors.New("invalid download signature")
+	ErrURLExpired       = errors.New("download URL expired")
+)
+
+// URLSigner signs download URLs of stored objects. A signature covers the
+// object key, the user it was issued to and its expiry, so a URL can neither
+// be pointed at another object nor extended.
+type URLSigner struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func NewURLSigner(secret []byte) *URLSigner {
+	return &URLSigner{secret: secret, now: time.Now}
+}
+
+// Sign returns the query ("user", "expires", "sig") to append to the
+// download URL of key, valid for userID until expiresAt.
+func (s *URLSigner) Sign(key, userID string, expiresAt time.Time) url.Values {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return url.Values{
+		"user":    {userID},
+		"expires": {expires},
+		"sig":     {s.signature(key, userID, expires)},
+	}
+}
+
+// Verify checks the query of a download URL of key and returns the user it
+// was issued to. It fails with ErrInvalidSignature or ErrURLExpired.
+func (s *URLSigner) Verify(key string, query url.Values) (string, error) {
+	userID, expires := query.Get("user"), query.Get("expires")
+	if userID == "" || !hmac.Equal([]byte(query.Get("sig")), []byte(s.signature(key, userID, expires))) {
+		return "", ErrInvalidSignature
+	}
+	expiry, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	if !s.now().Before(time.Unix(expiry, 0)) {
+		return "", ErrURLExpired
+	}
+	return userID, nil
+}
+
+func (s *URLSigner) signature(key, userID, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signingContext + key + "\n" + userID + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,77 @@
+// Package storage keeps blobs, such as chat attachments, behind a small Store
+// interface. FileStore writes to a local directory for development; S3Store
+// talks to any S3-compatible service (AWS S3, MinIO, R2) for production.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"ride-sharing/shared/env"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes a stored blob.
+type Object struct {
+	Key         string
+	ContentType string
+	Size        int64
+}
+
+// Store keeps blobs by key. Keys are slash-separated paths of letters,
+// digits, '-', '_' and '.', such as "chat/<tripID>/<attachmentID>".
+type Store interface {
+	// Put stores obj.Size bytes read from body under obj.Key, replacing any
+	// object with that key.
+	Put(ctx context.Context, obj Object, body io.Reader) error
+	// Get returns the object stored under key, or ErrNotFound. The caller
+	// closes the body.
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+}
+
+// ValidateKey rejects keys that could escape the store's root, such as
+// "../x" or "/x".
+func ValidateKey(key string) error {
+	if key == "" || len(key) > 512 {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+				return ErrInvalidKey
+			}
+		}
+	}
+	return nil
+}
+
+// NewFromEnv returns the store selected by STORAGE_BACKEND:
+//
+//	fs (default)  STORAGE_DIR, default /tmp/ride-sharing-storage
+//	s3            S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY
+func NewFromEnv() (Store, error) {
+	switch backend := env.GetString("STORAGE_BACKEND", "fs"); backend {
+	case "fs":
+		return NewFileStore(env.GetString("STORAGE_DIR", "/tmp/ride-sharing-storage"))
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:        env.GetString("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:          env.GetString("S3_REGION", "us-east-1"),
+			Bucket:          env.GetString("S3_BUCKET", ""),
+			AccessKeyID:     env.GetString("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: env.GetString("S3_SECRET_ACCESS_KEY", ""),
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q: want fs or s3", backend)
+	}
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateKey(t *testing.T) {
+	for key, valid := range map[string]bool{
+		"chat/trip-1/3f2b.jpg": true,
+		"a":                    true,
+		"":                     false,
+		"/chat/x":              false,
+		"chat//x":              false,
+		"chat/../x":            false,
+		"chat/x y":             false,
+		`chat\x`:               false,
+	} {
+		if err := ValidateKey(key); (err == nil) != valid {
+			t.Errorf("ValidateKey(%q) = %v, want valid %v", key, err, valid)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := Object{Key: "chat/trip-1/a1", ContentType: "image/png", Size: 5}
+	if err := store.Put(ctx, obj, strings.NewReader("hello")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	body, got, err := store.Get(ctx, obj.Key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "hello" || got != obj {
+		t.Errorf("Get = %q, %+v; want %q, %+v", data, got, "hello", obj)
+	}
+
+	if err := store.Put(ctx, Object{Key: "chat/trip-1/short", Size: 10}, strings.NewReader("hello")); err == nil {
+		t.Error("Put of a short body succeeded")
+	}
+	if _, _, err := store.Get(ctx, "chat/trip-1/short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after a failed Put = %v, want ErrNotFound", err)
+	}
+	if _, _, err := store.Get(ctx, "chat/../../etc/passwd"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Get of an escaping key = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	objects := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(data)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "image/jpeg")
+			io.WriteString(w, data)
+		}
+	}))
+	defer srv.Close()
+
+	store, err := NewS3Store(S3Config{Endpoint: srv.URL, Region: "eu-west-1", Bucket: "media", AccessKeyID: "key", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, Object{Key: "chat/t1/a1", ContentType: "image/jpeg", Size: 3}, strings.NewReader("jpg")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := objects["/media/chat/t1/a1"]; !ok {
+		t.Fatalf("Put stored %v, want /media/chat/t1/a1", objects)
+	}
+	body, obj, err := store.Get(ctx, "chat/t1/a1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "jpg" || obj.ContentType != "image/jpeg" {
+		t.Errorf("Get = %q, %+v", data, obj)
+	}
+	if _, _, err := store.Get(ctx, "chat/t1/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing key = %v, want ErrNotFound", err)
+	}
+}
+
+func TestURLSigner(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	signer := NewURLSigner([]byte("secret"))
+	signer.now = func() time.Time { return now }
+	query := signer.Sign("chat/t1/a1", "rider-1", now.Add(time.Minute))
+
+	if user, err := signer.Verify("chat/t1/a1", query); err != nil || user != "rider-1" {
+		t.Errorf("Verify = %q, %v; want rider-1", user, err)
+	}
+	if _, err := signer.Verify("chat/t1/a2", query); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify for another key = %v, want ErrInvalidSignature", err)
+	}
+
+	forged := signer.Sign("chat/t1/a1", "rider-1", now.Add(time.Minute))
+	forged.Set("user", "someone-else")
+	if _, err := signer.Verify("chat/t1/a1", forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify for another user = %v, want ErrInvalidSignature", err)
+	}
+
+	signer.now = func() time.Time { return now.Add(time.Minute) }
+	if _, err := signer.Verify("chat/t1/a1", query); !errors.Is(err, ErrURLExpired) {
+		t.Errorf("Verify after expiry = %v, want ErrURLExpired", err)
+	}
+}
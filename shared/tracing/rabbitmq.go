@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 
 	"ride-sharing/shared/contracts"
+	pbe "ride-sharing/shared/proto/events"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
 )
 
 // amqpHeadersCarrier implements the TextMapCarrier interface for AMQP headers
@@ -36,16 +38,31 @@ func (c amqpHeadersCarrier) Keys() []string {
 	return keys
 }
 
+// ownerID returns the owner of a JSON or protobuf encoded AMQP message body.
+func ownerID(contentType string, body []byte) string {
+	if contentType == "application/x-protobuf" {
+		var envelope pbe.Envelope
+		if err := proto.Unmarshal(body, &envelope); err == nil {
+			return envelope.OwnerID
+		}
+		return ""
+	}
+	var msgBody contracts.AmqpMessage
+	if err := json.Unmarshal(body, &msgBody); err == nil {
+		return msgBody.OwnerID
+	}
+	return ""
+}
+
 // TracedPublisher wraps the RabbitMQ publish function with tracing
 func TracedPublisher(ctx context.Context, exchange, routingKey string, msg amqp.Publishing, publish func(context.Context, string, string, amqp.Publishing) error) error {
 	return RunInSpan(ctx, "rabbitmq", "rabbitmq.publish", []attribute.KeyValue{
 		attribute.String("messaging.destination", exchange),
 		attribute.String("messaging.routing_key", routingKey),
 	}, func(ctx context.Context, span trace.Span) error {
-		// Try to extract and add message details to span (map[string]any if you don't know the type)
-		var msgBody contracts.AmqpMessage
-		if err := json.Unmarshal(msg.Body, &msgBody); err == nil && msgBody.OwnerID != "" {
-			span.SetAttributes(attribute.String("messaging.owner_id", msgBody.OwnerID))
+		// Try to extract and add message details to span
+		if owner := ownerID(msg.ContentType, msg.Body); owner != "" {
+			span.SetAttributes(attribute.String("messaging.owner_id", owner))
 		}
 
 		// Inject trace context into message headers
@@ -69,10 +86,9 @@ func TracedConsumer(delivery amqp.Delivery, handler func(context.Context, amqp.D
 		attribute.String("messaging.destination", delivery.Exchange),
 		attribute.String("messaging.routing_key", delivery.RoutingKey),
 	}, func(ctx context.Context, span trace.Span) error {
-		// Try to extract and add message details to span (map[string]any if you don't know the type)
-		var msgBody contracts.AmqpMessage
-		if err := json.Unmarshal(delivery.Body, &msgBody); err == nil && msgBody.OwnerID != "" {
-			span.SetAttributes(attribute.String("messaging.owner_id", msgBody.OwnerID))
+		// Try to extract and add message details to span
+		if owner := ownerID(delivery.ContentType, delivery.Body); owner != "" {
+			span.SetAttributes(attribute.String("messaging.owner_id", owner))
 		}
 
 		if err := handler(ctx, delivery); err != nil {
@@ -0,0 +1,187 @@
+// Package tripshare mints and checks the tokens of public live-trip share
+// links. trip-service mints and revokes them; ws-gateway checks them before it
+// streams a trip to a viewer who has no account.
+//
+// A token is "<shareID>.<tripID>.<expiry unix seconds>.<signature>", signed
+// with HMAC-SHA256. The signature and expiry are checked without Redis; the
+// share record in Redis is what makes a token revocable.
+package tripshare
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultTTL is how long a share link works unless the rider asks for
+	// less. Links stop working earlier when the trip ends.
+	DefaultTTL = 4 * time.Hour
+	// MaxTTL is the longest a share link may work.
+	MaxTTL = 12 * time.Hour
+)
+
+// signingContext separates share signatures from anything else signed with
+// the same secret.
+const signingContext = "tripshare.v1:"
+
+var (
+	ErrInvalidToken = errors.New("invalid share token")
+	ErrExpired      = errors.New("share token expired")
+	ErrRevoked      = errors.New("share revoked")
+)
+
+func shareKey(shareID string) string     { return "trip_share:" + shareID }      // hash tripID, riderID, expiresAt
+func tripSharesKey(tripID string) string { return "trip:" + tripID + ":shares" } // set of share IDs
+
+// Share is a share link of a trip.
+type Share struct {
+	ID        string
+	TripID    string
+	RiderID   string
+	ExpiresAt time.Time
+}
+
+// Store mints, checks and revokes share links.
+type Store struct {
+	rdb    redis.UniversalClient
+	secret []byte
+	now    func() time.Time
+}
+
+func NewStore(rdb redis.UniversalClient, secret []byte) *Store {
+	return &Store{rdb: rdb, secret: secret, now: time.Now}
+}
+
+// Create records a share of tripID, which belongs to riderID, and returns its
+// token. ttl is capped at MaxTTL; zero means DefaultTTL.
+func (s *Store) Create(ctx context.Context, tripID, riderID string, ttl time.Duration) (string, Share, error) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	ttl = min(ttl, MaxTTL)
+	share := Share{
+		ID:        uuid.NewString(),
+		TripID:    tripID,
+		RiderID:   riderID,
+		ExpiresAt: s.now().Add(ttl).Truncate(time.Second),
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, shareKey(share.ID), map[string]any{
+		"tripID":    share.TripID,
+		"riderID":   share.RiderID,
+		"expiresAt": share.ExpiresAt.Unix(),
+	})
+	pipe.ExpireAt(ctx, shareKey(share.ID), share.ExpiresAt)
+	pipe.SAdd(ctx, tripSharesKey(tripID), share.ID)
+	pipe.Expire(ctx, tripSharesKey(tripID), MaxTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", Share{}, fmt.Errorf("failed to store share of trip %s: %w", tripID, err)
+	}
+	return s.sign(share), share, nil
+}
+
+func (s *Store) sign(share Share) string {
+	payload := share.ID + "." + share.TripID + "." + strconv.FormatInt(share.ExpiresAt.Unix(), 10)
+	return payload + "." + s.signature(payload)
+}
+
+func (s *Store) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signingContext + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns the share the token stands for. It fails with ErrInvalidToken,
+// ErrExpired or ErrRevoked; a revoked share is one whose trip ended.
+func (s *Store) Verify(ctx context.Context, token string) (Share, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return Share{}, ErrInvalidToken
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(s.signature(payload))) {
+		return Share{}, ErrInvalidToken
+	}
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return Share{}, ErrInvalidToken
+	}
+	share := Share{ID: parts[0], TripID: parts[1], ExpiresAt: time.Unix(expiry, 0)}
+	if !s.now().Before(share.ExpiresAt) {
+		return Share{}, ErrExpired
+	}
+
+	fields, err := s.rdb.HGetAll(ctx, shareKey(share.ID)).Result()
+	if err != nil {
+		return Share{}, fmt.Errorf("failed to look up share %s: %w", share.ID, err)
+	}
+	if len(fields) == 0 {
+		return Share{}, ErrRevoked
+	}
+	if fields["tripID"] != share.TripID {
+		return Share{}, ErrInvalidToken
+	}
+	share.RiderID = fields["riderID"]
+	return share, nil
+}
+
+// Active reports whether the share still works, for streams that outlive the
+// check in Verify.
+func (s *Store) Active(ctx context.Context, share Share) (bool, error) {
+	if !s.now().Before(share.ExpiresAt) {
+		return false, nil
+	}
+	n, err := s.rdb.Exists(ctx, shareKey(share.ID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to look up share %s: %w", share.ID, err)
+	}
+	return n == 1, nil
+}
+
+// Revoke revokes one share of tripID. It reports whether the share existed.
+func (s *Store) Revoke(ctx context.Context, tripID, shareID string) (bool, error) {
+	owner, err := s.rdb.HGet(ctx, shareKey(shareID), "tripID").Result()
+	if errors.Is(err, redis.Nil) || (err == nil && owner != tripID) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up share %s: %w", shareID, err)
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, shareKey(shareID))
+	pipe.SRem(ctx, tripSharesKey(tripID), shareID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to revoke share %s: %w", shareID, err)
+	}
+	return true, nil
+}
+
+// RevokeTrip revokes every share of tripID and returns how many there were.
+func (s *Store) RevokeTrip(ctx context.Context, tripID string) (int, error) {
+	ids, err := s.rdb.SMembers(ctx, tripSharesKey(tripID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list shares of trip %s: %w", tripID, err)
+	}
+
+	pipe := s.rdb.TxPipeline()
+	for _, id := range ids {
+		pipe.Del(ctx, shareKey(id))
+	}
+	pipe.Del(ctx, tripSharesKey(tripID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to revoke shares of trip %s: %w", tripID, err)
+	}
+	return len(ids), nil
+}
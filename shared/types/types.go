@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"math"
+)
+
 type Route struct {
 	Distance float64     `json:"distance"`
 	Duration float64     `json:"duration"`
@@ -14,3 +19,18 @@ type Coordinate struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 }
+
+// Validate checks that c is a WGS84 position. 0,0 is rejected as well: it is
+// what clients send when they have no fix yet.
+func (c Coordinate) Validate() error {
+	if math.IsNaN(c.Latitude) || c.Latitude < -90 || c.Latitude > 90 {
+		return fmt.Errorf("latitude %v out of range [-90, 90]", c.Latitude)
+	}
+	if math.IsNaN(c.Longitude) || c.Longitude < -180 || c.Longitude > 180 {
+		return fmt.Errorf("longitude %v out of range [-180, 180]", c.Longitude)
+	}
+	if c.Latitude == 0 && c.Longitude == 0 {
+		return fmt.Errorf("position 0,0 is not a real fix")
+	}
+	return nil
+}
@@ -0,0 +1,647 @@
+// Command schemagen generates the Go payload structs and the zod schemas used
+// by the web app from shared/schemas/events.schema.json.
+//
+//	go run ./tools/schemagen          # rewrite the generated files
+//	go run ./tools/schemagen -check   # fail when they are out of date
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const schemaPath = "shared/schemas/events.schema.json"
+
+// goPackages lists the Go packages payload types can be generated into, with
+// the output file and the dispatch function for each.
+var goPackages = []struct {
+	name     string
+	file     string
+	keyField string // x-routing-keys or x-ws-types
+	dispatch string
+	param    string
+	doc      string
+}{
+	{
+		name:     "messaging",
+		file:     "shared/messaging/events_gen.go",
+		keyField: "x-routing-keys",
+		dispatch: "ValidatePayload",
+		param:    "routingKey",
+		doc: "// ValidatePayload decodes data as the payload of routingKey and validates it.\n" +
+			"// Routing keys without a schema are accepted unchecked.",
+	},
+	{
+		name:     "contracts",
+		file:     "shared/contracts/ws_gen.go",
+		keyField: "x-ws-types",
+		dispatch: "ValidateWSData",
+		param:    "msgType",
+		doc: "// ValidateWSData decodes data as the payload of a WebSocket frame of type\n" +
+			"// msgType and validates it. Frame types without a schema are accepted unchecked.",
+	},
+}
+
+const tsFile = "web/src/lib/schemas/generated.ts"
+
+type property struct {
+	Name        string
+	Type        string    `json:"type"`
+	Ref         string    `json:"$ref"`
+	Description string    `json:"description"`
+	Minimum     *float64  `json:"minimum"`
+	Maximum     *float64  `json:"maximum"`
+	MinLength   *int      `json:"minLength"` // in characters
+	MaxLength   *int      `json:"maxLength"`
+	Items       *property `json:"items"` // element of an array; a $ref or a primitive type
+	MaxItems    *int      `json:"maxItems"`
+	OmitEmpty   bool      `json:"x-omitempty"`
+	GoName      string    `json:"x-go-name"`
+}
+
+// properties keeps the declaration order of a JSON Schema "properties" object,
+// which encoding/json maps would lose.
+type properties []property
+
+func (p *properties) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		prop := property{Name: tok.(string)}
+		if err := dec.Decode(&prop); err != nil {
+			return fmt.Errorf("property %s: %w", prop.Name, err)
+		}
+		*p = append(*p, prop)
+	}
+	return nil
+}
+
+type definition struct {
+	Name        string
+	Description string     `json:"description"`
+	Type        string     `json:"type"`
+	Required    []string   `json:"required"`
+	Properties  properties `json:"properties"`
+	GoPackage   string     `json:"x-go-package"`
+	GoType      string     `json:"x-go-type"`
+	GoImport    string     `json:"x-go-import"`
+	GoValidate  bool       `json:"x-go-validate"` // the x-go-type has a Validate() error method
+	TSSchema    string     `json:"x-ts-schema"`
+	TSImport    string     `json:"x-ts-import"`
+	RoutingKeys []string   `json:"x-routing-keys"`
+	WSTypes     []string   `json:"x-ws-types"`
+}
+
+// ref returns the definition p refers to, directly or as its array items.
+func (p property) ref() string {
+	if p.Items != nil {
+		return p.Items.Ref
+	}
+	return p.Ref
+}
+
+func (d *definition) external() bool { return d.GoType != "" || d.TSSchema != "" }
+
+func (d *definition) required(name string) bool { return slices.Contains(d.Required, name) }
+
+func (d *definition) keys(field string) []string {
+	if field == "x-routing-keys" {
+		return d.RoutingKeys
+	}
+	return d.WSTypes
+}
+
+type schema struct {
+	defs   []*definition
+	byName map[string]*definition
+}
+
+func loadSchema(path string) (*schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Defs json.RawMessage `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	s := &schema{byName: make(map[string]*definition)}
+	dec := json.NewDecoder(bytes.NewReader(doc.Defs))
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("parse $defs: %w", err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		def := &definition{Name: tok.(string)}
+		if err := dec.Decode(def); err != nil {
+			return nil, fmt.Errorf("definition %s: %w", def.Name, err)
+		}
+		s.defs = append(s.defs, def)
+		s.byName[def.Name] = def
+	}
+	return s, s.validate()
+}
+
+func (s *schema) validate() error {
+	for _, def := range s.defs {
+		if def.external() {
+			continue
+		}
+		if def.Type != "object" {
+			return fmt.Errorf("definition %s: only object types are supported", def.Name)
+		}
+		for _, name := range def.Required {
+			if !slices.ContainsFunc(def.Properties, func(p property) bool { return p.Name == name }) {
+				return fmt.Errorf("definition %s: required property %s is not declared", def.Name, name)
+			}
+		}
+		for _, p := range def.Properties {
+			if p.Ref != "" {
+				if _, err := s.ref(p.Ref); err != nil {
+					return fmt.Errorf("definition %s: property %s: %w", def.Name, p.Name, err)
+				}
+				continue
+			}
+			switch p.Type {
+			case "string", "number", "integer", "boolean":
+			case "array":
+				if p.Items == nil {
+					return fmt.Errorf("definition %s: property %s: array needs items", def.Name, p.Name)
+				}
+				if p.Items.Ref == "" {
+					switch p.Items.Type {
+					case "string", "number", "integer", "boolean":
+					default:
+						return fmt.Errorf("definition %s: property %s: array items must be a $ref or a primitive type", def.Name, p.Name)
+					}
+					break
+				}
+				if _, err := s.ref(p.Items.Ref); err != nil {
+					return fmt.Errorf("definition %s: property %s: %w", def.Name, p.Name, err)
+				}
+			default:
+				return fmt.Errorf("definition %s: property %s: unsupported type %q", def.Name, p.Name, p.Type)
+			}
+			if (p.MinLength != nil || p.MaxLength != nil) && p.Type != "string" {
+				return fmt.Errorf("definition %s: property %s: minLength/maxLength need a string", def.Name, p.Name)
+			}
+			if p.MaxItems != nil && p.Type != "array" {
+				return fmt.Errorf("definition %s: property %s: maxItems needs an array", def.Name, p.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *schema) ref(ref string) (*definition, error) {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	def, ok := s.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown $ref %q", ref)
+	}
+	return def, nil
+}
+
+// ordered returns the definitions with every referenced definition before the
+// ones that use it, which zod needs since schemas are plain constants.
+func (s *schema) ordered() []*definition {
+	var out []*definition
+	seen := make(map[string]bool)
+	var visit func(def *definition)
+	visit = func(def *definition) {
+		if seen[def.Name] {
+			return
+		}
+		seen[def.Name] = true
+		for _, p := range def.Properties {
+			if ref := p.ref(); ref != "" {
+				dep, _ := s.ref(ref)
+				visit(dep)
+			}
+		}
+		out = append(out, def)
+	}
+	for _, def := range s.defs {
+		visit(def)
+	}
+	return out
+}
+
+// ── Go ───────────────────────────────────────────────────────────────────────
+
+func goFieldName(p property) string {
+	if p.GoName != "" {
+		return p.GoName
+	}
+	return strings.ToUpper(p.Name[:1]) + p.Name[1:]
+}
+
+func (s *schema) goType(p property) string {
+	if p.Ref != "" {
+		def, _ := s.ref(p.Ref)
+		if def.GoType != "" {
+			return def.GoType
+		}
+		return "*" + def.Name
+	}
+	switch p.Type {
+	case "array":
+		return "[]" + s.goType(*p.Items)
+	case "number":
+		return "float64"
+	case "integer":
+		return "int64"
+	case "boolean":
+		return "bool"
+	default:
+		return "string"
+	}
+}
+
+func formatNumber(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+
+// docText returns the doc comment for a definition. Descriptions that start in
+// lower case continue the type name, as Go doc comments do.
+func docText(def *definition) string {
+	text := def.Description
+	if text != "" && unicode.IsLower(rune(text[0])) {
+		text = def.Name + " " + text
+	}
+	return text
+}
+
+// wrap breaks text into lines of at most width characters.
+func wrap(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (s *schema) generateGo(pkg, keyField, dispatch, param, dispatchDoc string) ([]byte, error) {
+	var defs []*definition
+	imports := map[string]bool{"encoding/json": true, "fmt": true}
+	for _, def := range s.defs {
+		if def.GoPackage != pkg {
+			continue
+		}
+		defs = append(defs, def)
+		for _, p := range def.Properties {
+			if p.MinLength != nil || p.MaxLength != nil {
+				imports["unicode/utf8"] = true
+			}
+			if p.ref() == "" {
+				continue
+			}
+			if ref, _ := s.ref(p.ref()); ref.GoImport != "" {
+				imports[ref.GoImport] = true
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by schemagen from %s. DO NOT EDIT.\n\npackage %s\n\nimport (\n", schemaPath, pkg)
+	var std, local []string
+	for imp := range imports {
+		if strings.Contains(imp, " ") {
+			local = append(local, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	slices.Sort(std)
+	slices.Sort(local)
+	for _, imp := range std {
+		fmt.Fprintf(&b, "\t%q\n", imp)
+	}
+	if len(local) > 0 {
+		b.WriteString("\n")
+	}
+	for _, imp := range local {
+		alias, path, _ := strings.Cut(imp, " ")
+		if alias == filepath.Base(path) {
+			alias = ""
+		}
+		fmt.Fprintf(&b, "\t%s %q\n", alias, path)
+	}
+	b.WriteString(")\n")
+
+	for _, def := range defs {
+		b.WriteString("\n")
+		for _, line := range wrap(docText(def), 77) {
+			fmt.Fprintf(&b, "// %s\n", line)
+		}
+		fmt.Fprintf(&b, "type %s struct {\n", def.Name)
+		for _, p := range def.Properties {
+			tag := p.Name
+			if p.OmitEmpty {
+				tag += ",omitempty"
+			}
+			comment := ""
+			if p.Description != "" {
+				comment = " // " + p.Description
+			}
+			fmt.Fprintf(&b, "\t%s %s `json:%q`%s\n", goFieldName(p), s.goType(p), tag, comment)
+		}
+		b.WriteString("}\n\n")
+
+		fmt.Fprintf(&b, "// Validate checks d against the %s schema.\n", def.Name)
+		fmt.Fprintf(&b, "func (d *%s) Validate() error {\n", def.Name)
+		for _, p := range def.Properties {
+			s.writeGoChecks(&b, def, p)
+		}
+		b.WriteString("\treturn nil\n}\n")
+	}
+
+	b.WriteString("\ntype validator interface {\n\tValidate() error\n}\n\n")
+	fmt.Fprintf(&b, "var %sTypes = map[string]func() validator{\n", strings.ToLower(dispatch[:1])+dispatch[1:])
+	for _, def := range defs {
+		for _, key := range def.keys(keyField) {
+			fmt.Fprintf(&b, "\t%q: func() validator { return new(%s) },\n", key, def.Name)
+		}
+	}
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "%s\nfunc %s(%s string, data []byte) error {\n", dispatchDoc, dispatch, param)
+	fmt.Fprintf(&b, "\tnewPayload, ok := %sTypes[%s]\n", strings.ToLower(dispatch[:1])+dispatch[1:], param)
+	b.WriteString("\tif !ok {\n\t\treturn nil\n\t}\n\tpayload := newPayload()\n")
+	fmt.Fprintf(&b, "\tif err := json.Unmarshal(data, payload); err != nil {\n\t\treturn fmt.Errorf(\"decode %%s payload: %%w\", %s, err)\n\t}\n", param)
+	fmt.Fprintf(&b, "\tif err := payload.Validate(); err != nil {\n\t\treturn fmt.Errorf(\"invalid %%s payload: %%w\", %s, err)\n\t}\n", param)
+	b.WriteString("\treturn nil\n}\n")
+
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated %s code: %w\n%s", pkg, err, b.Bytes())
+	}
+	return out, nil
+}
+
+func (s *schema) writeGoChecks(b *bytes.Buffer, def *definition, p property) {
+	field := "d." + goFieldName(p)
+	if def.required(p.Name) {
+		switch {
+		case p.Ref != "":
+			if typ := s.goType(p); strings.HasPrefix(typ, "*") {
+				fmt.Fprintf(b, "\tif %s == nil {\n\t\treturn fmt.Errorf(\"%s is required\")\n\t}\n", field, p.Name)
+			}
+		case p.Type == "string":
+			fmt.Fprintf(b, "\tif %s == \"\" {\n\t\treturn fmt.Errorf(\"%s is required\")\n\t}\n", field, p.Name)
+		case p.Type == "array":
+			fmt.Fprintf(b, "\tif len(%s) == 0 {\n\t\treturn fmt.Errorf(\"%s is required\")\n\t}\n", field, p.Name)
+		}
+	}
+	if p.MaxItems != nil {
+		fmt.Fprintf(b, "\tif len(%s) > %d {\n\t\treturn fmt.Errorf(\"%s has more than %d items\")\n\t}\n", field, *p.MaxItems, p.Name, *p.MaxItems)
+	}
+	if p.Items != nil && p.Items.Ref != "" {
+		if ref, _ := s.ref(p.Items.Ref); !ref.external() {
+			fmt.Fprintf(b, "\tfor i, item := range %s {\n\t\tif item == nil {\n\t\t\treturn fmt.Errorf(\"%s[%%d] is required\", i)\n\t\t}\n", field, p.Name)
+			fmt.Fprintf(b, "\t\tif err := item.Validate(); err != nil {\n\t\t\treturn fmt.Errorf(\"%s[%%d]: %%w\", i, err)\n\t\t}\n\t}\n", p.Name)
+		}
+	}
+	if p.Ref != "" {
+		ref, _ := s.ref(p.Ref)
+		switch {
+		case !ref.external():
+			fmt.Fprintf(b, "\tif %s != nil {\n\t\tif err := %s.Validate(); err != nil {\n\t\t\treturn fmt.Errorf(\"%s: %%w\", err)\n\t\t}\n\t}\n", field, field, p.Name)
+		case ref.GoValidate && strings.HasPrefix(ref.GoType, "*"):
+			fmt.Fprintf(b, "\tif %s != nil {\n\t\tif err := %s.Validate(); err != nil {\n\t\t\treturn fmt.Errorf(\"%s: %%w\", err)\n\t\t}\n\t}\n", field, field, p.Name)
+		case ref.GoValidate:
+			fmt.Fprintf(b, "\tif err := %s.Validate(); err != nil {\n\t\treturn fmt.Errorf(\"%s: %%w\", err)\n\t}\n", field, p.Name)
+		}
+	}
+	if p.MinLength != nil && !(def.required(p.Name) && *p.MinLength <= 1) {
+		fmt.Fprintf(b, "\tif n := utf8.RuneCountInString(%s); n < %d", field, *p.MinLength)
+		if !def.required(p.Name) {
+			fmt.Fprintf(b, " && n > 0")
+		}
+		fmt.Fprintf(b, " {\n\t\treturn fmt.Errorf(\"%s is shorter than %d characters\")\n\t}\n", p.Name, *p.MinLength)
+	}
+	if p.MaxLength != nil {
+		fmt.Fprintf(b, "\tif utf8.RuneCountInString(%s) > %d {\n\t\treturn fmt.Errorf(\"%s is longer than %d characters\")\n\t}\n", field, *p.MaxLength, p.Name, *p.MaxLength)
+	}
+	switch {
+	case p.Minimum != nil && p.Maximum != nil:
+		fmt.Fprintf(b, "\tif %s < %s || %s > %s {\n\t\treturn fmt.Errorf(\"%s %%v out of range [%s, %s]\", %s)\n\t}\n",
+			field, formatNumber(*p.Minimum), field, formatNumber(*p.Maximum), p.Name, formatNumber(*p.Minimum), formatNumber(*p.Maximum), field)
+	case p.Minimum != nil:
+		fmt.Fprintf(b, "\tif %s < %s {\n\t\treturn fmt.Errorf(\"%s %%v is below %s\", %s)\n\t}\n", field, formatNumber(*p.Minimum), p.Name, formatNumber(*p.Minimum), field)
+	case p.Maximum != nil:
+		fmt.Fprintf(b, "\tif %s > %s {\n\t\treturn fmt.Errorf(\"%s %%v is above %s\", %s)\n\t}\n", field, formatNumber(*p.Maximum), p.Name, formatNumber(*p.Maximum), field)
+	}
+}
+
+// ── TypeScript ───────────────────────────────────────────────────────────────
+
+func tsSchemaName(def *definition) string {
+	if def.TSSchema != "" {
+		return def.TSSchema
+	}
+	return def.Name + "Schema"
+}
+
+func (s *schema) tsType(def *definition, p property) string {
+	var expr string
+	if p.Ref != "" {
+		ref, _ := s.ref(p.Ref)
+		expr = tsSchemaName(ref)
+	} else {
+		switch p.Type {
+		case "string":
+			expr = "z.string()"
+			switch {
+			case p.MinLength != nil:
+				expr += ".min(" + strconv.Itoa(*p.MinLength) + ")"
+			case def.required(p.Name):
+				expr += ".min(1)"
+			}
+			if p.MaxLength != nil {
+				expr += ".max(" + strconv.Itoa(*p.MaxLength) + ")"
+			}
+		case "integer":
+			expr = "z.number().int()"
+		case "boolean":
+			expr = "z.boolean()"
+		case "array":
+			if p.Items.Ref != "" {
+				ref, _ := s.ref(p.Items.Ref)
+				expr = "z.array(" + tsSchemaName(ref) + ")"
+			} else {
+				expr = "z.array(" + tsPrimitive(p.Items.Type) + ")"
+			}
+			if def.required(p.Name) {
+				expr += ".min(1)"
+			}
+			if p.MaxItems != nil {
+				expr += ".max(" + strconv.Itoa(*p.MaxItems) + ")"
+			}
+		default:
+			expr = "z.number()"
+		}
+		if p.Minimum != nil {
+			expr += ".min(" + formatNumber(*p.Minimum) + ")"
+		}
+		if p.Maximum != nil {
+			expr += ".max(" + formatNumber(*p.Maximum) + ")"
+		}
+	}
+	if !def.required(p.Name) {
+		expr += ".optional()"
+	}
+	return expr
+}
+
+// tsPrimitive returns the zod schema of a primitive array item.
+func tsPrimitive(typ string) string {
+	switch typ {
+	case "string":
+		return "z.string()"
+	case "integer":
+		return "z.number().int()"
+	case "boolean":
+		return "z.boolean()"
+	default:
+		return "z.number()"
+	}
+}
+
+func (s *schema) generateTS() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by schemagen from %s. DO NOT EDIT.\n", schemaPath)
+	b.WriteString("import { z } from 'zod';\n")
+
+	imports := make(map[string][]string)
+	var modules []string
+	for _, def := range s.defs {
+		if def.TSImport == "" {
+			continue
+		}
+		if _, ok := imports[def.TSImport]; !ok {
+			modules = append(modules, def.TSImport)
+		}
+		imports[def.TSImport] = append(imports[def.TSImport], def.TSSchema)
+	}
+	for _, mod := range modules {
+		names := imports[mod]
+		slices.Sort(names)
+		fmt.Fprintf(&b, "import { %s } from '%s';\n", strings.Join(names, ", "), mod)
+	}
+
+	for _, def := range s.ordered() {
+		if def.external() {
+			continue
+		}
+		b.WriteString("\n")
+		if lines := wrap(docText(def), 77); len(lines) == 1 {
+			fmt.Fprintf(&b, "/** %s */\n", lines[0])
+		} else if len(lines) > 1 {
+			b.WriteString("/**\n")
+			for _, line := range lines {
+				fmt.Fprintf(&b, " * %s\n", line)
+			}
+			b.WriteString(" */\n")
+		}
+		fmt.Fprintf(&b, "export const %s = z.object({\n", tsSchemaName(def))
+		for _, p := range def.Properties {
+			fmt.Fprintf(&b, "  %s: %s,\n", p.Name, s.tsType(def, p))
+		}
+		b.WriteString("});\n")
+		fmt.Fprintf(&b, "export type %s = z.infer<typeof %s>;\n", def.Name, tsSchemaName(def))
+	}
+
+	s.writeTSMap(&b, "AmqpPayloadSchemas", "Data schema for every AMQP routing key.", "x-routing-keys")
+	s.writeTSMap(&b, "WsDataSchemas", "Data schema for every WebSocket frame type.", "x-ws-types")
+	return b.Bytes()
+}
+
+func (s *schema) writeTSMap(b *bytes.Buffer, name, doc, keyField string) {
+	fmt.Fprintf(b, "\n/** %s */\nexport const %s = {\n", doc, name)
+	for _, def := range s.defs {
+		for _, key := range def.keys(keyField) {
+			fmt.Fprintf(b, "  '%s': %s,\n", key, tsSchemaName(def))
+		}
+	}
+	b.WriteString("} as const;\n")
+}
+
+// ── main ─────────────────────────────────────────────────────────────────────
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	check := flag.Bool("check", false, "report generated files that are out of date instead of writing them")
+	flag.Parse()
+
+	s, err := loadSchema(filepath.Join(*root, schemaPath))
+	if err != nil {
+		log.Fatalf("load schema: %v", err)
+	}
+
+	outputs := map[string][]byte{tsFile: s.generateTS()}
+	for _, pkg := range goPackages {
+		src, err := s.generateGo(pkg.name, pkg.keyField, pkg.dispatch, pkg.param, pkg.doc)
+		if err != nil {
+			log.Fatal(err)
+		}
+		outputs[pkg.file] = src
+	}
+
+	files := make([]string, 0, len(outputs))
+	for file := range outputs {
+		files = append(files, file)
+	}
+	slices.Sort(files)
+
+	var stale []string
+	for _, file := range files {
+		path := filepath.Join(*root, file)
+		if *check {
+			if current, err := os.ReadFile(path); err != nil || !bytes.Equal(current, outputs[file]) {
+				stale = append(stale, file)
+			}
+			continue
+		}
+		if err := os.WriteFile(path, outputs[file], 0o644); err != nil {
+			log.Fatalf("write %s: %v", file, err)
+		}
+	}
+	if len(stale) > 0 {
+		log.Fatalf("generated files are out of date with %s, run `go run ./tools/schemagen`:\n  %s", schemaPath, strings.Join(stale, "\n  "))
+	}
+}
@@ -115,6 +115,7 @@ func runDriver(id, pkg, name, token string, wg *sync.WaitGroup) {
 				"latitude":  pos.lat,
 				"longitude": pos.lng,
 			},
+			"timestamp": time.Now().UnixMilli(),
 		})
 		msg, _ := json.Marshal(wsMessage{Type: cmdLocation, Data: payload})
 		if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
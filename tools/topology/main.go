@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"ride-sharing/shared/messaging"
+)
+
+const usage = `usage: go run ./tools/topology <command> [flags]
+
+commands:
+  validate   check the declarative topology table for mistakes
+  diff       compare the table against a live broker (management API)
+  mermaid    render producers, exchanges and consumers as a Mermaid diagram
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	topology := messaging.DefaultTopology
+
+	switch os.Args[1] {
+	case "validate":
+		if err := topology.Validate(); err != nil {
+			log.Fatalf("topology is invalid:\n%v", err)
+		}
+		fmt.Printf("topology OK: %d exchanges, %d queues, %d produced routing keys\n",
+			len(topology.Exchanges), len(topology.Queues), len(topology.Producers))
+
+	case "diff":
+		fs := flag.NewFlagSet("diff", flag.ExitOnError)
+		mgmtURL := fs.String("url", "http://localhost:15672", "RabbitMQ management API base URL")
+		user := fs.String("user", "guest", "management API user")
+		pass := fs.String("pass", "guest", "management API password")
+		vhost := fs.String("vhost", "/", "virtual host")
+		fs.Parse(os.Args[2:])
+
+		client := &managementClient{base: strings.TrimRight(*mgmtURL, "/"), user: *user, pass: *pass, vhost: *vhost}
+		live, err := client.fetch()
+		if err != nil {
+			log.Fatalf("failed to read live topology: %v", err)
+		}
+		changes := diffTopology(topology, live)
+		if len(changes) == 0 {
+			fmt.Println("live broker matches the topology table")
+			return
+		}
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+		os.Exit(1)
+
+	case "mermaid":
+		fmt.Print(renderMermaid(topology))
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// ── management API ───────────────────────────────────────────────────────────
+
+type liveQueue struct {
+	Name      string         `json:"name"`
+	Durable   bool           `json:"durable"`
+	Type      string         `json:"type"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+type liveExchange struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type liveBinding struct {
+	Source          string `json:"source"`
+	Destination     string `json:"destination"`
+	DestinationType string `json:"destination_type"`
+	RoutingKey      string `json:"routing_key"`
+}
+
+type liveTopology struct {
+	Exchanges []liveExchange
+	Queues    []liveQueue
+	Bindings  []liveBinding
+}
+
+type managementClient struct {
+	base, user, pass, vhost string
+}
+
+func (c *managementClient) get(path string, out any) error {
+	req, err := http.NewRequest(http.MethodGet, c.base+"/api/"+path+"/"+url.PathEscape(c.vhost), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.user, c.pass)
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", req.URL, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *managementClient) fetch() (*liveTopology, error) {
+	live := &liveTopology{}
+	if err := c.get("exchanges", &live.Exchanges); err != nil {
+		return nil, err
+	}
+	if err := c.get("queues", &live.Queues); err != nil {
+		return nil, err
+	}
+	if err := c.get("bindings", &live.Bindings); err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+// ── diff ─────────────────────────────────────────────────────────────────────
+
+// diffTopology lists what would have to change on the live broker to match the
+// table: "+" missing on the broker, "-" unknown to the table, "~" mismatched.
+func diffTopology(t messaging.Topology, live *liveTopology) []string {
+	var changes []string
+
+	liveExchanges := make(map[string]liveExchange)
+	for _, ex := range live.Exchanges {
+		liveExchanges[ex.Name] = ex
+	}
+	for _, ex := range t.Exchanges {
+		got, ok := liveExchanges[ex.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+ exchange %s (%s)", ex.Name, ex.Kind))
+		case got.Type != ex.Kind:
+			changes = append(changes, fmt.Sprintf("~ exchange %s: type %s, want %s", ex.Name, got.Type, ex.Kind))
+		}
+	}
+
+	liveQueues := make(map[string]liveQueue)
+	for _, q := range live.Queues {
+		liveQueues[q.Name] = q
+	}
+	declared := make(map[string]struct{})
+	for _, q := range t.Queues {
+		declared[q.Name] = struct{}{}
+		got, ok := liveQueues[q.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("+ queue %s (owner %s)", q.Name, q.Owner))
+			continue
+		}
+		changes = append(changes, diffArguments(q.Name, q.Arguments(), got.Arguments)...)
+	}
+	for _, q := range live.Queues {
+		if _, ok := declared[q.Name]; !ok && !strings.HasPrefix(q.Name, "amq.") {
+			changes = append(changes, fmt.Sprintf("- queue %s (not in topology table)", q.Name))
+		}
+	}
+
+	type bindingKey struct{ exchange, queue, key string }
+	liveBindings := make(map[bindingKey]struct{})
+	for _, b := range live.Bindings {
+		if b.DestinationType != "queue" || b.Source == "" {
+			continue // skip the implicit default-exchange bindings
+		}
+		liveBindings[bindingKey{b.Source, b.Destination, b.RoutingKey}] = struct{}{}
+	}
+	wanted := make(map[bindingKey]struct{})
+	for _, q := range t.Queues {
+		for _, key := range q.Bindings {
+			bk := bindingKey{q.Exchange, q.Name, key}
+			wanted[bk] = struct{}{}
+			if _, ok := liveBindings[bk]; !ok {
+				changes = append(changes, fmt.Sprintf("+ binding %s -[%s]-> %s", bk.exchange, bk.key, bk.queue))
+			}
+		}
+	}
+	for bk := range liveBindings {
+		if _, ok := declared[bk.queue]; !ok {
+			continue // already reported as an unknown queue
+		}
+		if _, ok := wanted[bk]; !ok {
+			changes = append(changes, fmt.Sprintf("- binding %s -[%s]-> %s", bk.exchange, bk.key, bk.queue))
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i][2:] < changes[j][2:] })
+	return changes
+}
+
+func diffArguments(queue string, want map[string]any, got map[string]any) []string {
+	var changes []string
+	for k, v := range want {
+		g, ok := got[k]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("~ queue %s: missing argument %s=%v (queue must be re-created)", queue, k, v))
+			continue
+		}
+		if fmt.Sprint(g) != fmt.Sprint(v) {
+			changes = append(changes, fmt.Sprintf("~ queue %s: argument %s=%v, want %v (queue must be re-created)", queue, k, g, v))
+		}
+	}
+	for k, g := range got {
+		if _, ok := want[k]; !ok {
+			changes = append(changes, fmt.Sprintf("~ queue %s: unexpected argument %s=%v", queue, k, g))
+		}
+	}
+	return changes
+}
+
+// ── mermaid ──────────────────────────────────────────────────────────────────
+
+// renderMermaid draws producers → exchanges → queues → consuming services.
+func renderMermaid(t messaging.Topology) string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	ids := map[string]string{}
+	nodeID := func(kind, name string) string {
+		key := kind + ":" + name
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := fmt.Sprintf("%s%d", kind, len(ids))
+		ids[key] = id
+		return id
+	}
+
+	services := map[string]struct{}{}
+	for _, producers := range t.Producers {
+		for _, p := range producers {
+			services[p] = struct{}{}
+		}
+	}
+	for _, q := range t.Queues {
+		services[q.Owner] = struct{}{}
+	}
+	b.WriteString("  subgraph services\n")
+	for _, name := range sortedKeys(services) {
+		fmt.Fprintf(&b, "    %s([%s])\n", nodeID("svc", name), mermaidLabel(name))
+	}
+	b.WriteString("  end\n")
+
+	for _, ex := range t.Exchanges {
+		fmt.Fprintf(&b, "  %s{{%s}}\n", nodeID("ex", ex.Name), mermaidLabel(ex.Name))
+	}
+
+	// producer -- routing key --> exchange
+	keys := make([]string, 0, len(t.Producers))
+	for key := range t.Producers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, p := range t.Producers[key] {
+			fmt.Fprintf(&b, "  %s -- %s --> %s\n", nodeID("svc", p), mermaidLabel(key), nodeID("ex", messaging.TripExchange))
+		}
+	}
+
+	// exchange -- binding --> queue --> consumer
+	for _, q := range t.Queues {
+		label := q.Name
+		if q.Type == messaging.QueueTypeQuorum {
+			label += " (quorum)"
+		}
+		if q.MaxPriority > 0 {
+			label += fmt.Sprintf(" (priority ≤%d)", q.MaxPriority)
+		}
+		if slices.Contains(q.Accepts, messaging.ContentTypeProtobuf) {
+			label += " (protobuf)"
+		}
+		fmt.Fprintf(&b, "  %s[(%s)]\n", nodeID("q", q.Name), mermaidLabel(label))
+		fmt.Fprintf(&b, "  %s -- %s --> %s\n", nodeID("ex", q.Exchange), mermaidLabel(strings.Join(q.Bindings, ", ")), nodeID("q", q.Name))
+		fmt.Fprintf(&b, "  %s --> %s\n", nodeID("q", q.Name), nodeID("svc", q.Owner))
+		if q.DeadLetter != nil {
+			fmt.Fprintf(&b, "  %s -. dead-letter .-> %s\n", nodeID("q", q.Name), nodeID("ex", q.DeadLetter.Exchange))
+		}
+	}
+
+	return b.String()
+}
+
+// mermaidLabel quotes a node or edge label so characters such as ( ) # * and
+// ≤ in queue names, bindings and annotations don't break the diagram.
+func mermaidLabel(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -55,12 +55,13 @@ export const DriverMap = ({ packageSlug }: { packageSlug: CarPackageSlug }) => {
     return <div>Waiting for location...</div>
   }
 
-  const handleSendChatMessage = (tripID: string, text: string) => {
+  const handleSendChatMessage = (tripID: string, text: string, attachmentID?: string) => {
     sendMessage({
       type: TripEvents.ChatMessageSend,
       data: {
         tripID,
         text,
+        attachmentID,
         messageID: crypto.randomUUID(),
       },
     }, { reportNotReady: true, queueIfNotReady: true });
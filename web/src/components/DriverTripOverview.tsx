@@ -18,7 +18,7 @@ interface DriverTripOverviewProps {
   status?: TripEvents | null,
   userID: string,
   chatMessages: ChatMessageData[],
-  onSendChatMessage: (tripID: string, text: string) => void,
+  onSendChatMessage: (tripID: string, text: string, attachmentID?: string) => void,
   onAcceptTrip?: () => void,
   onDeclineTrip?: () => void,
   onCancelTrip?: () => void,
@@ -91,7 +91,7 @@ export const DriverTripOverview = ({ trip, status, userID, chatMessages, onSendC
             currentUserID={userID}
             peerLabel={trip.userID}
             messages={chatMessages}
-            onSend={(text) => onSendChatMessage(trip.id, text)}
+            onSend={(text, attachmentID) => onSendChatMessage(trip.id, text, attachmentID)}
           />
           <Button variant="destructive" onClick={onCancelTrip}>Cancel trip</Button>
         </div>
@@ -73,12 +73,13 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
         return <div>Waiting for location...</div>;
     }
 
-    const handleSendChatMessage = (tripID: string, text: string) => {
+    const handleSendChatMessage = (tripID: string, text: string, attachmentID?: string) => {
         sendMessage({
             type: TripEvents.ChatMessageSend,
             data: {
                 tripID,
                 text,
+                attachmentID,
                 messageID: crypto.randomUUID(),
             },
         }, { reportNotReady: true });
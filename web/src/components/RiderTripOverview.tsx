@@ -34,7 +34,7 @@ interface TripOverviewProps {
   paymentSession?: PaymentEventSessionCreatedData | null;
   userID: string;
   chatMessages: ChatMessageData[];
-  onSendChatMessage: (tripID: string, text: string) => void;
+  onSendChatMessage: (tripID: string, text: string, attachmentID?: string) => void;
   onPackageSelect: (carPackage: RouteFare) => void;
   onCancel: () => void;
 }
@@ -83,7 +83,7 @@ export const RiderTripOverview = ({
             currentUserID={userID}
             peerLabel={assignedDriver?.name ?? 'Driver'}
             messages={chatMessages}
-            onSend={(text) => onSendChatMessage(paymentSession.tripID, text)}
+            onSend={(text, attachmentID) => onSendChatMessage(paymentSession.tripID, text, attachmentID)}
           />
           <StripePaymentButton paymentSession={paymentSession} />
           <Button variant="destructive" className="w-full" onClick={onCancel}>
@@ -140,7 +140,7 @@ export const RiderTripOverview = ({
             currentUserID={userID}
             peerLabel={assignedDriver?.name ?? 'Driver'}
             messages={chatMessages}
-            onSend={(text) => onSendChatMessage(trip.tripID, text)}
+            onSend={(text, attachmentID) => onSendChatMessage(trip.tripID, text, attachmentID)}
           />
         )}
         <Button variant="destructive" className="w-full" onClick={onCancel}>
@@ -1,16 +1,33 @@
-import { useMemo, useState } from 'react';
+import { useMemo, useRef, useState } from 'react';
 import { ChatMessageData } from '../contracts';
+import { API_URL } from '../constants';
+import { useGetChatAttachmentURLQuery, useUploadChatAttachmentMutation } from '../store/api/tripApi';
 import { Button } from './ui/button';
 
+// Matches CHAT_ATTACHMENT_TYPES on api-gateway; the server has the last word.
+const ATTACHMENT_ACCEPT = 'image/jpeg,image/png,image/webp';
+
 interface TripChatPanelProps {
   title: string;
   tripID: string;
   currentUserID: string;
   peerLabel?: string;
   messages: ChatMessageData[];
-  onSend: (text: string) => void;
+  onSend: (text: string, attachmentID?: string) => void;
 }
 
+const ChatAttachmentImage = ({ tripID, attachmentID }: { tripID: string; attachmentID: string }) => {
+  const { data, isError } = useGetChatAttachmentURLQuery({ tripID, attachmentID });
+  if (isError) return <span className="text-gray-500">[attachment unavailable]</span>;
+  if (!data) return <span className="text-gray-500">Loading photo…</span>;
+  return (
+    <a href={API_URL + data.data.url} target="_blank" rel="noreferrer">
+      {/* eslint-disable-next-line @next/next/no-img-element -- signed, per-user URL */}
+      <img src={API_URL + data.data.url} alt="Chat attachment" className="mt-1 max-h-32 rounded" />
+    </a>
+  );
+};
+
 export const TripChatPanel = ({
   title,
   tripID,
@@ -20,6 +37,9 @@ export const TripChatPanel = ({
   onSend,
 }: TripChatPanelProps) => {
   const [text, setText] = useState('');
+  const [uploadError, setUploadError] = useState<string | null>(null);
+  const fileInputRef = useRef<HTMLInputElement>(null);
+  const [uploadAttachment, { isLoading: isUploading }] = useUploadChatAttachmentMutation();
 
   const scopedMessages = useMemo(
     () => messages.filter((m) => m.tripID === tripID),
@@ -33,6 +53,21 @@ export const TripChatPanel = ({
     setText('');
   };
 
+  const handleAttach = async (file: File | undefined) => {
+    if (!file) return;
+    setUploadError(null);
+    try {
+      const { data } = await uploadAttachment({ tripID, file }).unwrap();
+      onSend(text.trim(), data.attachmentID);
+      setText('');
+    } catch (err) {
+      const message = (err as { data?: { message?: string } })?.data?.message;
+      setUploadError(message ?? 'Failed to upload the photo.');
+    } finally {
+      if (fileInputRef.current) fileInputRef.current.value = '';
+    }
+  };
+
   return (
     <div className="flex flex-col gap-3 border rounded-md p-3">
       <h4 className="text-sm font-semibold">{title}</h4>
@@ -47,11 +82,13 @@ export const TripChatPanel = ({
               {msg.senderID === currentUserID ? 'You' : peerLabel ?? msg.senderID}
             </span>
             {': '}
-            <span>{msg.text}</span>
+            {msg.text && <span>{msg.text}</span>}
+            {msg.attachmentID && <ChatAttachmentImage tripID={msg.tripID} attachmentID={msg.attachmentID} />}
           </div>
         ))}
       </div>
 
+      {uploadError && <p className="text-xs text-red-500">{uploadError}</p>}
       <div className="flex gap-2">
         <input
           className="flex-1 border rounded px-2 py-1 text-sm"
@@ -60,6 +97,16 @@ export const TripChatPanel = ({
           onKeyDown={(e) => e.key === 'Enter' && handleSend()}
           placeholder="Type a message"
         />
+        <input
+          ref={fileInputRef}
+          type="file"
+          accept={ATTACHMENT_ACCEPT}
+          className="hidden"
+          onChange={(e) => handleAttach(e.target.files?.[0])}
+        />
+        <Button variant="outline" disabled={isUploading} onClick={() => fileInputRef.current?.click()}>
+          {isUploading ? 'Uploading…' : 'Photo'}
+        </Button>
         <Button onClick={handleSend}>Send</Button>
       </div>
     </div>
@@ -1,4 +1,22 @@
 import { Coordinate, Driver, Route, RouteFare, Trip } from "./types";
+import type {
+  DriverETAChangedData,
+  DriverLocationEventData,
+  TripGeofenceData,
+  PaymentEventSessionCreatedData,
+  WSChatMessageReadData,
+  WSChatMessageSendData,
+  WSChatTypingData,
+  WSDriverLocationData,
+  WSDriverTripResponseData,
+  WSErrorData,
+  WSReconnectData,
+  WSTripRefData,
+} from "./lib/schemas/generated";
+
+// Frame payloads shared with the Go services are generated from
+// shared/schemas/events.schema.json.
+export type { PaymentEventSessionCreatedData };
 
 
 // These are the endpoints the API Gateway must have for the frontend to work correctly
@@ -6,6 +24,9 @@ export enum BackendEndpoints {
   PREVIEW_TRIP = "/trip/preview",
   START_TRIP = "/trip/start",
   CANCEL_TRIP = "/trip/cancel",
+  CHAT_HISTORY = "/chat/history",
+  CHAT_ATTACHMENTS = "/chat/attachments",
+  CHAT_ATTACHMENT_URL = "/chat/attachments/url",
   WS_DRIVERS = "/drivers",
   WS_RIDERS = "/riders",
 }
@@ -23,11 +44,21 @@ export enum TripEvents {
   DriverTripDecline = "driver.cmd.trip_decline",
   DriverRegister = "driver.cmd.register",
   DriverEventLocation = "driver.event.location",
+  DriverEventETAChanged = "driver.event.eta_changed",
+  DriverNearby = "trip.event.driver_nearby",
+  DriverAtPickup = "trip.event.driver_at_pickup",
+  DriverAtDropoff = "trip.event.driver_at_dropoff",
+  DriverZoneEntered = "trip.event.driver_zone_entered",
+  DriverZoneExited = "trip.event.driver_zone_exited",
   PaymentSessionCreated = "payment.event.session_created",
   ChatMessageSend = "chat.message.send",
   ChatMessageReceived = "chat.message.received",
+  ChatMessageRead = "chat.message.read",
+  ChatTyping = "chat.typing",
   WsTopicSubscribe = "ws.topic.subscribe",
   WsTopicUnsubscribe = "ws.topic.unsubscribe",
+  WsError = "ws.error",
+  WsReconnect = "ws.reconnect",
 }
 
 // Every server message may carry an optional topic for client-side filtering.
@@ -36,13 +67,19 @@ export type ServerWsMessage = (
   | DriverAssignedRequest
   | DriverLocationRequest
   | DriverEventLocationRequest
+  | DriverETAChangedRequest
+  | TripGeofenceRequest
   | ChatMessageReceivedRequest
+  | ChatMessageReadMessage
+  | ChatTypingMessage
   | DriverTripRequest
   | DriverRegisterRequest
   | TripCreatedRequest
   | NoDriversFoundRequest
   | TripCancelledRequest
   | TripCompletedRequest
+  | WsErrorMessage
+  | WsReconnectMessage
 ) & { topic?: string };
 
 // Messages sent from the client to the server via the websocket
@@ -50,6 +87,8 @@ export type ClientWsMessage =
   | DriverResponseToTripResponse
   | DriverLocationMessage
   | ChatMessageSendRequest
+  | ChatMessageReadMessage
+  | ChatTypingMessage
   | WsTopicSubscribeMessage
   | WsTopicUnsubscribeMessage
   | TripCancelRequest
@@ -80,6 +119,16 @@ interface TripCancelledRequest {
   };
 }
 
+interface WsErrorMessage {
+  type: TripEvents.WsError;
+  data: WSErrorData;
+}
+
+interface WsReconnectMessage {
+  type: TripEvents.WsReconnect;
+  data: WSReconnectData;
+}
+
 interface TripCompletedRequest {
   type: TripEvents.Completed;
   data: {
@@ -96,13 +145,6 @@ interface DriverTripRequest {
   data: Trip;
 }
 
-export interface PaymentEventSessionCreatedData {
-  tripID: string;
-  sessionID: string;
-  amount: number;
-  currency: string;
-}
-
 interface PaymentSessionCreatedRequest {
   type: TripEvents.PaymentSessionCreated;
   data: PaymentEventSessionCreatedData;
@@ -120,7 +162,12 @@ interface DriverLocationRequest {
 
 interface DriverEventLocationRequest {
   type: TripEvents.DriverEventLocation;
-  data: Coordinate;
+  data: DriverLocationEventData;
+}
+
+interface DriverETAChangedRequest {
+  type: TripEvents.DriverEventETAChanged;
+  data: DriverETAChangedData;
 }
 
 export interface ChatMessageData {
@@ -129,6 +176,17 @@ export interface ChatMessageData {
   text: string;
   sentAt: number;
   messageID?: string;
+  attachmentID?: string;
+}
+
+interface TripGeofenceRequest {
+  type:
+    | TripEvents.DriverNearby
+    | TripEvents.DriverAtPickup
+    | TripEvents.DriverAtDropoff
+    | TripEvents.DriverZoneEntered
+    | TripEvents.DriverZoneExited;
+  data: TripGeofenceData;
 }
 
 interface ChatMessageReceivedRequest {
@@ -138,34 +196,69 @@ interface ChatMessageReceivedRequest {
 
 interface ChatMessageSendRequest {
   type: TripEvents.ChatMessageSend;
-  data: {
+  data: WSChatMessageSendData;
+}
+
+// Read receipts and typing frames use the same type in both directions;
+// ws-gateway fills in readerID/readAt and userID before broadcasting them.
+interface ChatMessageReadMessage {
+  type: TripEvents.ChatMessageRead;
+  data: WSChatMessageReadData;
+}
+
+interface ChatTypingMessage {
+  type: TripEvents.ChatTyping;
+  data: WSChatTypingData;
+}
+
+export interface ChatReadState {
+  userID: string;
+  messageID: string;
+  sentAt: number;
+  readAt: number;
+}
+
+// POST /chat/attachments?tripID= (multipart "file") and
+// GET /chat/attachments/url?tripID=&attachmentID= — url is relative to API_URL
+// and only works for the user it was issued to until expiresAt.
+export interface HTTPChatAttachmentResponse {
+  attachmentID: string;
+  tripID: string;
+  contentType?: string;
+  size?: number;
+  url: string;
+  expiresAt: number;
+}
+
+// GET /chat/history?tripID=&before=&limit= — messages are newest first.
+export interface HTTPChatHistoryResponse {
+  messages?: {
+    id: string;
     tripID: string;
+    senderID: string;
     text: string;
-    messageID?: string;
-  };
+    sentAt: number;
+    delivered?: boolean;
+    flags?: string[]; // moderation flags, e.g. "contact"
+    attachmentID?: string;
+  }[];
+  nextCursor?: string;
+  readStates?: ChatReadState[];
 }
 
 interface DriverResponseToTripResponse {
   type: TripEvents.DriverTripAccept | TripEvents.DriverTripDecline;
-  data: {
-    tripID: string;
-    riderID: string;
-  };
+  data: WSDriverTripResponseData;
 }
 
 interface DriverLocationMessage {
   type: TripEvents.DriverLocation;
-  data: {
-    location: Coordinate;
-    geohash: string;
-  };
+  data: WSDriverLocationData;
 }
 
 interface TripCancelRequest {
   type: TripEvents.TripCmdCancel;
-  data: {
-    tripID: string;
-  };
+  data: WSTripRefData;
 }
 
 export interface HTTPTripPreviewResponse {
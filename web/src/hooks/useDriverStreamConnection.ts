@@ -3,6 +3,7 @@ import { WEBSOCKET_URL } from "../constants";
 import { CarPackageSlug } from '../types';
 import { TripEvents, ClientWsMessage, BackendEndpoints } from '../contracts';
 import { parseWsMessage, validateClientMessage } from '../lib/ws/parseWsMessage';
+import { lastEventIDParam, saveLastEventID } from '../lib/ws/eventCursor';
 import { useAppDispatch } from '../store/store';
 import {
   setOwnerUserID,
@@ -10,6 +11,8 @@ import {
   setRequestedTrip,
   setTripStatus,
   addChatMessage,
+  chatMessageRead,
+  setChatTyping,
   completeTrip,
   resetTrip,
 } from '../store/slices/driverSlice';
@@ -123,13 +126,28 @@ export const useDriverStreamConnection = ({
       }, delayMs);
     };
 
+    // A draining gateway node asks its clients to move to another node. The
+    // socket is closed without the usual backoff once the delay has passed.
+    const migrateAfter = (socket: WebSocket, delayMs: number) => {
+      clearReconnectTimer();
+      reconnectTimerRef.current = setTimeout(() => {
+        reconnectTimerRef.current = null;
+        socket.onclose = null;
+        if (wsRef.current === socket) {
+          wsRef.current = null;
+        }
+        socket.close(1000, 'Gateway draining');
+        connect();
+      }, delayMs);
+    };
+
     const connect = () => {
       if (!shouldReconnectRef.current) {
         return;
       }
 
       const websocket = new WebSocket(
-        `${WEBSOCKET_URL}${BackendEndpoints.WS_DRIVERS}?token=${encodeURIComponent(accessToken)}&packageSlug=${encodeURIComponent(packageSlug)}`,
+        `${WEBSOCKET_URL}${BackendEndpoints.WS_DRIVERS}?token=${encodeURIComponent(accessToken)}&packageSlug=${encodeURIComponent(packageSlug)}&${lastEventIDParam(userID)}`,
       );
       wsRef.current = websocket;
 
@@ -152,6 +170,10 @@ export const useDriverStreamConnection = ({
           return;
         }
 
+        if (result.id) {
+          saveLastEventID(userID, result.id);
+        }
+
         const message = result.message;
 
         switch (message.type) {
@@ -171,6 +193,12 @@ export const useDriverStreamConnection = ({
           case TripEvents.ChatMessageReceived:
             dispatch(addChatMessage(message.data));
             break;
+          case TripEvents.ChatMessageRead:
+            dispatch(chatMessageRead(message.data));
+            break;
+          case TripEvents.ChatTyping:
+            dispatch(setChatTyping(message.data));
+            break;
           case TripEvents.Cancelled: {
             const cancelledTripID = message.data?.tripID;
             if (cancelledTripID) {
@@ -194,6 +222,12 @@ export const useDriverStreamConnection = ({
             dispatch(completeTrip());
             break;
           }
+          case TripEvents.WsError:
+            emitError(message.data.message);
+            break;
+          case TripEvents.WsReconnect:
+            migrateAfter(websocket, message.data.delayMs);
+            break;
         }
       };
 
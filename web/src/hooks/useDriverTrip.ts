@@ -48,7 +48,12 @@ export function useDriverTrip(packageSlug: CarPackageSlug) {
         const gh = Geohash.encode(coord.latitude, coord.longitude, 7);
         sendMessage({
           type: TripEvents.DriverLocation,
-          data: { location: coord, geohash: gh },
+          data: {
+            location: coord,
+            geohash: gh,
+            timestamp: Math.round(pos.timestamp),
+            accuracy: pos.coords.accuracy,
+          },
         });
       },
       () => {
@@ -69,7 +74,7 @@ export function useDriverTrip(packageSlug: CarPackageSlug) {
       const gh = Geohash.encode(driverLocation.latitude, driverLocation.longitude, 7);
       sendMessage({
         type: TripEvents.DriverLocation,
-        data: { location: driverLocation, geohash: gh },
+        data: { location: driverLocation, geohash: gh, timestamp: Date.now() },
       });
     };
 
@@ -2,6 +2,7 @@ import { useCallback, useEffect, useRef } from 'react';
 import { WEBSOCKET_URL } from "../constants";
 import { TripEvents, BackendEndpoints, ClientWsMessage } from '../contracts';
 import { parseWsMessage, validateClientMessage } from '../lib/ws/parseWsMessage';
+import { lastEventIDParam, saveLastEventID } from '../lib/ws/eventCursor';
 import { useAppDispatch } from '../store/store';
 import {
   setOwnerUserID,
@@ -10,7 +11,13 @@ import {
   setPaymentSession,
   setAssignedDriver,
   setAssignedDriverLocation,
+  setDriverETA,
+  setDriverProximity,
+  driverEnteredZone,
+  driverExitedZone,
   addChatMessage,
+  chatMessageRead,
+  setChatTyping,
   completeTrip,
   resetTrip,
 } from '../store/slices/riderSlice';
@@ -121,12 +128,27 @@ export function useRiderStreamConnection(userID: string, accessToken: string) {
       }, delayMs);
     };
 
+    // A draining gateway node asks its clients to move to another node. The
+    // socket is closed without the usual backoff once the delay has passed.
+    const migrateAfter = (socket: WebSocket, delayMs: number) => {
+      clearReconnectTimer();
+      reconnectTimerRef.current = setTimeout(() => {
+        reconnectTimerRef.current = null;
+        socket.onclose = null;
+        if (wsRef.current === socket) {
+          wsRef.current = null;
+        }
+        socket.close(1000, 'Gateway draining');
+        connect();
+      }, delayMs);
+    };
+
     const connect = () => {
       if (!shouldReconnectRef.current) {
         return;
       }
 
-      const ws = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_RIDERS}?token=${encodeURIComponent(accessToken)}`);
+      const ws = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_RIDERS}?token=${encodeURIComponent(accessToken)}&${lastEventIDParam(userID)}`);
       wsRef.current = ws;
 
       ws.onopen = () => {
@@ -147,6 +169,10 @@ export function useRiderStreamConnection(userID: string, accessToken: string) {
           return;
         }
 
+        if (result.id) {
+          saveLastEventID(userID, result.id);
+        }
+
         const message = result.message;
 
         switch (message.type) {
@@ -154,7 +180,27 @@ export function useRiderStreamConnection(userID: string, accessToken: string) {
             dispatch(setDrivers(message.data));
             break;
           case TripEvents.DriverEventLocation:
-            dispatch(setAssignedDriverLocation(message.data));
+            dispatch(setAssignedDriverLocation({
+              latitude: message.data.latitude,
+              longitude: message.data.longitude,
+            }));
+            if (message.data.eta) {
+              dispatch(setDriverETA(message.data.eta));
+            }
+            break;
+          case TripEvents.DriverEventETAChanged:
+            dispatch(setDriverETA(message.data.eta));
+            break;
+          case TripEvents.DriverNearby:
+          case TripEvents.DriverAtPickup:
+          case TripEvents.DriverAtDropoff:
+            dispatch(setDriverProximity(message.type));
+            break;
+          case TripEvents.DriverZoneEntered:
+            dispatch(driverEnteredZone(message.data));
+            break;
+          case TripEvents.DriverZoneExited:
+            dispatch(driverExitedZone(message.data.fence));
             break;
           case TripEvents.PaymentSessionCreated:
             dispatch(setPaymentSession(message.data));
@@ -185,6 +231,12 @@ export function useRiderStreamConnection(userID: string, accessToken: string) {
           case TripEvents.ChatMessageReceived:
             dispatch(addChatMessage(message.data));
             break;
+          case TripEvents.ChatMessageRead:
+            dispatch(chatMessageRead(message.data));
+            break;
+          case TripEvents.ChatTyping:
+            dispatch(setChatTyping(message.data));
+            break;
           case TripEvents.Cancelled: {
             dispatch(resetTrip());
             dispatch(setTripStatus(TripEvents.Cancelled));
@@ -208,6 +260,12 @@ export function useRiderStreamConnection(userID: string, accessToken: string) {
             dispatch(completeTrip());
             break;
           }
+          case TripEvents.WsError:
+            emitError(message.data.message);
+            break;
+          case TripEvents.WsReconnect:
+            migrateAfter(ws, message.data.delayMs);
+            break;
         }
       };
 
@@ -4,8 +4,8 @@ import { CarPackageSlug } from '../../types';
 // ─── Primitives ───────────────────────────────────────────────────────────────
 
 export const CoordinateSchema = z.object({
-  latitude: z.number(),
-  longitude: z.number(),
+  latitude: z.number().min(-90).max(90),
+  longitude: z.number().min(-180).max(180),
 });
 
 // ─── Driver ───────────────────────────────────────────────────────────────────
@@ -62,26 +62,3 @@ export const TripSchema = z.object({
   route: RouteSchema.optional(),
   driver: TripDriverSchema.optional(),
 });
-
-// ─── Payment ──────────────────────────────────────────────────────────────────
-
-export const PaymentSessionDataSchema = z.object({
-  tripID: z.string().min(1),
-  sessionID: z.string().min(1),
-  amount: z.number(),
-  currency: z.string().min(1),
-});
-
-export type PaymentSessionData = z.infer<typeof PaymentSessionDataSchema>;
-
-// ─── Chat ─────────────────────────────────────────────────────────────────────
-
-export const ChatMessageDataSchema = z.object({
-  tripID: z.string().min(1),
-  senderID: z.string().min(1),
-  text: z.string().min(1),
-  sentAt: z.number(),
-  messageID: z.string().optional(),
-});
-
-export type ChatMessageData = z.infer<typeof ChatMessageDataSchema>;
@@ -0,0 +1,419 @@
+// Code generated by schemagen from shared/schemas/events.schema.json. DO NOT EDIT.
+import { z } from 'zod';
+import { CoordinateSchema, TripSchema } from './domain.schemas';
+
+/**
+ * TripEventData is the payload of every trip event that carries the trip
+ * itself.
+ */
+export const TripEventDataSchema = z.object({
+  trip: TripSchema,
+  pickupLat: z.number().min(-90).max(90).optional(),
+  pickupLng: z.number().min(-180).max(180).optional(),
+});
+export type TripEventData = z.infer<typeof TripEventDataSchema>;
+
+/**
+ * DriverTripResponseData is the driver's answer to a trip offer, enriched by
+ * ws-gateway with the driver's identity.
+ */
+export const DriverTripResponseDataSchema = z.object({
+  tripID: z.string().min(1),
+  riderID: z.string().min(1),
+  driverID: z.string().min(1),
+  driverName: z.string().optional(),
+  packageSlug: z.string().optional(),
+});
+export type DriverTripResponseData = z.infer<typeof DriverTripResponseDataSchema>;
+
+/** PaymentEventSessionCreatedData tells the rider which checkout session to pay. */
+export const PaymentEventSessionCreatedDataSchema = z.object({
+  tripID: z.string().min(1),
+  sessionID: z.string().min(1),
+  amount: z.number().min(0),
+  currency: z.string().min(1),
+});
+export type PaymentEventSessionCreatedData = z.infer<typeof PaymentEventSessionCreatedDataSchema>;
+
+/**
+ * PaymentTripResponseData asks payment-service to open a checkout session for
+ * an accepted trip.
+ */
+export const PaymentTripResponseDataSchema = z.object({
+  tripID: z.string().min(1),
+  userID: z.string().min(1),
+  driverID: z.string().min(1),
+  amount: z.number().min(0),
+  currency: z.string().min(1),
+});
+export type PaymentTripResponseData = z.infer<typeof PaymentTripResponseDataSchema>;
+
+/** PaymentStatusUpdateData reports a successful payment from the Stripe webhook. */
+export const PaymentStatusUpdateDataSchema = z.object({
+  tripID: z.string().min(1),
+  userID: z.string().optional(),
+  driverID: z.string().optional(),
+});
+export type PaymentStatusUpdateData = z.infer<typeof PaymentStatusUpdateDataSchema>;
+
+/**
+ * DriverLocationUpdateData is a driver position forwarded by ws-gateway to
+ * driver-service.
+ */
+export const DriverLocationUpdateDataSchema = z.object({
+  packageSlug: z.string().optional(),
+  latitude: z.number().min(-90).max(90),
+  longitude: z.number().min(-180).max(180),
+  timestamp: z.number().int().min(0).optional(),
+  accuracy: z.number().min(0).optional(),
+});
+export type DriverLocationUpdateData = z.infer<typeof DriverLocationUpdateDataSchema>;
+
+/**
+ * RouteETA is the driver's estimated time and route distance to the pickup, or
+ * to the destination once the rider is picked up.
+ */
+export const RouteETASchema = z.object({
+  target: z.string().min(1),
+  etaSeconds: z.number().int().min(0),
+  distanceMeters: z.number().min(0),
+});
+export type RouteETA = z.infer<typeof RouteETASchema>;
+
+/** DriverLocationEventData is the assigned driver's position sent to the rider. */
+export const DriverLocationEventDataSchema = z.object({
+  tripID: z.string().optional(),
+  latitude: z.number().min(-90).max(90),
+  longitude: z.number().min(-180).max(180),
+  bearing: z.number().min(0).max(360).optional(),
+  speed: z.number().min(0).optional(),
+  eta: RouteETASchema.optional(),
+});
+export type DriverLocationEventData = z.infer<typeof DriverLocationEventDataSchema>;
+
+/**
+ * DriverETAChangedData is sent to the rider when the ETA moves by more than
+ * driver-service's threshold since the last one announced, and when its target
+ * changes.
+ */
+export const DriverETAChangedDataSchema = z.object({
+  tripID: z.string().min(1),
+  eta: RouteETASchema,
+  previousEtaSeconds: z.number().int().min(0).optional(),
+});
+export type DriverETAChangedData = z.infer<typeof DriverETAChangedDataSchema>;
+
+/** TracePoint is one position of a driver's trace. */
+export const TracePointSchema = z.object({
+  latitude: z.number().min(-90).max(90),
+  longitude: z.number().min(-180).max(180),
+  at: z.number().int().min(0),
+  speed: z.number().min(0).optional(),
+});
+export type TracePoint = z.infer<typeof TracePointSchema>;
+
+/**
+ * DriverTraceData is a batch of the down-sampled positions of a driver on a
+ * trip, sent by driver-service for trip-service to store.
+ */
+export const DriverTraceDataSchema = z.object({
+  tripID: z.string().min(1),
+  driverID: z.string().min(1),
+  points: z.array(TracePointSchema).min(1).max(500),
+});
+export type DriverTraceData = z.infer<typeof DriverTraceDataSchema>;
+
+/**
+ * TripGeofenceData is sent to the rider and trip-service when the driver of a
+ * trip crosses one of its geofences: the circles around its pickup and dropoff,
+ * or a named zone.
+ */
+export const TripGeofenceDataSchema = z.object({
+  tripID: z.string().min(1),
+  driverID: z.string().min(1),
+  fence: z.string().min(1),
+  zoneName: z.string().optional(),
+  zoneKind: z.string().optional(),
+  latitude: z.number().min(-90).max(90),
+  longitude: z.number().min(-180).max(180),
+  distanceMeters: z.number().min(0).optional(),
+  at: z.number().int().min(0),
+});
+export type TripGeofenceData = z.infer<typeof TripGeofenceDataSchema>;
+
+/** TripCompletedData is published once per participant when a trip is paid. */
+export const TripCompletedDataSchema = z.object({
+  tripID: z.string().min(1),
+});
+export type TripCompletedData = z.infer<typeof TripCompletedDataSchema>;
+
+/**
+ * TripCancelledData is the payload published on trip.event.cancelled. It
+ * carries both riderID and driverID so the ws-gateway cancel consumer can
+ * fan-out to both parties and clean up Redis state in one shot.
+ */
+export const TripCancelledDataSchema = z.object({
+  tripID: z.string().min(1),
+  riderID: z.string().min(1),
+  driverID: z.string().optional(),
+  driverAccepted: z.boolean().optional(),
+  cancelledBy: z.string().optional(),
+  reason: z.string().optional(),
+});
+export type TripCancelledData = z.infer<typeof TripCancelledDataSchema>;
+
+/**
+ * PaymentVoidSessionData asks payment-service to expire the unpaid checkout
+ * session of a trip.
+ */
+export const PaymentVoidSessionDataSchema = z.object({
+  tripID: z.string().min(1),
+  sessionID: z.string().optional(),
+  reason: z.string().optional(),
+});
+export type PaymentVoidSessionData = z.infer<typeof PaymentVoidSessionDataSchema>;
+
+/**
+ * DriverReleaseData asks driver-service to drop the pairing of a driver with a
+ * trip that will not be completed.
+ */
+export const DriverReleaseDataSchema = z.object({
+  tripID: z.string().min(1),
+  driverID: z.string().min(1),
+  riderID: z.string().min(1),
+  reason: z.string().optional(),
+});
+export type DriverReleaseData = z.infer<typeof DriverReleaseDataSchema>;
+
+/**
+ * DriverPresenceChangedData is published by driver-service when a driver goes
+ * offline, either on disconnect or because their heartbeats stopped.
+ * driver-service removes the driver from the GEO index when it consumes it.
+ */
+export const DriverPresenceChangedDataSchema = z.object({
+  driverID: z.string().min(1),
+  state: z.string().min(1),
+  previousState: z.string().min(1),
+  packageSlug: z.string().optional(),
+  lastSeen: z.number().int().optional(),
+  reason: z.string().optional(),
+});
+export type DriverPresenceChangedData = z.infer<typeof DriverPresenceChangedDataSchema>;
+
+/**
+ * ChatMessageData is the payload published to ChatCmdSendQueue by ws-gateway
+ * and consumed by chat-service for persistence and delivery acknowledgement.
+ */
+export const ChatMessageDataSchema = z.object({
+  messageID: z.string().min(1),
+  tripID: z.string().min(1),
+  senderID: z.string().min(1),
+  text: z.string().optional(),
+  attachmentID: z.string().optional(),
+  sentAt: z.number().int(),
+  flags: z.array(z.string()).max(16).optional(),
+  blocked: z.boolean().optional(),
+});
+export type ChatMessageData = z.infer<typeof ChatMessageDataSchema>;
+
+/**
+ * ChatFlaggedData is the support event chat-service publishes once it stored a
+ * message moderation flagged. The text stays in chat-service.
+ */
+export const ChatFlaggedDataSchema = z.object({
+  tripID: z.string().min(1),
+  messageID: z.string().min(1),
+  senderID: z.string().min(1),
+  flags: z.array(z.string()).min(1).max(16),
+  blocked: z.boolean().optional(),
+  sentAt: z.number().int(),
+});
+export type ChatFlaggedData = z.infer<typeof ChatFlaggedDataSchema>;
+
+/**
+ * ChatDeliveredData is the payload published by chat-service once a message has
+ * been persisted, allowing ws-gateway to emit a delivery receipt.
+ */
+export const ChatDeliveredDataSchema = z.object({
+  messageID: z.string().min(1),
+  tripID: z.string().min(1),
+});
+export type ChatDeliveredData = z.infer<typeof ChatDeliveredDataSchema>;
+
+/**
+ * ChatReadData is the payload published to ChatCmdReadQueue by ws-gateway when
+ * a participant has read a trip chat up to a message.
+ */
+export const ChatReadDataSchema = z.object({
+  tripID: z.string().min(1),
+  readerID: z.string().min(1),
+  messageID: z.string().min(1),
+  readAt: z.number().int(),
+});
+export type ChatReadData = z.infer<typeof ChatReadDataSchema>;
+
+/** WSTopicControlData is the payload for legacy subscribe/unsubscribe frames. */
+export const WSTopicControlDataSchema = z.object({
+  topic: z.string().min(1).max(128),
+});
+export type WSTopicControlData = z.infer<typeof WSTopicControlDataSchema>;
+
+/** WSRoomControlData is the payload for ws.room.join / ws.room.leave frames. */
+export const WSRoomControlDataSchema = z.object({
+  roomID: z.string().min(1).max(128),
+});
+export type WSRoomControlData = z.infer<typeof WSRoomControlDataSchema>;
+
+/** WSDriverLocationData is the position a driver's client reports. */
+export const WSDriverLocationDataSchema = z.object({
+  location: CoordinateSchema,
+  geohash: z.string().max(12).optional(),
+  timestamp: z.number().int().min(0).optional(),
+  accuracy: z.number().min(0).optional(),
+});
+export type WSDriverLocationData = z.infer<typeof WSDriverLocationDataSchema>;
+
+/** WSDriverTripResponseData is a driver's accept or decline of a trip offer. */
+export const WSDriverTripResponseDataSchema = z.object({
+  tripID: z.string().min(1).max(64),
+  riderID: z.string().min(1).max(64),
+});
+export type WSDriverTripResponseData = z.infer<typeof WSDriverTripResponseDataSchema>;
+
+/** WSChatMessageSendData is the payload the client sends with chat.message.send. */
+export const WSChatMessageSendDataSchema = z.object({
+  tripID: z.string().min(1).max(64),
+  messageID: z.string().max(64).optional(),
+  text: z.string().max(1000).optional(),
+  attachmentID: z.string().max(64).optional(),
+});
+export type WSChatMessageSendData = z.infer<typeof WSChatMessageSendDataSchema>;
+
+/** WSChatMessageReceivedData is the payload broadcast to chat room members. */
+export const WSChatMessageReceivedDataSchema = z.object({
+  tripID: z.string().min(1),
+  roomID: z.string().min(1),
+  senderID: z.string().min(1),
+  messageID: z.string().optional(),
+  text: z.string().optional(),
+  attachmentID: z.string().optional(),
+  sentAt: z.number().int(),
+});
+export type WSChatMessageReceivedData = z.infer<typeof WSChatMessageReceivedDataSchema>;
+
+/**
+ * WSChatMessageReadData is a read receipt. The client sends it with the last
+ * message it showed; ws-gateway fills in the reader and broadcasts it to the
+ * chat room.
+ */
+export const WSChatMessageReadDataSchema = z.object({
+  tripID: z.string().min(1).max(64),
+  messageID: z.string().min(1).max(64),
+  readerID: z.string().optional(),
+  readAt: z.number().int().optional(),
+});
+export type WSChatMessageReadData = z.infer<typeof WSChatMessageReadDataSchema>;
+
+/**
+ * WSChatTypingData tells the chat room a participant started or stopped typing.
+ * It is relayed but never stored.
+ */
+export const WSChatTypingDataSchema = z.object({
+  tripID: z.string().min(1).max(64),
+  typing: z.boolean(),
+  userID: z.string().optional(),
+});
+export type WSChatTypingData = z.infer<typeof WSChatTypingDataSchema>;
+
+/** WSTripRefData identifies the trip a lifecycle frame refers to. */
+export const WSTripRefDataSchema = z.object({
+  tripID: z.string().min(1),
+});
+export type WSTripRefData = z.infer<typeof WSTripRefDataSchema>;
+
+/**
+ * WSErrorData is the payload of the ws.error frame the gateway sends when it
+ * rejects a client frame.
+ */
+export const WSErrorDataSchema = z.object({
+  code: z.string().min(1),
+  message: z.string().min(1),
+  roomID: z.string().optional(),
+  messageID: z.string().optional(),
+  frameType: z.string().optional(),
+});
+export type WSErrorData = z.infer<typeof WSErrorDataSchema>;
+
+/**
+ * WSReconnectData is the payload of the ws.reconnect frame a draining gateway
+ * node sends before it shuts down. The client should reconnect after delayMs,
+ * resuming from its lastEventID.
+ */
+export const WSReconnectDataSchema = z.object({
+  delayMs: z.number().int().min(0),
+  reason: z.string().min(1),
+});
+export type WSReconnectData = z.infer<typeof WSReconnectDataSchema>;
+
+/** Data schema for every AMQP routing key. */
+export const AmqpPayloadSchemas = {
+  'trip.event.created': TripEventDataSchema,
+  'trip.event.driver_assigned': TripEventDataSchema,
+  'trip.event.driver_not_interested': TripEventDataSchema,
+  'trip.event.no_drivers_found': TripEventDataSchema,
+  'driver.cmd.trip_request': TripEventDataSchema,
+  'driver.cmd.trip_accept': DriverTripResponseDataSchema,
+  'driver.cmd.trip_decline': DriverTripResponseDataSchema,
+  'payment.event.session_created': PaymentEventSessionCreatedDataSchema,
+  'payment.cmd.create_session': PaymentTripResponseDataSchema,
+  'payment.event.success': PaymentStatusUpdateDataSchema,
+  'driver.cmd.location': DriverLocationUpdateDataSchema,
+  'driver.event.location': DriverLocationEventDataSchema,
+  'driver.event.eta_changed': DriverETAChangedDataSchema,
+  'driver.event.trace': DriverTraceDataSchema,
+  'trip.event.driver_nearby': TripGeofenceDataSchema,
+  'trip.event.driver_at_pickup': TripGeofenceDataSchema,
+  'trip.event.driver_at_dropoff': TripGeofenceDataSchema,
+  'trip.event.driver_zone_entered': TripGeofenceDataSchema,
+  'trip.event.driver_zone_exited': TripGeofenceDataSchema,
+  'trip.event.completed': TripCompletedDataSchema,
+  'trip.event.cancelled': TripCancelledDataSchema,
+  'payment.cmd.void_session': PaymentVoidSessionDataSchema,
+  'driver.cmd.release': DriverReleaseDataSchema,
+  'driver.event.presence_changed': DriverPresenceChangedDataSchema,
+  'chat.cmd.send': ChatMessageDataSchema,
+  'trip.event.chat_flagged': ChatFlaggedDataSchema,
+  'chat.event.delivered': ChatDeliveredDataSchema,
+  'chat.cmd.read': ChatReadDataSchema,
+} as const;
+
+/** Data schema for every WebSocket frame type. */
+export const WsDataSchemas = {
+  'trip.event.created': TripSchema,
+  'driver.cmd.trip_request': TripSchema,
+  'trip.event.driver_assigned': TripSchema,
+  'payment.event.session_created': PaymentEventSessionCreatedDataSchema,
+  'driver.event.location': DriverLocationEventDataSchema,
+  'driver.event.eta_changed': DriverETAChangedDataSchema,
+  'trip.event.driver_nearby': TripGeofenceDataSchema,
+  'trip.event.driver_at_pickup': TripGeofenceDataSchema,
+  'trip.event.driver_at_dropoff': TripGeofenceDataSchema,
+  'trip.event.driver_zone_entered': TripGeofenceDataSchema,
+  'trip.event.driver_zone_exited': TripGeofenceDataSchema,
+  'ws.topic.subscribe': WSTopicControlDataSchema,
+  'ws.topic.unsubscribe': WSTopicControlDataSchema,
+  'ws.room.join': WSRoomControlDataSchema,
+  'ws.room.leave': WSRoomControlDataSchema,
+  'driver.cmd.location': WSDriverLocationDataSchema,
+  'driver.cmd.trip_accept': WSDriverTripResponseDataSchema,
+  'driver.cmd.trip_decline': WSDriverTripResponseDataSchema,
+  'chat.message.send': WSChatMessageSendDataSchema,
+  'chat.message.received': WSChatMessageReceivedDataSchema,
+  'chat.message.read': WSChatMessageReadDataSchema,
+  'chat.typing': WSChatTypingDataSchema,
+  'trip.event.completed': WSTripRefDataSchema,
+  'trip.event.cancelled': WSTripRefDataSchema,
+  'trip.cmd.cancel': WSTripRefDataSchema,
+  'ws.error': WSErrorDataSchema,
+  'ws.reconnect': WSReconnectDataSchema,
+} as const;
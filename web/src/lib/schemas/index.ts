@@ -8,6 +8,7 @@
  *   import { ServerWsMessageSchema, AuthUserSchema } from '@/lib/schemas';
  */
 export * from './domain.schemas';
+export * from './generated';
 export * from './ws.server.schemas';
 export * from './ws.client.schemas';
 export * from './http.schemas';
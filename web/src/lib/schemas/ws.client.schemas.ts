@@ -3,63 +3,69 @@
  *
  * Validating outgoing payloads catches shape mistakes at the send site
  * before they hit the wire, giving clearer errors than a silent drop.
+ * Frame data schemas are generated from shared/schemas/events.schema.json.
  */
 import { z } from 'zod';
 import { TripEvents } from '../../contracts';
-import { CoordinateSchema } from './domain.schemas';
+import {
+  WSChatMessageReadDataSchema,
+  WSChatMessageSendDataSchema,
+  WSChatTypingDataSchema,
+  WSDriverLocationDataSchema,
+  WSDriverTripResponseDataSchema,
+  WSTopicControlDataSchema,
+  WSTripRefDataSchema,
+} from './generated';
 
 // ─── Topic control (legacy + room model) ─────────────────────────────────────
 
 export const WsTopicSubscribeSchema = z.object({
   type: z.literal(TripEvents.WsTopicSubscribe),
-  data: z.object({ topic: z.string().min(1) }),
+  data: WSTopicControlDataSchema,
 });
 
 export const WsTopicUnsubscribeSchema = z.object({
   type: z.literal(TripEvents.WsTopicUnsubscribe),
-  data: z.object({ topic: z.string().min(1) }),
+  data: WSTopicControlDataSchema,
 });
 
 // ─── Driver → server ─────────────────────────────────────────────────────────
 
 export const DriverLocationMessageSchema = z.object({
   type: z.literal(TripEvents.DriverLocation),
-  data: z.object({
-    location: CoordinateSchema,
-    geohash: z.string().min(1),
-  }),
+  data: WSDriverLocationDataSchema,
 });
 
 export const DriverTripAcceptSchema = z.object({
   type: z.literal(TripEvents.DriverTripAccept),
-  data: z.object({
-    tripID: z.string().min(1),
-    riderID: z.string().min(1),
-  }),
+  data: WSDriverTripResponseDataSchema,
 });
 
 export const DriverTripDeclineSchema = z.object({
   type: z.literal(TripEvents.DriverTripDecline),
-  data: z.object({
-    tripID: z.string().min(1),
-    riderID: z.string().min(1),
-  }),
+  data: WSDriverTripResponseDataSchema,
 });
 
 // ─── Rider → server ───────────────────────────────────────────────────────────
 
 export const ChatMessageSendSchema = z.object({
   type: z.literal(TripEvents.ChatMessageSend),
-  data: z.object({
-    tripID: z.string().min(1),
-    text: z.string().min(1),
-    messageID: z.string().optional(),
-  }),
+  data: WSChatMessageSendDataSchema,
+});
+
+export const ChatMessageReadClientSchema = z.object({
+  type: z.literal(TripEvents.ChatMessageRead),
+  data: WSChatMessageReadDataSchema,
+});
+
+export const ChatTypingClientSchema = z.object({
+  type: z.literal(TripEvents.ChatTyping),
+  data: WSChatTypingDataSchema,
 });
 
 export const TripCancelClientSchema = z.object({
   type: z.literal(TripEvents.TripCmdCancel),
-  data: z.object({ tripID: z.string().min(1) }),
+  data: WSTripRefDataSchema,
 });
 
 // ─── Full client message discriminated union ─────────────────────────────────
@@ -72,6 +78,8 @@ export const ClientWsMessageSchema = z.discriminatedUnion('type', [
   DriverTripAcceptSchema,
   DriverTripDeclineSchema,
   ChatMessageSendSchema,
+  ChatMessageReadClientSchema,
+  ChatTypingClientSchema,
   TripCancelClientSchema,
 ]);
 
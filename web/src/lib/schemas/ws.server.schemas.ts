@@ -3,17 +3,24 @@
  *
  * Each event is a discriminated z.object keyed on `type`.
  * The union is tagged so TypeScript narrows cleanly after
- * a safeParse / discriminatedUnion parse.
+ * a safeParse / discriminatedUnion parse. Frame data schemas shared with
+ * the Go services are generated from shared/schemas/events.schema.json.
  */
 import { z } from 'zod';
 import { TripEvents } from '../../contracts';
+import { DriverSchema, TripSchema } from './domain.schemas';
 import {
-  CoordinateSchema,
-  DriverSchema,
-  ChatMessageDataSchema,
-  PaymentSessionDataSchema,
-  TripSchema,
-} from './domain.schemas';
+  DriverETAChangedDataSchema,
+  DriverLocationEventDataSchema,
+  PaymentEventSessionCreatedDataSchema,
+  TripGeofenceDataSchema,
+  WSChatMessageReadDataSchema,
+  WSChatMessageReceivedDataSchema,
+  WSChatTypingDataSchema,
+  WSErrorDataSchema,
+  WSReconnectDataSchema,
+  WSTripRefDataSchema,
+} from './generated';
 
 // ─── Individual event schemas ─────────────────────────────────────────────────
 
@@ -26,7 +33,25 @@ export const DriverLocationEventSchema = z.object({
 export const DriverEventLocationSchema = z.object({
   type: z.literal(TripEvents.DriverEventLocation),
   topic: z.string().optional(),
-  data: CoordinateSchema,
+  data: DriverLocationEventDataSchema,
+});
+
+export const DriverETAChangedSchema = z.object({
+  type: z.literal(TripEvents.DriverEventETAChanged),
+  topic: z.string().optional(),
+  data: DriverETAChangedDataSchema,
+});
+
+export const TripGeofenceSchema = z.object({
+  type: z.enum([
+    TripEvents.DriverNearby,
+    TripEvents.DriverAtPickup,
+    TripEvents.DriverAtDropoff,
+    TripEvents.DriverZoneEntered,
+    TripEvents.DriverZoneExited,
+  ]),
+  topic: z.string().optional(),
+  data: TripGeofenceDataSchema,
 });
 
 export const DriverRegisterSchema = z.object({
@@ -38,11 +63,7 @@ export const DriverRegisterSchema = z.object({
 export const DriverTripRequestSchema = z.object({
   type: z.literal(TripEvents.DriverTripRequest),
   topic: z.string().optional(),
-  // Gateway may nest under `trip` or send flat — handle both
-  data: z.union([
-    TripSchema,
-    z.object({ trip: TripSchema }).transform((d) => d.trip),
-  ]),
+  data: TripSchema,
 });
 
 export const DriverAssignedSchema = z.object({
@@ -54,17 +75,7 @@ export const DriverAssignedSchema = z.object({
 export const TripCreatedSchema = z.object({
   type: z.literal(TripEvents.Created),
   topic: z.string().optional(),
-  // May arrive as { trip: Trip, pickupLat, pickupLng } or flat Trip
-  data: z.union([
-    TripSchema,
-    z
-      .object({
-        trip: TripSchema,
-        pickupLat: z.number().optional(),
-        pickupLng: z.number().optional(),
-      })
-      .transform((d) => d.trip),
-  ]),
+  data: TripSchema,
 });
 
 export const NoDriversFoundSchema = z.object({
@@ -77,25 +88,49 @@ export const TripCancelledSchema = z.object({
   type: z.literal(TripEvents.Cancelled),
   topic: z.string().optional(),
   // data is optional at envelope level but tripID must be a non-empty string when present
-  data: z.object({ tripID: z.string().min(1) }).optional(),
+  data: WSTripRefDataSchema.optional(),
 });
 
 export const TripCompletedSchema = z.object({
-	 type: z.literal(TripEvents.Completed),
-	 topic: z.string().optional(),
-	 data: z.object({ tripID: z.string().min(1) }).optional(),
+  type: z.literal(TripEvents.Completed),
+  topic: z.string().optional(),
+  data: WSTripRefDataSchema.optional(),
 });
 
 export const PaymentSessionCreatedSchema = z.object({
   type: z.literal(TripEvents.PaymentSessionCreated),
   topic: z.string().optional(),
-  data: PaymentSessionDataSchema,
+  data: PaymentEventSessionCreatedDataSchema,
 });
 
 export const ChatMessageReceivedSchema = z.object({
   type: z.literal(TripEvents.ChatMessageReceived),
   topic: z.string().optional(),
-  data: ChatMessageDataSchema,
+  data: WSChatMessageReceivedDataSchema,
+});
+
+export const ChatMessageReadSchema = z.object({
+  type: z.literal(TripEvents.ChatMessageRead),
+  topic: z.string().optional(),
+  data: WSChatMessageReadDataSchema,
+});
+
+export const ChatTypingSchema = z.object({
+  type: z.literal(TripEvents.ChatTyping),
+  topic: z.string().optional(),
+  data: WSChatTypingDataSchema,
+});
+
+export const WsErrorSchema = z.object({
+  type: z.literal(TripEvents.WsError),
+  topic: z.string().optional(),
+  data: WSErrorDataSchema,
+});
+
+export const WsReconnectSchema = z.object({
+  type: z.literal(TripEvents.WsReconnect),
+  topic: z.string().optional(),
+  data: WSReconnectDataSchema,
 });
 
 // ─── Discriminated union ──────────────────────────────────────────────────────
@@ -106,6 +141,8 @@ export const ChatMessageReceivedSchema = z.object({
 export const ServerWsMessageSchema = z.discriminatedUnion('type', [
   DriverLocationEventSchema,
   DriverEventLocationSchema,
+  DriverETAChangedSchema,
+  TripGeofenceSchema,
   DriverRegisterSchema,
   DriverTripRequestSchema,
   DriverAssignedSchema,
@@ -115,6 +152,10 @@ export const ServerWsMessageSchema = z.discriminatedUnion('type', [
   TripCompletedSchema,
   PaymentSessionCreatedSchema,
   ChatMessageReceivedSchema,
+  ChatMessageReadSchema,
+  ChatTypingSchema,
+  WsErrorSchema,
+  WsReconnectSchema,
 ]);
 
 export type ParsedServerWsMessage = z.infer<typeof ServerWsMessageSchema>;
@@ -0,0 +1,37 @@
+/**
+ * eventCursor
+ *
+ * Remembers the stream ID of the last user-direct WebSocket event per user so
+ * a reconnecting (or reloaded) client resumes exactly where it left off:
+ *
+ *   const url = `${base}?token=...&${lastEventIDParam(userID)}`;
+ *   ...
+ *   if (result.id) saveLastEventID(userID, result.id);
+ *
+ * A client that has never received an event asks for the whole retained
+ * stream ("0"), so events sent before its first connection are not lost.
+ */
+
+const storageKey = (userID: string) => `ws:lastEventID:${userID}`;
+
+export function loadLastEventID(userID: string): string {
+  if (typeof window === 'undefined') return '0';
+  try {
+    return window.localStorage.getItem(storageKey(userID)) || '0';
+  } catch {
+    return '0';
+  }
+}
+
+export function saveLastEventID(userID: string, id: string): void {
+  if (typeof window === 'undefined') return;
+  try {
+    window.localStorage.setItem(storageKey(userID), id);
+  } catch {
+    // storage unavailable (private mode, quota) — resume falls back to "0"
+  }
+}
+
+export function lastEventIDParam(userID: string): string {
+  return `lastEventID=${encodeURIComponent(loadLastEventID(userID))}`;
+}
@@ -15,7 +15,9 @@ import { ClientWsMessageSchema, ValidatedClientWsMessage } from '../schemas/ws.c
 
 // ─── Result types ─────────────────────────────────────────────────────────────
 
-export type WsParseSuccess = { ok: true; message: ParsedServerWsMessage };
+// id is the stream ID of a user-direct event; pass the last one seen as
+// lastEventID when reconnecting.
+export type WsParseSuccess = { ok: true; message: ParsedServerWsMessage; id?: string };
 export type WsParseFailure = { ok: false; error: string; raw: unknown };
 export type WsParseResult = WsParseSuccess | WsParseFailure;
 
@@ -52,7 +54,8 @@ export function parseWsMessage(rawFrame: string): WsParseResult {
     };
   }
 
-  return { ok: true, message: result.data };
+  const id = (json as { id?: unknown }).id;
+  return { ok: true, message: result.data, id: typeof id === 'string' ? id : undefined };
 }
 
 // ─── Outbound validation ──────────────────────────────────────────────────────
@@ -4,6 +4,8 @@ import type { AxiosError, AxiosRequestConfig } from 'axios';
 import apiClient from '../../lib/axios';
 import {
   BackendEndpoints,
+  HTTPChatAttachmentResponse,
+  HTTPChatHistoryResponse,
   HTTPTripPreviewRequestPayload,
   HTTPTripPreviewResponse,
   HTTPTripStartRequestPayload,
@@ -15,6 +17,7 @@ interface AxiosBaseQueryArgs {
   method?: AxiosRequestConfig['method'];
   data?: AxiosRequestConfig['data'];
   params?: AxiosRequestConfig['params'];
+  headers?: AxiosRequestConfig['headers'];
 }
 
 const axiosBaseQuery: BaseQueryFn<AxiosBaseQueryArgs, unknown, unknown> = async ({
@@ -22,9 +25,10 @@ const axiosBaseQuery: BaseQueryFn<AxiosBaseQueryArgs, unknown, unknown> = async
   method = 'GET',
   data,
   params,
+  headers,
 }) => {
   try {
-    const result = await apiClient({ url, method, data, params });
+    const result = await apiClient({ url, method, data, params, headers });
     return { data: result.data };
   } catch (axiosError) {
     const err = axiosError as AxiosError;
@@ -62,7 +66,42 @@ export const tripApi = createApi({
         data: payload,
       }),
     }),
+    getChatHistory: builder.query<{ data: HTTPChatHistoryResponse }, { tripID: string; before?: string; limit?: number }>({
+      query: (params) => ({
+        url: BackendEndpoints.CHAT_HISTORY,
+        params,
+      }),
+    }),
+    uploadChatAttachment: builder.mutation<{ data: HTTPChatAttachmentResponse }, { tripID: string; file: File }>({
+      query: ({ tripID, file }) => {
+        const form = new FormData();
+        form.append('file', file);
+        return {
+          url: BackendEndpoints.CHAT_ATTACHMENTS,
+          method: 'POST',
+          params: { tripID },
+          data: form,
+          // Overrides the client's JSON default so axios sends the form as is.
+          headers: { 'Content-Type': 'multipart/form-data' },
+        };
+      },
+    }),
+    getChatAttachmentURL: builder.query<{ data: HTTPChatAttachmentResponse }, { tripID: string; attachmentID: string }>({
+      query: (params) => ({
+        url: BackendEndpoints.CHAT_ATTACHMENT_URL,
+        params,
+      }),
+      // Signed URLs expire; refetch well before the default 15 minutes.
+      keepUnusedDataFor: 300,
+    }),
   }),
 });
 
-export const { usePreviewTripMutation, useStartTripMutation, useCancelTripMutation } = tripApi;
+export const {
+  usePreviewTripMutation,
+  useStartTripMutation,
+  useCancelTripMutation,
+  useLazyGetChatHistoryQuery,
+  useUploadChatAttachmentMutation,
+  useGetChatAttachmentURLQuery,
+} = tripApi;
@@ -1,6 +1,7 @@
 import { createSlice, PayloadAction } from '@reduxjs/toolkit';
 import { Driver, Trip } from '../../types';
 import { ChatMessageData, TripEvents } from '../../contracts';
+import type { WSChatMessageReadData, WSChatTypingData } from '../../lib/schemas/generated';
 
 interface DriverState {
   ownerUserID: string | null;
@@ -8,6 +9,9 @@ interface DriverState {
   requestedTrip: Trip | null;
   tripStatus: TripEvents | null;
   chatMessages: ChatMessageData[];
+  // The last message each participant has read, and who is typing.
+  chatReadBy: Record<string, string>;
+  chatTypingUserIDs: string[];
   error: string | null;
 }
 
@@ -17,6 +21,8 @@ const initialState: DriverState = {
   requestedTrip: null,
   tripStatus: null,
   chatMessages: [],
+  chatReadBy: {},
+  chatTypingUserIDs: [],
   error: null,
 };
 
@@ -39,6 +45,17 @@ const driverSlice = createSlice({
     addChatMessage(state, action: PayloadAction<ChatMessageData>) {
       state.chatMessages.push(action.payload);
     },
+    chatMessageRead(state, action: PayloadAction<WSChatMessageReadData>) {
+      if (action.payload.readerID) {
+        state.chatReadBy[action.payload.readerID] = action.payload.messageID;
+      }
+    },
+    setChatTyping(state, action: PayloadAction<WSChatTypingData>) {
+      const { userID, typing } = action.payload;
+      if (!userID) return;
+      state.chatTypingUserIDs = state.chatTypingUserIDs.filter((id) => id !== userID);
+      if (typing) state.chatTypingUserIDs.push(userID);
+    },
     setError(state, action: PayloadAction<string | null>) {
       state.error = action.payload;
     },
@@ -46,11 +63,15 @@ const driverSlice = createSlice({
       state.tripStatus = TripEvents.Completed;
       state.requestedTrip = null;
       state.chatMessages = [];
+      state.chatReadBy = {};
+      state.chatTypingUserIDs = [];
     },
     resetTrip(state) {
       state.tripStatus = null;
       state.requestedTrip = null;
       state.chatMessages = [];
+      state.chatReadBy = {};
+      state.chatTypingUserIDs = [];
     },
     clearState() {
       return initialState;
@@ -64,6 +85,8 @@ export const {
   setRequestedTrip,
   setTripStatus,
   addChatMessage,
+  chatMessageRead,
+  setChatTyping,
   setError,
   completeTrip,
   resetTrip,
@@ -1,6 +1,7 @@
 import { createSlice, PayloadAction } from '@reduxjs/toolkit';
 import { Driver, TripPreview } from '../../types';
 import { ChatMessageData, PaymentEventSessionCreatedData, TripEvents } from '../../contracts';
+import type { RouteETA, TripGeofenceData, WSChatMessageReadData, WSChatTypingData } from '../../lib/schemas/generated';
 
 import { Coordinate } from '../../types';
 
@@ -11,7 +12,14 @@ interface RiderState {
   paymentSession: PaymentEventSessionCreatedData | null;
   assignedDriver: Driver | null;
   assignedDriverLocation: Coordinate | null;
+  driverETA: RouteETA | null;
+  // The last pickup/dropoff fence the driver crossed, and the zones they are in.
+  driverProximity: TripEvents.DriverNearby | TripEvents.DriverAtPickup | TripEvents.DriverAtDropoff | null;
+  driverZones: TripGeofenceData[];
   chatMessages: ChatMessageData[];
+  // The last message each participant has read, and who is typing.
+  chatReadBy: Record<string, string>;
+  chatTypingUserIDs: string[];
   trip: TripPreview | null;
   destination: [number, number] | null;
   error: string | null;
@@ -24,7 +32,12 @@ const initialState: RiderState = {
   paymentSession: null,
   assignedDriver: null,
   assignedDriverLocation: null,
+  driverETA: null,
+  driverProximity: null,
+  driverZones: [],
   chatMessages: [],
+  chatReadBy: {},
+  chatTypingUserIDs: [],
   trip: null,
   destination: null,
   error: null,
@@ -58,9 +71,33 @@ const riderSlice = createSlice({
     setAssignedDriverLocation(state, action: PayloadAction<Coordinate | null>) {
       state.assignedDriverLocation = action.payload;
     },
+    setDriverETA(state, action: PayloadAction<RouteETA | null>) {
+      state.driverETA = action.payload;
+    },
+    setDriverProximity(state, action: PayloadAction<RiderState['driverProximity']>) {
+      state.driverProximity = action.payload;
+    },
+    driverEnteredZone(state, action: PayloadAction<TripGeofenceData>) {
+      state.driverZones = state.driverZones.filter((zone) => zone.fence !== action.payload.fence);
+      state.driverZones.push(action.payload);
+    },
+    driverExitedZone(state, action: PayloadAction<string>) {
+      state.driverZones = state.driverZones.filter((zone) => zone.fence !== action.payload);
+    },
     addChatMessage(state, action: PayloadAction<ChatMessageData>) {
       state.chatMessages.push(action.payload);
     },
+    chatMessageRead(state, action: PayloadAction<WSChatMessageReadData>) {
+      if (action.payload.readerID) {
+        state.chatReadBy[action.payload.readerID] = action.payload.messageID;
+      }
+    },
+    setChatTyping(state, action: PayloadAction<WSChatTypingData>) {
+      const { userID, typing } = action.payload;
+      if (!userID) return;
+      state.chatTypingUserIDs = state.chatTypingUserIDs.filter((id) => id !== userID);
+      if (typing) state.chatTypingUserIDs.push(userID);
+    },
     setError(state, action: PayloadAction<string | null>) {
       state.error = action.payload;
     },
@@ -71,7 +108,12 @@ const riderSlice = createSlice({
       state.destination = null;
       state.assignedDriver = null;
       state.assignedDriverLocation = null;
+      state.driverETA = null;
+      state.driverProximity = null;
+      state.driverZones = [];
       state.chatMessages = [];
+      state.chatReadBy = {};
+      state.chatTypingUserIDs = [];
       state.drivers = [];
     },
     resetTrip(state) {
@@ -81,7 +123,12 @@ const riderSlice = createSlice({
       state.destination = null;
       state.assignedDriver = null;
       state.assignedDriverLocation = null;
+      state.driverETA = null;
+      state.driverProximity = null;
+      state.driverZones = [];
       state.chatMessages = [];
+      state.chatReadBy = {};
+      state.chatTypingUserIDs = [];
     },
     clearState() {
       return initialState;
@@ -96,7 +143,13 @@ export const {
   setPaymentSession,
   setAssignedDriver,
   setAssignedDriverLocation,
+  setDriverETA,
+  setDriverProximity,
+  driverEnteredZone,
+  driverExitedZone,
   addChatMessage,
+  chatMessageRead,
+  setChatTyping,
   setTrip,
   setDestination,
   setError,